@@ -0,0 +1,159 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role determines which endpoints a token may use. Each role may also do
+// everything the roles before it can.
+type Role int
+
+const (
+	rolePublic  Role = iota // No token needed, only for health checks
+	RoleRead                // View state, e.g. a wall-mounted dashboard
+	RoleControl             // Switch and dim devices
+	RoleAdmin               // Pair, unpair and reconfigure
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleControl:
+		return "control"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+// ParseRole is the inverse of Role.String
+func ParseRole(s string) (Role, error) {
+	for _, r := range []Role{RoleRead, RoleControl, RoleAdmin} {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role: %q", s)
+}
+
+// Token is what a bearer token permits, and who it was issued to
+type Token struct {
+	Role Role
+	Name string // Attributes commands, e.g. "home-assistant". Optional.
+}
+
+// source returns what commands made with the token are attributed to, see
+// lwl.WithSource. Unnamed tokens are identified by a digest, which does not
+// reveal the token.
+func (t Token) source(token string) string {
+	if t.Name != "" {
+		return "http:" + t.Name
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "http:token-" + hex.EncodeToString(sum[:4])
+}
+
+// UnmarshalYAML accepts either a role, or a mapping of role and name
+func (t *Token) UnmarshalYAML(n *yaml.Node) error {
+	var raw struct {
+		Role string `yaml:"role"`
+		Name string `yaml:"name"`
+	}
+	if n.Kind == yaml.ScalarNode {
+		raw.Role = n.Value
+	} else if err := n.Decode(&raw); err != nil {
+		return err
+	}
+	role, err := ParseRole(raw.Role)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	*t = Token{Role: role, Name: raw.Name}
+	return nil
+}
+
+// LoadTokens reads a YAML file mapping bearer tokens to roles, optionally
+// with the name of whoever holds it, e.g.
+//
+//	"6b1f0c...": read
+//	"a93e77...": {role: admin, name: home-assistant}
+func LoadTokens(fn string) (map[string]Token, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]Token
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return out, nil
+}
+
+// Routes for clients which only support basic authentication, such as
+// OwnTracks, on which the password is the token and the username ignored.
+// Elsewhere tokens are only accepted as bearer tokens, so that a browser
+// cannot be tricked into sending one it has cached.
+var basicAuthRoutes = map[string]bool{
+	"POST /presence": true,
+}
+
+// require wraps a handler so that it is only called for requests bearing a
+// token with at least the given role, or from a local process with it, see
+// ServeUnix. Commands made by the handler are attributed to the token or
+// process.
+func (s *Server) require(role Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := r.Context().Value(peerKey{}).(peer); ok {
+			have, known := s.peerRole(p)
+			switch {
+			case p.err != nil:
+				writeError(w, http.StatusForbidden, fmt.Errorf("unable to identify client: %w", p.err))
+			case !known:
+				writeError(w, http.StatusForbidden, fmt.Errorf("uid %d may not use the API", p.uid))
+			case have < role:
+				writeError(w, http.StatusForbidden, fmt.Errorf("requires %v role, uid %d has %v", role, p.uid, have))
+			default:
+				h(w, r.WithContext(lwl.WithSource(r.Context(), p.source())))
+			}
+			return
+		}
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found && basicAuthRoutes[r.Pattern] {
+			_, token, found = r.BasicAuth()
+		}
+		have, known := s.lookup(token)
+		switch {
+		case !found || !known:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or unknown bearer token"))
+		case have.Role < role:
+			writeError(w, http.StatusForbidden, fmt.Errorf("requires %v role, token has %v", role, have.Role))
+		default:
+			h(w, r.WithContext(lwl.WithSource(r.Context(), have.source(token))))
+		}
+	}
+}
+
+// lookup returns a token's role and name, comparing in constant time so that
+// response timing does not reveal partial matches
+func (s *Server) lookup(token string) (Token, bool) {
+	var out Token
+	for t, tok := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			out = tok
+		}
+	}
+	return out, out.Role != 0
+}
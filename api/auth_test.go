@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestRequire(t *testing.T) {
+	reg := lwl.NewRegistry(nil)
+	reg.Device("R1D1")
+	s := New(nil, reg, map[string]Token{
+		"r": {Role: RoleRead},
+		"c": {Role: RoleControl},
+		"a": {Role: RoleAdmin},
+	})
+	h := s.Handler()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{name: "NoToken", method: "GET", path: "/devices", want: http.StatusUnauthorized},
+		{name: "UnknownToken", method: "GET", path: "/devices", token: "x", want: http.StatusUnauthorized},
+		{name: "Read", method: "GET", path: "/devices", token: "r", want: http.StatusOK},
+		{name: "AdminCanRead", method: "GET", path: "/devices/R1D1", token: "a", want: http.StatusOK},
+		{name: "ReadCannotControl", method: "POST", path: "/devices/R1D1/on", token: "r", want: http.StatusForbidden},
+		{name: "ControlCannotLock", method: "POST", path: "/devices/R1D1/lock/full", token: "c", want: http.StatusForbidden},
+		{name: "ControlCannotUnpair", method: "POST", path: "/hub/unpair", token: "c", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("want %d got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "tokens.yaml")
+	os.WriteFile(fn, []byte(`
+"6b1f0c": read
+"a93e77": {role: admin, name: home-assistant}
+`), 0o600)
+	tokens, err := LoadTokens(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tokens["6b1f0c"]; got.Role != RoleRead || got.source("6b1f0c") != "http:token-2e901a45" {
+		t.Errorf("unnamed token: %+v, %s", got, got.source("6b1f0c"))
+	}
+	if got := tokens["a93e77"]; got.Role != RoleAdmin || got.source("a93e77") != "http:home-assistant" {
+		t.Errorf("named token: %+v", got)
+	}
+
+	os.WriteFile(fn, []byte(`"6b1f0c": root`), 0o600)
+	if _, err := LoadTokens(fn); err == nil {
+		t.Error("accepted unknown role")
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+)
+
+// batteryStatus is the JSON representation of a battery.Summary
+type batteryStatus struct {
+	Serial        string     `json:"serial"`
+	Name          string     `json:"name,omitempty"`
+	Prod          string     `json:"prod"`
+	Volts         float64    `json:"volts"`
+	Slope         float64    `json:"slope"`          // Volts per day
+	DaysRemaining *float64   `json:"days_remaining"` // Null if not discharging
+	Replace       *time.Time `json:"replace"`        // Null if not discharging
+	LastSeen      time.Time  `json:"last_seen"`
+}
+
+func (s *Server) getBatteries(w http.ResponseWriter, r *http.Request) {
+	if s.Batteries == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("battery readings are not being recorded"))
+		return
+	}
+	readings, err := s.Batteries.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var names map[string]string
+	if s.Names != nil {
+		names = s.Names()
+	}
+
+	out := []batteryStatus{}
+	for _, sum := range battery.Summarise(readings) {
+		b := batteryStatus{
+			Serial:   sum.Serial,
+			Name:     names[sum.Serial],
+			Prod:     sum.Prod,
+			Volts:    sum.Latest.Volts,
+			Slope:    sum.Slope,
+			LastSeen: sum.Latest.Time,
+		}
+		if !math.IsInf(sum.DaysRemaining, 1) {
+			b.DaysRemaining = &sum.DaysRemaining
+			b.Replace = &sum.Replace
+		}
+		out = append(out, b)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
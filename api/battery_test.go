@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestGetBatteries(t *testing.T) {
+	s := New(nil, lwl.NewRegistry(nil), map[string]Token{"r": {Role: RoleRead}})
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/batteries", nil)
+		req.Header.Set("Authorization", "Bearer r")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := get(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a history want 503 got %d", rec.Code)
+	}
+
+	s.Batteries = battery.NewHistory(filepath.Join(t.TempDir(), "battery.jsonl"))
+	s.Names = func() map[string]string { return map[string]string{"24C702": "lounge"} }
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []float64{2.9, 2.8, 2.7} {
+		s.Batteries.Append(battery.Reading{Serial: "24C702", Prod: "valve", Time: t0.AddDate(0, 0, i), Volts: v})
+	}
+	s.Batteries.Append(battery.Reading{Serial: "F00D01", Prod: "valve", Time: t0, Volts: 3.0})
+
+	rec := get()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	var got []batteryStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 devices got %+v", got)
+	}
+	// 0.1V/day from 2.7V reaches 2.4V three days later
+	if b := got[0]; b.Name != "lounge" || b.Replace == nil || !b.Replace.Equal(t0.AddDate(0, 0, 5)) {
+		t.Errorf("want lounge replaced by %v got %+v", t0.AddDate(0, 0, 5), b)
+	}
+	if b := got[1]; b.Replace != nil || b.DaysRemaining != nil {
+		t.Errorf("single reading should have no prediction, got %+v", b)
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// logState is the daemon's logging, see getLog
+type logState struct {
+	Level      string     `json:"level"`
+	Until      *time.Time `json:"until,omitempty"`       // When a temporary level ends
+	TraceUntil *time.Time `json:"trace_until,omitempty"` // When wire tracing ends
+}
+
+func (s *Server) logState() logState {
+	var st logState
+	if s.LogLevel != nil {
+		st.Level = s.LogLevel.Level().String()
+		if until, ok := s.LogLevel.Temporary(); ok {
+			st.Until = &until
+		}
+	}
+	if s.c != nil {
+		if until, ok := s.c.TraceUntil(); ok {
+			st.TraceUntil = &until
+		}
+	}
+	return st
+}
+
+func (s *Server) getLog(w http.ResponseWriter, r *http.Request) {
+	if s.LogLevel == nil {
+		writeError(w, http.StatusNotFound, errors.New("log level cannot be changed"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.logState())
+}
+
+// setLog changes the log level and wire tracing, for a while if "for" is
+// given, so that debug messages can be captured from a misbehaving daemon
+// without restarting it
+func (s *Server) setLog(w http.ResponseWriter, r *http.Request) {
+	if s.LogLevel == nil {
+		writeError(w, http.StatusNotFound, errors.New("log level cannot be changed"))
+		return
+	}
+	var body struct {
+		Level   *slog.Level `json:"level"`
+		For     string      `json:"for"`
+		Trace   bool        `json:"trace"`
+		Restore bool        `json:"restore"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	var d time.Duration
+	if body.For != "" {
+		var err error
+		if d, err = time.ParseDuration(body.For); err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid for: %q", body.For))
+			return
+		}
+	}
+	if body.Trace && d == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("trace needs for, so that it is not left on"))
+		return
+	}
+
+	if body.Restore {
+		s.LogLevel.Restore()
+		if s.c != nil {
+			s.c.Trace(0)
+		}
+	}
+	if body.Level != nil {
+		s.LogLevel.Set(*body.Level, d)
+	}
+	if body.Trace && s.c != nil {
+		s.c.Trace(d)
+		slog.Info("Tracing messages to and from the LightwaveLink", "for", d)
+	}
+	writeJSON(w, http.StatusOK, s.logState())
+}
+
+// getBugReport describes the daemon now, see bugreport.Bundle
+func (s *Server) getBugReport(w http.ResponseWriter, r *http.Request) {
+	if s.BugReport == nil {
+		writeError(w, http.StatusNotFound, errors.New("bug reports are not enabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.BugReport.Snapshot("requested"))
+}
+
+// replay re-emits the messages in a capture, sent as the request body, to
+// the daemon's subscribers (rules, occupancy, telemetry and so on) as if
+// they had just been received. Rules fired by them are dry runs.
+func (s *Server) replay(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to time.Time
+	for _, v := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if q.Get(v.name) == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, q.Get(v.name))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %w", v.name, err))
+			return
+		}
+		*v.t = t
+	}
+	speed := 1.0
+	if v := q.Get("speed"); v != "" {
+		var err error
+		if speed, err = strconv.ParseFloat(v, 64); err != nil || speed < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid speed: %q", v))
+			return
+		}
+	}
+	p, err := lwl.NewPcapReader(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid capture: %w", err))
+		return
+	}
+
+	slog.Info("Replaying capture", "from", from, "to", to, "speed", speed)
+	n, err := s.c.Replay(r.Context(), p, from, to, speed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("replayed %d messages: %w", n, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"replayed": n})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/loglevel"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestDebugLog(t *testing.T) {
+	c := &lwl.Client{}
+	s := New(c, lwl.NewRegistry(c), map[string]Token{"a": {Role: RoleAdmin}})
+	s.LogLevel = loglevel.New(slog.LevelInfo)
+	post := func(body string) (int, logState) {
+		req := httptest.NewRequest("POST", "/debug/log", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer a")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		var st logState
+		json.Unmarshal(rec.Body.Bytes(), &st)
+		return rec.Code, st
+	}
+
+	code, st := post(`{"level":"DEBUG","for":"15m","trace":true}`)
+	if code != http.StatusOK || st.Level != "DEBUG" || st.Until == nil || st.TraceUntil == nil {
+		t.Fatalf("got %d %+v", code, st)
+	}
+	if s.LogLevel.Level() != slog.LevelDebug {
+		t.Errorf("level not changed: %v", s.LogLevel.Level())
+	}
+	if code, st = post(`{"restore":true}`); code != http.StatusOK || st.Level != "INFO" || st.Until != nil || st.TraceUntil != nil {
+		t.Errorf("restore: %d %+v", code, st)
+	}
+	for _, body := range []string{`{"trace":true}`, `{"level":"LOUD"}`, `{"for":"soon"}`} {
+		if code, _ := post(body); code != http.StatusBadRequest {
+			t.Errorf("%s: want 400 got %d", body, code)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := lwl.NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 71), Port: 9760}
+	host := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 200), Port: 9761}
+	t0 := time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)
+	for i, msg := range []string{
+		`*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"433T","fn":"on","room":3,"dev":1}`,
+		`*!{"trans":2,"mac":"20:3B:85","time":1767297489,"pkt":"433T","fn":"off","room":3,"dev":1}`,
+	} {
+		if err := w.WritePacket(t0.Add(time.Duration(i)*time.Minute), hub, host, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ch := c.SubscribeContext(t.Context(), 10, lwl.DropNewest)
+	s := New(c, lwl.NewRegistry(c), map[string]Token{"a": {Role: RoleAdmin}})
+	post := func(query string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/debug/replay"+query, body)
+		req.Header.Set("Authorization", "Bearer a")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("", strings.NewReader("not a capture")); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid capture: want 400 got %d", rec.Code)
+	}
+	if rec := post("?speed=fast", bytes.NewReader(buf.Bytes())); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid speed: want 400 got %d", rec.Code)
+	}
+
+	rec := post("?speed=0&from="+t0.Add(time.Second).Format(time.RFC3339), &buf)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"replayed":1`) {
+		t.Fatalf("want 1 replayed, got %d: %s", rec.Code, rec.Body)
+	}
+	if r := <-ch; r.Trans != 2 || !r.Replay {
+		t.Errorf("want replay of trans 2, got %v (replay %v)", &r, r.Replay)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/energy"
+)
+
+// energyReport is the response to an energy query
+type energyReport struct {
+	Currency string         `json:"currency"`
+	Periods  []energy.Usage `json:"periods"`
+}
+
+func (s *Server) getEnergy(w http.ResponseWriter, r *http.Request) {
+	if s.Telemetry == nil || s.Tariff == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("energy use is not being recorded, or no tariff is configured"))
+		return
+	}
+
+	q := r.URL.Query()
+	period, err := energy.ParsePeriod(cmp.Or(q.Get("period"), string(energy.Day)))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+			return
+		}
+	}
+	from := period.Back(to, 6)
+	if v := q.Get("from"); v != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+			return
+		}
+	}
+
+	usage, err := energy.Query(s.Telemetry, s.Tariff, from, to, period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, energyReport{Currency: s.Tariff.Currency, Periods: usage})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/energy"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestGetEnergy(t *testing.T) {
+	tele := telemetry.NewLog(filepath.Join(t.TempDir(), "telemetry.jsonl"))
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	err := tele.Put(
+		telemetry.Point{Series: "A1B2C3.today", Time: day.Add(12 * time.Hour), Value: 4000},
+		telemetry.Point{Series: "A1B2C3.power", Time: day.Add(12 * time.Hour), Value: 250}, // Not energy
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(nil, lwl.NewRegistry(nil), map[string]Token{"r": {Role: RoleRead}})
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer r")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/energy"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a tariff want 503 got %d", rec.Code)
+	}
+
+	s.Telemetry = tele
+	s.Tariff = &energy.Tariff{Currency: "£", Standing: 0.5, Unit: 0.25}
+	if rec := get("/energy?period=fortnight"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid period want 400 got %d", rec.Code)
+	}
+
+	rec := get("/energy?from=2026-10-14&to=2026-10-14")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	var got energyReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Currency != "£" || len(got.Periods) != 1 || got.Periods[0].KWh != 4 || got.Periods[0].Cost != 1.5 {
+		t.Errorf("got %+v, want 4kWh costing £1.50", got)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The Grafana endpoints implement the protocol of the SimpleJSON (and
+// compatible, e.g. Infinity) data source, serving series from Telemetry
+
+// grafanaSearch is the body of a search request
+type grafanaSearch struct {
+	Target string `json:"target"` // Substring to filter series by
+}
+
+// grafanaQuery is the body of a query request
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is a series in the response to a query
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"` // [value, Unix milliseconds]
+}
+
+// grafanaTest answers the "Save & test" button of the data source
+func (s *Server) grafanaTest(w http.ResponseWriter, r *http.Request) {
+	if s.Telemetry == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("telemetry is not being recorded"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) grafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req grafanaSearch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	out := []string{}
+	if s.Telemetry != nil {
+		series, err := s.Telemetry.Series()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, name := range series {
+			if strings.Contains(name, req.Target) {
+				out = append(out, name)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	out := []grafanaSeries{}
+	for _, t := range req.Targets {
+		gs := grafanaSeries{Target: t.Target, Datapoints: [][2]float64{}}
+		if s.Telemetry != nil {
+			ps, err := s.Telemetry.Query(t.Target, req.Range.From, req.Range.To)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			for _, p := range ps {
+				gs.Datapoints = append(gs.Datapoints, [2]float64{p.Value, float64(p.Time.UnixMilli())})
+			}
+		}
+		out = append(out, gs)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
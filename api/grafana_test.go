@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestGrafanaQuery(t *testing.T) {
+	tele := telemetry.NewLog(filepath.Join(t.TempDir(), "telemetry.jsonl"))
+	t0 := time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC)
+	err := tele.Put(
+		telemetry.Point{Series: "24C702.temp", Time: t0, Value: 19.5},
+		telemetry.Point{Series: "24C702.temp", Time: t0.Add(2 * time.Hour), Value: 20}, // Outside range
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(nil, lwl.NewRegistry(nil), map[string]Token{"r": {Role: RoleRead}})
+	s.Telemetry = tele
+
+	body := `{"range":{"from":"2026-10-15T02:00:00Z","to":"2026-10-15T04:00:00Z"},"targets":[{"target":"24C702.temp"}]}`
+	req := httptest.NewRequest("POST", "/grafana/query", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer r")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+
+	var got []grafanaSeries
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := [2]float64{19.5, float64(t0.UnixMilli())}
+	if len(got) != 1 || len(got[0].Datapoints) != 1 || got[0].Datapoints[0] != want {
+		t.Fatalf("want one series with datapoint %v, got %+v", want, got)
+	}
+}
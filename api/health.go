@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// The LWL counts as reachable if heard from this recently. Otherwise /readyz
+// probes it, so a quiet network does not make the daemon look unready.
+const hubFresh = 5 * time.Minute
+
+// How long /readyz waits for the LWL to answer a probe
+const probeTimeout = 2 * time.Second
+
+// How long the result of a probe is reused, so that frequent readiness checks
+// don't flood an unresponsive LWL with commands
+const probeReuse = 30 * time.Second
+
+// health is the JSON representation of /healthz and /readyz
+type health struct {
+	Status string                 `json:"status"` // "ok" or "fail"
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+type healthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// hubProbe is the result of the most recent probe of the LWL by /readyz
+type hubProbe struct {
+	mu     sync.Mutex // Held while probing, so concurrent requests share one
+	at     time.Time
+	detail string
+	err    error
+}
+
+// healthz reports that the daemon is running, without checking anything it
+// depends on, e.g. for a liveness probe
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health{Status: "ok"})
+}
+
+// readyz reports whether the daemon can do its job: the LWL is reachable and
+// accepts its commands, and telemetry (if enabled) can be recorded
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	out := health{Status: "ok", Checks: make(map[string]healthCheck)}
+	check := func(name string, err error, detail string) {
+		c := healthCheck{OK: err == nil, Detail: detail}
+		if err != nil {
+			c.Detail = err.Error()
+			out.Status = "fail"
+		}
+		out.Checks[name] = c
+	}
+
+	detail, err := s.checkHub(r.Context())
+	check("hub", err, detail)
+	if errors.Is(err, lwl.ErrNotRegistered) {
+		check("registration", err, "")
+	} else {
+		registered, known := s.c.Registered()
+		switch {
+		case !known:
+			check("registration", errors.New("unknown, no reply from the LightwaveLink yet"), "")
+		case !registered:
+			check("registration", lwl.ErrNotRegistered, "")
+		default:
+			check("registration", nil, "registered")
+		}
+	}
+	if s.Telemetry != nil {
+		// Putting nothing checks the store is writable, without changing it
+		check("telemetry", s.Telemetry.Put(), "writable")
+	}
+
+	code := http.StatusOK
+	if out.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, out)
+}
+
+// checkHub returns an error if the LWL has not been heard from recently, and
+// does not answer a probe. Probes are made at most every probeReuse.
+func (s *Server) checkHub(ctx context.Context) (string, error) {
+	if ago := time.Since(s.c.LastHeard()); ago < hubFresh {
+		return fmt.Sprintf("heard %v ago", ago.Round(time.Second)), nil
+	}
+
+	p := &s.probe
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ago := time.Since(p.at); ago < probeReuse {
+		if p.err != nil {
+			return "", p.err
+		}
+		return fmt.Sprintf("%s %v ago", p.detail, ago.Round(time.Second)), nil
+	}
+
+	// The probe is shared, so isn't abandoned if this request is
+	ctx, cancel := context.WithTimeout(lwl.WithSource(context.WithoutCancel(ctx), "health"), probeTimeout)
+	defer cancel()
+	p.at, p.detail, p.err = time.Now(), "answered a probe", nil
+	if _, err := s.c.Do(ctx, lwl.CmdHubCall); err != nil {
+		p.detail, p.err = "", err
+		if errors.Is(err, context.DeadlineExceeded) {
+			p.err = errors.New("no reply from the LightwaveLink")
+		}
+		return "", p.err
+	}
+	return p.detail, nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestHealth(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	s := New(c, lwl.NewRegistry(c), nil) // No tokens needed
+	s.Telemetry = telemetry.NewMemory()
+	get := func(path string) (int, health) {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var out health
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, rec.Body)
+		}
+		return rec.Code, out
+	}
+
+	if code, out := get("/healthz"); code != http.StatusOK || out.Status != "ok" {
+		t.Fatalf("healthz: want 200 ok, got %d %+v", code, out)
+	}
+
+	// Nothing heard yet, so the hub is probed
+	code, out := get("/readyz")
+	if code != http.StatusOK || out.Status != "ok" {
+		t.Fatalf("readyz: want 200 ok, got %d %+v", code, out)
+	}
+	for _, name := range []string{"hub", "registration", "telemetry"} {
+		if !out.Checks[name].OK {
+			t.Errorf("want %s ok, got %+v", name, out.Checks[name])
+		}
+	}
+
+	// Once unpaired, the next reply says so
+	h.SetRegistered(false)
+	if _, err := c.Do(t.Context(), lwl.CmdHubCall); err == nil {
+		t.Fatal("want error from unregistered hub")
+	}
+	code, out = get("/readyz")
+	if code != http.StatusServiceUnavailable || out.Checks["registration"].OK {
+		t.Fatalf("readyz: want 503 with registration failing, got %d %+v", code, out)
+	}
+}
+
+func TestHealthProbeReused(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	// A recent failed probe is reported again, rather than probing anew
+	s := New(c, lwl.NewRegistry(c), nil)
+	s.probe.at, s.probe.err = time.Now(), errors.New("no reply from the LightwaveLink")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("want 503, got %d %s", rec.Code, rec.Body)
+	}
+	if got := h.Received(); len(got) != 0 {
+		t.Errorf("hub was probed: %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long the response to a request with an Idempotency-Key is remembered
+const idempotencyTTL = 24 * time.Hour
+
+// How often responses older than idempotencyTTL are forgotten
+const idempotencySweep = time.Hour
+
+// Largest request body with an Idempotency-Key, which is read in full to
+// check that a reused key is for the same request
+const maxIdempotentBody = 64 << 10
+
+// idempotencyStore remembers the responses to requests bearing an
+// Idempotency-Key header, so that a retried request is answered without
+// being performed again, e.g. toggling a light twice
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotent // Keyed by credentials and key
+	swept   time.Time              // When expired entries were last removed
+}
+
+// idempotent is the response to a request with an Idempotency-Key
+type idempotent struct {
+	request string    // Method and path, which a reused key must match
+	body    [32]byte  // SHA-256 of the request body, which must match too
+	at      time.Time // When the request was first received
+	done    bool      // The response below is complete
+	status  int
+	header  http.Header
+	resp    []byte
+}
+
+// responseRecorder captures a response, as well as writing it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// idempotent wraps a handler so that requests with the same Idempotency-Key
+// header (and credentials) are only performed once. Repeats are answered
+// with the original response, plus an Idempotent-Replayed header, or 409 if
+// the original is still in progress, or 422 if the method, path or body
+// differ. Server errors are not remembered, so that the request can be
+// retried.
+func (s *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			h(w, r)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		body := sha256.Sum256(data)
+		id := r.Header.Get("Authorization") + "\x00" + key
+		request := r.Method + " " + r.URL.Path
+		now := time.Now()
+
+		st := &s.idempotency
+		st.mu.Lock()
+		if st.entries == nil {
+			st.entries = make(map[string]*idempotent)
+		}
+		if now.Sub(st.swept) > idempotencySweep {
+			for k, e := range st.entries {
+				if e.done && now.Sub(e.at) > idempotencyTTL {
+					delete(st.entries, k)
+				}
+			}
+			st.swept = now
+		}
+		e, seen := st.entries[id]
+		if !seen {
+			e = &idempotent{request: request, body: body, at: now}
+			st.entries[id] = e
+		}
+		st.mu.Unlock()
+
+		switch {
+		case seen && e.request != request:
+			writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("idempotency key %q was used for %s", key, e.request))
+			return
+		case seen && e.body != body:
+			writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("idempotency key %q was used with a different body", key))
+			return
+		case seen && !e.done:
+			writeError(w, http.StatusConflict, fmt.Errorf("request with idempotency key %q is in progress", key))
+			return
+		case seen:
+			for k, v := range e.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(e.status)
+			w.Write(e.resp)
+			return
+		}
+
+		// Forget the request unless it completes, including if h panics,
+		// so that it is not stuck in progress
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		finished := false
+		defer func() {
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			if !finished || rec.status >= 500 {
+				delete(st.entries, id)
+				return
+			}
+			e.done, e.status, e.header, e.resp = true, rec.status, w.Header().Clone(), rec.body.Bytes()
+		}()
+		h(rec, r)
+		finished = true
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// countingHub is a HubClient which counts the commands sent to it
+type countingHub struct {
+	sent int
+	err  error
+}
+
+func (f *countingHub) Do(ctx context.Context, cmd lwl.Command) (lwl.Response, error) {
+	f.sent++
+	return lwl.Response{}, f.err
+}
+
+func (f *countingHub) Subscribe(sid string, chr chan lwl.Response, chs chan string) string {
+	return sid
+}
+func (f *countingHub) Unsubscribe(sid string)                         {}
+func (f *countingHub) Events(ctx context.Context) <-chan lwl.Response { return nil }
+func (f *countingHub) Close() error                                   { return nil }
+
+func TestIdempotency(t *testing.T) {
+	hub := &countingHub{}
+	h := New(nil, lwl.NewRegistry(hub), map[string]Token{"c": {Role: RoleControl}, "d": {Role: RoleControl}}).Handler()
+	post := func(path, token, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"a":1}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/devices/R1D1/on", "c", "k1"); rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	rec := post("/devices/R1D1/on", "c", "k1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("want replayed 200, got %d %v", rec.Code, rec.Header())
+	}
+	if hub.sent != 1 {
+		t.Fatalf("want 1 command sent, got %d", hub.sent)
+	}
+	if rec := post("/devices/R1D1/off", "c", "k1"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key: want 422 got %d", rec.Code)
+	}
+	req := httptest.NewRequest("POST", "/devices/R1D1/on", strings.NewReader(`{"a":2}`))
+	req.Header.Set("Authorization", "Bearer c")
+	req.Header.Set("Idempotency-Key", "k1")
+	rec = httptest.NewRecorder()
+	if h.ServeHTTP(rec, req); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key with another body: want 422 got %d", rec.Code)
+	}
+	if post("/devices/R1D1/on", "d", "k1"); hub.sent != 2 {
+		t.Fatalf("another token's key: want 2 commands sent, got %d", hub.sent)
+	}
+
+	// Server errors are forgotten, so the request can be retried
+	hub.err = errors.New("no reply")
+	if rec := post("/devices/R1D1/off", "c", "k2"); rec.Code != http.StatusBadGateway {
+		t.Fatalf("want 502 got %d", rec.Code)
+	}
+	hub.err = nil
+	if rec := post("/devices/R1D1/off", "c", "k2"); rec.Code != http.StatusOK || hub.sent != 4 {
+		t.Fatalf("want retry sent, got %d after %d commands", rec.Code, hub.sent)
+	}
+}
+
+func TestIdempotencyForgets(t *testing.T) {
+	s := New(nil, nil, nil)
+	panicked := true
+	h := s.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		if panicked {
+			panic("oops")
+		}
+	})
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	func() {
+		defer func() { recover() }()
+		serve("k1")
+	}()
+	panicked = false
+	if rec := serve("k1"); rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("after a panic: want the request performed, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Expired responses are swept, whichever key the next request has
+	s.idempotency.entries["\x00k1"].at = time.Now().Add(-idempotencyTTL - time.Minute)
+	s.idempotency.swept = time.Now().Add(-idempotencySweep - time.Minute)
+	serve("k2")
+	if _, ok := s.idempotency.entries["\x00k1"]; ok || len(s.idempotency.entries) != 1 {
+		t.Fatalf("want k1 swept, got %v", s.idempotency.entries)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// deviceLatency is the JSON representation of a device's lwl.LatencySnapshot,
+// in milliseconds
+type deviceLatency struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	Samples int64   `json:"samples"`
+	Min     float64 `json:"min_ms"`
+	Mean    float64 `json:"mean_ms"`
+	Max     float64 `json:"max_ms"`
+	StdDev  float64 `json:"stddev_ms"`
+	Jitter  float64 `json:"jitter_ms"`
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// getLatency reports the round-trip times of commands to each device, see
+// lwl.Client.DeviceLatency
+func (s *Server) getLatency(w http.ResponseWriter, r *http.Request) {
+	names := make(map[string]string)
+	for _, d := range s.reg.Devices() {
+		if n := d.Name(); n != d.ID() {
+			names[d.ID()] = n
+		}
+	}
+
+	lat := s.c.DeviceLatency()
+	out := []deviceLatency{}
+	for _, id := range slices.Sorted(maps.Keys(lat)) {
+		l := lat[id]
+		out = append(out, deviceLatency{
+			ID:      id,
+			Name:    names[id],
+			Samples: l.Samples,
+			Min:     ms(l.Min),
+			Mean:    ms(l.Mean),
+			Max:     ms(l.Max),
+			StdDev:  ms(l.StdDev),
+			Jitter:  ms(l.Jitter),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
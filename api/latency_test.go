@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestGetLatency(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	reg := lwl.NewRegistry(c)
+	if err := reg.SetAlias("R1D2", "lounge"); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range []*lwl.Command{lwl.CmdOn.New("R1D2"), lwl.CmdOn.New("R3D1")} {
+		if _, err := c.Do(t.Context(), *cmd); err != nil {
+			t.Fatal(cmd, err)
+		}
+	}
+
+	s := New(c, reg, map[string]Token{"r": {Role: RoleRead}})
+	req := httptest.NewRequest("GET", "/latency", nil)
+	req.Header.Set("Authorization", "Bearer r")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	var got []deviceLatency
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "R1D2" || got[0].Name != "lounge" || got[0].Samples != 1 || got[1].ID != "R3D1" || got[1].Name != "" {
+		t.Errorf("got %+v", got)
+	}
+}
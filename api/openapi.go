@@ -0,0 +1,40 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI is the OpenAPI 3 specification of the API, in YAML
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// openAPIJSON converts OpenAPI to JSON, once
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(OpenAPI, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+})
+
+func serveOpenAPIYAML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(OpenAPI)
+}
+
+func serveOpenAPIJSON(w http.ResponseWriter, r *http.Request) {
+	b, err := openAPIJSON()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow hosted Swagger UI
+	w.Write(b)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// TestOpenAPI checks that every route is documented, and vice versa
+func TestOpenAPI(t *testing.T) {
+	s := New(nil, lwl.NewRegistry(nil), nil)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	documented := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for _, rt := range s.routes() {
+		key := rt.method + " " + strings.TrimSuffix(rt.path, "{$}")
+		if !documented[key] {
+			t.Errorf("%s is not in openapi.yaml", key)
+		}
+		delete(documented, key)
+	}
+	for key := range documented {
+		t.Errorf("%s is in openapi.yaml, but not served", key)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/meermanr/LightwaveRF-go/presence"
+)
+
+// Largest presence update accepted, which is ample for OwnTracks
+const maxPresenceBody = 64 << 10
+
+func (s *Server) getPresence(w http.ResponseWriter, r *http.Request) {
+	out := map[string]bool{}
+	if s.Presence != nil {
+		out = s.Presence.People()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// postPresence accepts either a presence.Update, or a message from OwnTracks
+// in HTTP mode, which identifies the person with the X-Limit-U header
+func (s *Server) postPresence(w http.ResponseWriter, r *http.Request) {
+	if s.Presence == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("presence is not enabled"))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPresenceBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var probe struct {
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if probe.Type != "" {
+		u, ok, err := s.Presence.DecodeOwnTracks(data, r.Header.Get("X-Limit-U"))
+		if err == nil && ok {
+			_, err = s.Presence.Apply(u)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		// OwnTracks expects a list of messages to show the user
+		writeJSON(w, http.StatusOK, []any{})
+		return
+	}
+
+	var u presence.Update
+	if err := json.Unmarshal(data, &u); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.Presence.Apply(u); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Presence.People())
+}
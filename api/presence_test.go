@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/presence"
+)
+
+func TestPresence(t *testing.T) {
+	s := New(nil, lwl.NewRegistry(nil), map[string]Token{"c": {Role: RoleControl}})
+	var events []string
+	s.Presence = presence.NewTracker("home", func(e presence.Event) { events = append(events, e.Fn) })
+
+	post := func(body string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/presence", strings.NewReader(body))
+		auth(req)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer c") }
+	ownTracks := func(r *http.Request) {
+		r.SetBasicAuth("anyone", "c")
+		r.Header.Set("X-Limit-U", "bob")
+	}
+
+	rec := post(`{"person":"alice","event":"enter","region":"home"}`, bearer)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"alice":true`) {
+		t.Fatalf("want alice home, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = post(`{"_type":"transition","event":"enter","desc":"Home","tid":"bo"}`, ownTracks)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("want [] for OwnTracks, got %d: %s", rec.Code, rec.Body)
+	}
+	if p := s.Presence.People(); !p["bob"] {
+		t.Fatalf("want bob home, got %v", p)
+	}
+	if got := strings.Join(events, ","); got != "arrive,firstHome,arrive" {
+		t.Fatalf("want arrive,firstHome,arrive got %s", got)
+	}
+
+	if rec := post(`{"person":"alice","event":"enter","region":"home"}`, func(*http.Request) {}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401 without a token, got %d", rec.Code)
+	}
+	if rec := post(`{"person":"alice"}`, bearer); rec.Code != http.StatusBadRequest {
+		t.Fatalf("want 400 for an incomplete update, got %d", rec.Code)
+	}
+
+	// Basic authentication is only for presence updates
+	req := httptest.NewRequest("GET", "/presence", nil)
+	req.SetBasicAuth("anyone", "c")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401 for basic authentication of GET /presence, got %d", rec.Code)
+	}
+}
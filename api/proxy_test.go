@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies("127.0.0.1, 10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{TrustedProxies: proxies}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   string
+	}{
+		{name: "Direct", remote: "192.168.1.5:1234", want: "192.168.1.5"},
+		{name: "UntrustedForwarded", remote: "192.168.1.5:1234", xff: "1.2.3.4", want: "192.168.1.5"},
+		{name: "ViaProxy", remote: "127.0.0.1:1234", xff: "192.168.1.7", want: "192.168.1.7"},
+		{name: "ViaTwoProxies", remote: "127.0.0.1:1234", xff: "192.168.1.7, 10.1.2.3", want: "192.168.1.7"},
+		{name: "Spoofed", remote: "127.0.0.1:1234", xff: "6.6.6.6, 192.168.1.7", want: "192.168.1.7"},
+		{name: "Malformed", remote: "127.0.0.1:1234", xff: "bogus", want: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if got := s.clientIP(r); got != tt.want {
+				t.Fatalf("want %s got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"github.com/meermanr/LightwaveRF-go/rules"
+)
+
+func (s *Server) listRules(w http.ResponseWriter, r *http.Request) {
+	out := []rules.Status{}
+	if s.Rules != nil {
+		out = s.Rules.Rules()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) enableRule(w http.ResponseWriter, r *http.Request) {
+	s.setRuleEnabled(w, r, true)
+}
+
+func (s *Server) disableRule(w http.ResponseWriter, r *http.Request) {
+	s.setRuleEnabled(w, r, false)
+}
+
+func (s *Server) setRuleEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	if s.Rules == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no rules are configured"))
+		return
+	}
+	if err := s.Rules.SetEnabled(r.PathValue("rule"), enabled); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listScenes(w http.ResponseWriter, r *http.Request) {
+	out := []string{}
+	if s.Rules != nil {
+		out = s.Rules.Scenes()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// runScene starts a scene, which continues after the response since it may
+// include long waits
+func (s *Server) runScene(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("scene")
+	if s.Rules == nil || !slices.Contains(s.Rules.Scenes(), name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such scene: %s", name))
+		return
+	}
+	ctx := context.WithoutCancel(r.Context())
+	panics.Go("scene", func() {
+		if err := s.Rules.RunScene(ctx, name); err != nil {
+			slog.Error("Scene failed", "scene", name, "err", err)
+		}
+	})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// recordScene starts recording a scene, for up to the duration in the path
+// (e.g. 2h)
+func (s *Server) recordScene(w http.ResponseWriter, r *http.Request) {
+	if s.Rules == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("scenes are not enabled"))
+		return
+	}
+	d, err := time.ParseDuration(r.PathValue("duration"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.Rules.Record(r.PathValue("scene"), d); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	st, _ := s.Rules.Recording()
+	writeJSON(w, http.StatusAccepted, st)
+}
+
+func (s *Server) getRecording(w http.ResponseWriter, r *http.Request) {
+	if s.Rules == nil {
+		writeError(w, http.StatusNotFound, rules.ErrNotRecording)
+		return
+	}
+	st, ok := s.Rules.Recording()
+	if !ok {
+		writeError(w, http.StatusNotFound, rules.ErrNotRecording)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// stopRecording stops recording a scene, responding with its steps, e.g.
+// "R1D1 on" or "wait 5s"
+func (s *Server) stopRecording(w http.ResponseWriter, r *http.Request) {
+	if s.Rules == nil {
+		writeError(w, http.StatusNotFound, rules.ErrNotRecording)
+		return
+	}
+	sc, err := s.Rules.StopRecording()
+	switch {
+	case errors.Is(err, rules.ErrNotRecording):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusUnprocessableEntity, err)
+	default:
+		steps := make([]string, len(sc.Steps))
+		for i, st := range sc.Steps {
+			steps[i] = st.String()
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"scene": sc.Name, "steps": steps})
+	}
+}
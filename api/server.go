@@ -0,0 +1,396 @@
+// Package api serves an HTTP interface for monitoring and controlling devices
+// via a LightwaveRF Link (LWL)
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+	"github.com/meermanr/LightwaveRF-go/bugreport"
+	"github.com/meermanr/LightwaveRF-go/buildinfo"
+	"github.com/meermanr/LightwaveRF-go/contact"
+	"github.com/meermanr/LightwaveRF-go/energy"
+	"github.com/meermanr/LightwaveRF-go/loglevel"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/occupancy"
+	"github.com/meermanr/LightwaveRF-go/presence"
+	"github.com/meermanr/LightwaveRF-go/rules"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// Server implements the HTTP API. Every endpoint, except the health checks,
+// requires a bearer token, see LoadTokens.
+type Server struct {
+	c      *lwl.Client
+	reg    *lwl.Registry
+	tokens map[string]Token
+
+	// Status returns the most recent statusPush from each heating device,
+	// keyed by serial. Optional.
+	Status func() map[string]lwl.Response
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For and
+	// X-Forwarded-Proto headers are believed, see ParseProxies. Optional.
+	TrustedProxies []netip.Prefix
+
+	// Telemetry is served to Grafana. Optional.
+	Telemetry telemetry.Store
+
+	// Tariff prices the energy use recorded in Telemetry. Optional.
+	Tariff *energy.Tariff
+
+	// Rules can be listed, enabled and disabled, and their scenes run.
+	// Optional.
+	Rules *rules.Engine
+
+	// Presence is updated by phone geofencing apps. Optional.
+	Presence *presence.Tracker
+
+	// Occupancy of areas with PIRs can be listed. Optional.
+	Occupancy *occupancy.Estimator
+
+	// Contacts on doors and windows can be listed. Optional.
+	Contacts *contact.Tracker
+
+	// Batteries of heating devices are summarised from their history, with
+	// predicted replacement dates. Optional.
+	Batteries *battery.History
+
+	// Names returns the configured name of each device, by serial.
+	// Optional.
+	Names func() map[string]string
+
+	// UnixRoles are the roles of users, by uid, connecting to the Unix
+	// socket, see ServeUnix and ParseUnixRoles. Optional.
+	UnixRoles map[uint32]Role
+
+	// LogLevel of the daemon may be changed at runtime. Optional.
+	LogLevel *loglevel.Control
+
+	// BugReport describes the daemon, for lwlctl bugreport. Optional.
+	BugReport *bugreport.Bundler
+
+	// MaxRequests served at once, beyond which requests are refused with
+	// 503 Service Unavailable, or 0 for no limit
+	MaxRequests int
+
+	idempotency idempotencyStore // Responses to control requests, see idempotent
+	probe       hubProbe         // Most recent probe of the LWL, see checkHub
+	alarms      alarms           // Sunrise alarms, see startSunrise
+}
+
+// New returns a Server commanding devices in reg via c
+func New(c *lwl.Client, reg *lwl.Registry, tokens map[string]Token) *Server {
+	return &Server{c: c, reg: reg, tokens: tokens}
+}
+
+// route is an endpoint of the API
+type route struct {
+	method, path string
+	role         Role
+	handler      http.HandlerFunc
+}
+
+// routes returns every endpoint of the API. Each must be described in
+// openapi.yaml.
+func (s *Server) routes() []route {
+	return []route{
+		{"GET", "/devices", RoleRead, s.listDevices},
+		{"GET", "/devices/{name}", RoleRead, s.getDevice},
+		{"POST", "/devices/{name}/on", RoleControl, s.deviceOn},
+		{"POST", "/devices/{name}/off", RoleControl, s.deviceOff},
+		{"POST", "/devices/{name}/dim/{level}", RoleControl, s.deviceDim},
+		{"POST", "/devices/{name}/lock/{mode}", RoleAdmin, s.deviceLock},
+		{"POST", "/devices/{name}/sunrise/{wake}", RoleControl, s.startSunrise},
+		{"DELETE", "/devices/{name}/sunrise", RoleControl, s.cancelSunrise},
+		{"GET", "/status", RoleRead, s.getStatus},
+		{"POST", "/hub/unpair", RoleAdmin, s.unpair},
+		{"GET", "/hub/unknown", RoleRead, s.getUnknown},
+		{"GET", "/version", RoleRead, s.getVersion},
+		{"GET", "/latency", RoleRead, s.getLatency},
+		{"POST", "/commands/{command}", RoleAdmin, s.sendCommand},
+		{"GET", "/debug/log", RoleAdmin, s.getLog},
+		{"POST", "/debug/log", RoleAdmin, s.setLog},
+		{"GET", "/debug/bugreport", RoleAdmin, s.getBugReport},
+		{"POST", "/debug/replay", RoleAdmin, s.replay},
+		{"GET", "/rules", RoleRead, s.listRules},
+		{"POST", "/rules/{rule}/enable", RoleAdmin, s.enableRule},
+		{"POST", "/rules/{rule}/disable", RoleAdmin, s.disableRule},
+		{"GET", "/scenes", RoleRead, s.listScenes},
+		{"POST", "/scenes/{scene}/run", RoleControl, s.runScene},
+		{"POST", "/scenes/{scene}/record/{duration}", RoleAdmin, s.recordScene},
+		{"GET", "/recording", RoleRead, s.getRecording},
+		{"POST", "/recording/stop", RoleAdmin, s.stopRecording},
+		{"GET", "/presence", RoleRead, s.getPresence},
+		{"POST", "/presence", RoleControl, s.postPresence},
+		{"GET", "/occupancy", RoleRead, s.getOccupancy},
+		{"GET", "/contacts", RoleRead, s.getContacts},
+		{"GET", "/energy", RoleRead, s.getEnergy},
+		{"GET", "/batteries", RoleRead, s.getBatteries},
+		{"GET", "/grafana/{$}", RoleRead, s.grafanaTest},
+		{"GET", "/healthz", rolePublic, s.healthz},
+		{"GET", "/readyz", rolePublic, s.readyz},
+		{"POST", "/grafana/search", RoleRead, s.grafanaSearch},
+		{"POST", "/grafana/query", RoleRead, s.grafanaQuery},
+	}
+}
+
+// Handler returns the routes of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		h := rt.handler
+		if rt.method == "POST" && rt.role >= RoleControl {
+			h = s.idempotent(h)
+		}
+		if rt.role != rolePublic {
+			h = s.require(rt.role, h)
+		}
+		mux.HandleFunc(rt.method+" "+rt.path, h)
+	}
+	// The specification is public, so that tools such as Swagger UI can
+	// fetch it without a token
+	mux.HandleFunc("GET /openapi.yaml", serveOpenAPIYAML)
+	mux.HandleFunc("GET /openapi.json", serveOpenAPIJSON)
+	return s.logRequests(s.limit(mux))
+}
+
+// device is the JSON representation of a Device
+type device struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	On    bool   `json:"on"`
+	Level int    `json:"level,omitempty"`
+	Lock  string `json:"lock"`
+	State string `json:"state"` // Confidence in On and Level, see lwl.Confidence
+}
+
+func newDevice(d *lwl.Device) device {
+	st := d.State()
+	return device{
+		ID:    st.ID,
+		Name:  d.Name(),
+		On:    st.On,
+		Level: st.Level,
+		Lock:  d.LockMode().String(),
+		State: st.Confidence.String(),
+	}
+}
+
+func (s *Server) listDevices(w http.ResponseWriter, r *http.Request) {
+	out := []device{}
+	for _, d := range s.reg.Devices() {
+		out = append(out, newDevice(d))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getDevice(w http.ResponseWriter, r *http.Request) {
+	d, err := s.reg.Resolve(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newDevice(d))
+}
+
+func (s *Server) deviceOn(w http.ResponseWriter, r *http.Request) {
+	s.command(w, r, func(ctx context.Context, d *lwl.Device) error {
+		return d.On(ctx)
+	})
+}
+
+func (s *Server) deviceOff(w http.ResponseWriter, r *http.Request) {
+	s.command(w, r, func(ctx context.Context, d *lwl.Device) error {
+		return d.Off(ctx)
+	})
+}
+
+func (s *Server) deviceDim(w http.ResponseWriter, r *http.Request) {
+	level, err := strconv.Atoi(r.PathValue("level"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid dim level: %w", err))
+		return
+	}
+	s.command(w, r, func(ctx context.Context, d *lwl.Device) error {
+		return d.Dim(ctx, level)
+	})
+}
+
+func (s *Server) deviceLock(w http.ResponseWriter, r *http.Request) {
+	var mode lwl.LockMode
+	switch m := r.PathValue("mode"); m {
+	case lwl.Unlocked.String():
+		mode = lwl.Unlocked
+	case lwl.LockPartial.String():
+		mode = lwl.LockPartial
+	case lwl.LockFull.String():
+		mode = lwl.LockFull
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown lock mode: %q", m))
+		return
+	}
+	s.command(w, r, func(ctx context.Context, d *lwl.Device) error {
+		return d.Lock(ctx, mode)
+	})
+}
+
+// command resolves the device named in the request, and performs fn on it.
+// The optional "text" query parameter, e.g. "Side Lamp|Half Brightness", is
+// shown on the LWL's screen. With "dry_run=true" the command is not sent.
+func (s *Server) command(w http.ResponseWriter, r *http.Request, fn func(context.Context, *lwl.Device) error) {
+	d, err := s.reg.Resolve(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	ctx := r.Context()
+	if dry, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dry {
+		ctx = lwl.WithDryRun(ctx)
+	}
+	if text := r.URL.Query().Get("text"); text != "" {
+		if has, known := s.c.HasScreen(); known && !has {
+			writeError(w, http.StatusBadRequest, lwl.ErrNoScreen)
+			return
+		}
+		line1, line2, _ := strings.Cut(text, "|")
+		ctx = lwl.WithScreenText(ctx, line1, line2)
+	}
+	timeout := lwl.CommandTimeout
+	if d.Confirm() {
+		timeout = lwl.ConfirmTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := fn(ctx, d); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newDevice(d))
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	out := map[string]lwl.Response{}
+	if s.Status != nil {
+		out = s.Status()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getOccupancy(w http.ResponseWriter, r *http.Request) {
+	out := map[string]occupancy.State{}
+	if s.Occupancy != nil {
+		out = s.Occupancy.State()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getContacts(w http.ResponseWriter, r *http.Request) {
+	out := map[string]contact.State{}
+	if s.Contacts != nil {
+		out = s.Contacts.State()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) unpair(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), lwl.CommandTimeout)
+	defer cancel()
+	if _, err := s.c.Do(ctx, lwl.CmdDeregister); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getUnknown(w http.ResponseWriter, r *http.Request) {
+	out := []lwl.QuarantinedMessage{}
+	if s.c != nil {
+		out = append(out, s.c.Quarantine()...)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// getVersion describes the daemon's build, see buildinfo.Info
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+// sendCommand sends a command from the catalog, see lwl.Commands, so that
+// lwlctl can share the daemon's connection to the LWL
+func (s *Server) sendCommand(w http.ResponseWriter, r *http.Request) {
+	ci, ok := lwl.LookupCommand(r.PathValue("command"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown command: %q", r.PathValue("command")))
+		return
+	}
+	var body struct {
+		Args []string `json:"args"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	cmd, err := ci.Build(body.Args...)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), lwl.CommandTimeout)
+	defer cancel()
+	resp, err := s.c.Do(ctx, *cmd)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	// The LWL's reply verbatim, or null if there was none (e.g. a legacy-only
+	// command)
+	raw := json.RawMessage("null")
+	if msg := strings.TrimPrefix(resp.String(), "*!"); msg != "" {
+		raw = json.RawMessage(msg)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"response": raw})
+}
+
+// limit refuses requests beyond MaxRequests at once
+func (s *Server) limit(h http.Handler) http.Handler {
+	if s.MaxRequests <= 0 {
+		return h
+	}
+	sem := make(chan struct{}, s.MaxRequests)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			h.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, errors.New("too many requests at once"))
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write HTTP response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestDryRun(t *testing.T) {
+	reg := lwl.NewRegistry(&lwl.Client{}) // Not listening, so any transmission would fail
+	s := New(nil, reg, map[string]Token{"c": {Role: RoleControl}})
+
+	req := httptest.NewRequest("POST", "/devices/R1D1/on?dry_run=true", nil)
+	req.Header.Set("Authorization", "Bearer c")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"on":true`) {
+		t.Fatalf("want device on, got %s", rec.Body)
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+)
+
+// How long a sunrise alarm ramps up for, unless the request says otherwise
+const defaultSunrisePeriod = 30 * time.Minute
+
+// alarms are the sunrise alarms waiting or ramping, at most one per device
+type alarms struct {
+	mu      sync.Mutex
+	pending map[string]*context.CancelFunc // Keyed by device ID
+}
+
+// replace records the alarm for a device, cancelling any earlier one
+func (a *alarms) replace(id string, cancel *context.CancelFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending == nil {
+		a.pending = make(map[string]*context.CancelFunc)
+	}
+	if earlier := a.pending[id]; earlier != nil {
+		(*earlier)()
+	}
+	a.pending[id] = cancel
+}
+
+// done forgets the alarm for a device, unless it has since been replaced
+func (a *alarms) done(id string, cancel *context.CancelFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending[id] == cancel {
+		delete(a.pending, id)
+	}
+}
+
+// cancel cancels the alarm for a device, reporting whether there was one
+func (a *alarms) cancel(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cancel := a.pending[id]
+	if cancel == nil {
+		return false
+	}
+	(*cancel)()
+	delete(a.pending, id)
+	return true
+}
+
+// sunrise is the JSON representation of a scheduled sunrise alarm
+type sunrise struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"` // When the ramp begins
+	Wake  time.Time `json:"wake"`  // When it reaches full brightness
+}
+
+// startSunrise schedules lwl.SunriseAlarm on the device, to reach full
+// brightness at the next occurrence of the time of day in the path (e.g.
+// 07:00). The optional "period" query parameter, e.g. 20m, is how long the
+// ramp takes. Scheduling another alarm on the device replaces this one.
+func (s *Server) startSunrise(w http.ResponseWriter, r *http.Request) {
+	d, err := s.reg.Resolve(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	at, err := timeofday.Parse(r.PathValue("wake"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	period := defaultSunrisePeriod
+	if p := r.URL.Query().Get("period"); p != "" {
+		period, err = time.ParseDuration(p)
+		if err == nil && period <= 0 {
+			err = fmt.Errorf("period should be positive, got %s", p)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	wake := timeofday.Next(time.Now(), at)
+
+	ctx := lwl.WithSource(context.WithoutCancel(r.Context()), lwl.Source(r.Context())+"/sunrise")
+	ctx, cancel := context.WithCancel(ctx)
+	s.alarms.replace(d.ID(), &cancel)
+	panics.Go("sunrise", func() {
+		defer s.alarms.done(d.ID(), &cancel)
+		defer cancel()
+		switch err := lwl.SunriseAlarm(ctx, d, wake, period); {
+		case errors.Is(err, context.Canceled):
+			slog.Info("Sunrise alarm cancelled", "device", d, "wake", wake)
+		case err != nil:
+			slog.Error("Sunrise alarm failed", "device", d, "err", err)
+		}
+	})
+	slog.Info("Sunrise alarm set", "device", d, "wake", wake, "period", period)
+	writeJSON(w, http.StatusAccepted, sunrise{ID: d.ID(), Start: wake.Add(-period), Wake: wake})
+}
+
+// cancelSunrise cancels the device's sunrise alarm, leaving it at whatever
+// level the ramp had reached
+func (s *Server) cancelSunrise(w http.ResponseWriter, r *http.Request) {
+	d, err := s.reg.Resolve(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if !s.alarms.cancel(d.ID()) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no sunrise alarm for %s", d.ID()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
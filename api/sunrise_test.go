@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestSunrise(t *testing.T) {
+	reg := lwl.NewRegistry(&lwl.Client{}) // Not listening, so any transmission would fail
+	s := New(nil, reg, map[string]Token{"c": {Role: RoleControl}})
+	h := s.Handler()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer c")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/devices/R1D1/sunrise/7am", "/devices/R1D1/sunrise/07:00?period=-5m"} {
+		if rec := do("POST", path); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: want 400 got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	// Far enough ahead that the ramp does not begin during the test
+	wake := time.Now().Add(2 * time.Hour).Truncate(time.Minute)
+	rec := do("POST", "/devices/R1D1/sunrise/"+wake.Format("15:04")+"?period=20m")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("want 202 got %d: %s", rec.Code, rec.Body)
+	}
+	var got sunrise
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "R1D1" || !got.Wake.Equal(wake) || !got.Start.Equal(wake.Add(-20*time.Minute)) {
+		t.Errorf("want R1D1 from %v to %v, got %+v", wake.Add(-20*time.Minute), wake, got)
+	}
+
+	if rec := do("DELETE", "/devices/R1D1/sunrise"); rec.Code != http.StatusNoContent {
+		t.Fatalf("want 204 got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do("DELETE", "/devices/R1D1/sunrise"); rec.Code != http.StatusNotFound {
+		t.Fatalf("want 404 once cancelled, got %d: %s", rec.Code, rec.Body)
+	}
+}
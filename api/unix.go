@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// peer is the process at the other end of a Unix socket connection, see
+// ServeUnix
+type peer struct {
+	uid uint32
+	pid int32
+	err error // Credentials could not be read
+}
+
+type peerKey struct{}
+
+// ParseUnixRoles parses a comma separated list of users (by name or uid) and
+// their roles, e.g. "alice=control,1001=read", for use as Server.UnixRoles
+func ParseUnixRoles(s string) (map[uint32]Role, error) {
+	out := make(map[uint32]Role)
+	for f := range strings.SplitSeq(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		name, r, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q should look like user=role", f)
+		}
+		role, err := ParseRole(r)
+		if err != nil {
+			return nil, err
+		}
+		uid, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			if uid, err = strconv.ParseUint(u.Uid, 10, 32); err != nil {
+				return nil, fmt.Errorf("user %s: %w", name, err)
+			}
+		}
+		out[uint32(uid)] = role
+	}
+	return out, nil
+}
+
+// source returns what the process's commands are attributed to, see
+// lwl.WithSource, e.g. "unix:alice"
+func (p peer) source() string {
+	if u, err := user.LookupId(strconv.FormatUint(uint64(p.uid), 10)); err == nil {
+		return "unix:" + u.Username
+	}
+	return fmt.Sprintf("unix:uid-%d", p.uid)
+}
+
+// peerRole returns the role of a process connected to the Unix socket. Root,
+// and the user the daemon runs as, are administrators, since they could
+// reconfigure it anyway.
+func (s *Server) peerRole(p peer) (Role, bool) {
+	if p.err != nil {
+		return 0, false
+	}
+	if p.uid == 0 || p.uid == uint32(os.Getuid()) {
+		return RoleAdmin, true
+	}
+	role, ok := s.UnixRoles[p.uid]
+	return role, ok
+}
+
+// ServeUnix serves the API on a Unix socket at path until the context is
+// done. Clients are authorised by the credentials of their process, see
+// peerRole, instead of a bearer token, so that local tools need neither a
+// token nor a TCP port. The socket may be connected to by the daemon's user
+// and group.
+func (s *Server) ServeUnix(ctx context.Context, path string) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		os.Remove(path) // Left behind by a daemon which crashed
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		l.Close()
+		return err
+	}
+	hs := &http.Server{
+		Handler: s.Handler(),
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			var p peer
+			p.uid, p.pid, p.err = peerCred(c)
+			return context.WithValue(ctx, peerKey{}, p)
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		hs.Close()
+	}()
+	slog.Info("Serving HTTP API", "socket", path, "users", len(s.UnixRoles))
+	if err := hs.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestServeUnix(t *testing.T) {
+	reg := lwl.NewRegistry(nil)
+	reg.Device("R1D1")
+	s := New(nil, reg, nil)
+	path := filepath.Join(t.TempDir(), "api.sock")
+	go s.ServeUnix(t.Context(), path)
+
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for range 50 {
+		if resp, err = hc.Get("http://lightwaverf/devices/R1D1"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond) // Until listening
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("own user got %d, want admin", resp.StatusCode)
+	}
+}
+
+func TestPeerRole(t *testing.T) {
+	roles, err := ParseUnixRoles("1001=read, 1002=control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(nil, nil, nil)
+	s.UnixRoles = roles
+	for _, tt := range []struct {
+		uid   uint32
+		want  Role
+		known bool
+	}{
+		{0, RoleAdmin, true},
+		{1001, RoleRead, true},
+		{1002, RoleControl, true},
+		{1003, 0, false},
+	} {
+		if got, known := s.peerRole(peer{uid: tt.uid}); got != tt.want || known != tt.known {
+			t.Errorf("uid %d: got %v, %v", tt.uid, got, known)
+		}
+	}
+	for _, bad := range []string{"1001", "1001=root", "no-such-user=read"} {
+		if _, err := ParseUnixRoles(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package api
+
+import (
+	"errors"
+	"net"
+)
+
+func peerCred(c net.Conn) (uint32, int32, error) {
+	return 0, 0, errors.New("peer credentials are only supported on Linux")
+}
@@ -0,0 +1,75 @@
+// Package audit keeps an append-only log of the commands sent to a LightwaveRF
+// Link (LWL), so that questions such as "who turned the heating off at 3am?"
+// can be answered
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/jsonl"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Log is an lwl.Auditor which persists records as JSON lines
+type Log struct {
+	f *jsonl.File
+}
+
+// NewLog returns a Log which appends to the named file
+func NewLog(fn string) *Log {
+	return &Log{f: jsonl.New(fn)}
+}
+
+// Audit implements lwl.Auditor. Failures are logged, as there is nobody to
+// return them to.
+func (l *Log) Audit(r lwl.CommandRecord) {
+	if err := l.f.Append(r); err != nil {
+		slog.Error("Failed to write audit log", "fn", l.f.Name(), "err", err)
+	}
+}
+
+// Filter selects records from a Log. Zero-valued fields match everything.
+type Filter struct {
+	Since   time.Time // Sent at or after
+	Until   time.Time // Sent before
+	Source  string    // See lwl.SourceMatches, e.g. "rule" matches "rule:dusk"
+	Command string    // Substring match, e.g. "R1D1"
+	Result  string    // Exact match: "ok", "err", "timeout", "dry-run" or "held-off"
+}
+
+// Match reports whether the record is selected by the filter
+func (f Filter) Match(r lwl.CommandRecord) bool {
+	switch {
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	case f.Source != "" && !lwl.SourceMatches(r.Source, f.Source):
+		return false
+	case f.Command != "" && !strings.Contains(r.Command, f.Command):
+		return false
+	case f.Result != "" && r.Result != f.Result:
+		return false
+	}
+	return true
+}
+
+// Query returns the records selected by the filter, in the order they were
+// written. A missing log is treated as empty.
+func (l *Log) Query(f Filter) ([]lwl.CommandRecord, error) {
+	var out []lwl.CommandRecord
+	err := l.f.Each(func(line []byte) error {
+		var r lwl.CommandRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		if f.Match(r) {
+			out = append(out, r)
+		}
+		return nil
+	})
+	return out, err
+}
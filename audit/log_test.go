@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestQuery(t *testing.T) {
+	l := NewLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+
+	t0 := time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC)
+	records := []lwl.CommandRecord{
+		{Time: t0, Source: "scheduler", Command: "!R1F*tP16", Result: "ok"},
+		{Time: t0.Add(time.Minute), Source: "cli", Command: "!R1D1F1", Result: "timeout"},
+		{Time: t0.Add(time.Hour), Source: "cli", Command: "!R1D1F0", Result: "ok"},
+		{Time: t0.Add(2 * time.Hour), Source: "rule:dusk/scene:evening", Command: "!R1D2F1", Result: "ok"},
+		{Time: t0.Add(3 * time.Hour), Source: "rules", Command: "!R1D2F0", Result: "ok"},
+	}
+	for _, r := range records {
+		l.Audit(r)
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want int // Number of records selected
+	}{
+		{name: "All", f: Filter{}, want: 5},
+		{name: "Source", f: Filter{Source: "cli"}, want: 2},
+		{name: "SourcePrefix", f: Filter{Source: "rule"}, want: 1},
+		{name: "SourceRule", f: Filter{Source: "rule:dusk"}, want: 1},
+		{name: "Command", f: Filter{Command: "R1D1"}, want: 2},
+		{name: "Result", f: Filter{Result: "timeout"}, want: 1},
+		{name: "Window", f: Filter{Since: t0.Add(time.Second), Until: t0.Add(time.Hour)}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("Query(%+v) returned %d records, want %d: %v", tt.f, len(got), tt.want, got)
+			}
+		})
+	}
+}
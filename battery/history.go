@@ -0,0 +1,52 @@
+// Package battery records the battery voltages reported by LightwaveRF
+// heating devices, and estimates when they will need replacing
+package battery
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/jsonl"
+)
+
+// Reading is a battery voltage reported by a device at a point in time
+type Reading struct {
+	Serial string    `json:"serial"` // e.g. "24C702"
+	Prod   string    `json:"prod"`   // e.g. "valve"
+	Time   time.Time `json:"time"`
+	Volts  float64   `json:"volts"`
+}
+
+// History is an append-only log of readings, persisted as JSON lines
+type History struct {
+	f *jsonl.File
+}
+
+// NewHistory returns a History which persists readings to the named file
+func NewHistory(fn string) *History {
+	return &History{f: jsonl.New(fn)}
+}
+
+// Append adds a reading to the end of the log
+func (h *History) Append(r Reading) error {
+	return h.f.Append(r)
+}
+
+// Load returns every reading in the log, oldest first. A missing log is
+// treated as empty.
+func (h *History) Load() ([]Reading, error) {
+	var out []Reading
+	err := h.f.Each(func(line []byte) error {
+		var r Reading
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	})
+	slices.SortStableFunc(out, func(a, b Reading) int {
+		return a.Time.Compare(b.Time)
+	})
+	return out, err
+}
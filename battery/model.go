@@ -0,0 +1,94 @@
+package battery
+
+import (
+	"math"
+	"time"
+)
+
+// Model predicts when a device's batteries will reach LowVolts, from readings
+// taken since they were last replaced (oldest first). It returns false if the
+// batteries do not appear to be discharging.
+type Model interface {
+	Predict(rs []Reading) (time.Time, bool)
+}
+
+// Linear fits a straight line to voltage over time. It suits devices with a
+// steady load, such as valves, whose motors dominate their consumption.
+type Linear struct{}
+
+// Predict implements Model
+func (Linear) Predict(rs []Reading) (time.Time, bool) {
+	xs, ys := make([]float64, len(rs)), make([]float64, len(rs))
+	for i, r := range rs {
+		xs[i] = days(rs[0].Time, r.Time)
+		ys[i] = r.Volts
+	}
+	m, c, ok := fit(xs, ys)
+	if !ok || m >= -flatSlope {
+		return time.Time{}, false
+	}
+	return at(rs[0].Time, (LowVolts-c)/m), true
+}
+
+// Exponential fits an exponential decay towards Floor volts. It suits devices
+// which are mostly idle, such as thermostats and sensors, whose voltage falls
+// quickly when fresh and then levels off.
+type Exponential struct {
+	Floor float64 // Voltage approached but never reached, e.g. 2.0 for 2x AA
+}
+
+// Predict implements Model
+func (e Exponential) Predict(rs []Reading) (time.Time, bool) {
+	var xs, ys []float64
+	for _, r := range rs {
+		if r.Volts <= e.Floor {
+			continue // Outside the model, ln() is undefined
+		}
+		xs = append(xs, days(rs[0].Time, r.Time))
+		ys = append(ys, math.Log(r.Volts-e.Floor))
+	}
+	m, c, ok := fit(xs, ys)
+	if !ok || m >= 0 || LowVolts <= e.Floor {
+		return time.Time{}, false
+	}
+	return at(rs[0].Time, (math.Log(LowVolts-e.Floor)-c)/m), true
+}
+
+// ModelFor returns the discharge model used for a product type (Reading.Prod)
+func ModelFor(prod string) Model {
+	switch prod {
+	case "valve":
+		return Linear{}
+	default:
+		return Exponential{Floor: 2.0}
+	}
+}
+
+// fit returns the gradient and intercept of the least-squares straight line
+// through the given points, or false if they span less than an hour
+func fit(xs, ys []float64) (m, c float64, ok bool) {
+	if len(xs) < 2 || xs[len(xs)-1]-xs[0] < 1.0/24 {
+		return 0, 0, false
+	}
+	var n, sx, sy, sxx, sxy float64
+	for i := range xs {
+		n++
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	m = (n*sxy - sx*sy) / (n*sxx - sx*sx)
+	c = (sy - m*sx) / n
+	return m, c, true
+}
+
+// days returns the (fractional) number of days from t0 to t
+func days(t0, t time.Time) float64 {
+	return t.Sub(t0).Hours() / 24
+}
+
+// at returns the time the given (fractional) number of days after t0
+func at(t0 time.Time, d float64) time.Time {
+	return t0.Add(time.Duration(d * 24 * float64(time.Hour)))
+}
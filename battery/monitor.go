@@ -0,0 +1,51 @@
+package battery
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Monitor records the battery voltage reported by each statusPush, and
+// notifies when a device's batteries become low
+type Monitor struct {
+	hist   *History
+	notify func(msg string, args ...any)
+
+	mu  sync.Mutex
+	low map[string]bool // Serial -> Already notified
+}
+
+// NewMonitor returns a Monitor which records readings in hist, and notifies
+// via notify, e.g. notify.Notifier.Notify
+func NewMonitor(hist *History, notify func(msg string, args ...any)) *Monitor {
+	return &Monitor{hist: hist, notify: notify, low: make(map[string]bool)}
+}
+
+// Observe records the battery voltage in a message received at time t, if it
+// has one. Name is the device's configured name, for the notification.
+func (m *Monitor) Observe(msg lwl.Response, name string, t time.Time) {
+	if msg.Fn != "statusPush" || msg.Batt <= 0 {
+		return
+	}
+	r := Reading{
+		Serial: msg.Serial,
+		Prod:   msg.Prod,
+		Time:   t,
+		Volts:  math.Round(float64(msg.Batt)*100) / 100, // Reported to 2 d.p.
+	}
+	if err := m.hist.Append(r); err != nil {
+		slog.Error("Failed to record battery reading", "fn", m.hist.f.Name(), "err", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	isLow := r.Volts <= LowVolts
+	if isLow && !m.low[r.Serial] {
+		m.notify("Low battery", "name", name, "serial", r.Serial, "volts", r.Volts)
+	}
+	m.low[r.Serial] = isLow
+}
@@ -0,0 +1,32 @@
+package battery
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestMonitor(t *testing.T) {
+	hist := NewHistory(filepath.Join(t.TempDir(), "battery.jsonl"))
+	var notes int
+	m := NewMonitor(hist, func(string, ...any) { notes++ })
+
+	t0 := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for i, v := range []float32{2.5, 2.4, 2.39, 2.5, 2.3} {
+		m.Observe(lwl.Response{Fn: "statusPush", Serial: "24C702", Prod: "valve", Batt: v}, "Lounge", t0.Add(time.Duration(i)*time.Hour))
+	}
+	m.Observe(lwl.Response{Fn: "meterData", Serial: "A1B2C3"}, "", t0) // No battery
+
+	rs, err := hist.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 5 || rs[2].Volts != 2.39 {
+		t.Errorf("recorded %v", rs)
+	}
+	if notes != 2 {
+		t.Errorf("notified %d times, want once per fall to low", notes)
+	}
+}
@@ -0,0 +1,97 @@
+package battery
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LowVolts is the level below which batteries are considered low
+const LowVolts = 2.40
+
+// A rise of at least this many volts between readings means the batteries
+// were replaced, so earlier readings say nothing about the new ones
+const replacedJump = 0.2
+
+// Slopes smaller than this (in volts/day) are considered flat
+const flatSlope = 0.0005
+
+// Summary describes the state of one device's batteries
+type Summary struct {
+	Serial        string
+	Prod          string
+	Latest        Reading
+	Slope         float64   // Volts per day, since the batteries were last replaced
+	Replace       time.Time // Predicted time of reaching LowVolts, or zero if not discharging
+	DaysRemaining float64   // Days from Latest until Replace, or +Inf if not discharging
+}
+
+// Trend returns an arrow indicating the direction of Slope
+func (s Summary) Trend() string {
+	switch {
+	case s.Slope < -flatSlope:
+		return "↓"
+	case s.Slope > flatSlope:
+		return "↑"
+	default:
+		return "→"
+	}
+}
+
+// Summarise returns a Summary per device, ordered by serial. Readings must be
+// oldest first, as returned by History.Load.
+func Summarise(readings []Reading) []Summary {
+	bySerial := make(map[string][]Reading)
+	for _, r := range readings {
+		bySerial[r.Serial] = append(bySerial[r.Serial], r)
+	}
+
+	out := make([]Summary, 0, len(bySerial))
+	for serial, rs := range bySerial {
+		rs = sinceReplaced(rs)
+		latest := rs[len(rs)-1]
+		s := Summary{
+			Serial:        serial,
+			Prod:          latest.Prod,
+			Latest:        latest,
+			Slope:         slope(rs),
+			DaysRemaining: math.Inf(1),
+		}
+		if latest.Volts <= LowVolts {
+			s.Replace = latest.Time
+			s.DaysRemaining = 0
+		} else if t, ok := ModelFor(s.Prod).Predict(rs); ok {
+			s.Replace = t
+			s.DaysRemaining = max(days(latest.Time, t), 0)
+		}
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b Summary) int {
+		return strings.Compare(a.Serial, b.Serial)
+	})
+	return out
+}
+
+// sinceReplaced returns the readings taken since the batteries were last
+// replaced
+func sinceReplaced(rs []Reading) []Reading {
+	for i := len(rs) - 1; i > 0; i-- {
+		if rs[i].Volts-rs[i-1].Volts >= replacedJump {
+			return rs[i:]
+		}
+	}
+	return rs
+}
+
+// slope returns the gradient of voltage over time, in volts per day, or 0 if
+// the readings span too short a time to be meaningful
+func slope(rs []Reading) float64 {
+	xs, ys := make([]float64, len(rs)), make([]float64, len(rs))
+	for i, r := range rs {
+		xs[i] = days(rs[0].Time, r.Time)
+		ys[i] = r.Volts
+	}
+	m, _, _ := fit(xs, ys)
+	return m
+}
@@ -0,0 +1,95 @@
+package battery
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarise(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	readings := []Reading{
+		// Discharging at 0.01V/day, with a battery change part way through
+		{Serial: "24C702", Prod: "valve", Time: t0, Volts: 2.45},
+		{Serial: "24C702", Prod: "valve", Time: t0.Add(1 * day), Volts: 3.10},
+		{Serial: "24C702", Prod: "valve", Time: t0.Add(2 * day), Volts: 3.09},
+		{Serial: "24C702", Prod: "valve", Time: t0.Add(3 * day), Volts: 3.08},
+		// Flat
+		{Serial: "D88002", Prod: "valve", Time: t0, Volts: 3.00},
+		{Serial: "D88002", Prod: "valve", Time: t0.Add(10 * day), Volts: 3.00},
+		// Already low
+		{Serial: "6E8002", Prod: "valve", Time: t0, Volts: 2.30},
+	}
+
+	got := Summarise(readings)
+	if len(got) != 3 {
+		t.Fatalf("want 3 summaries, got %d", len(got))
+	}
+
+	s := got[0]
+	if s.Serial != "24C702" || s.Trend() != "↓" || math.Abs(s.Slope+0.01) > 1e-9 {
+		t.Errorf("24C702: %+v", s)
+	}
+	if math.Abs(s.DaysRemaining-68) > 1e-6 {
+		t.Errorf("24C702: want 68 days remaining, got %v", s.DaysRemaining)
+	}
+
+	if s := got[1]; s.Serial != "6E8002" || s.DaysRemaining != 0 {
+		t.Errorf("6E8002: %+v", s)
+	}
+
+	if s := got[2]; s.Serial != "D88002" || s.Trend() != "→" || !math.IsInf(s.DaysRemaining, 1) {
+		t.Errorf("D88002: %+v", s)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(filepath.Join(t.TempDir(), "battery.jsonl"))
+
+	rs, err := h.Load()
+	if err != nil || len(rs) != 0 {
+		t.Fatalf("missing history should be empty: %v %v", rs, err)
+	}
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range []Reading{
+		{Serial: "24C702", Time: t0.Add(time.Hour), Volts: 3.01},
+		{Serial: "24C702", Time: t0, Volts: 3.02},
+	} {
+		if err := h.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rs, err = h.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || !rs[0].Time.Equal(t0) || rs[0].Volts != 3.02 {
+		t.Fatalf("Load() = %+v", rs)
+	}
+}
+
+func TestExponential(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rs []Reading
+	for d := 0; d <= 60; d += 10 {
+		rs = append(rs, Reading{
+			Serial: "D41602",
+			Prod:   "tmr1ch",
+			Time:   t0.Add(time.Duration(d) * 24 * time.Hour),
+			Volts:  2.0 + math.Exp(-0.01*float64(d)),
+		})
+	}
+
+	got, ok := ModelFor("tmr1ch").Predict(rs)
+	if !ok {
+		t.Fatal("Predict() should find batteries discharging")
+	}
+	want := t0.Add(time.Duration(-math.Log(0.4) / 0.01 * 24 * float64(time.Hour)))
+	if d := got.Sub(want).Abs(); d > time.Minute {
+		t.Fatalf("Predict() = %v, want %v", got, want)
+	}
+}
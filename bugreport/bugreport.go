@@ -0,0 +1,284 @@
+// Package bugreport recovers panics in the daemon's goroutines, writing a
+// diagnostic bundle to disk so that the fault can be reported, and keeping
+// the daemon running where it is safe to. Panics in goroutines and timer
+// callbacks started within other packages reach it through package panics,
+// see Bundler.ReportPanics. lwlctl bugreport collects the bundles.
+package bugreport
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/buildinfo"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"gopkg.in/yaml.v3"
+)
+
+// Number of recent messages from the LWL kept for a bundle
+const eventsSize = 200
+
+// How many times a goroutine is restarted after panicking, and how long
+// after, see Go
+const maxRestarts = 3
+
+var restartDelay = time.Second
+
+// Bundle is a snapshot of the daemon, to attach to a bug report
+type Bundle struct {
+	Time   time.Time         `json:"time"`
+	Reason string            `json:"reason"` // E.g. "panic in rules: ..." or "requested"
+	Build  buildinfo.Info    `json:"build"`
+	Uptime string            `json:"uptime"`
+	Flags  map[string]string `json:"flags"`            // Secrets redacted, see Redact
+	Config map[string]string `json:"config,omitempty"` // File name -> contents, secrets redacted, see RedactYAML
+	Events []Event           `json:"events"`           // Most recent messages from the LWL, oldest first
+	Stacks string            `json:"stacks"`           // Of every goroutine
+}
+
+// Event is a message from the LWL, with its MAC anonymised
+type Event struct {
+	Time time.Time `json:"time"`
+	Msg  string    `json:"msg"`
+}
+
+// Bundler writes bundles to a directory
+type Bundler struct {
+	dir   string
+	start time.Time
+
+	// Flags are the daemon's configuration, see Redact. Optional.
+	Flags map[string]string
+
+	// ConfigFiles are the daemon's YAML configuration files, read afresh
+	// for each bundle, see RedactYAML. Those missing are left out. Optional.
+	ConfigFiles []string
+
+	// MaxEvents is how many recent messages from the LWL are kept, see
+	// Watch. Default 200.
+	MaxEvents int
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// New returns a Bundler writing to dir, which is created if need be
+func New(dir string) *Bundler {
+	return &Bundler{dir: dir, start: time.Now()}
+}
+
+// Watch keeps the most recent messages from the LWL for bundles, until msgs
+// is closed or the context is done
+func (b *Bundler) Watch(ctx context.Context, msgs <-chan lwl.Response) {
+	for {
+		select {
+		case r, ok := <-msgs:
+			if !ok {
+				return
+			}
+			msg := r.String()
+			if r.Mac != "" {
+				msg = strings.ReplaceAll(msg, r.Mac, "00:00:00")
+			}
+			b.mu.Lock()
+			if size := cmp.Or(b.MaxEvents, eventsSize); len(b.events) >= size {
+				b.events = slices.Delete(b.events, 0, len(b.events)-size+1)
+			}
+			b.events = append(b.events, Event{Time: time.Now(), Msg: msg})
+			b.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Snapshot returns a bundle describing the daemon now
+func (b *Bundler) Snapshot(reason string) Bundle {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	b.mu.Lock()
+	events := slices.Clone(b.events)
+	b.mu.Unlock()
+	var config map[string]string
+	for _, fn := range b.ConfigFiles {
+		data, err := os.ReadFile(fn)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		var s string
+		if err == nil {
+			s, err = RedactYAML(data)
+		}
+		if err != nil {
+			s = fmt.Sprintf("(withheld: %v)", err)
+		}
+		if config == nil {
+			config = make(map[string]string)
+		}
+		config[fn] = s
+	}
+	return Bundle{
+		Time:   time.Now(),
+		Reason: reason,
+		Build:  buildinfo.Get(),
+		Uptime: time.Since(b.start).Round(time.Second).String(),
+		Flags:  b.Flags,
+		Config: config,
+		Events: events,
+		Stacks: string(buf),
+	}
+}
+
+// Write saves a bundle in the directory, returning its file name
+func (b *Bundler) Write(bundle Bundle) (string, error) {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return "", err
+	}
+	buf, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fn := filepath.Join(b.dir, "bugreport-"+bundle.Time.UTC().Format("20060102T150405.000Z")+".json")
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return fn, os.Rename(tmp, fn)
+}
+
+// crashed writes a bundle for a panic, logging where
+func (b *Bundler) crashed(name string, v any) {
+	reason := fmt.Sprintf("panic in %s: %v", name, v)
+	fn, err := b.Write(b.Snapshot(reason))
+	if err != nil {
+		slog.Error("Unable to write bug report", "reason", reason, "err", err)
+		return
+	}
+	slog.Error("Wrote bug report, please attach it to an issue", "reason", reason, "fn", fn)
+}
+
+// Go runs f in a goroutine. If it panics, a bundle is written and f is
+// restarted, up to maxRestarts times, after which the goroutine is left
+// stopped; either way the rest of the daemon keeps running. f should be a
+// long-running loop whose state survives restarts, e.g. Engine.Run, so should
+// be given channels such as Client.Events rather than subscribe itself.
+func (b *Bundler) Go(name string, f func()) {
+	go func() {
+		for restarts := 0; ; restarts++ {
+			if !b.run(name, f) {
+				return // Returned normally
+			}
+			if restarts == maxRestarts {
+				slog.Error("Giving up restarting after panics", "goroutine", name, "restarts", restarts)
+				return
+			}
+			time.Sleep(restartDelay)
+			slog.Warn("Restarting after panic", "goroutine", name)
+		}
+	}()
+}
+
+// run calls f, reporting whether it panicked
+func (b *Bundler) run(name string, f func()) (panicked bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			b.crashed(name, v)
+			panicked = true
+		}
+	}()
+	f()
+	return false
+}
+
+// ReportPanics writes a bundle for each panic recovered by package panics,
+// from goroutines and timer callbacks started within other packages. Unlike
+// Go, what panicked is not restarted.
+func (b *Bundler) ReportPanics() {
+	panics.SetHandler(b.crashed)
+}
+
+// Recover writes a bundle if the calling goroutine is panicking, and then
+// panics again, since it is not safe to continue. Defer it at the start of
+// main.
+func (b *Bundler) Recover(name string) {
+	if v := recover(); v != nil {
+		b.crashed(name, v)
+		panic(v)
+	}
+}
+
+// Words which, in the name of a flag, mean its value is redacted
+var secretWords = []string{"password", "secret", "token", "key", "location"}
+
+// Redact returns the values of the flags in fs, with secrets redacted: the
+// passwords in URLs, flags whose names suggest secrets, e.g. -location, and
+// MACs, e.g. -hub-mac, which are anonymised as in the LWL's messages
+func Redact(fs *flag.FlagSet) map[string]string {
+	out := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		out[f.Name] = redact(f.Name, f.Value.String())
+	})
+	return out
+}
+
+// redact returns the value of a flag or YAML key, see Redact
+func redact(name, v string) string {
+	name = strings.ToLower(name)
+	if u, err := url.Parse(v); err == nil && u.User != nil {
+		v = u.Redacted()
+	}
+	switch {
+	case v == "":
+	case slices.ContainsFunc(secretWords, func(w string) bool { return strings.Contains(name, w) }):
+		v = "xxxxx"
+	case strings.Contains(name, "mac") && v != "auto":
+		v = "00:00:00"
+	}
+	return v
+}
+
+// RedactYAML returns a YAML document with secrets redacted as by Redact, in
+// the values of mappings according to their keys, and in URLs anywhere. The
+// paths and queries of URLs are redacted too, as those of e.g. private
+// calendars are secret.
+func RedactYAML(data []byte) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return "", err
+	}
+	var walk func(n *yaml.Node, key string)
+	walk = func(n *yaml.Node, key string) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			n.Value = redact(key, n.Value)
+			if u, err := url.Parse(n.Value); err == nil && u.Host != "" && (u.Path != "" || u.RawQuery != "") {
+				u.Path, u.RawPath, u.RawQuery, u.Fragment = "/xxxxx", "", "", ""
+				n.Value = u.String()
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], n.Content[i].Value)
+			}
+		default:
+			for _, c := range n.Content {
+				walk(c, key)
+			}
+		}
+	}
+	walk(&doc, "")
+	out, err := yaml.Marshal(&doc)
+	return string(out), err
+}
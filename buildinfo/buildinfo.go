@@ -0,0 +1,66 @@
+// Package buildinfo describes how the daemon and lwlctl were built, from the
+// information embedded by the Go toolchain, so that support requests can
+// name the exact build.
+package buildinfo
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Info describes a build
+type Info struct {
+	Version  string    `json:"version"`            // Module version, e.g. v1.2.3, or "(devel)" if built from a checkout
+	Commit   string    `json:"commit,omitempty"`   // VCS revision, if built from a checkout
+	Time     time.Time `json:"time,omitzero"`      // Of Commit
+	Modified bool      `json:"modified,omitempty"` // The checkout had uncommitted changes
+	Go       string    `json:"go"`                 // Toolchain, e.g. go1.25.0
+	OS       string    `json:"os"`                 // GOOS/GOARCH, e.g. linux/arm64
+}
+
+// Get returns the Info of the running binary
+var Get = sync.OnceValue(func() Info {
+	i := Info{Version: "unknown", Go: runtime.Version(), OS: runtime.GOOS + "/" + runtime.GOARCH}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return i
+	}
+	i.Version = bi.Main.Version
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			i.Commit = s.Value
+		case "vcs.time":
+			i.Time, _ = time.Parse(time.RFC3339, s.Value)
+		case "vcs.modified":
+			i.Modified = s.Value == "true"
+		}
+	}
+	return i
+})
+
+// String describes the build on one line, e.g.
+// "v1.2.3 (3f2c1a9, modified) go1.25.0 linux/arm64"
+func (i Info) String() string {
+	var b strings.Builder
+	b.WriteString(i.Version)
+	if i.Commit != "" {
+		fmt.Fprintf(&b, " (%.7s", i.Commit)
+		if i.Modified {
+			b.WriteString(", modified")
+		}
+		b.WriteString(")")
+	}
+	fmt.Fprintf(&b, " %s %s", i.Go, i.OS)
+	return b.String()
+}
+
+// LogValue implements slog.LogValuer
+func (i Info) LogValue() slog.Value {
+	return slog.StringValue(i.String())
+}
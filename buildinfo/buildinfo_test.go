@@ -0,0 +1,13 @@
+package buildinfo
+
+import "testing"
+
+func TestString(t *testing.T) {
+	i := Info{Version: "v1.2.3", Commit: "3f2c1a9e8d7c", Modified: true, Go: "go1.25.0", OS: "linux/arm64"}
+	if got, want := i.String(), "v1.2.3 (3f2c1a9, modified) go1.25.0 linux/arm64"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := Get(); got.Go == "" || got.Version == "" {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,178 @@
+// Package census counts the kinds of message a LightwaveRF Link (LWL) sends,
+// by pkt, fn, type, prod and the names of their fields, but not their
+// values. Users may opt in to keeping a census, and share its report so that
+// maintainers can see which messages are worth decoding.
+package census
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// Kind is a combination of pkt, fn, type and prod, with the fields seen in
+// one message
+type Kind struct {
+	Pkt    string   `json:"pkt,omitempty"`
+	Fn     string   `json:"fn,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Prod   string   `json:"prod,omitempty"`
+	Fields []string `json:"fields"` // Sorted
+	Count  int      `json:"count"`  // Messages of this kind
+}
+
+// key identifies a Kind
+func (k Kind) key() string {
+	return strings.Join([]string{k.Pkt, k.Fn, k.Type, k.Prod, strings.Join(k.Fields, ",")}, "|")
+}
+
+// Census counts messages by Kind
+type Census struct {
+	mu    sync.Mutex
+	since time.Time
+	kinds map[string]*Kind
+}
+
+// New returns an empty Census
+func New() *Census {
+	return &Census{since: time.Now(), kinds: make(map[string]*Kind)}
+}
+
+// file is the JSON representation of a Census
+type file struct {
+	Since time.Time `json:"since"`
+	Kinds []Kind    `json:"kinds"`
+}
+
+// Load reads a Census written by Save
+func Load(fn string) (*Census, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	c := &Census{since: f.Since, kinds: make(map[string]*Kind, len(f.Kinds))}
+	for _, k := range f.Kinds {
+		c.kinds[k.key()] = &k
+	}
+	return c, nil
+}
+
+// Save writes the census to a file, replacing it atomically
+func (c *Census) Save(fn string) error {
+	buf, err := json.MarshalIndent(file{Since: c.since, Kinds: c.Kinds()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// Observe counts a message. Only the names of its fields are kept, not their
+// values.
+func (c *Census) Observe(r lwl.Response) {
+	if r.Replay {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := wire.UnmarshalJSON([]byte(r.String()), &fields); err != nil {
+		return
+	}
+	k := Kind{Pkt: r.Pkt, Fn: r.Fn, Type: r.Type, Prod: r.Prod, Fields: slices.Sorted(maps.Keys(fields))}
+	key := k.key()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if have, ok := c.kinds[key]; ok {
+		have.Count++
+		return
+	}
+	k.Count = 1
+	c.kinds[key] = &k
+}
+
+// Kinds returns a copy of the kinds counted, most common first
+func (c *Census) Kinds() []Kind {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Kind, 0, len(c.kinds))
+	for _, k := range c.kinds {
+		out = append(out, *k)
+	}
+	slices.SortFunc(out, func(a, b Kind) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.key(), b.key())
+	})
+	return out
+}
+
+// Report writes the census as a Markdown table, suitable for pasting into a
+// GitHub issue
+func (c *Census) Report(w io.Writer) error {
+	fmt.Fprintf(w, "LightwaveLink protocol census since %s\n\n", c.since.Format(time.DateOnly))
+	fmt.Fprintln(w, "| count | pkt | fn | type | prod | fields |")
+	fmt.Fprintln(w, "|---:|---|---|---|---|---|")
+	for _, k := range c.Kinds() {
+		if _, err := fmt.Fprintf(w, "| %d | %s | %s | %s | %s | %s |\n", k.Count, k.Pkt, k.Fn, k.Type, k.Prod, strings.Join(k.Fields, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run counts msgs, saving the census to fn every interval and when the
+// context is done
+func (c *Census) Run(ctx context.Context, msgs <-chan lwl.Response, fn string, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	save := func() {
+		if err := c.Save(fn); err != nil {
+			slog.Error("Unable to save protocol census", "fn", fn, "err", err)
+		}
+	}
+	for {
+		select {
+		case r, ok := <-msgs:
+			if !ok {
+				save()
+				return
+			}
+			c.Observe(r)
+		case <-t.C:
+			save()
+		case <-ctx.Done():
+			save()
+			return
+		}
+	}
+}
+
+// LoadOrNew reads a Census written by Save, or returns an empty one if the
+// file does not exist
+func LoadOrNew(fn string) (*Census, error) {
+	c, err := Load(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	return c, err
+}
@@ -0,0 +1,72 @@
+package census
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestCensus(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+	events := c.Events(t.Context())
+	if _, err := c.Do(t.Context(), lwl.CmdHubCall); err != nil {
+		t.Fatal(err)
+	}
+	<-events // The reply to hubCall
+
+	cen := New()
+	for _, fields := range []map[string]any{
+		{"pkt": "433T", "fn": "on", "room": 1, "dev": 2},
+		{"pkt": "433T", "fn": "on", "room": 3, "dev": 4},
+		{"pkt": "868R", "fn": "statusPush", "prod": "valve", "serial": "ABC123", "cTemp": 19.5},
+	} {
+		if err := h.Push(fields); err != nil {
+			t.Fatal(err)
+		}
+		cen.Observe(<-events)
+	}
+
+	kinds := cen.Kinds()
+	if len(kinds) != 2 || kinds[0].Count != 2 || kinds[0].Fn != "on" {
+		t.Fatalf("got %+v", kinds)
+	}
+	if want := []string{"cTemp", "fn", "mac", "pkt", "prod", "serial", "time", "trans"}; !slices.Equal(kinds[1].Fields, want) {
+		t.Errorf("fields %q, want %q", kinds[1].Fields, want)
+	}
+
+	fn := filepath.Join(t.TempDir(), "census.json")
+	if err := cen.Save(fn); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Observe(lwl.Response{Pkt: "433T", Fn: "on", Replay: true}) // Not counted
+	var b strings.Builder
+	if err := loaded.Report(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| 2 | 433T | on |  |  | dev, fn, mac, pkt, room, time, trans |") {
+		t.Errorf("report:\n%s", b.String())
+	}
+	for _, value := range []string{"ABC123", "19.5"} {
+		if strings.Contains(b.String(), value) {
+			t.Errorf("report includes value %s:\n%s", value, b.String())
+		}
+	}
+}
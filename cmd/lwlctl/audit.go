@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/audit"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// auditQuery prints the commands recorded in the audit log
+func auditQuery(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "Only show commands sent this recently (0 for all)")
+	var f audit.Filter
+	fs.StringVar(&f.Source, "source", "", "Only show commands from this source, e.g. cli, rule or rule:dusk")
+	fs.StringVar(&f.Command, "command", "", "Only show commands containing this text, e.g. R1D1")
+	fs.StringVar(&f.Result, "result", "", "Only show commands with this result: ok, err, timeout, dry-run or held-off")
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if *since > 0 {
+		f.Since = time.Now().Add(-*since)
+	}
+	if *auditFile == "" {
+		return fmt.Errorf("no audit log, see -audit")
+	}
+
+	records, err := audit.NewLog(*auditFile).Query(f)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		if records == nil {
+			records = []lwl.CommandRecord{} // [] rather than null
+		}
+		return printJSON(records)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSOURCE\tCOMMAND\tRESULT\tLATENCY\tERROR")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\n",
+			r.Time.Local().Format(time.DateTime),
+			r.Source,
+			r.Command,
+			r.Result,
+			r.Latency.Round(time.Millisecond),
+			r.Error,
+		)
+	}
+	return w.Flush()
+}
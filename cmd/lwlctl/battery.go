@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+)
+
+// batteryReport prints the latest battery voltage of each device, with its
+// trend, estimated days remaining and predicted replacement date, from the
+// daemon's battery history
+func batteryReport(args []string) error {
+	fs := flag.NewFlagSet("battery", flag.ContinueOnError)
+	historyFile := fs.String("history", "battery.jsonl", "Battery history written by the daemon")
+	configFile := fs.String("config", "config.yaml", "Configuration file naming each serial")
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	readings, err := battery.NewHistory(*historyFile).Load()
+	if err != nil {
+		return err
+	}
+	if len(readings) == 0 {
+		return fmt.Errorf("no battery readings in %s; is the daemon running?", *historyFile)
+	}
+	names := loadNames(*configFile)
+	summaries := battery.Summarise(readings)
+
+	if *jsonOut {
+		out := make([]batteryJSON, len(summaries))
+		for i, s := range summaries {
+			out[i] = newBatteryJSON(s, names[s.Serial])
+		}
+		return printJSON(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIAL\tNAME\tPROD\tVOLTS\tTREND\tDAYS LEFT\tREPLACE BY\tLAST SEEN")
+	for _, s := range summaries {
+		days, replace := "?", "?"
+		if !math.IsInf(s.DaysRemaining, 1) {
+			days = fmt.Sprintf("%.0f", s.DaysRemaining)
+			replace = s.Replace.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\t%s\t%s\t%s\n",
+			s.Serial,
+			names[s.Serial],
+			s.Prod,
+			s.Latest.Volts,
+			s.Trend(),
+			days,
+			replace,
+			s.Latest.Time.Format(time.DateTime),
+		)
+	}
+	return w.Flush()
+}
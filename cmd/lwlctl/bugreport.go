@@ -0,0 +1,90 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// bugReport collects the bundles written by the daemon when part of it
+// panicked, and a description of it now if it is running, into one archive
+// to attach to an issue
+func bugReport(args []string) error {
+	fs := flag.NewFlagSet("bugreport", flag.ContinueOnError)
+	dir := fs.String("dir", "bugreports", "Directory given to the daemon's -bugreports flag")
+	out := fs.String("o", "lightwaverf-bugreport.tar.gz", "Archive to write")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bugreport [flags]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	files := make(map[string][]byte)
+	fns, err := filepath.Glob(filepath.Join(*dir, "bugreport-*.json"))
+	if err != nil {
+		return err
+	}
+	for _, fn := range fns {
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		files[filepath.Base(fn)] = data
+	}
+	if d, ok := findDaemon(); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		var bundle json.RawMessage
+		if err := d.do(ctx, "GET", "/debug/bugreport", nil, &bundle); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to describe the running daemon: %v\n", err)
+		} else {
+			files["running.json"] = bundle
+		}
+	}
+	if len(files) == 0 {
+		return errors.New("no bug reports found, and the daemon is not running, see -dir and -daemon")
+	}
+
+	if err := writeArchive(*out, files); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d reports to %s\n", len(files), *out)
+	fmt.Println("Secrets have been redacted from the daemon's flags, but please check it before attaching it to an issue.")
+	return nil
+}
+
+// writeArchive writes files, by name, to a gzipped tar archive
+func writeArchive(fn string, files map[string][]byte) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
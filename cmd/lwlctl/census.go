@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/meermanr/LightwaveRF-go/census"
+)
+
+// censusReport prints the protocol census kept by the daemon's -census flag,
+// to share
+func censusReport(args []string) error {
+	fs := flag.NewFlagSet("census", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: census [flags] FILE\n")
+		fs.PrintDefaults()
+	}
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageError{fmt.Errorf("expected the file given to the daemon's -census flag")}
+	}
+	c, err := census.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		return printJSON(c.Kinds())
+	}
+	if err := c.Report(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println("\nOnly the names of fields are counted, not their values, so this may be shared to help support more of the protocol.")
+	return nil
+}
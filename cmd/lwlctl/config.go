@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadNames reads the daemon's configuration file, which maps serials (and
+// Room+Device identifiers) to names. Names are only used to decorate output,
+// so problems are logged rather than returned.
+func loadNames(fn string) map[string]string {
+	names := make(map[string]string)
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		slog.Debug("Unable to read configuration file", "fn", fn, "err", err)
+		return names
+	}
+	if err := yaml.Unmarshal(data, &names); err != nil {
+		slog.Warn("Unable to parse configuration file", "fn", fn, "err", err)
+	}
+	return names
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// How long to wait for the daemon to answer a health check
+const probeTimeout = time.Second
+
+// daemon is a running lightwaverf daemon, which sends commands on our behalf
+// so that we do not compete with it for the LightwaveLink's UDP port
+type daemon struct {
+	hc    *http.Client
+	base  string // URL, without trailing slash
+	token string
+}
+
+// findDaemon returns the daemon given by -daemon, if it is running
+func findDaemon() (*daemon, bool) {
+	if *daemonAddr == "" {
+		return nil, false
+	}
+	d := &daemon{hc: &http.Client{}, base: strings.TrimSuffix(*daemonAddr, "/"), token: os.Getenv("LWL_TOKEN")}
+	if !strings.Contains(*daemonAddr, "://") {
+		path := *daemonAddr
+		d.base = "http://lightwaverf"
+		d.hc.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", d.base+"/healthz", nil)
+	if err != nil {
+		slog.Warn("Invalid -daemon", "addr", *daemonAddr, "err", err)
+		return nil, false
+	}
+	resp, err := d.hc.Do(req)
+	if err != nil {
+		slog.Debug("Daemon not running, using the LightwaveLink directly", "addr", *daemonAddr, "err", err)
+		return nil, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		slog.Debug("Daemon not healthy, using the LightwaveLink directly", "addr", *daemonAddr, "status", resp.Status)
+		return nil, false
+	}
+	slog.Debug("Using daemon", "addr", *daemonAddr)
+	return d, true
+}
+
+// send asks the daemon to send a command from the catalog, returning the
+// LightwaveLink's reply, which is null if there was none
+func (d *daemon) send(ctx context.Context, name string, args ...string) (json.RawMessage, error) {
+	var out struct {
+		Response json.RawMessage `json:"response"`
+	}
+	err := d.do(ctx, "POST", "/commands/"+name, map[string][]string{"args": args}, &out)
+	return out.Response, err
+}
+
+// response is send, decoding the LightwaveLink's reply, which is the zero
+// Response if there was none
+func (d *daemon) response(ctx context.Context, name string, args ...string) (lwl.Response, error) {
+	var r lwl.Response
+	raw, err := d.send(ctx, name, args...)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return r, fmt.Errorf("daemon: %w", err)
+	}
+	return r, nil
+}
+
+// do makes a request of the daemon's API, decoding the JSON response into
+// out. A body, if not nil, is sent as JSON, unless it is an io.Reader, which
+// is sent as it is.
+func (d *daemon) do(ctx context.Context, method, path string, body, out any) error {
+	var rd io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		rd, contentType = b, "application/octet-stream"
+	default:
+		j, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(j)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.base+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if d.token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.token)
+	}
+	resp, err := d.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+		return nil
+	}
+	var e struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return fmt.Errorf("daemon: %s", resp.Status)
+	}
+	switch {
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return fmt.Errorf("daemon: %s: %w", e.Error, context.DeadlineExceeded)
+	case strings.Contains(e.Error, lwl.ErrNotRegistered.Error()):
+		return fmt.Errorf("daemon: %w", lwl.ErrNotRegistered)
+	}
+	return fmt.Errorf("daemon: %s: %s", resp.Status, e.Error)
+}
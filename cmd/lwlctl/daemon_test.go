@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestDaemon(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/commands/hub_info":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"missing or unknown bearer token"}`))
+				return
+			}
+			w.Write([]byte(`{"response":{"fn":"hubCall","fw":"U2.94D"}}`))
+		case "/commands/heating_status":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"not registered with LightwaveLink"}`))
+		default:
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(`{"error":"context deadline exceeded"}`))
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("LWL_TOKEN", "secret")
+	defer func(addr string) { *daemonAddr = addr }(*daemonAddr)
+	*daemonAddr = ts.URL
+
+	d, ok := findDaemon()
+	if !ok {
+		t.Fatal("daemon not found")
+	}
+	raw, err := d.send(context.Background(), "hub_info")
+	if err != nil || string(raw) != `{"fn":"hubCall","fw":"U2.94D"}` {
+		t.Errorf("got %s, %v", raw, err)
+	}
+	if r, err := d.response(context.Background(), "hub_info"); err != nil || r.Fw != "U2.94D" {
+		t.Errorf("got %+v, %v", r, err)
+	}
+	if _, err := d.response(context.Background(), "heating_status", "R7"); !errors.Is(err, lwl.ErrNotRegistered) {
+		t.Errorf("got %v, want ErrNotRegistered", err)
+	}
+	if _, err := d.send(context.Background(), "rooms"); !errors.Is(err, context.DeadlineExceeded) || exitCode(err) != exitTimeout {
+		t.Errorf("got %v, want a timeout", err)
+	}
+
+	ts.Close()
+	if _, ok := findDaemon(); ok {
+		t.Error("found a stopped daemon")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/audit"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// doctor checks each step needed to communicate with the LWL, and prints a
+// diagnosis of anything which fails
+func doctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 3*time.Second, "How long to wait for each response")
+	samples := fs.Int("samples", 5, "Number of probes used to measure round-trip latency")
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	type result struct {
+		Check  string `json:"check"`
+		OK     bool   `json:"ok"`
+		Detail string `json:"detail"`
+	}
+	var results []result
+	problems := []string{}
+	check := func(ok bool, what string, detail any) {
+		results = append(results, result{what, ok, fmt.Sprint(detail)})
+		if *jsonOut {
+			return
+		}
+		status := " OK "
+		if !ok {
+			status = "FAIL"
+		}
+		fmt.Printf("[%s] %-22s %v\n", status, what, detail)
+	}
+
+	defer func() {
+		if *jsonOut {
+			printJSON(struct {
+				Checks   []result `json:"checks"`
+				Problems []string `json:"problems"`
+			}{results, problems})
+			return
+		}
+		fmt.Println()
+		if len(problems) == 0 {
+			fmt.Println("Diagnosis: All checks passed")
+			return
+		}
+		fmt.Println("Diagnosis:")
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+	}()
+
+	// Probes are sent via the daemon, if it is running, since only one
+	// process can listen for the LWL's replies
+	var probe func(context.Context) (lwl.Response, error)
+	if d, ok := findDaemon(); ok {
+		check(true, "Daemon", fmt.Sprintf("running at %s, probing via it", *daemonAddr))
+		probe = func(ctx context.Context) (lwl.Response, error) {
+			return d.response(ctx, "hub_info")
+		}
+	} else {
+		c, err := open()
+		if err != nil {
+			check(false, "Listen on UDP port", err)
+			problems = append(problems, "Another program (e.g. the daemon, or another LightwaveRF tool) is using the port. Stop it and try again, start both with -reuseport, or give the daemon's API with -daemon.")
+			return errors.New("problems found")
+		}
+		check(true, "Listen on UDP port", "available")
+		if *auditFile != "" {
+			c.SetAuditor(audit.NewLog(*auditFile))
+		}
+		go c.Listen()
+		probe = func(ctx context.Context) (lwl.Response, error) {
+			return c.Do(ctx, lwl.CmdHubCall)
+		}
+	}
+
+	// Any reply, even an error, proves the LWL is reachable
+	ctx, cancel := context.WithTimeout(cliContext(), *timeout)
+	start := time.Now()
+	r, err := probe(ctx)
+	rtt := time.Since(start)
+	cancel()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		check(false, "Probe LightwaveLink", "no response")
+		problems = append(problems, "No reply from the LightwaveLink. Check it is powered on, connected to the same network (subnet) as this host, and that UDP ports 9760 and 9761 are not firewalled.")
+		return fmt.Errorf("problems found: %w", errUnreachable)
+	case errors.Is(err, lwl.ErrNotRegistered):
+		check(true, "Probe LightwaveLink", fmt.Sprintf("responded in %v", rtt))
+		check(false, "Registration", "not registered")
+		problems = append(problems, "This host is not paired with the LightwaveLink. Start the daemon, and press the button on the LightwaveLink when its LED flashes.")
+		return fmt.Errorf("problems found: %w", err)
+	case err != nil:
+		check(false, "Probe LightwaveLink", err)
+		problems = append(problems, "Unexpected reply from the LightwaveLink. Re-run with -verbose to see the traffic.")
+		return fmt.Errorf("problems found: %w", err)
+	}
+	check(true, "Probe LightwaveLink", fmt.Sprintf("responded from %s in %v", r.IP, rtt))
+	check(true, "Registration", "registered")
+
+	// Firmware
+	fw, err := lwl.ParseFirmware(r.Fw)
+	switch {
+	case err != nil:
+		check(false, "Firmware", err)
+		problems = append(problems, "Unrecognised firmware version; this tool has only been tested with N2.94D.")
+	case !fw.AtLeast(2, 92):
+		check(false, "Firmware", fmt.Sprintf("%v (%s)", fw, fw.Model))
+		problems = append(problems, "Firmware older than 2.92 does not send JSON responses, which this tool relies on. Update the LightwaveLink via the official app.")
+	default:
+		check(true, "Firmware", fmt.Sprintf("%v (%s)", fw, fw.Model))
+	}
+
+	// Latency
+	ls := lwl.NewLatencyStats("@H")
+	for range *samples {
+		ctx, cancel := context.WithTimeout(cliContext(), *timeout)
+		start := time.Now()
+		_, err := probe(ctx)
+		cancel()
+		if err != nil {
+			check(false, "Round-trip latency", err)
+			problems = append(problems, "The LightwaveLink stopped responding part way through. Check for Wi-Fi or network congestion.")
+			return fmt.Errorf("problems found: %w", err)
+		}
+		ls.Sample(time.Since(start))
+	}
+	check(true, "Round-trip latency", strings.Join(strings.Fields(ls.String()), " "))
+
+	return nil
+}
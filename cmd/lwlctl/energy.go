@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/energy"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// energyReport prints the energy used, and its cost, in each of the most
+// recent days, weeks or months, from the daemon's telemetry
+func energyReport(args []string) error {
+	fs := flag.NewFlagSet("energy", flag.ContinueOnError)
+	telemetryFile := fs.String("telemetry", "telemetry.jsonl", "Telemetry written by the daemon")
+	tariffFile := fs.String("tariff", "tariff.yaml", "Unit prices and standing charge (YAML)")
+	period := fs.String("period", "day", "Total per day, week or month")
+	n := fs.Int("n", 7, "Number of periods to report, ending with the current one")
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	p, err := energy.ParsePeriod(*period)
+	if err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	tariff, err := energy.LoadTariff(*tariffFile)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	usage, err := energy.Query(telemetry.NewLog(*telemetryFile), tariff, p.Back(now, *n-1), now, p)
+	if err != nil {
+		return err
+	}
+
+	var kwh, cost float64
+	for _, u := range usage {
+		kwh += u.KWh
+		cost += u.Cost
+	}
+	if *jsonOut {
+		return printJSON(struct {
+			Currency string         `json:"currency"`
+			Periods  []energy.Usage `json:"periods"`
+			KWh      float64        `json:"kwh"`
+			Cost     float64        `json:"cost"`
+		}{tariff.Currency, usage, kwh, cost})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "FROM\tTO\tKWH\tCOST\t")
+	for _, u := range usage {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t\n",
+			u.From.Format(time.DateOnly),
+			u.To.AddDate(0, 0, -1).Format(time.DateOnly), // Inclusive
+			u.KWh,
+			tariff.Format(u.Cost),
+		)
+	}
+	fmt.Fprintf(w, "Total\t\t%.2f\t%s\t\n", kwh, tariff.Format(cost))
+	return w.Flush()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Exit statuses, so that scripts can tell failures apart
+const (
+	exitOK            = 0
+	exitError         = 1  // Any failure not listed below
+	exitTimeout       = 2  // The LightwaveLink did not reply in time
+	exitNotRegistered = 3  // This host is not paired with the LightwaveLink
+	exitUnreachable   = 4  // Commands could not be sent, or nothing was heard from the LightwaveLink
+	exitUsage         = 64 // Invalid arguments, as EX_USAGE in sysexits.h
+)
+
+// errUnreachable is returned when the LightwaveLink did not reply at all,
+// rather than with an error or too slowly
+var errUnreachable = errors.New("no reply from the LightwaveLink")
+
+// usageError marks an error as caused by invalid arguments
+type usageError struct {
+	error
+}
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+// exitCode returns the exit status for an error returned by a subcommand
+func exitCode(err error) int {
+	var opErr *net.OpError
+	var usage usageError
+	sendFailed := errors.As(err, &opErr) && opErr.Op == "write" // Not e.g. "listen", if the port is in use
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, lwl.ErrNotRegistered):
+		return exitNotRegistered
+	case errors.Is(err, errUnreachable), errors.Is(err, lwl.ErrNoHubAddr), sendFailed:
+		return exitUnreachable
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	default:
+		return exitError
+	}
+}
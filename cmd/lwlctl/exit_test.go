@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{usageError{flag.ErrHelp}, exitOK},
+		{errors.New("boom"), exitError},
+		{context.DeadlineExceeded, exitTimeout},
+		{fmt.Errorf("problems found: %w", lwl.ErrNotRegistered), exitNotRegistered},
+		{fmt.Errorf("problems found: %w", errUnreachable), exitUnreachable},
+		{lwl.ErrNoHubAddr, exitUnreachable},
+		{fmt.Errorf("send to 192.168.1.2:9760: %w", &net.OpError{Op: "write", Err: errors.New("no route to host")}), exitUnreachable},
+		{&net.OpError{Op: "listen", Err: errors.New("address already in use")}, exitError},
+		{usageError{errors.New("expected a command")}, exitUsage},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%v: want %d got %d", tt.err, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// addJSONFlag lets -json be given after the subcommand, as well as before it
+func addJSONFlag(fs *flag.FlagSet) {
+	fs.BoolVar(jsonOut, "json", *jsonOut, "Print JSON rather than text, for scripts")
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// responseJSON returns the JSON of a message from the LightwaveLink, without
+// its "*!" prefix, or null if there was none (e.g. a legacy-only command)
+func responseJSON(r lwl.Response) json.RawMessage {
+	s := strings.TrimPrefix(r.String(), "*!")
+	if s == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s)
+}
+
+// batteryJSON is a battery.Summary as printed by -json
+type batteryJSON struct {
+	Serial        string     `json:"serial"`
+	Name          string     `json:"name,omitempty"`
+	Prod          string     `json:"prod"`
+	Volts         float64    `json:"volts"`
+	Slope         float64    `json:"slope"`          // Volts per day
+	DaysRemaining *float64   `json:"days_remaining"` // Null if not discharging
+	Replace       *time.Time `json:"replace"`        // Null if not discharging
+	LastSeen      time.Time  `json:"last_seen"`
+}
+
+func newBatteryJSON(s battery.Summary, name string) batteryJSON {
+	out := batteryJSON{
+		Serial:   s.Serial,
+		Name:     name,
+		Prod:     s.Prod,
+		Volts:    s.Latest.Volts,
+		Slope:    s.Slope,
+		LastSeen: s.Latest.Time,
+	}
+	if !math.IsInf(s.DaysRemaining, 1) {
+		out.DaysRemaining = &s.DaysRemaining
+		out.Replace = &s.Replace
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// logLevel shows or changes the running daemon's log level and wire tracing,
+// e.g. "log -for 15m -trace debug" to capture what a misbehaving daemon is
+// doing without restarting it
+func logLevel(args []string) error {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	d := fs.Duration("for", 0, "How long to change the level for, and to trace, e.g. 15m. Without it, the level is changed until changed again.")
+	trace := fs.Bool("trace", false, "Log every message to and from the LightwaveLink. Needs -for.")
+	restore := fs.Bool("restore", false, "End a temporary level and tracing")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: log [flags] [debug|info|warn|error]\n")
+		fs.PrintDefaults()
+	}
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if fs.NArg() > 1 {
+		return usageError{errors.New("expected at most one level")}
+	}
+	if *trace && *d <= 0 {
+		return usageError{errors.New("-trace needs -for, so that it is not left on")}
+	}
+
+	dm, ok := findDaemon()
+	if !ok {
+		return errors.New("the daemon is not running, see -daemon")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	var st struct {
+		Level      string     `json:"level"`
+		Until      *time.Time `json:"until,omitempty"`
+		TraceUntil *time.Time `json:"trace_until,omitempty"`
+	}
+	if fs.NArg() == 0 && !*trace && !*restore {
+		if err := dm.do(ctx, "GET", "/debug/log", nil, &st); err != nil {
+			return err
+		}
+	} else {
+		body := map[string]any{"trace": *trace, "restore": *restore}
+		if fs.NArg() == 1 {
+			body["level"] = fs.Arg(0)
+		}
+		if *d > 0 {
+			body["for"] = d.String()
+		}
+		if err := dm.do(ctx, "POST", "/debug/log", body, &st); err != nil {
+			return err
+		}
+	}
+
+	if *jsonOut {
+		return printJSON(st)
+	}
+	fmt.Printf("Level: %s", st.Level)
+	if st.Until != nil {
+		fmt.Printf(" until %s", st.Until.Local().Format(time.TimeOnly))
+	}
+	fmt.Println()
+	if st.TraceUntil != nil {
+		fmt.Printf("Tracing until %s\n", st.TraceUntil.Local().Format(time.TimeOnly))
+	}
+	return nil
+}
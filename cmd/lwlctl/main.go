@@ -0,0 +1,128 @@
+// Command lwlctl is a command line tool for inspecting and controlling a
+// LightwaveRF Link (LWL)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+
+	"github.com/MatusOllah/slogcolor"
+)
+
+var isVerbose = flag.Bool("verbose", false, "Enable display of DEBUG log messages")
+var auditFile = flag.String("audit", "audit.jsonl", "Audit log shared with the daemon (empty to disable)")
+var reusePort = flag.Bool("reuseport", false, "Share the UDP port with the daemon, if it was started with -reuseport")
+var jsonOut = flag.Bool("json", false, "Print JSON rather than text, for scripts")
+var daemonAddr = flag.String("daemon", "/run/lightwaverf/api.sock", "Send via the daemon's API when it is running, given as its -unix socket or a URL (with a token in $LWL_TOKEN); empty to always use the LightwaveLink directly")
+var commandsFile = flag.String("commands", "commands.yaml", "Extra commands (YAML) for send, as given to the daemon")
+
+// subcommand is an action selected by the first non-flag argument
+type subcommand struct {
+	name  string
+	usage string                    // One line description, for -help
+	run   func(args []string) error // Given arguments after the subcommand name
+}
+
+var subcommands = []subcommand{
+	{name: "audit", usage: "Query the log of commands sent to the LightwaveLink", run: auditQuery},
+	{name: "battery", usage: "Report battery levels, trends and estimated days remaining", run: batteryReport},
+	{name: "bugreport", usage: "Collect the daemon's bug reports into an archive, to attach to an issue", run: bugReport},
+	{name: "census", usage: "Print the kinds of message counted by the daemon's -census flag, to share", run: censusReport},
+	{name: "doctor", usage: "Diagnose common problems communicating with the LightwaveLink", run: doctor},
+	{name: "energy", usage: "Report energy use and its cost per day, week or month", run: energyReport},
+	{name: "log", usage: "Show or change the daemon's log level, and trace messages to and from the LightwaveLink", run: logLevel},
+	{name: "replay", usage: "Replay messages from a capture, optionally faster than real time", run: replay},
+	{name: "report", usage: "Summarise a month of energy use, heating, batteries and hub uptime", run: monthlyReport},
+	{name: "rf-test", usage: "Measure how reliably a heating device receives from the LightwaveLink", run: rfTest},
+	{name: "schema", usage: "Print a JSON Schema of the messages the LightwaveLink sends", run: schema},
+	{name: "screen", usage: "Brighten or dim the LightwaveLink's screen (LW500) or LED", run: screen},
+	{name: "send", usage: "Send a command to the LightwaveLink by name, e.g. \"send on R1D1\"", run: send},
+	{name: "unknown", usage: "Print messages from the LightwaveLink which were not understood, to share", run: unknown},
+	{name: "version", usage: "Print the build of lwlctl and the daemon, to include in support requests", run: version},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] <subcommand> [args]\n\nSubcommands:\n", os.Args[0])
+	for _, s := range subcommands {
+		fmt.Fprintf(out, "  %-10s %s\n", s.name, s.usage)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nExit status:\n")
+	for _, e := range []struct {
+		code int
+		what string
+	}{
+		{exitOK, "Success"},
+		{exitError, "Failure, other than those below"},
+		{exitTimeout, "The LightwaveLink did not reply in time"},
+		{exitNotRegistered, "This host is not paired with the LightwaveLink"},
+		{exitUnreachable, "The LightwaveLink could not be reached"},
+		{exitUsage, "Invalid arguments"},
+	} {
+		fmt.Fprintf(out, "  %-10d %s\n", e.code, e.what)
+	}
+}
+
+func main() {
+	// Command line arguments
+	flag.Usage = usage
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitCode(usageError{err}))
+	}
+
+	// Logging
+	opts := slogcolor.DefaultOptions
+	switch *isVerbose {
+	case true:
+		opts.Level = slog.LevelDebug
+	case false:
+		opts.Level = slog.LevelWarn
+	}
+	slog.SetDefault(slog.New(slogcolor.NewHandler(os.Stderr, opts)))
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, s := range subcommands {
+		if s.name != name {
+			continue
+		}
+		err := s.run(args)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		}
+		if code := exitCode(err); code != exitOK {
+			os.Exit(code)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n\n", name)
+	usage()
+	os.Exit(exitUsage)
+}
+
+// open returns a Client, sharing the UDP port if -reuseport is set
+func open() (*lwl.Client, error) {
+	if *reusePort {
+		return lwl.OpenShared()
+	}
+	return lwl.Open()
+}
+
+// cliContext returns a context attributing commands to this tool
+func cliContext() context.Context {
+	return lwl.WithSource(context.Background(), "cli")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/audit"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// rfTest repeatedly asks a heating device for its status, and reports how
+// reliably (and after how many attempts) it acknowledges, to help users
+// position the LWL
+func rfTest(args []string) error {
+	fs := flag.NewFlagSet("rf-test", flag.ContinueOnError)
+	count := fs.Int("count", 10, "Number of requests to send")
+	interval := fs.Duration("interval", 2*time.Second, "Delay between requests")
+	timeout := fs.Duration("timeout", 15*time.Second, "How long to wait for each acknowledgement, including retries")
+	addJSONFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: rf-test [flags] <heating device, e.g. R7>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	id := fs.Arg(0)
+	switch {
+	case !lwl.ValidID(id):
+		fs.Usage()
+		return usageError{fmt.Errorf("invalid device: %q", id)}
+	case strings.Contains(id, "D"):
+		return errors.New("433 MHz devices (lights, sockets, etc) do not acknowledge commands, so their reception cannot be measured; test a heating device, e.g. R7")
+	}
+
+	// Requests are sent via the daemon, if it is running, since only one
+	// process can listen for the LWL's replies
+	var request func(context.Context) (lwl.Response, error)
+	if d, ok := findDaemon(); ok {
+		request = func(ctx context.Context) (lwl.Response, error) {
+			return d.response(ctx, "heating_status", id)
+		}
+	} else {
+		c, err := open()
+		if err != nil {
+			return err
+		}
+		if *auditFile != "" {
+			c.SetAuditor(audit.NewLog(*auditFile))
+		}
+		go c.Listen()
+		request = func(ctx context.Context) (lwl.Response, error) {
+			return c.Do(ctx, *lwl.CmdHeatingStatus.New(id))
+		}
+	}
+
+	type probe struct {
+		Acked    bool   `json:"acked"`
+		Attempts int32  `json:"attempts,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+	var probes []probe
+	var s lwl.RFStats
+	progress := io.Writer(os.Stdout)
+	if *jsonOut {
+		progress = io.Discard
+	}
+	for i := range *count {
+		if i > 0 {
+			time.Sleep(*interval)
+		}
+		ctx, cancel := context.WithTimeout(cliContext(), *timeout)
+		r, err := request(ctx)
+		cancel()
+		var p probe
+		switch {
+		case err != nil:
+			p.Error = err.Error()
+			fmt.Fprintf(progress, "%2d: %v\n", i+1, err)
+		case r.Status == "success":
+			p.Acked, p.Attempts = true, r.Attempts
+			s.Acked++
+			s.Attempts += int64(r.Attempts)
+			fmt.Fprintf(progress, "%2d: acknowledged after %d attempt(s)\n", i+1, r.Attempts)
+		default:
+			s.Failed++
+			fmt.Fprintf(progress, "%2d: not acknowledged\n", i+1)
+		}
+		probes = append(probes, p)
+	}
+
+	if s.Acked+s.Failed == 0 {
+		return fmt.Errorf("no acknowledgements seen from %s; is it paired?", id)
+	}
+	verdict := "Good"
+	switch {
+	case s.AckRate() < 0.9:
+		verdict = "Poor: move the LightwaveLink closer to the device, or away from metal and other radios"
+	case s.MeanAttempts() > 1.5:
+		verdict = "Marginal: the device is near the edge of the LightwaveLink's range"
+	}
+	if *jsonOut {
+		return printJSON(struct {
+			Device       string  `json:"device"`
+			Probes       []probe `json:"probes"`
+			AckRate      float64 `json:"ack_rate"`
+			MeanAttempts float64 `json:"mean_attempts"`
+			Verdict      string  `json:"verdict"`
+		}{id, probes, s.AckRate(), s.MeanAttempts(), verdict})
+	}
+	fmt.Printf("\n%s: %v\n%s\n", id, s, verdict)
+	return nil
+}
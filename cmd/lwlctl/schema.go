@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// schema prints a JSON Schema of the messages the LightwaveLink sends, for
+// validating them in other languages
+func schema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	addJSONFlag(fs) // Always JSON
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	b, err := lwl.Schema()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(b, '\n'))
+	return err
+}
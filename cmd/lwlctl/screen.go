@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/audit"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// screen brightens or dims the screen of an LW500. An LW930 has no screen, so
+// only its LED is affected.
+func screen(args []string) error {
+	fs := flag.NewFlagSet("screen", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 3*time.Second, "How long to wait for each response")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: screen [flags] bright|dim\n")
+		fs.PrintDefaults()
+	}
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	var cmd lwl.Command
+	var name string // In the catalog, for the daemon
+	switch fs.Arg(0) {
+	case "bright":
+		cmd, name = lwl.CmdSetHubUIBright, "hub_ui_bright"
+	case "dim":
+		cmd, name = lwl.CmdSetHubUIDim, "hub_ui_dim"
+	default:
+		fs.Usage()
+		return usageError{errors.New("expected bright or dim")}
+	}
+
+	var has bool
+	var err error
+	if d, ok := findDaemon(); ok {
+		has, err = screenViaDaemon(d, name, *timeout)
+	} else {
+		has, err = screenDirect(cmd, *timeout)
+	}
+	if err != nil {
+		return err
+	}
+	if !has && !*jsonOut {
+		fmt.Println("This LightwaveLink (LW930) has no screen, so only its LED changed")
+	}
+	if *jsonOut {
+		return printJSON(struct {
+			Screen bool `json:"screen"` // False if only the LED changed
+		}{has})
+	}
+	return nil
+}
+
+// screenDirect sends cmd to the LightwaveLink, reporting whether it has a
+// screen
+func screenDirect(cmd lwl.Command, timeout time.Duration) (bool, error) {
+	c, err := open()
+	if err != nil {
+		return false, err
+	}
+	if *auditFile != "" {
+		c.SetAuditor(audit.NewLog(*auditFile))
+	}
+	go c.Listen()
+
+	// The reply reveals the model
+	ctx, cancel := context.WithTimeout(cliContext(), timeout)
+	defer cancel()
+	if _, err := c.Do(ctx, lwl.CmdHubCall); err != nil {
+		return false, err
+	}
+	has, _ := c.HasScreen()
+
+	ctx, cancel = context.WithTimeout(cliContext(), timeout)
+	defer cancel()
+	_, err = c.Do(ctx, cmd)
+	return has, err
+}
+
+// screenViaDaemon is screenDirect, for when the daemon is running
+func screenViaDaemon(d *daemon, name string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(cliContext(), timeout)
+	defer cancel()
+	r, err := d.response(ctx, "hub_info")
+	if err != nil {
+		return false, err
+	}
+	f, err := lwl.ParseFirmware(r.Fw)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel = context.WithTimeout(cliContext(), timeout)
+	defer cancel()
+	_, err = d.send(ctx, name)
+	return !lwl.QuirksFor(f).NoScreen, err
+}
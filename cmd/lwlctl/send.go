@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/audit"
+	"github.com/meermanr/LightwaveRF-go/config"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// send sends a command chosen by name (see lwl.Commands), and prints the
+// LightwaveLink's reply
+func send(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 3*time.Second, "How long to wait for a response")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: send [flags] <command> [args]\n\nCommands:\n")
+		for _, ci := range lwl.Commands() {
+			fmt.Fprintf(out, "  %-32s %s\n", ci.Usage(), ci.Description)
+		}
+		fmt.Fprintf(out, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if _, err := config.LoadCommands(*commandsFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usageError{errors.New("expected a command")}
+	}
+
+	ci, ok := lwl.LookupCommand(fs.Arg(0))
+	if !ok {
+		return usageError{fmt.Errorf("unknown command %q, see send -help", fs.Arg(0))}
+	}
+	cmd, err := ci.Build(fs.Args()[1:]...)
+	if err != nil {
+		return usageError{err}
+	}
+
+	ctx, cancel := context.WithTimeout(cliContext(), *timeout)
+	defer cancel()
+	if d, ok := findDaemon(); ok {
+		raw, err := d.send(ctx, ci.Name, fs.Args()[1:]...)
+		if err != nil {
+			return err
+		}
+		return printResponse(raw)
+	}
+
+	c, err := open()
+	if err != nil {
+		return err
+	}
+	if *auditFile != "" {
+		c.SetAuditor(audit.NewLog(*auditFile))
+	}
+	go c.Listen()
+
+	r, err := c.Do(ctx, *cmd)
+	if err != nil {
+		return err
+	}
+	return printResponse(responseJSON(r))
+}
+
+// printResponse prints a message from the LightwaveLink, as returned by
+// responseJSON
+func printResponse(raw json.RawMessage) error {
+	if *jsonOut {
+		return printJSON(raw)
+	}
+	if len(raw) > 0 && string(raw) != "null" {
+		fmt.Println("*!" + string(raw))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// unknown prints the messages from the LightwaveLink which were not
+// understood, from the daemon if it is running, or else by listening for a
+// while
+func unknown(args []string) error {
+	fs := flag.NewFlagSet("unknown", flag.ContinueOnError)
+	listen := fs.Duration("listen", time.Minute, "How long to listen, if the daemon is not running")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: unknown [flags]\n")
+		fs.PrintDefaults()
+	}
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	var msgs []lwl.QuarantinedMessage
+	if d, ok := findDaemon(); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		if err := d.do(ctx, "GET", "/hub/unknown", nil, &msgs); err != nil {
+			return err
+		}
+	} else {
+		c, err := open()
+		if err != nil {
+			return err
+		}
+		go c.Listen()
+		if !*jsonOut {
+			fmt.Printf("Listening for %v...\n", *listen)
+		}
+		time.Sleep(*listen)
+		msgs = c.Quarantine()
+	}
+
+	if *jsonOut {
+		if msgs == nil {
+			msgs = []lwl.QuarantinedMessage{} // [] rather than null
+		}
+		return printJSON(msgs)
+	}
+	if len(msgs) == 0 {
+		fmt.Println("Every message was understood")
+		return nil
+	}
+	for _, m := range msgs {
+		fmt.Printf("%s  %s (%d)\n", m.Time.Local().Format(time.DateTime), m.Reason, m.Count)
+		for _, msg := range m.Msgs {
+			fmt.Printf("  %s\n", msg)
+		}
+	}
+	fmt.Println("\nThe LightwaveLink's MAC has been removed, so these may be shared to help support more of the protocol.")
+	return nil
+}
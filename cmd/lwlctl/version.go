@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/meermanr/LightwaveRF-go/buildinfo"
+)
+
+// version prints the build of lwlctl, and of the daemon if it is running, to
+// include in support requests
+func version(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	addJSONFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	out := map[string]buildinfo.Info{"lwlctl": buildinfo.Get()}
+	if d, ok := findDaemon(); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		var daemon buildinfo.Info
+		if err := d.do(ctx, "GET", "/version", nil, &daemon); err != nil {
+			return err
+		}
+		out["daemon"] = daemon
+	}
+
+	if *jsonOut {
+		return printJSON(out)
+	}
+	fmt.Printf("lwlctl: %s\n", out["lwlctl"])
+	if daemon, ok := out["daemon"]; ok {
+		fmt.Printf("daemon: %s\n", daemon)
+	}
+	return nil
+}
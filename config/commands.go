@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadCommands reads a YAML list of custom commands (see lwl.CommandSpec),
+// and registers them so they can be used by name like the built-in ones. It
+// returns the number registered, and every problem found, joined.
+func LoadCommands(fn string) (int, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return 0, err
+	}
+	var specs []lwl.CommandSpec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return 0, err
+	}
+
+	var n int
+	var errs []error
+	for i, s := range specs {
+		if err := lwl.RegisterCommand(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: entry %d: %w", fn, i+1, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestLoadCommands(t *testing.T) {
+	write := func(yaml string) string {
+		fn := filepath.Join(t.TempDir(), "commands.yaml")
+		if err := os.WriteFile(fn, []byte(yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return fn
+	}
+
+	n, err := LoadCommands(write(`
+- name: config_valve_position
+  description: Set the position of a radiator valve
+  format: "!%sF*tP%d"
+  pkt: 868R
+  fn: ack
+  args:
+    - {name: room, type: room}
+    - {name: position, type: int, min: 50, max: 60}
+- name: on
+  format: "!%sF1"
+  args:
+    - {name: device, type: device}
+`))
+	if n != 1 || err == nil || !strings.Contains(err.Error(), "already defined") {
+		t.Fatalf("want 1 registered and on rejected, got %d, %v", n, err)
+	}
+	ci, ok := lwl.LookupCommand("config_valve_position")
+	if !ok {
+		t.Fatal("not registered")
+	}
+	if got, want := ci.Usage(), "config_valve_position <room> <position>"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+
+	_, err = LoadCommands(write(`
+- name: config_bad_type
+  format: "!%sF1"
+  args:
+    - {name: device, type: colour}
+`))
+	if err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Fatalf("want error about the unknown arg type, got %v", err)
+	}
+}
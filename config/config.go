@@ -0,0 +1,193 @@
+// Package config reads and writes the daemon's configuration file, which
+// names devices
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"gopkg.in/yaml.v3"
+)
+
+// Config maps serials (and Room+Device identifiers) to names, persisted as
+// YAML, and records the most recent status of each device
+type Config struct {
+	mu     sync.RWMutex            // Mutex
+	names  map[string]string       // Serial -> Name, e.g. "24C702" -> "Master Bedroom", or Room+Device -> Alias, e.g. "R1D1" -> "kitchen_ceiling"
+	status map[string]lwl.Response // Serial -> most recent statusPush
+	yaml   yaml.Node               // Decoded YAML, inc. comments
+}
+
+// Load reads the named file, preserving its comments for Write
+func (c *Config) Load(fn string) error {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Decode into yaml.Node, preserving comments et  al
+	if err := yaml.Unmarshal(data, &c.yaml); err != nil {
+		return err
+	}
+	// Extract just the data
+	if err := yaml.Unmarshal(data, &c.names); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Write adds any names not already in the named file to it
+func (c *Config) Write(fn string) error {
+	// TODO: Atomically replace config, so if things go wrong the original is
+	// preserved
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Find names not in original config
+	newNames := maps.Clone(c.names)
+
+	// Find (or create) root mapping of serial -> name
+	var mapping *yaml.Node
+	if len(c.yaml.Content) == 0 {
+		// Add a mapping node
+		mapping = &yaml.Node{
+			Kind: yaml.MappingNode,
+		}
+		c.yaml.Content = append(c.yaml.Content, mapping)
+	} else {
+		mapping = c.yaml.Content[0]
+	}
+
+	// mapping.Content is a list of [key, value, key, value, ...]
+	for i := 0; i < len(mapping.Content); i += 2 {
+		k := mapping.Content[i]
+		delete(newNames, k.Value)
+	}
+
+	if len(newNames) == 0 {
+		slog.Debug("Not writing out config, as no new data to add", "fn", fn)
+		return nil
+	}
+
+	// Append missing names to YAML document
+	for k, v := range newNames {
+		yk := &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Value: k,
+			Tag:   "!!str",
+			Style: yaml.DoubleQuotedStyle,
+		}
+		yv := &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Value: v,
+			Tag:   "!!str",
+			Style: yaml.DoubleQuotedStyle,
+		}
+		mapping.Content = append(mapping.Content, yk, yv)
+	}
+
+	f, err := os.CreateTemp(".", strings.Join([]string{".", fn, "*"}, ""))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	enc := yaml.NewEncoder(f)
+	enc.SetIndent(2)
+	defer enc.Close()
+
+	if err := enc.Encode(&c.yaml); err != nil {
+		return err
+	}
+
+	os.Rename(f.Name(), fn)
+	return nil
+}
+
+// New returns an empty Config
+func New() *Config {
+	return &Config{
+		names:  make(map[string]string),
+		status: make(map[string]lwl.Response),
+	}
+}
+
+// Merge adds entries which are not already present in the configuration,
+// returning the number added
+func (c *Config) Merge(names map[string]string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	added := 0
+	for k, v := range names {
+		if _, found := c.names[k]; !found {
+			c.names[k] = v
+			added++
+		}
+	}
+	return added
+}
+
+// ApplyAliases registers every Room+Device -> Alias entry in the
+// configuration with the registry, and those Serial -> Name entries whose
+// names are also valid aliases
+func (c *Config) ApplyAliases(reg *lwl.Registry) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	for k, v := range c.names {
+		switch {
+		case strings.HasPrefix(k, "R"): // Serial numbers are hexadecimal, so never start with R
+		case lwl.ValidSerial(k) && lwl.ValidAlias(v) && v != "name": // Not a new device's placeholder, see Seen
+		default:
+			continue // e.g. "Boiler switch"
+		}
+		if err := reg.SetAlias(k, v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Seen records the given status, and returns the name entry from the
+// configuration file (which may be empty)
+func (c *Config) Seen(status lwl.Response) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if status.Serial == "" {
+		return ""
+	}
+	name, found := c.names[status.Serial]
+	if !found {
+		name = "[New]"
+		c.names[status.Serial] = "name"
+	}
+	if c.status == nil {
+		c.status = make(map[string]lwl.Response)
+	}
+	c.status[status.Serial] = status
+	return name
+}
+
+// Snapshot returns a copy of the most recent statusPush from each device
+func (c *Config) Snapshot() map[string]lwl.Response {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.status)
+}
+
+// Names returns a copy of the names in the configuration
+func (c *Config) Names() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.names)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// export is the subset of a LightwaveRF app/cloud settings export we use to
+// name devices, e.g.
+//
+//	{"rooms": [
+//	    {"id": 1, "name": "Kitchen", "devices": [
+//	        {"id": 1, "name": "Ceiling"},
+//	        {"id": 2, "name": "Under cupboard"}
+//	    ]}
+//	]}
+type export struct {
+	Rooms []struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		Devices []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"devices"`
+	} `json:"rooms"`
+}
+
+// Import reads a LightwaveRF settings export and returns an alias for every
+// named device, keyed by Room+Device identifier, e.g. "R1D1" ->
+// "kitchen_ceiling".
+func Import(r io.Reader) (map[string]string, error) {
+	var e export
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+
+	out := make(map[string]string)
+	for _, room := range e.Rooms {
+		for _, dev := range room.Devices {
+			id := fmt.Sprintf("R%dD%d", room.ID, dev.ID)
+			if !lwl.ValidID(id) {
+				return nil, fmt.Errorf("invalid device in export: %q (%s/%s)", id, room.Name, dev.Name)
+			}
+			alias := slug(room.Name + " " + dev.Name)
+			if alias == "" {
+				continue
+			}
+			out[id] = alias
+		}
+	}
+	return out, nil
+}
+
+// slug lower-cases a name, and replaces runs of anything other than letters
+// and digits with an underscore, e.g. "Under cupboard" -> "under_cupboard"
+func slug(name string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(name) {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			pending = b.Len() > 0
+			continue
+		}
+		if pending {
+			b.WriteRune('_')
+			pending = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestImportNames(t *testing.T) {
+	in := `{"rooms": [
+		{"id": 1, "name": "Kitchen", "devices": [
+			{"id": 1, "name": "Ceiling"},
+			{"id": 2, "name": "Under-cupboard  lights!"}
+		]},
+		{"id": 3, "name": "Hall", "devices": [{"id": 16, "name": "Lamp"}]}
+	]}`
+	want := map[string]string{
+		"R1D1":  "kitchen_ceiling",
+		"R1D2":  "kitchen_under_cupboard_lights",
+		"R3D16": "hall_lamp",
+	}
+
+	got, err := Import(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("Import() = %v, want %v", got, want)
+	}
+
+	if _, err := Import(strings.NewReader(`{"rooms": [{"id": 99, "devices": [{"id": 1}]}]}`)); err == nil {
+		t.Fatal("Import() should reject invalid rooms")
+	}
+}
@@ -0,0 +1,187 @@
+// Package contact tracks whether doors and windows are open, from the
+// messages a LightwaveRF Link (LWL) reports when magnetic contact sensors
+// change. Each sensor is paired to a device slot, which it switches on when
+// opened and off when closed:
+//
+//   - name: back_door
+//     sensor: R4D1
+//   - name: garage
+//     sensor: R4D2
+//     invert: true # Switches on when closed
+//
+// Changes are passed to rules as events, e.g. when: {pkt: contact, fn: open,
+// sensor: back_door}.
+package contact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/sensor"
+)
+
+// Pkt identifies contact events, like Response.Pkt for messages from the LWL
+const Pkt = "contact"
+
+// Functions of contact events, like Response.Fn
+const (
+	FnOpen  = "open"
+	FnClose = "close"
+)
+
+// Fields are those of an Event besides pkt and fn, which rules may match, see
+// rules.Check
+var Fields = []string{"sensor"}
+
+// Sensor is a magnetic contact sensor, as configured in YAML
+type Sensor struct {
+	Name   string `yaml:"name"`
+	ID     string `yaml:"sensor"` // Room+Device the sensor is paired to, e.g. R4D1
+	Invert bool   `yaml:"invert"` // Switches on when closed, rather than opened. Optional.
+}
+
+// Load reads sensors from a YAML file
+func Load(fn string) ([]Sensor, error) {
+	var ss []Sensor
+	if err := sensor.Load(fn, &ss); err != nil {
+		return nil, err
+	}
+	return ss, Check(ss)
+}
+
+// Check returns every problem found with the sensors, joined
+func Check(ss []Sensor) error {
+	var errs []error
+	names := make(map[string]bool)
+	ids := make(map[string]bool)
+	for i, s := range ss {
+		bad := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("sensor %d (%s): %s", i+1, s.Name, fmt.Sprintf(format, args...)))
+		}
+		switch {
+		case s.Name == "":
+			bad("missing name")
+		case names[s.Name]:
+			bad("duplicate name")
+		}
+		names[s.Name] = true
+
+		switch {
+		case !lwl.ValidID(s.ID) || lwl.ValidID(s.ID+"D1"):
+			bad("invalid sensor %q, should be like R4D1", s.ID)
+		case ids[s.ID]:
+			bad("%s is already another sensor", s.ID)
+		}
+		ids[s.ID] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Event is a sensor opening or closing. It is a rules.Event, with fields
+// "pkt" (always Pkt), "fn" and "sensor".
+type Event struct {
+	Fn     string
+	Sensor string
+}
+
+// Field implements rules.Event
+func (e Event) Field(name string) (any, bool) {
+	switch name {
+	case "pkt":
+		return Pkt, true
+	case "fn":
+		return e.Fn, true
+	case "sensor":
+		return e.Sensor, true
+	}
+	return nil, false
+}
+
+// LogValue implements slog.LogValuer.
+func (e Event) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("pkt", Pkt), slog.String("fn", e.Fn), slog.String("sensor", e.Sensor))
+}
+
+// State is whether a sensor is open. Until it first reports, its state is
+// unknown.
+type State struct {
+	Known   bool      `json:"known"`
+	Open    bool      `json:"open"`
+	Changed time.Time `json:"changed,omitzero"` // Most recently reported
+}
+
+// Tracker records the state of each sensor
+type Tracker struct {
+	byID   map[string]Sensor // Keyed by Sensor.ID
+	notify func(Event)       // Called with each Event, e.g. rules.Engine.Handle
+
+	mu    sync.Mutex
+	state map[string]State // Keyed by Sensor.Name
+}
+
+// NewTracker returns a Tracker for sensors, which should have passed Check,
+// calling notify with each resulting Event
+func NewTracker(ss []Sensor, notify func(Event)) *Tracker {
+	t := &Tracker{byID: make(map[string]Sensor), notify: notify, state: make(map[string]State)}
+	for _, s := range ss {
+		t.byID[s.ID] = s
+		t.state[s.Name] = State{}
+	}
+	return t
+}
+
+// Run observes each message until the channel is closed or the context is
+// done
+func (t *Tracker) Run(ctx context.Context, msgs <-chan lwl.Response) {
+	sensor.Run(ctx, msgs, t.Observe)
+}
+
+// Observe records the state reported by a sensor, e.g. {pkt: 433T, fn: on,
+// room: 4, dev: 1}. Other messages are ignored (see sensor.Report), as are
+// repeats of a sensor's current state, since sensors transmit several times
+// to be sure of being heard.
+func (t *Tracker) Observe(r lwl.Response, now time.Time) {
+	id, ok := sensor.Report(r)
+	if !ok || (r.Fn != "on" && r.Fn != "off") {
+		return
+	}
+	s, ok := t.byID[id]
+	if !ok {
+		return
+	}
+	open := (r.Fn == "on") != s.Invert
+
+	t.mu.Lock()
+	old := t.state[s.Name]
+	if old.Known && old.Open == open {
+		t.mu.Unlock()
+		return
+	}
+	t.state[s.Name] = State{Known: true, Open: open, Changed: now}
+	t.mu.Unlock()
+
+	ev := Event{Fn: FnClose, Sensor: s.Name}
+	if open {
+		ev.Fn = FnOpen
+	}
+	slog.Info("Contact", "event", ev)
+	if t.notify != nil {
+		t.notify(ev)
+	}
+}
+
+// State returns the state of each sensor, by name
+func (t *Tracker) State() map[string]State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]State, len(t.state))
+	for name, s := range t.state {
+		out[name] = s
+	}
+	return out
+}
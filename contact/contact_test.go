@@ -0,0 +1,67 @@
+package contact
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/rules"
+)
+
+func TestCheck(t *testing.T) {
+	err := Check([]Sensor{
+		{Name: "back_door", ID: "R4D1"},
+		{Name: "back_door", ID: "R4"},
+		{Name: "window", ID: "R4D1"},
+	})
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	for _, want := range []string{"duplicate name", "invalid sensor", "already another sensor"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	var got []string
+	tr := NewTracker([]Sensor{
+		{Name: "back_door", ID: "R4D1"},
+		{Name: "garage", ID: "R4D2", Invert: true},
+	}, func(e Event) { got = append(got, e.Sensor+" "+e.Fn) })
+
+	now := time.Now()
+	for _, r := range []lwl.Response{
+		{Pkt: "433T", Fn: "on", Room: 4, Dev: 1},
+		{Pkt: "433T", Fn: "on", Room: 4, Dev: 1}, // Repeated transmission
+		{Pkt: "433T", Fn: "on", Room: 4, Dev: 2},
+		{Pkt: "433T", Fn: "on", Room: 4, Dev: 3}, // Not a sensor
+		{Pkt: "433T", Fn: "dim", Room: 4, Dev: 1, Param: 5},
+		{Pkt: "433T", Fn: "off", Room: 4, Dev: 1, Echo: true}, // Our own command to R4D1
+		{Pkt: "433T", Fn: "off", Room: 4, Dev: 1},
+	} {
+		tr.Observe(r, now)
+	}
+	want := "back_door open,garage close,back_door close"
+	if s := strings.Join(got, ","); s != want {
+		t.Fatalf("want %s got %s", want, s)
+	}
+	st := tr.State()
+	if !st["back_door"].Known || st["back_door"].Open || !st["garage"].Known || st["garage"].Open {
+		t.Fatalf("want both known and closed, got %+v", st)
+	}
+}
+
+func TestRule(t *testing.T) {
+	rs := []rules.Rule{{
+		Name: "Back door light",
+		When: map[string]string{"pkt": "contact", "fn": "open", "sensor": "back_door"},
+		Then: rules.Action{Device: "R4D3", Action: "on"},
+		For:  5 * time.Minute,
+	}}
+	if err := rules.Check(rs, Fields...); err != nil {
+		t.Fatal(err)
+	}
+}
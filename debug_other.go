@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/loglevel"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// toggleDebugOnSignal does nothing, as there is no SIGUSR1 here. Use
+// lwlctl log instead.
+func toggleDebugOnSignal(ctx context.Context, c *lwl.Client, lv *loglevel.Control, d time.Duration) {}
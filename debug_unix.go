@@ -0,0 +1,39 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/loglevel"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// toggleDebugOnSignal logs debug messages and traces messages to and from
+// the LWL for d on SIGUSR1, or stops early on another, until the context is
+// done. E.g. pkill -USR1 LightwaveRF-go
+func toggleDebugOnSignal(ctx context.Context, c *lwl.Client, lv *loglevel.Control, d time.Duration) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-sig:
+			if _, ok := lv.Temporary(); ok {
+				lv.Restore()
+				c.Trace(0)
+				continue
+			}
+			lv.Set(slog.LevelDebug, d)
+			c.Trace(d)
+			slog.Info("Tracing messages to and from the LightwaveLink", "for", d)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package energy
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// Threshold detects sustained high consumption, such as an immersion heater
+// left on
+type Threshold struct {
+	Watts float64
+	For   time.Duration
+
+	mu    sync.Mutex
+	above map[string]time.Time // Meter -> when its power rose above Watts
+	fired map[string]bool      // Meter -> already alerted, until power falls again
+}
+
+// ParseThreshold parses a threshold of the form watts:duration, e.g.
+// "2500:90m". An empty string means no threshold.
+func ParseThreshold(s string) (*Threshold, error) {
+	if s == "" {
+		return nil, nil
+	}
+	w, d, found := strings.Cut(s, ":")
+	watts, err1 := strconv.ParseFloat(w, 64)
+	dur, err2 := time.ParseDuration(d)
+	if !found || err1 != nil || err2 != nil || watts <= 0 || dur <= 0 {
+		return nil, fmt.Errorf("power threshold should look like 2500:90m, got %q", s)
+	}
+	return &Threshold{Watts: watts, For: dur}, nil
+}
+
+// Observe records a meter's power at time t, returning how long it has been
+// above the threshold, and true once per excursion when that exceeds For
+func (th *Threshold) Observe(meter string, t time.Time, watts float64) (time.Duration, bool) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if th.above == nil {
+		th.above, th.fired = make(map[string]time.Time), make(map[string]bool)
+	}
+
+	if watts <= th.Watts {
+		delete(th.above, meter)
+		delete(th.fired, meter)
+		return 0, false
+	}
+	since, ok := th.above[meter]
+	if !ok {
+		th.above[meter] = t
+		since = t
+	}
+	d := t.Sub(since)
+	if d < th.For || th.fired[meter] {
+		return d, false
+	}
+	th.fired[meter] = true
+	return d, true
+}
+
+// The baseline load is measured overnight, when little else should be on
+const (
+	nightFrom = 1 * time.Hour
+	nightTo   = 5 * time.Hour
+)
+
+// minBaseline is the least usual baseline a jump is measured against, in
+// watts, so that a meter which is usually near zero overnight doesn't alert
+// whenever anything at all is left on
+const minBaseline = 50
+
+// Baseline returns a meter's mean power between 01:00 and 05:00 on the given
+// day, from its "power" series, or false if there are no readings
+func Baseline(s telemetry.Store, meter string, day time.Time) (float64, bool, error) {
+	start := Day.Start(day)
+	ps, err := s.Query(meter+".power", start.Add(nightFrom), start.Add(nightTo))
+	if err != nil || len(ps) == 0 {
+		return 0, false, err
+	}
+	var sum float64
+	for _, p := range ps {
+		sum += p.Value
+	}
+	return sum / float64(len(ps)), true, nil
+}
+
+// BaselineJump compares a meter's baseline load on the given day with the
+// median of the nights before. It returns both, and whether the former
+// exceeds the latter, or minBaseline if that is more, by more than factor.
+// Nights without readings are skipped.
+func BaselineJump(s telemetry.Store, meter string, day time.Time, nights int, factor float64) (last, usual float64, jumped bool, err error) {
+	last, ok, err := Baseline(s, meter, day)
+	if err != nil || !ok {
+		return 0, 0, false, err
+	}
+	var prior []float64
+	for n := 1; n <= nights; n++ {
+		b, ok, err := Baseline(s, meter, day.AddDate(0, 0, -n))
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if ok {
+			prior = append(prior, b)
+		}
+	}
+	if len(prior) == 0 {
+		return last, 0, false, nil
+	}
+	slices.Sort(prior)
+	usual = prior[len(prior)/2]
+	if len(prior)%2 == 0 {
+		usual = (prior[len(prior)/2-1] + usual) / 2
+	}
+	return last, usual, last > max(usual, minBaseline)*factor, nil
+}
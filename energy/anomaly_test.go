@@ -0,0 +1,78 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestParseThreshold(t *testing.T) {
+	th, err := ParseThreshold("2500:90m")
+	if err != nil || th.Watts != 2500 || th.For != 90*time.Minute {
+		t.Errorf("got %+v, %v", th, err)
+	}
+	for _, s := range []string{"2500", "x:90m", "2500:x", "-1:90m"} {
+		if _, err := ParseThreshold(s); err == nil {
+			t.Errorf("ParseThreshold(%q) should fail", s)
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	th := &Threshold{Watts: 2000, For: time.Hour}
+	t0 := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		after time.Duration
+		watts float64
+		fire  bool
+	}{
+		{0, 3000, false},
+		{59 * time.Minute, 3000, false},
+		{60 * time.Minute, 3000, true},
+		{70 * time.Minute, 3000, false}, // Once per excursion
+		{80 * time.Minute, 100, false},
+		{90 * time.Minute, 3000, false}, // Restarts
+		{150 * time.Minute, 3000, true},
+	} {
+		if _, fire := th.Observe("A", t0.Add(tc.after), tc.watts); fire != tc.fire {
+			t.Errorf("after %v at %vW: fire %v, want %v", tc.after, tc.watts, fire, tc.fire)
+		}
+	}
+}
+
+func TestBaselineJump(t *testing.T) {
+	tele := telemetry.NewMemory()
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	put := func(d int, watts float64) {
+		for h := range 24 {
+			tele.Put(telemetry.Point{Series: "A.power", Time: day.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour), Value: watts})
+		}
+	}
+	for d, w := range []float64{100, 120, 110} {
+		put(d-3, w)
+	}
+	put(0, 200)
+
+	last, usual, jumped, err := BaselineJump(tele, "A", day, 7, 1.5)
+	if err != nil || last != 200 || usual != 110 || !jumped {
+		t.Errorf("got %v, %v, %v, %v; want 200, 110, true", last, usual, jumped, err)
+	}
+	if _, _, jumped, _ := BaselineJump(tele, "A", day.AddDate(0, 0, -1), 7, 1.5); jumped {
+		t.Error("110 vs 110 should not be a jump")
+	}
+
+	// A meter which is usually near idle needs more than a few watts to alert
+	tele = telemetry.NewMemory()
+	for d, w := range []float64{2, 3, 2} {
+		put(d-3, w)
+	}
+	put(0, 40)
+	if _, _, jumped, _ := BaselineJump(tele, "A", day, 7, 1.5); jumped {
+		t.Error("40W over a 2W baseline should not be a jump")
+	}
+	put(1, 80)
+	if _, _, jumped, _ := BaselineJump(tele, "A", day.AddDate(0, 0, 1), 7, 1.5); !jumped {
+		t.Error("80W over a 2W baseline should be a jump")
+	}
+}
@@ -0,0 +1,89 @@
+package energy
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// NotifyDaily notifies the energy used, and its cost, each day shortly after
+// midnight, and likewise for each week and month as they end, until the
+// context is done. Notify may be e.g. notify.Notifier.Notify.
+func NotifyDaily(ctx context.Context, tele telemetry.Store, tariff *Tariff, notify func(msg string, args ...any)) {
+	for {
+		now := time.Now()
+		next := Day.Next(Day.Start(now)).Add(5 * time.Minute) // Allow meters to report
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		yesterday := next.AddDate(0, 0, -1)
+		for _, p := range endedPeriods(next) {
+			usage, err := Query(tele, tariff, yesterday, yesterday, p)
+			if err != nil || len(usage) != 1 {
+				slog.Error("Failed to total energy use", "period", p, "err", err)
+				continue
+			}
+			u := usage[0]
+			notify("Energy use", "period", p, "from", u.From.Format(time.DateOnly), "kwh", round2(u.KWh), "cost", tariff.Format(u.Cost))
+		}
+	}
+}
+
+// endedPeriods returns the periods which ended at the midnight before t
+func endedPeriods(t time.Time) []Period {
+	out := []Period{Day}
+	for _, p := range []Period{Week, Month} {
+		if p.Start(t).Equal(Day.Start(t)) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// WatchBaseline notifies each morning, once the night is over, of any meter
+// whose overnight load exceeds that of the previous week by factor, until the
+// context is done
+func WatchBaseline(ctx context.Context, tele telemetry.Store, factor float64, notify func(msg string, args ...any)) {
+	for {
+		now := time.Now()
+		next := Day.Start(now).Add(5*time.Hour + 5*time.Minute)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		series, err := tele.Series()
+		if err != nil {
+			slog.Error("Failed to list telemetry series", "err", err)
+			continue
+		}
+		for _, s := range series {
+			meter, ok := strings.CutSuffix(s, ".power")
+			if !ok {
+				continue
+			}
+			last, usual, jumped, err := BaselineJump(tele, meter, next, 7, factor)
+			switch {
+			case err != nil:
+				slog.Error("Failed to compare overnight load", "meter", meter, "err", err)
+			case jumped:
+				notify("Overnight load jumped", "meter", meter, "watts", round2(last), "usual", round2(usual))
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package energy
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEndedPeriods(t *testing.T) {
+	for _, tc := range []struct {
+		at   time.Time
+		want []Period
+	}{
+		{time.Date(2026, 10, 15, 0, 5, 0, 0, time.Local), []Period{Day}},
+		{time.Date(2026, 10, 19, 0, 5, 0, 0, time.Local), []Period{Day, Week}},
+		{time.Date(2026, 6, 1, 0, 5, 0, 0, time.Local), []Period{Day, Week, Month}},
+	} {
+		if got := endedPeriods(tc.at); !slices.Equal(got, tc.want) {
+			t.Errorf("endedPeriods(%v) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+}
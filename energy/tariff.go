@@ -0,0 +1,86 @@
+// Package energy totals the energy use reported by LightwaveRF energy
+// monitors, and what it cost under a tariff
+package energy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+	"gopkg.in/yaml.v3"
+)
+
+// Tariff is the price of electricity, as YAML:
+//
+//	currency: £
+//	standing: 0.53
+//	unit: 0.27
+//	rates:
+//	  - {from: "00:30", to: "05:30", unit: 0.08}
+type Tariff struct {
+	Currency string  `yaml:"currency"` // Symbol, e.g. "£". Optional.
+	Standing float64 `yaml:"standing"` // Charge per day
+	Unit     float64 `yaml:"unit"`     // Price per kWh, outside Rates
+	Rates    []Rate  `yaml:"rates"`    // Time-of-use prices, e.g. an overnight rate. Optional.
+}
+
+// Rate is a price per kWh during part of each day. To may be before From,
+// for a period spanning midnight.
+type Rate struct {
+	From string  `yaml:"from"` // e.g. "00:30"
+	To   string  `yaml:"to"`
+	Unit float64 `yaml:"unit"`
+}
+
+// LoadTariff reads a tariff from a YAML file
+func LoadTariff(fn string) (*Tariff, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var t Tariff
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, t.Check()
+}
+
+// Check returns every problem found with the tariff, joined
+func (t *Tariff) Check() error {
+	var errs []error
+	if t.Standing < 0 || t.Unit < 0 {
+		errs = append(errs, errors.New("standing charge and unit price cannot be negative"))
+	}
+	for _, r := range t.Rates {
+		_, err1 := timeofday.Parse(r.From)
+		_, err2 := timeofday.Parse(r.To)
+		if err := errors.Join(err1, err2); err != nil {
+			errs = append(errs, fmt.Errorf("rate %s-%s: %w", r.From, r.To, err))
+		}
+		if r.Unit < 0 {
+			errs = append(errs, fmt.Errorf("rate %s-%s: unit price cannot be negative", r.From, r.To))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UnitAt returns the price per kWh at time tm. The first matching Rate
+// applies. The tariff must have passed Check.
+func (t *Tariff) UnitAt(tm time.Time) float64 {
+	now := timeofday.SinceMidnight(tm)
+	for _, r := range t.Rates {
+		from, _ := timeofday.Parse(r.From)
+		to, _ := timeofday.Parse(r.To)
+		if timeofday.Within(now, from, to) {
+			return r.Unit
+		}
+	}
+	return t.Unit
+}
+
+// Format formats an amount of money in the tariff's currency, e.g. "£1.23"
+func (t *Tariff) Format(amount float64) string {
+	return fmt.Sprintf("%s%.2f", t.Currency, amount)
+}
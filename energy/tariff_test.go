@@ -0,0 +1,40 @@
+package energy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnitAt(t *testing.T) {
+	tariff := &Tariff{Unit: 0.27, Rates: []Rate{
+		{From: "23:30", To: "05:30", Unit: 0.08},
+		{From: "16:00", To: "19:00", Unit: 0.40},
+	}}
+	for _, tc := range []struct {
+		at   string
+		want float64
+	}{
+		{"23:29", 0.27},
+		{"23:30", 0.08},
+		{"00:00", 0.08},
+		{"05:29", 0.08},
+		{"05:30", 0.27},
+		{"16:00", 0.40},
+		{"19:00", 0.27},
+	} {
+		tm, _ := time.Parse("15:04", tc.at)
+		if got := tariff.UnitAt(tm); got != tc.want {
+			t.Errorf("UnitAt(%s) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	err := (&Tariff{Unit: -1, Rates: []Rate{{From: "7am", To: "08:00", Unit: 0.1}}}).Check()
+	for _, want := range []string{"negative", "invalid time of day"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+}
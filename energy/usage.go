@@ -0,0 +1,145 @@
+package energy
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// Period is a calendar period over which usage is totalled
+type Period string
+
+const (
+	Day   Period = "day"
+	Week  Period = "week" // Starting on Monday
+	Month Period = "month"
+)
+
+// ParsePeriod parses "day", "week" or "month"
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case Day, Week, Month:
+		return p, nil
+	}
+	return "", fmt.Errorf("period should be day, week or month, got %q", s)
+}
+
+// Start returns the start of the period containing t
+func (p Period) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p {
+	case Week:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Next returns the start of the period after the one starting at t
+func (p Period) Next(t time.Time) time.Time {
+	switch p {
+	case Week:
+		return t.AddDate(0, 0, 7)
+	case Month:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// Back returns the start of the period n periods before the one containing t
+func (p Period) Back(t time.Time, n int) time.Time {
+	start := p.Start(t)
+	switch p {
+	case Week:
+		return start.AddDate(0, 0, -7*n)
+	case Month:
+		return start.AddDate(0, -n, 0)
+	default:
+		return start.AddDate(0, 0, -n)
+	}
+}
+
+// Usage is the energy used during a period, and what it cost
+type Usage struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	KWh  float64   `json:"kwh"`
+	Cost float64   `json:"cost"` // Including standing charges
+}
+
+// Summarise returns the usage in each period, from the one containing from
+// to the one containing to. Points are readings of each
+// meter's energy use so far today (the "today" series recorded by
+// telemetry), oldest first, from the start of the first period.
+func Summarise(ps []telemetry.Point, t *Tariff, from, to time.Time, p Period) []Usage {
+	var out []Usage
+	for start := p.Start(from); !start.After(to); start = p.Next(start) {
+		end := p.Next(start)
+		days := 0
+		for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+			days++
+		}
+		out = append(out, Usage{From: start, To: end, Cost: float64(days) * t.Standing})
+	}
+	if len(out) == 0 {
+		return out
+	}
+
+	bySeries := make(map[string][]telemetry.Point)
+	for _, pt := range ps {
+		bySeries[pt.Series] = append(bySeries[pt.Series], pt)
+	}
+	for _, series := range slices.Sorted(maps.Keys(bySeries)) {
+		prev := 0.0 // Periods start at midnight, when meters reset
+		for _, pt := range bySeries[series] {
+			wh := pt.Value - prev
+			if wh < 0 {
+				wh = pt.Value // Reset at midnight
+			}
+			prev = pt.Value
+			i, ok := slices.BinarySearchFunc(out, pt.Time, func(u Usage, tm time.Time) int {
+				switch {
+				case !u.To.After(tm):
+					return -1
+				case u.From.After(tm):
+					return 1
+				}
+				return 0
+			})
+			if !ok {
+				continue
+			}
+			out[i].KWh += wh / 1000
+			out[i].Cost += wh / 1000 * t.UnitAt(pt.Time)
+		}
+	}
+	return out
+}
+
+// Query summarises the usage of every meter recorded in a telemetry store.
+// See Summarise.
+func Query(s telemetry.Store, t *Tariff, from, to time.Time, p Period) ([]Usage, error) {
+	series, err := s.Series()
+	if err != nil {
+		return nil, err
+	}
+	var ps []telemetry.Point
+	for _, name := range series {
+		if !strings.HasSuffix(name, ".today") {
+			continue
+		}
+		got, err := s.Query(name, p.Start(from), p.Next(p.Start(to)))
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, got...)
+	}
+	return Summarise(ps, t, from, to, p), nil
+}
@@ -0,0 +1,58 @@
+package energy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestPeriod(t *testing.T) {
+	tm := time.Date(2026, 10, 15, 13, 0, 0, 0, time.UTC) // Thursday
+	for _, tc := range []struct {
+		p                 Period
+		start, next, back string
+	}{
+		{Day, "2026-10-15", "2026-10-16", "2026-10-13"},
+		{Week, "2026-10-12", "2026-10-19", "2026-09-28"},
+		{Month, "2026-10-01", "2026-11-01", "2026-08-01"},
+	} {
+		start := tc.p.Start(tm)
+		if got := start.Format(time.DateOnly); got != tc.start {
+			t.Errorf("%s: Start = %s, want %s", tc.p, got, tc.start)
+		}
+		if got := tc.p.Next(start).Format(time.DateOnly); got != tc.next {
+			t.Errorf("%s: Next = %s, want %s", tc.p, got, tc.next)
+		}
+		if got := tc.p.Back(tm, 2).Format(time.DateOnly); got != tc.back {
+			t.Errorf("%s: Back = %s, want %s", tc.p, got, tc.back)
+		}
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	tariff := &Tariff{Standing: 0.5, Unit: 0.25, Rates: []Rate{{From: "00:00", To: "06:00", Unit: 0.1}}}
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	at := func(d int, h int) time.Time { return day.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour) }
+	ps := []telemetry.Point{
+		{Series: "A.today", Time: at(0, 1), Value: 1000},  // 1kWh at 0.1
+		{Series: "A.today", Time: at(0, 12), Value: 3000}, // 2kWh at 0.25
+		{Series: "A.today", Time: at(1, 1), Value: 500},   // Reset: 0.5kWh at 0.1
+		{Series: "B.today", Time: at(1, 12), Value: 2000}, // Second meter: 2kWh at 0.25
+	}
+	got := Summarise(ps, tariff, day, at(1, 23), Day)
+	want := []Usage{
+		{From: at(0, 0), To: at(1, 0), KWh: 3, Cost: 0.5 + 0.1 + 0.5},
+		{From: at(1, 0), To: at(2, 0), KWh: 2.5, Cost: 0.5 + 0.05 + 0.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d periods, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.From.Equal(w.From) || !g.To.Equal(w.To) || math.Abs(g.KWh-w.KWh) > 1e-9 || math.Abs(g.Cost-w.Cost) > 1e-9 {
+			t.Errorf("period %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
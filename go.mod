@@ -5,6 +5,7 @@ go 1.25.0
 require (
 	github.com/MatusOllah/slogcolor v1.7.0
 	github.com/davecgh/go-spew v1.1.1
+	golang.org/x/sys v0.14.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -12,5 +13,4 @@ require (
 	github.com/fatih/color v1.16.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.14.0 // indirect
 )
@@ -0,0 +1,82 @@
+package heating
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/ics"
+)
+
+// How long Calendar.Update has to fetch the feed
+const fetchTimeout = 30 * time.Second
+
+// Calendar overrides every room's target during the events of an iCalendar
+// feed whose summary contains Match, e.g. the "Away" events of a family
+// calendar. Holidays take precedence.
+type Calendar struct {
+	Source  string        `yaml:"source"`  // URL (http, https or webcal) or file name
+	Match   string        `yaml:"match"`   // Case-insensitive. Defaults to "Away".
+	Temp    float64       `yaml:"temp"`    // e.g. a setback
+	Refresh time.Duration `yaml:"refresh"` // How often the feed is fetched. Defaults to 15m.
+
+	mu     sync.Mutex
+	events []ics.Event // Those matching, as last fetched
+}
+
+// Update fetches the feed, replacing the events previously fetched
+func (c *Calendar) Update(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	es, err := ics.Fetch(ctx, c.Source)
+	if err != nil && len(es) == 0 {
+		return err
+	}
+	if err != nil {
+		slog.Warn("Some heating calendar events were not understood", "err", err)
+	}
+	match := strings.ToLower(c.Match)
+	es = slices.DeleteFunc(es, func(e ics.Event) bool {
+		return !strings.Contains(strings.ToLower(e.Summary), match)
+	})
+	c.mu.Lock()
+	c.events = es
+	c.mu.Unlock()
+	slog.Debug("Fetched heating calendar", "events", len(es))
+	return nil
+}
+
+// Run updates the events every Refresh until the context is done. If the
+// feed cannot be fetched, the events last fetched are kept.
+func (c *Calendar) Run(ctx context.Context) {
+	t := time.NewTicker(c.Refresh)
+	defer t.Stop()
+	for {
+		if err := c.Update(ctx); err != nil {
+			slog.Warn("Unable to fetch heating calendar", "err", err)
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// during returns the matching event at time t, if any
+func (c *Calendar) during(t time.Time) (ics.Event, bool) {
+	if c == nil {
+		return ics.Event{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.events {
+		if e.Contains(t) {
+			return e, true
+		}
+	}
+	return ics.Event{}, false
+}
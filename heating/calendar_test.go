@@ -0,0 +1,61 @@
+package heating
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testCalendar = `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20261020
+DTEND;VALUE=DATE:20261023
+SUMMARY:Away in Cornwall
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20261014
+SUMMARY:Dentist
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestCalendar(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "family.ics")
+	if err := os.WriteFile(fn, []byte(testCalendar), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := *testSchedule
+	s.Calendar = &Calendar{Source: fn, Match: "away", Temp: 12, Refresh: time.Hour}
+	if err := s.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Calendar.Update(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want float64
+		why  string
+	}{
+		{time.Date(2026, 10, 14, 7, 0, 0, 0, time.Local), 20, "06:30-08:30"},
+		{time.Date(2026, 10, 20, 7, 0, 0, 0, time.Local), 12, `calendar "Away in Cornwall"`},
+		{time.Date(2026, 10, 22, 23, 59, 0, 0, time.Local), 12, `calendar "Away in Cornwall"`},
+		{time.Date(2026, 10, 23, 7, 0, 0, 0, time.Local), 20, "06:30-08:30"},
+	} {
+		temp, why, _ := s.Target("R1", tc.at)
+		if temp != tc.want || why != tc.why {
+			t.Errorf("Target(R1, %v) = %v, %q; want %v, %q", tc.at, temp, why, tc.want, tc.why)
+		}
+	}
+
+	// The events last fetched are kept if the feed is unavailable
+	os.Remove(fn)
+	if err := s.Calendar.Update(t.Context()); err == nil {
+		t.Error("fetched a missing feed")
+	}
+	if _, why, _ := s.Target("R1", time.Date(2026, 10, 21, 7, 0, 0, 0, time.Local)); why != `calendar "Away in Cornwall"` {
+		t.Errorf("events forgotten: %q", why)
+	}
+}
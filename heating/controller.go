@@ -0,0 +1,193 @@
+package heating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Heating devices revert to their own schedule when it next changes, so
+// targets are resent at least this often, even if unchanged
+const refresh = time.Hour
+
+// Controller sends each scheduled room its target temperature
+type Controller struct {
+	// Set sends a target temperature to a heating device, e.g.
+	// lwl.Client.SetTarget
+	Set func(ctx context.Context, room string, temp float64) error
+
+	// On switches on the failsafe boiler device, if configured, e.g. via
+	// lwl.Registry.Resolve
+	On func(ctx context.Context, device string) error
+
+	// Notify alerts the household, e.g. notify.Notifier.Notify. Optional;
+	// alerts are only logged without it.
+	Notify func(msg string, args ...any)
+
+	// Slot returns the heating device (e.g. R7) a valve is paired to, given
+	// its serial, e.g. lwl.Registry.Slot. Optional; without it, rooms given
+	// by serial are not scheduled, and windows are only detected in those.
+	Slot func(serial string) (string, bool)
+
+	sched *Schedule
+	now   func() time.Time // For testing
+
+	mu      sync.Mutex
+	sent    map[string]sent      // Room -> most recent target acknowledged
+	lost    map[string]time.Time // Room -> first failure since it was last acknowledged
+	windows map[string]*window   // Room -> window-open state
+	heldOff map[string]time.Time // Room -> until when a manual target holds off the schedule
+}
+
+// sent is a target temperature which was acknowledged
+type sent struct {
+	temp float64
+	at   time.Time
+	slot string // Heating device it was sent to, which changes if a valve is re-paired
+}
+
+// alert passes a message to Notify, or logs it
+func (c *Controller) alert(msg string, args ...any) {
+	if c.Notify == nil {
+		slog.Warn(msg, args...)
+		return
+	}
+	c.Notify(msg, args...)
+}
+
+// NewController returns a Controller applying the schedule via set
+func NewController(s *Schedule, set func(ctx context.Context, room string, temp float64) error) *Controller {
+	return &Controller{
+		Set:   set,
+		sched: s,
+		now:   time.Now,
+		sent:  make(map[string]sent),
+		lost:  make(map[string]time.Time),
+
+		windows: make(map[string]*window),
+		heldOff: make(map[string]time.Time),
+	}
+}
+
+// Run applies the schedule every interval, and passes each message to
+// Handle, until the context is done
+func (c *Controller) Run(ctx context.Context, interval time.Duration, msgs <-chan lwl.Response) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := c.Apply(ctx); err != nil {
+			slog.Warn("Unable to apply heating schedule", "err", err)
+		}
+	wait:
+		for {
+			select {
+			case r, ok := <-msgs:
+				if !ok {
+					return
+				}
+				c.Handle(ctx, r)
+			case <-t.C:
+				break wait
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// slot returns the heating device (e.g. R7) a scheduled room refers to,
+// which is looked up with Slot if the room is given by serial
+func (c *Controller) slot(room string) (string, bool) {
+	if !lwl.ValidSerial(room) {
+		return room, true
+	}
+	if c.Slot == nil {
+		return "", false
+	}
+	return c.Slot(room)
+}
+
+// Apply sends each room its target temperature, if it has changed or is
+// due to be refreshed. Rooms which fail are retried on the next Apply, and
+// are sent the failsafe target once they respond, if they were out of
+// contact for long enough.
+func (c *Controller) Apply(ctx context.Context) error {
+	now := c.now()
+	var errs []error
+	for _, room := range slices.Sorted(maps.Keys(c.sched.Rooms)) {
+		slot, ok := c.slot(room)
+		if !ok {
+			slog.Debug("Heating device not found yet", "serial", room)
+			continue
+		}
+		if c.windowOpen(room, now) {
+			continue
+		}
+		temp, why, _ := c.sched.Target(room, now)
+
+		c.mu.Lock()
+		last, ok := c.sent[room]
+		lost, isLost := c.lost[room]
+		until, held := c.heldOff[room]
+		c.mu.Unlock()
+		if ok && last.temp == temp && last.slot == slot && now.Sub(last.at) < refresh {
+			continue // A valve re-paired into another room is sent its target straight away
+		}
+		if held && now.Before(until) {
+			continue
+		}
+
+		f := c.sched.Failsafe
+		failsafe := isLost && f.applies(now) && now.Sub(lost) >= f.After
+		send := temp
+		if failsafe {
+			send, why = f.Temp, "failsafe"
+		}
+
+		slog.Info("Heating target", "room", room, "slot", slot, "temp", send, "why", why)
+		sctx := ctx
+		if failsafe {
+			sctx = lwl.WithSource(ctx, "heating:failsafe")
+		}
+		err := c.Set(sctx, slot, send)
+		var heldOff lwl.HeldOffError
+		if errors.As(err, &heldOff) {
+			slog.Info("Heating target held off by manual command", "room", room, "manual", heldOff.Manual, "by", heldOff.By, "until", heldOff.Until.Format(time.TimeOnly))
+			c.mu.Lock()
+			c.heldOff[room] = heldOff.Until // Resent once the hold-off ends
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			if !isLost {
+				c.mu.Lock()
+				c.lost[room] = now
+				c.mu.Unlock()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", room, err))
+			continue
+		}
+		c.mu.Lock()
+		c.sent[room] = sent{temp: temp, at: now, slot: slot} // The scheduled target, so a failsafe target is held until it changes
+		delete(c.lost, room)
+		delete(c.heldOff, room)
+		c.mu.Unlock()
+
+		if failsafe {
+			c.alert("Heating contact regained after failure, failsafe target sent", "room", room, "lost", now.Sub(lost).Round(time.Minute), "temp", send)
+			if f.Boiler != "" && c.On != nil {
+				if err := c.On(sctx, f.Boiler); err != nil {
+					errs = append(errs, fmt.Errorf("failsafe boiler %s: %w", f.Boiler, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
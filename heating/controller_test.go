@@ -0,0 +1,202 @@
+package heating
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestController(t *testing.T) {
+	var got map[string]float64
+	var fail bool
+	c := NewController(testSchedule, func(_ context.Context, room string, temp float64) error {
+		if fail {
+			return errors.New("no ack")
+		}
+		got[room] = temp
+		return nil
+	})
+	now := time.Date(2026, 10, 14, 6, 0, 0, 0, time.Local) // Wednesday
+	c.now = func() time.Time { return now }
+
+	apply := func(want map[string]float64) {
+		t.Helper()
+		got = make(map[string]float64)
+		if err := c.Apply(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("at %v sent %v, want %v", now.Format(time.Kitchen), got, want)
+		}
+	}
+
+	apply(map[string]float64{"R1": 16, "R2": 7})
+	now = now.Add(15 * time.Minute)
+	apply(map[string]float64{}) // Unchanged
+	now = now.Add(30 * time.Minute)
+	apply(map[string]float64{"R1": 20})
+
+	// Failures are retried
+	fail = true
+	now = now.Add(2 * time.Hour)
+	if err := c.Apply(context.Background()); err == nil {
+		t.Fatal("want error")
+	}
+	fail = false
+	apply(map[string]float64{"R1": 16, "R2": 7})
+
+	// Unchanged targets are refreshed
+	now = now.Add(refresh)
+	apply(map[string]float64{"R1": 16, "R2": 7})
+}
+
+func TestControllerHeldOff(t *testing.T) {
+	now := time.Date(2026, 10, 14, 6, 0, 0, 0, time.Local) // Wednesday
+	var tries int
+	c := NewController(testSchedule, func(_ context.Context, room string, temp float64) error {
+		if room != "R1" {
+			return nil
+		}
+		tries++
+		if tries == 1 {
+			return lwl.HeldOffError{Manual: "!R1F*tP22", By: "http:alice", At: now, Until: now.Add(time.Hour)}
+		}
+		return nil
+	})
+	c.now = func() time.Time { return now }
+
+	for range 3 { // Held off quietly, without asking again
+		if err := c.Apply(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(15 * time.Minute)
+	}
+	if tries != 1 {
+		t.Errorf("tried %d times during the hold-off, want 1", tries)
+	}
+	now = now.Add(30 * time.Minute)
+	if err := c.Apply(context.Background()); err != nil || tries != 2 {
+		t.Errorf("after the hold-off: tried %d times, %v", tries, err)
+	}
+}
+
+func TestControllerSerial(t *testing.T) {
+	s := *testSchedule
+	s.Rooms = map[string]string{"24C702": "living"}
+	got := make(map[string]float64)
+	c := NewController(&s, func(_ context.Context, room string, temp float64) error {
+		got[room] = temp
+		return nil
+	})
+	slots := map[string]string{}
+	c.Slot = func(serial string) (string, bool) {
+		slot, ok := slots[serial]
+		return slot, ok
+	}
+	now := time.Date(2026, 10, 14, 6, 0, 0, 0, time.Local) // Wednesday
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.Apply(ctx)
+	if len(got) != 0 {
+		t.Fatalf("sent %v before the valve's room was known", got)
+	}
+	slots["24C702"] = "R4"
+	c.Apply(ctx)
+	if want := map[string]float64{"R4": 16}; !maps.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+
+	// Re-paired into another room, which is sent the target straight away
+	clear(got)
+	now = now.Add(time.Minute)
+	slots["24C702"] = "R9"
+	c.Apply(ctx)
+	if want := map[string]float64{"R9": 16}; !maps.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
+
+func TestFailsafe(t *testing.T) {
+	s := *testSchedule
+	s.Failsafe = &Failsafe{After: 30 * time.Minute, Temp: 18, Boiler: "boiler", Months: winter}
+
+	var got []float64
+	var fail bool
+	c := NewController(&s, func(_ context.Context, room string, temp float64) error {
+		if room != "R1" {
+			return nil
+		}
+		if fail {
+			return errors.New("no ack")
+		}
+		got = append(got, temp)
+		return nil
+	})
+	var boiler, alerts int
+	c.On = func(_ context.Context, device string) error {
+		boiler++
+		return nil
+	}
+	c.Notify = func(string, ...any) { alerts++ }
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local) // Wednesday, setback
+	c.now = func() time.Time { return now }
+
+	apply := func(want ...float64) {
+		t.Helper()
+		got = nil
+		err := c.Apply(context.Background())
+		if fail != (err != nil) {
+			t.Fatalf("at %v: %v", now.Format(time.Kitchen), err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("at %v sent %v, want %v", now.Format(time.Kitchen), got, want)
+		}
+	}
+
+	apply(16)
+
+	// A short loss of contact does not trigger the failsafe
+	fail = true
+	now = now.Add(refresh)
+	apply()
+	fail = false
+	now = now.Add(10 * time.Minute)
+	apply(16)
+	if alerts != 0 || boiler != 0 {
+		t.Errorf("alerts=%d boiler=%d, want none", alerts, boiler)
+	}
+
+	// A long one does, and the failsafe target is held until the schedule changes
+	fail = true
+	now = now.Add(refresh)
+	apply()
+	now = now.Add(time.Minute)
+	apply()
+	fail = false
+	now = now.Add(30 * time.Minute)
+	apply(18)
+	if alerts != 1 || boiler != 1 {
+		t.Errorf("alerts=%d boiler=%d, want 1", alerts, boiler)
+	}
+	now = now.Add(time.Minute)
+	apply()
+	now = time.Date(2026, 10, 14, 17, 0, 0, 0, time.Local)
+	apply(21)
+
+	// Not in summer
+	now = time.Date(2026, 7, 15, 9, 0, 0, 0, time.Local)
+	fail = true
+	apply()
+	fail = false
+	now = now.Add(time.Hour)
+	apply(16)
+	if alerts != 1 {
+		t.Errorf("alerts=%d, want 1", alerts)
+	}
+}
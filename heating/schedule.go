@@ -0,0 +1,304 @@
+// Package heating applies weekly schedules to LightwaveRF heating devices,
+// such as radiator valves (TRVs), by sending each its target temperature as
+// the schedule changes
+package heating
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/ics"
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFrost is the frost protection floor, in Celsius, if none is
+// configured
+const DefaultFrost = 7.0
+
+// Schedule is the heating configuration, as YAML:
+//
+//	frost: 7
+//	profiles:
+//	  living:
+//	    setback: 16
+//	    weekday:
+//	      - {from: "06:30", to: "08:30", temp: 20}
+//	      - {from: "17:00", to: "22:30", temp: 21}
+//	    weekend:
+//	      - {from: "08:00", to: "23:00", temp: 20.5}
+//	rooms:
+//	  R7: living
+//	  24C702: bedroom # A valve, by serial, wherever it is paired
+//	holidays:
+//	  - {from: 2026-12-24, to: 2026-12-27, temp: 12}
+//	bank_holidays:
+//	  dates: [2026-12-28]
+//	  ics: bank-holidays.ics # e.g. from https://www.gov.uk/bank-holidays
+//	calendar:
+//	  source: https://example.com/family.ics
+//	  match: Away
+//	  temp: 12
+//	failsafe:
+//	  after: 30m
+//	  temp: 16
+//	  boiler: R9D1
+//	window:
+//	  drop: 1.5
+type Schedule struct {
+	Frost        float64            `yaml:"frost"`         // No room is set below this. Defaults to DefaultFrost.
+	Profiles     map[string]Profile `yaml:"profiles"`      // By name
+	Rooms        map[string]string  `yaml:"rooms"`         // Heating device (e.g. R7) or valve serial -> profile name
+	Holidays     []Holiday          `yaml:"holidays"`      // Override every profile
+	BankHolidays *BankHolidays      `yaml:"bank_holidays"` // Optional
+	Calendar     *Calendar          `yaml:"calendar"`      // Optional
+	Failsafe     *Failsafe          `yaml:"failsafe"`      // Optional
+	Window       *Window            `yaml:"window"`        // Optional
+}
+
+// Profile is a weekly schedule
+type Profile struct {
+	Setback float64 `yaml:"setback"` // Target outside blocks
+	Weekday []Block `yaml:"weekday"` // Monday to Friday
+	Weekend []Block `yaml:"weekend"` // Saturday and Sunday
+}
+
+// Block is a period of a day with its own target temperature. To may be
+// "24:00", for the end of the day.
+type Block struct {
+	From string  `yaml:"from"` // e.g. "06:30"
+	To   string  `yaml:"to"`
+	Temp float64 `yaml:"temp"`
+}
+
+// Holiday is a period, in whole days, during which every room targets Temp
+type Holiday struct {
+	From string  `yaml:"from"` // e.g. "2026-12-24"
+	To   string  `yaml:"to"`   // Inclusive
+	Temp float64 `yaml:"temp"`
+}
+
+// BankHolidays are days on which profiles follow their weekend blocks, rather
+// than their weekday blocks, from a list of dates and/or an iCalendar file,
+// every event in which is a bank holiday
+type BankHolidays struct {
+	Dates []string `yaml:"dates"` // e.g. "2026-12-28"
+	ICS   string   `yaml:"ics"`   // File name. Optional.
+
+	days map[string]bool // Dates, and those of the ICS events
+}
+
+// Failsafe is what to do when contact with a room's valve (or the hub) is
+// regained after being lost for a while, during winter. The valve may have
+// been left at a low target, or the boiler off, so the room is sent Temp and
+// Boiler is switched on.
+type Failsafe struct {
+	After  time.Duration `yaml:"after"`  // Minimum loss of contact, e.g. 30m
+	Temp   float64       `yaml:"temp"`   // Sent instead of the scheduled target, until that next changes
+	Boiler string        `yaml:"boiler"` // Device (or alias) switched on, e.g. a boiler relay. Optional.
+	Months []time.Month  `yaml:"months"` // Defaults to October to April
+}
+
+// winter are the default Failsafe.Months
+var winter = []time.Month{time.October, time.November, time.December, time.January, time.February, time.March, time.April}
+
+// applies reports whether the failsafe applies at time t
+func (f *Failsafe) applies(t time.Time) bool {
+	return f != nil && slices.Contains(f.Months, t.Month())
+}
+
+// Load reads a schedule from a YAML file
+func Load(fn string) (*Schedule, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var s Schedule
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Frost == 0 {
+		s.Frost = DefaultFrost
+	}
+	if s.Failsafe != nil && s.Failsafe.Months == nil {
+		s.Failsafe.Months = winter
+	}
+	if b := s.BankHolidays; b != nil {
+		b.days = make(map[string]bool)
+		for _, d := range b.Dates {
+			b.days[d] = true
+		}
+		if b.ICS != "" {
+			es, err := ics.Load(b.ICS)
+			if err != nil {
+				return nil, fmt.Errorf("bank holidays: %w", err)
+			}
+			for _, e := range es {
+				for _, d := range e.Days() {
+					b.days[d] = true
+				}
+			}
+		}
+	}
+	if c := s.Calendar; c != nil {
+		c.Match = cmp.Or(c.Match, "Away")
+		c.Refresh = cmp.Or(c.Refresh, 15*time.Minute)
+	}
+	if w := s.Window; w != nil {
+		w.Drop = cmp.Or(w.Drop, 1.5)
+		w.Within = cmp.Or(w.Within, 10*time.Minute)
+		w.Stable = cmp.Or(w.Stable, 15*time.Minute)
+		w.Temp = cmp.Or(w.Temp, s.Frost)
+	}
+	return &s, s.Check()
+}
+
+// Check returns every problem found with the schedule, joined
+func (s *Schedule) Check() error {
+	var errs []error
+	temp := func(where string, t float64) {
+		if t < lwl.TempMin || t > lwl.TempMax {
+			errs = append(errs, fmt.Errorf("%s: temperature %v out of range %v-%v", where, t, lwl.TempMin, lwl.TempMax))
+		}
+	}
+	temp("frost", s.Frost)
+	for _, name := range slices.Sorted(maps.Keys(s.Profiles)) {
+		p := s.Profiles[name]
+		temp(name+" setback", p.Setback)
+		for day, blocks := range map[string][]Block{"weekday": p.Weekday, "weekend": p.Weekend} {
+			for _, b := range blocks {
+				where := fmt.Sprintf("%s %s %s-%s", name, day, b.From, b.To)
+				from, err1 := timeofday.Parse(b.From)
+				to, err2 := timeofday.Parse(b.To)
+				if err := errors.Join(err1, err2); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", where, err))
+				} else if from >= to {
+					errs = append(errs, fmt.Errorf("%s: ends before it starts", where))
+				}
+				temp(where, b.Temp)
+			}
+		}
+	}
+	for _, room := range slices.Sorted(maps.Keys(s.Rooms)) {
+		if (!lwl.ValidID(room) || strings.Contains(room, "D")) && !lwl.ValidSerial(room) {
+			errs = append(errs, fmt.Errorf("rooms: %q should be a heating device, e.g. R7, or a serial, e.g. 24C702", room))
+		}
+		if _, ok := s.Profiles[s.Rooms[room]]; !ok {
+			errs = append(errs, fmt.Errorf("rooms: %s: no such profile %q", room, s.Rooms[room]))
+		}
+	}
+	for _, h := range s.Holidays {
+		where := fmt.Sprintf("holiday %s-%s", h.From, h.To)
+		from, err1 := time.Parse(time.DateOnly, h.From)
+		to, err2 := time.Parse(time.DateOnly, h.To)
+		if err := errors.Join(err1, err2); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		} else if to.Before(from) {
+			errs = append(errs, fmt.Errorf("%s: ends before it starts", where))
+		}
+		temp(where, h.Temp)
+	}
+	if b := s.BankHolidays; b != nil {
+		for _, d := range b.Dates {
+			if _, err := time.Parse(time.DateOnly, d); err != nil {
+				errs = append(errs, fmt.Errorf("bank holidays: %w", err))
+			}
+		}
+	}
+	if c := s.Calendar; c != nil {
+		if c.Source == "" {
+			errs = append(errs, errors.New("calendar: missing source"))
+		}
+		if c.Refresh <= 0 {
+			errs = append(errs, errors.New("calendar: refresh should be a positive duration, e.g. 15m"))
+		}
+		temp("calendar", c.Temp)
+	}
+	if f := s.Failsafe; f != nil {
+		if f.After <= 0 {
+			errs = append(errs, errors.New("failsafe: after should be a positive duration, e.g. 30m"))
+		}
+		temp("failsafe", f.Temp)
+		for _, m := range f.Months {
+			if m < time.January || m > time.December {
+				errs = append(errs, fmt.Errorf("failsafe: invalid month %d", m))
+			}
+		}
+	}
+	if w := s.Window; w != nil {
+		if w.Drop <= 0 || w.Within <= 0 || w.Stable <= 0 {
+			errs = append(errs, errors.New("window: drop, within and stable should be positive"))
+		}
+		temp("window", w.Temp)
+	}
+	return errors.Join(errs...)
+}
+
+// Target returns the temperature a room should target at time t, and why,
+// or false if the room is not scheduled. The schedule must have passed Check.
+func (s *Schedule) Target(room string, t time.Time) (temp float64, why string, ok bool) {
+	p, ok := s.Profiles[s.Rooms[room]]
+	if !ok {
+		return 0, "", false
+	}
+
+	temp, why = p.Setback, "setback"
+	if h, ok := s.holiday(t); ok {
+		temp, why = h.Temp, fmt.Sprintf("holiday %s-%s", h.From, h.To)
+	} else if e, ok := s.Calendar.during(t); ok {
+		temp, why = s.Calendar.Temp, fmt.Sprintf("calendar %q", e.Summary)
+	} else {
+		blocks, bank := p.Weekday, s.bankHoliday(t)
+		if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday || bank {
+			blocks = p.Weekend
+		}
+		now := timeofday.SinceMidnight(t)
+		for _, b := range blocks {
+			from, _ := timeofday.Parse(b.From)
+			to, _ := timeofday.Parse(b.To)
+			if now >= from && now < to {
+				temp, why = b.Temp, b.From+"-"+b.To
+				break
+			}
+		}
+		if bank {
+			why += " (bank holiday)"
+		}
+	}
+
+	if temp < s.Frost {
+		temp, why = s.Frost, why+", raised to frost floor"
+	}
+	return temp, why, true
+}
+
+// holiday returns the holiday covering t, if any
+func (s *Schedule) holiday(t time.Time) (Holiday, bool) {
+	day := t.Format(time.DateOnly)
+	for _, h := range s.Holidays {
+		if day >= h.From && day <= h.To {
+			return h, true
+		}
+	}
+	return Holiday{}, false
+}
+
+// bankHoliday reports whether t is on a bank holiday
+func (s *Schedule) bankHoliday(t time.Time) bool {
+	b := s.BankHolidays
+	if b == nil {
+		return false
+	}
+	day := t.Format(time.DateOnly)
+	if b.days == nil {
+		return slices.Contains(b.Dates, day) // Not loaded, e.g. in tests
+	}
+	return b.days[day]
+}
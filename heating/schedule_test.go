@@ -0,0 +1,104 @@
+package heating
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchedule = &Schedule{
+	Frost: 7,
+	Profiles: map[string]Profile{
+		"living": {
+			Setback: 16,
+			Weekday: []Block{{From: "06:30", To: "08:30", Temp: 20}, {From: "17:00", To: "24:00", Temp: 21}},
+			Weekend: []Block{{From: "08:00", To: "23:00", Temp: 20.5}},
+		},
+		"spare": {Setback: 5},
+	},
+	Rooms:    map[string]string{"R1": "living", "R2": "spare"},
+	Holidays: []Holiday{{From: "2026-12-24", To: "2026-12-27", Temp: 12}},
+}
+
+func TestTarget(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	for _, tc := range []struct {
+		room string
+		at   string
+		want float64
+		why  string
+	}{
+		{"R1", "2026-10-14 06:29", 16, "setback"}, // Wednesday
+		{"R1", "2026-10-14 06:30", 20, "06:30-08:30"},
+		{"R1", "2026-10-14 08:30", 16, "setback"},
+		{"R1", "2026-10-14 23:59", 21, "17:00-24:00"},
+		{"R1", "2026-10-17 07:00", 16, "setback"}, // Saturday
+		{"R1", "2026-10-17 08:00", 20.5, "08:00-23:00"},
+		{"R1", "2026-12-24 07:00", 12, "holiday 2026-12-24-2026-12-27"},
+		{"R1", "2026-12-27 23:59", 12, "holiday 2026-12-24-2026-12-27"},
+		{"R1", "2026-12-28 07:00", 20, "06:30-08:30"},
+		{"R2", "2026-10-14 12:00", 7, "setback, raised to frost floor"},
+	} {
+		temp, why, ok := testSchedule.Target(tc.room, at(tc.at))
+		if !ok || temp != tc.want || why != tc.why {
+			t.Errorf("Target(%s, %s) = %v, %q, %v; want %v, %q", tc.room, tc.at, temp, why, ok, tc.want, tc.why)
+		}
+	}
+	if _, _, ok := testSchedule.Target("R3", time.Now()); ok {
+		t.Error("unscheduled room should not be ok")
+	}
+}
+
+func TestBankHolidays(t *testing.T) {
+	s := *testSchedule
+	s.BankHolidays = &BankHolidays{Dates: []string{"2026-12-28"}}
+	for _, tc := range []struct {
+		at   string
+		want float64
+		why  string
+	}{
+		{"2026-12-28 07:00", 16, "setback (bank holiday)"}, // Monday
+		{"2026-12-28 08:00", 20.5, "08:00-23:00 (bank holiday)"},
+		{"2026-12-29 07:00", 20, "06:30-08:30"},
+	} {
+		at, err := time.ParseInLocation("2006-01-02 15:04", tc.at, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		temp, why, ok := s.Target("R1", at)
+		if !ok || temp != tc.want || why != tc.why {
+			t.Errorf("Target(R1, %s) = %v, %q, %v; want %v, %q", tc.at, temp, why, ok, tc.want, tc.why)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := testSchedule.Check(); err != nil {
+		t.Errorf("valid schedule: %v", err)
+	}
+
+	s := &Schedule{
+		Frost: 7,
+		Profiles: map[string]Profile{
+			"bad": {Setback: 50, Weekday: []Block{{From: "9:00", To: "08:00", Temp: 20}, {From: "7am", To: "08:00", Temp: 20}}},
+		},
+		Rooms:    map[string]string{"R1D1": "bad", "R2": "missing"},
+		Holidays: []Holiday{{From: "2026-12-27", To: "2026-12-24", Temp: 12}},
+		Failsafe: &Failsafe{Temp: 16, Months: []time.Month{13}},
+	}
+	err := s.Check()
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	for _, want := range []string{"out of range", "ends before it starts", "invalid time of day", "should be a heating device", "no such profile", "positive duration", "invalid month"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package heating
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Window configures window-open detection. Gen1 valves lack it, so it is
+// done here: a room whose temperature falls by at least Drop within Within
+// is sent Temp, until its temperature has not fallen further for Stable.
+type Window struct {
+	Drop   float64       `yaml:"drop"`   // Celsius. Defaults to 1.5.
+	Within time.Duration `yaml:"within"` // Defaults to 10m
+	Stable time.Duration `yaml:"stable"` // Defaults to 15m
+	Temp   float64       `yaml:"temp"`   // Defaults to the frost floor
+}
+
+// reading is a temperature reported by a valve
+type reading struct {
+	at   time.Time
+	temp float64
+}
+
+// window is the window-open state of a room
+type window struct {
+	recent []reading // Within Window.Within, oldest first
+	open   time.Time // When the window was detected open, or zero
+	low    float64   // Lowest temperature since open
+	lowAt  time.Time // When low was first reported
+}
+
+// Handle follows valve reports, to detect open windows. Valves report by
+// serial number, so those in rooms scheduled by room are found with Slot.
+func (c *Controller) Handle(ctx context.Context, r lwl.Response) {
+	if r.Fn != "statusPush" || r.Prod != "valve" || c.sched.Window == nil {
+		return
+	}
+	if _, scheduled := c.sched.Rooms[r.Serial]; scheduled {
+		c.reading(ctx, r.Serial, float64(r.CTemp))
+		return
+	}
+	if c.Slot == nil {
+		return
+	}
+	if room, ok := c.Slot(r.Serial); ok {
+		if _, scheduled := c.sched.Rooms[room]; scheduled {
+			c.reading(ctx, room, float64(r.CTemp))
+		}
+	}
+}
+
+// reading records a room's temperature, and turns the room down if it has
+// fallen far enough, fast enough, for a window to be open. The room is as
+// scheduled, i.e. it may be a valve's serial.
+func (c *Controller) reading(ctx context.Context, room string, temp float64) {
+	cfg, now := c.sched.Window, c.now()
+
+	c.mu.Lock()
+	w := c.windows[room]
+	if w == nil {
+		w = &window{}
+		c.windows[room] = w
+	}
+	if !w.open.IsZero() {
+		if temp < w.low {
+			w.low, w.lowAt = temp, now
+		}
+		c.mu.Unlock()
+		return
+	}
+	for len(w.recent) > 0 && now.Sub(w.recent[0].at) > cfg.Within {
+		w.recent = w.recent[1:]
+	}
+	w.recent = append(w.recent, reading{at: now, temp: temp})
+	high := temp
+	for _, r := range w.recent {
+		high = max(high, r.temp)
+	}
+	c.mu.Unlock()
+
+	if high-temp < cfg.Drop {
+		return
+	}
+	slot, ok := c.slot(room)
+	if !ok {
+		return
+	}
+	slog.Info("Window open", "room", room, "from", high, "to", temp, "temp", cfg.Temp)
+	if err := c.Set(lwl.WithSource(ctx, "heating:window"), slot, cfg.Temp); err != nil {
+		slog.Warn("Unable to turn down room with window open", "room", room, "err", err)
+		return // Retried on the next reading, while the drop is recent
+	}
+	c.mu.Lock()
+	*w = window{open: now, low: temp, lowAt: now}
+	c.mu.Unlock()
+}
+
+// windowOpen reports whether a room is in window-open mode, closing it once
+// the temperature has stabilised, so the scheduled target is restored
+func (c *Controller) windowOpen(room string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := c.windows[room]
+	if w == nil || w.open.IsZero() {
+		return false
+	}
+	if now.Sub(w.lowAt) < c.sched.Window.Stable {
+		return true
+	}
+	slog.Info("Window closed", "room", room, "open", now.Sub(w.open).Round(time.Minute))
+	delete(c.windows, room)
+	delete(c.sent, room) // Resend the scheduled target
+	return false
+}
@@ -0,0 +1,75 @@
+package heating
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestWindow(t *testing.T) {
+	s := *testSchedule
+	s.Window = &Window{Drop: 1.5, Within: 10 * time.Minute, Stable: 15 * time.Minute, Temp: 7}
+
+	var got []float64
+	c := NewController(&s, func(_ context.Context, room string, temp float64) error {
+		if room == "R1" {
+			got = append(got, temp)
+		}
+		return nil
+	})
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local) // Wednesday, setback
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	check := func(want ...float64) {
+		t.Helper()
+		if !slices.Equal(got, want) {
+			t.Errorf("at %v sent %v, want %v", now.Format(time.Kitchen), got, want)
+		}
+		got = nil
+	}
+	push := func(temp float32) {
+		c.Handle(ctx, lwl.Response{Pkt: "868R", Fn: "statusPush", Prod: "valve", Serial: "24C702", CTemp: temp})
+	}
+	step := func(d time.Duration) {
+		now = now.Add(d)
+		if err := c.Apply(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	step(0)
+	check(16)
+
+	// Unknown valves are ignored
+	push(19)
+	push(10)
+	check()
+
+	c.Slot = func(serial string) (string, bool) {
+		return "R1", serial == "24C702"
+	}
+
+	// A slow fall is not a window
+	for _, temp := range []float32{19, 18.5, 18, 17.5, 17} {
+		push(temp)
+		step(5 * time.Minute)
+	}
+	check()
+
+	// A fast one is, and the room is held down until the temperature stabilises
+	push(16.5)
+	step(3 * time.Minute)
+	push(15.4)
+	check(7)
+	step(10 * time.Minute)
+	push(15)
+	step(10 * time.Minute)
+	push(15.5)
+	check()
+	step(5 * time.Minute)
+	check(16)
+}
@@ -0,0 +1,334 @@
+// Package hue emulates a Philips Hue bridge on the local network, exposing
+// LightwaveRF devices as Hue lights, so that Amazon Echo (Alexa) devices and
+// the many apps which speak the Hue API can discover and control them,
+// locally, without the LightwaveRF cloud. This is the approach of fauxmo and
+// Home Assistant's emulated_hue.
+//
+// Only devices with aliases are exposed, named by their alias with
+// underscores as spaces, e.g. "kitchen ceiling" for kitchen_ceiling. Dimmers
+// (see SetDimmers) are dimmable lights; other devices are on/off plugs. Group
+// 0 contains every light. Like a bridge whose link button is always pressed,
+// anyone on the network may control them, so this should only be enabled on
+// a trusted network. Echo devices require the bridge to be served on port 80.
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Username given to every client which registers, see register. The bridge
+// does not distinguish clients.
+const username = "lightwaverf"
+
+// Version of the Hue API emulated
+const apiVersion = "1.46.0"
+
+// Hue API error types
+const (
+	errInvalidJSON      = 2
+	errNotAvailable     = 3
+	errInvalidParameter = 7
+	errInternal         = 901
+)
+
+// Bridge serves the subset of the Hue bridge API used by Echo devices and
+// Hue apps, see Handler and ServeSSDP
+type Bridge struct {
+	reg *lwl.Registry
+	id  string // e.g. 001788FFFE1A2B3C, which is also the basis of its serial and UDN
+
+	mu      sync.Mutex
+	dimmers map[string]bool // By device identifier, see SetDimmers
+}
+
+// NewBridge returns a Bridge exposing the devices in reg which have aliases
+func NewBridge(reg *lwl.Registry) *Bridge {
+	host, _ := os.Hostname()
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return &Bridge{reg: reg, id: fmt.Sprintf("001788FFFE%06X", h.Sum32()&0xffffff)}
+}
+
+// mac returns the bridge's (fictional) MAC address, as 12 hex digits
+func (b *Bridge) mac() string {
+	return b.id[:6] + b.id[10:]
+}
+
+// udn returns the bridge's UPnP unique device name
+func (b *Bridge) udn() string {
+	return "uuid:2f402f80-da50-11e1-9b23-" + strings.ToLower(b.mac())
+}
+
+// Handler returns the bridge's HTTP interface, to be served on port 80 of the
+// address advertised by ServeSSDP
+func (b *Bridge) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /description.xml", b.description)
+	mux.HandleFunc("POST /api", b.register)
+	mux.HandleFunc("GET /api/config", b.getPublicConfig)
+	mux.HandleFunc("GET /api/{user}", b.getAll)
+	mux.HandleFunc("GET /api/{user}/config", b.getConfig)
+	mux.HandleFunc("GET /api/{user}/lights", b.getLights)
+	mux.HandleFunc("GET /api/{user}/lights/{id}", b.getLight)
+	mux.HandleFunc("PUT /api/{user}/lights/{id}/state", b.putState)
+	mux.HandleFunc("GET /api/{user}/groups", b.getGroups)
+	mux.HandleFunc("GET /api/{user}/groups/{id}", b.getGroup)
+	mux.HandleFunc("PUT /api/{user}/groups/{id}/action", b.putAction)
+	mux.HandleFunc("GET /api/{user}/{resource}", b.getEmpty)
+	return mux
+}
+
+func (b *Bridge) description(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8" ?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+<specVersion><major>1</major><minor>0</minor></specVersion>
+<URLBase>http://%s/</URLBase>
+<device>
+<deviceType>urn:schemas-upnp-org:device:Basic:1</deviceType>
+<friendlyName>LightwaveRF (%s)</friendlyName>
+<manufacturer>Royal Philips Electronics</manufacturer>
+<manufacturerURL>http://www.philips.com</manufacturerURL>
+<modelDescription>Philips hue Personal Wireless Lighting</modelDescription>
+<modelName>Philips hue bridge 2015</modelName>
+<modelNumber>BSB002</modelNumber>
+<modelURL>http://www.meethue.com</modelURL>
+<serialNumber>%s</serialNumber>
+<UDN>%s</UDN>
+</device>
+</root>
+`, r.Host, r.Host, strings.ToLower(b.mac()), b.udn())
+}
+
+// register implements creating a user, which always succeeds
+func (b *Bridge) register(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceType string `json:"devicetype"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errInvalidJSON, "/", "body contains invalid JSON")
+		return
+	}
+	slog.Info("Hue client registered", "devicetype", req.DeviceType, "remote", r.RemoteAddr)
+	writeJSON(w, []any{map[string]any{"success": map[string]string{"username": username}}})
+}
+
+// publicConfig is the bridge configuration available without a username,
+// which apps use to identify bridges
+func (b *Bridge) publicConfig() map[string]any {
+	return map[string]any{
+		"name":             "LightwaveRF",
+		"datastoreversion": "126",
+		"swversion":        "1946157000",
+		"apiversion":       apiVersion,
+		"mac":              formatMAC(b.mac()),
+		"bridgeid":         b.id,
+		"factorynew":       false,
+		"replacesbridgeid": nil,
+		"modelid":          "BSB002",
+		"starterkitid":     "",
+	}
+}
+
+// config is the full bridge configuration
+func (b *Bridge) config(r *http.Request) map[string]any {
+	c := b.publicConfig()
+	now := time.Now()
+	c["ipaddress"] = localHost(r)
+	c["linkbutton"] = true
+	c["portalservices"] = false
+	c["UTC"] = now.UTC().Format("2006-01-02T15:04:05")
+	c["localtime"] = now.Format("2006-01-02T15:04:05")
+	c["timezone"] = now.Location().String()
+	c["whitelist"] = map[string]any{username: map[string]string{"name": "LightwaveRF"}}
+	return c
+}
+
+// localHost returns the address the request was sent to, without the port
+func localHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return r.Host
+	}
+	return host
+}
+
+// formatMAC formats 12 hex digits as a MAC address, e.g. 00:17:88:1a:2b:3c
+func formatMAC(hex string) string {
+	var parts []string
+	for i := 0; i < len(hex); i += 2 {
+		parts = append(parts, strings.ToLower(hex[i:i+2]))
+	}
+	return strings.Join(parts, ":")
+}
+
+func (b *Bridge) getPublicConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, b.publicConfig())
+}
+
+func (b *Bridge) getConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, b.config(r))
+}
+
+func (b *Bridge) getAll(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"lights":        b.allLights(),
+		"groups":        map[string]any{},
+		"config":        b.config(r),
+		"schedules":     map[string]any{},
+		"scenes":        map[string]any{},
+		"rules":         map[string]any{},
+		"sensors":       map[string]any{},
+		"resourcelinks": map[string]any{},
+	})
+}
+
+// getEmpty serves the resources the bridge has none of, e.g. sensors
+func (b *Bridge) getEmpty(w http.ResponseWriter, r *http.Request) {
+	switch res := r.PathValue("resource"); res {
+	case "schedules", "scenes", "rules", "sensors", "resourcelinks":
+		writeJSON(w, map[string]any{})
+	default:
+		writeError(w, errNotAvailable, "/"+res, fmt.Sprintf("resource, /%s, not available", res))
+	}
+}
+
+func (b *Bridge) getLights(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, b.allLights())
+}
+
+func (b *Bridge) allLights() map[string]light {
+	out := make(map[string]light)
+	for id, d := range b.lights() {
+		out[id] = b.newLight(id, d)
+	}
+	return out
+}
+
+func (b *Bridge) getLight(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	d, ok := b.lights()[id]
+	if !ok {
+		writeError(w, errNotAvailable, "/lights/"+id, fmt.Sprintf("resource, /lights/%s, not available", id))
+		return
+	}
+	writeJSON(w, b.newLight(id, d))
+}
+
+// putState changes the state of a light, see apply
+func (b *Bridge) putState(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	d, ok := b.lights()[id]
+	if !ok {
+		writeError(w, errNotAvailable, "/lights/"+id, fmt.Sprintf("resource, /lights/%s, not available", id))
+		return
+	}
+	b.change(w, r, "/lights/"+id+"/state/", []*lwl.Device{d})
+}
+
+// group is the Hue API representation of group 0, which contains every light
+type group struct {
+	Name   string     `json:"name"`
+	Lights []string   `json:"lights"`
+	Type   string     `json:"type"`
+	Action lightState `json:"action"`
+}
+
+func (b *Bridge) group0() group {
+	g := group{Name: "Group 0", Lights: []string{}, Type: "LightGroup"}
+	g.Action.Alert, g.Action.Mode, g.Action.Reachable = "none", "homeautomation", true
+	lights := b.allLights()
+	for _, id := range slices.Sorted(maps.Keys(lights)) {
+		g.Lights = append(g.Lights, id)
+		g.Action.On = g.Action.On || lights[id].State.On
+	}
+	return g
+}
+
+func (b *Bridge) getGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{}) // Group 0 is implicit
+}
+
+func (b *Bridge) getGroup(w http.ResponseWriter, r *http.Request) {
+	if id := r.PathValue("id"); id != "0" {
+		writeError(w, errNotAvailable, "/groups/"+id, fmt.Sprintf("resource, /groups/%s, not available", id))
+		return
+	}
+	writeJSON(w, b.group0())
+}
+
+// putAction changes the state of every light in group 0
+func (b *Bridge) putAction(w http.ResponseWriter, r *http.Request) {
+	if id := r.PathValue("id"); id != "0" {
+		writeError(w, errNotAvailable, "/groups/"+id, fmt.Sprintf("resource, /groups/%s, not available", id))
+		return
+	}
+	lights := b.lights()
+	var ds []*lwl.Device
+	for _, id := range slices.Sorted(maps.Keys(lights)) {
+		ds = append(ds, lights[id])
+	}
+	b.change(w, r, "/groups/0/action/", ds)
+}
+
+// change applies a stateChange from the request body to devices, responding
+// with the results for the first
+func (b *Bridge) change(w http.ResponseWriter, r *http.Request, prefix string, ds []*lwl.Device) {
+	address := strings.TrimSuffix(prefix, "/")
+	var ch stateChange
+	if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+		writeError(w, errInvalidJSON, address, "body contains invalid JSON")
+		return
+	}
+	if ch.On == nil && ch.Bri == nil && ch.BriInc == nil {
+		writeError(w, errInvalidParameter, address, "on or bri is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(lwl.WithSource(r.Context(), "hue"), lwl.CommandTimeout*time.Duration(max(1, len(ds))))
+	defer cancel()
+	out := []any{}
+	for i, d := range ds {
+		res, err := b.apply(ctx, d, ch, prefix)
+		if err != nil {
+			slog.Error("Hue command failed", "device", d, "err", err)
+			writeError(w, errInternal, address, err.Error())
+			return
+		}
+		if i == 0 {
+			out = res
+		}
+	}
+	writeJSON(w, out)
+}
+
+// writeJSON writes a response. The Hue API always responds 200 OK, even to
+// errors.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Unable to encode Hue response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, typ int, address, description string) {
+	writeJSON(w, []any{map[string]any{"error": map[string]any{
+		"type":        typ,
+		"address":     address,
+		"description": description,
+	}}})
+}
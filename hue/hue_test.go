@@ -0,0 +1,153 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// fakeHub is a HubClient which records the commands sent to it
+type fakeHub struct {
+	sent []string
+}
+
+func (f *fakeHub) Do(ctx context.Context, cmd lwl.Command) (lwl.Response, error) {
+	f.sent = append(f.sent, cmd.String())
+	return lwl.Response{}, nil
+}
+
+func (f *fakeHub) Subscribe(sid string, chr chan lwl.Response, chs chan string) string {
+	return sid
+}
+func (f *fakeHub) Unsubscribe(sid string)                         {}
+func (f *fakeHub) Events(ctx context.Context) <-chan lwl.Response { return nil }
+func (f *fakeHub) Close() error                                   { return nil }
+
+func TestBridge(t *testing.T) {
+	hub := &fakeHub{}
+	reg := lwl.NewRegistry(hub)
+	if err := reg.SetAlias("R1D2", "kitchen_ceiling"); err != nil {
+		t.Fatal(err)
+	}
+	reg.Device("R1D3") // No alias, so not exposed
+	h := NewBridge(reg).Handler()
+	do := func(method, path, body string) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != 200 {
+			t.Fatalf("%s %s: %d", method, path, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if got := do("POST", "/api", `{"devicetype":"Echo"}`); !strings.Contains(got, `"username":"lightwaverf"`) {
+		t.Errorf("register: %s", got)
+	}
+	var lights map[string]light
+	if err := json.Unmarshal([]byte(do("GET", "/api/lightwaverf/lights", "")), &lights); err != nil {
+		t.Fatal(err)
+	}
+	if len(lights) != 1 || lights["102"].Name != "kitchen ceiling" || lights["102"].State.On {
+		t.Fatalf("got %+v", lights)
+	}
+
+	if got := do("PUT", "/api/lightwaverf/lights/102/state", `{"on":true}`); !strings.Contains(got, `"/lights/102/state/on":true`) {
+		t.Errorf("put: %s", got)
+	}
+	if want := []string{"!R1D2F1"}; !slices.Equal(hub.sent, want) {
+		t.Errorf("sent %q, want %q", hub.sent, want)
+	}
+	if got := do("GET", "/api/lightwaverf/lights/102", ""); !strings.Contains(got, `"on":true`) {
+		t.Errorf("not on: %s", got)
+	}
+
+	if got := do("PUT", "/api/lightwaverf/lights/103/state", `{"on":true}`); !strings.Contains(got, `"type":3`) {
+		t.Errorf("unexposed device: %s", got)
+	}
+	if got := do("GET", "/description.xml", ""); !strings.Contains(got, "<modelName>Philips hue bridge 2015</modelName>") {
+		t.Errorf("description: %s", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	req := "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 3\r\nST: urn:schemas-upnp-org:device:basic:1\r\n\r\n"
+	st, ok := searchTarget(req)
+	if !ok {
+		t.Fatal("search not answered")
+	}
+	if _, ok := searchTarget(strings.Replace(req, "device:basic:1", "device:MediaRenderer:1", 1)); ok {
+		t.Error("answered search for another kind of device")
+	}
+	resp := NewBridge(nil).searchResponse(st, net.IPv4(192, 168, 1, 5), 80)
+	if !strings.Contains(resp, "LOCATION: http://192.168.1.5:80/description.xml\r\n") {
+		t.Errorf("got %q", resp)
+	}
+}
+
+func TestDimmer(t *testing.T) {
+	hub := &fakeHub{}
+	reg := lwl.NewRegistry(hub)
+	reg.SetAlias("R1D2", "kitchen_ceiling")
+	reg.SetAlias("R2D1", "fan")
+	b := NewBridge(reg)
+	if err := b.SetDimmers("kitchen_ceiling"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetDimmers("kitchen_ceiling,nosuch"); err == nil {
+		t.Error("accepted unknown dimmer")
+	}
+	b.SetDimmers("kitchen_ceiling")
+	h := b.Handler()
+	do := func(method, path, body string) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec.Body.String()
+	}
+
+	var l light
+	json.Unmarshal([]byte(do("GET", "/api/u/lights/102", "")), &l)
+	if l.Type != "Dimmable light" || l.State.Bri != briMax {
+		t.Errorf("got %+v", l)
+	}
+	if got := do("PUT", "/api/u/lights/102/state", `{"on":true,"bri":127}`); !strings.Contains(got, `"/lights/102/state/bri":127`) {
+		t.Errorf("put: %s", got)
+	}
+	if got := do("PUT", "/api/u/lights/201/state", `{"bri":127}`); got != "[]\n" {
+		t.Errorf("dimmed a plug: %s", got)
+	}
+	if got := do("PUT", "/api/u/groups/0/action", `{"on":false}`); !strings.Contains(got, `"/groups/0/action/on":false`) {
+		t.Errorf("group: %s", got)
+	}
+	if want := []string{"!R1D2FdP16", "!R1D2F0", "!R2D1F0"}; !slices.Equal(hub.sent, want) {
+		t.Errorf("sent %q, want %q", hub.sent, want)
+	}
+	if got := do("GET", "/api/u/groups/0", ""); !strings.Contains(got, `"lights":["102","201"]`) {
+		t.Errorf("group 0: %s", got)
+	}
+	if got := do("GET", "/api/config", ""); !strings.Contains(got, `"modelid":"BSB002"`) {
+		t.Errorf("config: %s", got)
+	}
+	if got := do("GET", "/api/u/sensors", ""); got != "{}\n" {
+		t.Errorf("sensors: %s", got)
+	}
+}
+
+func TestBriToLevel(t *testing.T) {
+	for bri := briMin; bri <= briMax; bri++ {
+		level := briToLevel(bri)
+		if level < levelMin || level > levelMax {
+			t.Fatalf("briToLevel(%d) = %d", bri, level)
+		}
+	}
+	for level := levelMin; level <= levelMax; level++ {
+		if got := briToLevel(levelToBri(level)); got != level {
+			t.Errorf("level %d round trips to %d", level, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+// Range of Hue brightness, see briToLevel
+const (
+	briMin = 1
+	briMax = 254
+)
+
+// Range of LightwaveRF dim levels, as accepted by lwl.Device.Dim
+const (
+	levelMin = 1
+	levelMax = 32
+)
+
+// briToLevel converts a Hue brightness (1-254) to a dim level (1-32)
+func briToLevel(bri int) int {
+	bri = min(max(bri, briMin), briMax)
+	return max(levelMin, int(math.Round(float64(bri)*levelMax/briMax)))
+}
+
+// levelToBri converts a dim level (1-32) to a Hue brightness (1-254). Unknown
+// levels (0) are taken to be full brightness.
+func levelToBri(level int) int {
+	if level == 0 {
+		return briMax
+	}
+	return min(briMax, int(math.Round(float64(level)*briMax/levelMax)))
+}
+
+// lightID returns the Hue light ID of a device, e.g. "101" for R1D1, which
+// is stable whatever other devices are configured
+func lightID(d *lwl.Device) string {
+	var room, dev int
+	fmt.Sscanf(d.ID(), "R%dD%d", &room, &dev)
+	return strconv.Itoa(room*100 + dev)
+}
+
+// lights returns the devices exposed, by light ID
+func (b *Bridge) lights() map[string]*lwl.Device {
+	out := make(map[string]*lwl.Device)
+	for _, d := range b.reg.Devices() {
+		if d.Name() == d.ID() || !strings.Contains(d.ID(), "D") {
+			continue // No alias, or a heating device
+		}
+		out[lightID(d)] = d
+	}
+	return out
+}
+
+// light is the Hue API representation of a light
+type light struct {
+	State            lightState `json:"state"`
+	Type             string     `json:"type"`
+	Name             string     `json:"name"`
+	ModelID          string     `json:"modelid"`
+	ManufacturerName string     `json:"manufacturername"`
+	ProductName      string     `json:"productname"`
+	UniqueID         string     `json:"uniqueid"`
+	SWVersion        string     `json:"swversion"`
+}
+
+type lightState struct {
+	On        bool   `json:"on"`
+	Bri       int    `json:"bri,omitempty"` // Dimmers only
+	Alert     string `json:"alert"`
+	Mode      string `json:"mode"`
+	Reachable bool   `json:"reachable"`
+}
+
+func (b *Bridge) newLight(id string, d *lwl.Device) light {
+	n, _ := strconv.Atoi(id)
+	st := d.State()
+	l := light{
+		State:            lightState{On: st.On, Alert: "none", Mode: "homeautomation", Reachable: true},
+		Type:             "On/Off plug-in unit",
+		Name:             strings.ReplaceAll(d.Name(), "_", " "),
+		ModelID:          "LOM001",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue Smart plug",
+		UniqueID:         fmt.Sprintf("00:17:88:01:%s:%s:%02x:%02x-0b", strings.ToLower(b.id[12:14]), strings.ToLower(b.id[14:16]), n>>8, n&0xff),
+		SWVersion:        "1.65.11_hB798F2BF",
+	}
+	if b.dimmer(d) {
+		l.State.Bri = levelToBri(st.Level)
+		l.Type, l.ModelID, l.ProductName = "Dimmable light", "LWB010", "Hue white lamp"
+	}
+	return l
+}
+
+// stateChange is a request to change the state of a light (or group)
+type stateChange struct {
+	On             *bool `json:"on"`
+	Bri            *int  `json:"bri"`
+	BriInc         *int  `json:"bri_inc"`        // -254 to 254
+	TransitionTime *int  `json:"transitiontime"` // In tenths of a second
+}
+
+// apply changes the state of a light, returning the Hue API results, keyed by
+// the address of each attribute under prefix (e.g. "/lights/101/state/").
+// Brightness is ignored by lights which are not dimmers. Transitions longer
+// than a command are performed in the background, with lwl.Device.FadeTo.
+func (b *Bridge) apply(ctx context.Context, d *lwl.Device, ch stateChange, prefix string) ([]any, error) {
+	out := []any{}
+	success := func(attr string, v any) {
+		out = append(out, map[string]any{"success": map[string]any{prefix + attr: v}})
+	}
+
+	bri := ch.Bri
+	if ch.BriInc != nil && bri == nil {
+		v := levelToBri(d.State().Level) + *ch.BriInc
+		bri = &v
+	}
+	if !b.dimmer(d) || (ch.On != nil && !*ch.On) {
+		bri = nil
+	}
+
+	switch {
+	case bri != nil:
+		level := briToLevel(*bri)
+		var fade time.Duration
+		if ch.TransitionTime != nil {
+			fade = time.Duration(*ch.TransitionTime) * 100 * time.Millisecond
+		}
+		if fade > lwl.CommandTimeout {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fade+lwl.CommandTimeout)
+			panics.Go("hue", func() {
+				defer cancel()
+				if err := d.FadeTo(ctx, level, fade); err != nil {
+					slog.Error("Hue transition failed", "device", d, "level", level, "err", err)
+				}
+			})
+		} else if err := d.Dim(ctx, level); err != nil {
+			return out, err
+		}
+		if ch.On != nil {
+			success("on", true)
+		}
+		success("bri", levelToBri(level))
+	case ch.On != nil && *ch.On:
+		if err := d.On(ctx); err != nil {
+			return out, err
+		}
+		success("on", true)
+	case ch.On != nil:
+		if err := d.Off(ctx); err != nil {
+			return out, err
+		}
+		success("on", false)
+	}
+	return out, nil
+}
+
+// SetDimmers parses a list of devices which are dimmers, e.g.
+// "kitchen_ceiling,R1D2", and exposes them as dimmable lights. Other devices
+// are exposed as on/off plugs.
+func (b *Bridge) SetDimmers(s string) error {
+	dimmers := make(map[string]bool)
+	var errs []error
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		d, err := b.reg.Resolve(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dimmers[d.ID()] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dimmers = dimmers
+	return errors.Join(errs...)
+}
+
+// dimmer reports whether a device is a dimmer, see SetDimmers
+func (b *Bridge) dimmer(d *lwl.Device) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dimmers[d.ID()]
+}
@@ -0,0 +1,100 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// SSDP multicast group, on which Echo devices search for Hue bridges
+var ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// Search targets answered, see ServeSSDP
+var searchTargets = []string{"ssdp:all", "upnp:rootdevice", "urn:schemas-upnp-org:device:basic:1"}
+
+// ServeSSDP answers SSDP searches for Hue bridges, until the context is
+// done, directing them to the bridge's Handler on the given port of this
+// host. The address advertised is that of the interface the search arrived
+// on.
+func (b *Bridge) ServeSSDP(ctx context.Context, port int) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, ssdpAddr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		st, ok := searchTarget(string(buf[:n]))
+		if !ok {
+			continue
+		}
+		ip, err := localIP(from)
+		if err != nil {
+			slog.Debug("Unable to answer SSDP search", "from", from, "err", err)
+			continue
+		}
+		if _, err := conn.WriteToUDP([]byte(b.searchResponse(st, ip, port)), from); err != nil {
+			slog.Debug("Unable to answer SSDP search", "from", from, "err", err)
+			continue
+		}
+		slog.Debug("Answered SSDP search", "from", from, "st", st)
+	}
+}
+
+// searchTarget returns the search target (ST) of an M-SEARCH request, if it
+// is one the bridge answers
+func searchTarget(req string) (string, bool) {
+	lines := strings.Split(req, "\r\n")
+	if !strings.HasPrefix(lines[0], "M-SEARCH ") {
+		return "", false
+	}
+	for _, line := range lines[1:] {
+		name, value, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(strings.TrimSpace(name), "ST") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		for _, st := range searchTargets {
+			if strings.EqualFold(value, st) {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// searchResponse is the reply to an M-SEARCH request
+func (b *Bridge) searchResponse(st string, ip net.IP, port int) string {
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+		"CACHE-CONTROL: max-age=100\r\n"+
+		"EXT:\r\n"+
+		"LOCATION: http://%s/description.xml\r\n"+
+		"SERVER: Linux/3.14.0 UPnP/1.0 IpBridge/1.17.0\r\n"+
+		"hue-bridgeid: %s\r\n"+
+		"ST: %s\r\n"+
+		"USN: %s::%s\r\n"+
+		"\r\n", net.JoinHostPort(ip.String(), fmt.Sprint(port)), b.id, st, b.udn(), st)
+}
+
+// localIP returns the address of the interface used to reach addr
+func localIP(addr *net.UDPAddr) (net.IP, error) {
+	conn, err := net.DialUDP("udp4", nil, addr) // Sends nothing
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,249 @@
+// Package ics reads the events of iCalendar (RFC 5545) files, such as the
+// bank holidays published by gov.uk or a family calendar's feed. Only what
+// schedules need is understood: each event's summary, start and end.
+// Recurring events are expanded into their occurrences, for daily and weekly
+// rules only, and those without an end only up to a year ahead. Other rules
+// are errors. Changes to single occurrences (RECURRENCE-ID) are not
+// understood.
+package ics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How far ahead recurring events without an end are expanded
+const horizon = 366 * 24 * time.Hour
+
+// Most occurrences a recurring event is expanded into
+const maxOccurrences = 5000
+
+// Event is a VEVENT
+type Event struct {
+	Summary string
+	Start   time.Time
+	End     time.Time // Exclusive
+	AllDay  bool      // Start and End are midnights, local time
+}
+
+// Days returns the dates the event covers, e.g. "2026-12-25", in local time
+func (e Event) Days() []string {
+	var out []string
+	start := e.Start.Local()
+	y, m, d := start.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, time.Local); day.Before(e.End) || day.Equal(start); day = day.AddDate(0, 0, 1) {
+		out = append(out, day.Format(time.DateOnly))
+	}
+	return out
+}
+
+// Contains reports whether t is during the event
+func (e Event) Contains(t time.Time) bool {
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// Load reads the events of an iCalendar file
+func Load(fn string) ([]Event, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	es, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return es, nil
+}
+
+// Fetch reads the events of a feed by URL (http, https or webcal), or of a
+// file by name
+func Fetch(ctx context.Context, src string) ([]Event, error) {
+	if after, ok := strings.CutPrefix(src, "webcal://"); ok {
+		src = "https://" + after
+	}
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return Load(src)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return Parse(resp.Body)
+}
+
+// Parse reads the events of an iCalendar stream
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out      []Event
+		ev       *Event
+		duration time.Duration
+		rule     string // RRULE, if any
+		ruleLine int
+		except   []time.Time // EXDATE
+		errs     []error
+	)
+	until := time.Now().Add(horizon)
+	for i, line := range lines {
+		name, params, value := splitLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			ev, duration, rule, except = &Event{}, 0, "", nil
+		case ev == nil:
+			// Outside an event, e.g. VTIMEZONE
+		case name == "END" && value == "VEVENT":
+			switch {
+			case ev.Start.IsZero():
+				errs = append(errs, fmt.Errorf("line %d: event %q has no DTSTART", i+1, ev.Summary))
+			case !ev.End.IsZero():
+			case duration > 0:
+				ev.End = ev.Start.Add(duration)
+			case ev.AllDay:
+				ev.End = ev.Start.AddDate(0, 0, 1)
+			default:
+				ev.End = ev.Start
+			}
+			switch {
+			case ev.Start.IsZero():
+			case rule != "":
+				es, err := expand(*ev, rule, except, until)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("line %d: event %q: RRULE: %w", ruleLine, ev.Summary, err))
+				}
+				out = append(out, es...)
+			default:
+				out = append(out, *ev)
+			}
+			ev = nil
+		case name == "SUMMARY":
+			ev.Summary = unescape(value)
+		case name == "DTSTART", name == "DTEND":
+			t, allDay, err := parseTime(params, value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %s: %w", i+1, name, err))
+				continue
+			}
+			if name == "DTSTART" {
+				ev.Start, ev.AllDay = t, allDay
+			} else {
+				ev.End = t
+			}
+		case name == "DURATION":
+			d, err := parseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %s: %w", i+1, name, err))
+				continue
+			}
+			duration = d
+		case name == "RRULE":
+			rule, ruleLine = value, i+1
+		case name == "EXDATE":
+			for v := range strings.SplitSeq(value, ",") {
+				t, _, err := parseTime(params, v)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("line %d: %s: %w", i+1, name, err))
+					continue
+				}
+				except = append(except, t)
+			}
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// unfold reads content lines, joining those continued on the next line,
+// which begin with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// splitLine splits a content line, e.g. "DTSTART;TZID=Europe/London:
+// 20261225T090000", into its name, parameters and value. Quoted parameter
+// values are not supported.
+func splitLine(line string) (name string, params map[string]string, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params = make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = v
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseTime parses a DATE or DATE-TIME value, in UTC (with a Z suffix), the
+// zone given by a TZID parameter, or else local time
+func parseTime(params map[string]string, value string) (t time.Time, allDay bool, err error) {
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == len("20060102"):
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	return t, false, err
+}
+
+var durationRE = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses a DURATION value, e.g. "PT1H30M" or "P1D"
+func parseDuration(value string) (time.Duration, error) {
+	m := durationRE.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(m[i+1])
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// unescape decodes a TEXT value
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package ics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261225\r\n" +
+	"DTEND;VALUE=DATE:20261227\r\n" +
+	"SUMMARY:Christmas Day\\, and Boxing\r\n" +
+	"  Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20261230T220000Z\r\n" +
+	"DURATION:PT4H\r\n" +
+	"SUMMARY:Away\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/London:20260801T090000\r\n" +
+	"SUMMARY:Reminder\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	es, err := Parse(strings.NewReader(testICS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 {
+		t.Fatalf("got %d events, want 3", len(es))
+	}
+
+	xmas := es[0]
+	if xmas.Summary != "Christmas Day, and Boxing Day" || !xmas.AllDay {
+		t.Errorf("got %+v", xmas)
+	}
+	if got, want := xmas.Days(), []string{"2026-12-25", "2026-12-26"}; !slices.Equal(got, want) {
+		t.Errorf("Days() = %v, want %v", got, want)
+	}
+
+	away := es[1]
+	if want := time.Date(2026, 12, 31, 2, 0, 0, 0, time.UTC); !away.End.Equal(want) || away.AllDay {
+		t.Errorf("got %+v, want end %v", away, want)
+	}
+	if !away.Contains(time.Date(2026, 12, 31, 1, 0, 0, 0, time.UTC)) || away.Contains(away.End) {
+		t.Errorf("Contains is wrong for %+v", away)
+	}
+
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	if want := time.Date(2026, 8, 1, 9, 0, 0, 0, london); !es[2].Start.Equal(want) || !es[2].End.Equal(want) {
+		t.Errorf("got %+v, want start and end %v", es[2], want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	es, err := Parse(strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nBEGIN:VEVENT\nDTSTART:20260101\nDURATION:P\nEND:VEVENT\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "invalid duration") {
+		t.Errorf("got %v", err)
+	}
+	if len(es) != 1 {
+		t.Errorf("got %d events, want the valid one", len(es))
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/family.ics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testICS))
+	}))
+	defer srv.Close()
+
+	es, err := Fetch(t.Context(), srv.URL+"/family.ics")
+	if err != nil || len(es) != 3 {
+		t.Errorf("got %d events, %v", len(es), err)
+	}
+	if _, err := Fetch(t.Context(), srv.URL+"/work.ics"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got %v, want 404", err)
+	}
+}
+
+func TestRecurring(t *testing.T) {
+	const head = "BEGIN:VEVENT\nSUMMARY:Away\nDTSTART:20261005T080000Z\nDTEND:20261005T170000Z\n"
+	starts := func(es []Event) []string {
+		var out []string
+		for _, e := range es {
+			out = append(out, e.Start.UTC().Format("Mon 2006-01-02T15"))
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		rule string
+		want []string
+	}{
+		{"RRULE:FREQ=DAILY;COUNT=3", []string{"Mon 2026-10-05T08", "Tue 2026-10-06T08", "Wed 2026-10-07T08"}},
+		{"RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20261009T080000Z", []string{"Mon 2026-10-05T08", "Wed 2026-10-07T08", "Fri 2026-10-09T08"}},
+		{"RRULE:FREQ=WEEKLY;BYDAY=FR,MO;UNTIL=20261012", []string{"Mon 2026-10-05T08", "Fri 2026-10-09T08", "Mon 2026-10-12T08"}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=3\nEXDATE:20261019T080000Z", []string{"Mon 2026-10-05T08", "Mon 2026-11-02T08"}},
+	} {
+		es, err := Parse(strings.NewReader(head + tc.rule + "\nEND:VEVENT\n"))
+		if err != nil {
+			t.Errorf("%s: %v", tc.rule, err)
+		}
+		if got := starts(es); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.rule, got, tc.want)
+		}
+		for _, e := range es {
+			if e.End.Sub(e.Start) != 9*time.Hour || e.Summary != "Away" {
+				t.Errorf("%s: got %+v", tc.rule, e)
+			}
+		}
+	}
+
+	// Without an end, occurrences stop a year or so ahead
+	es, err := Parse(strings.NewReader(head + "RRULE:FREQ=WEEKLY\nEND:VEVENT\n"))
+	if last := es[len(es)-1].Start; err != nil || last.After(time.Now().Add(horizon)) || last.Before(time.Now().AddDate(0, 11, 0)) {
+		t.Errorf("got %d events, last %v, %v", len(es), last, err)
+	}
+
+	for _, rule := range []string{"FREQ=MONTHLY", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=DAILY;BYHOUR=9", "FREQ=DAILY;COUNT=2;UNTIL=20261009"} {
+		es, err := Parse(strings.NewReader(head + "RRULE:" + rule + "\nEND:VEVENT\n"))
+		if err == nil || !strings.Contains(err.Error(), "line 5") || len(es) != 0 {
+			t.Errorf("%s: got %d events, %v; want an error", rule, len(es), err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Weekdays by their RRULE abbreviation
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// expand returns the occurrences of an event with a recurrence rule, e.g.
+// "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20261231", other than those in except, and
+// ending with the last before limit if the rule has neither COUNT nor UNTIL.
+// Only DAILY and WEEKLY rules, optionally with INTERVAL, COUNT, UNTIL and
+// (weekly) BYDAY, are understood.
+func expand(ev Event, rule string, except []time.Time, limit time.Time) ([]Event, error) {
+	var (
+		freq     string
+		interval = 1
+		count    int
+		until    time.Time
+		days     []int // Of the week, counting from Monday
+	)
+	for part := range strings.SplitSeq(rule, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			freq = strings.ToUpper(v)
+		case "INTERVAL":
+			interval, err = strconv.Atoi(v)
+			if err == nil && interval < 1 {
+				err = fmt.Errorf("invalid INTERVAL %q", v)
+			}
+		case "COUNT":
+			count, err = strconv.Atoi(v)
+			if err == nil && count < 1 {
+				err = fmt.Errorf("invalid COUNT %q", v)
+			}
+		case "UNTIL":
+			var allDay bool
+			until, allDay, err = parseTime(nil, v)
+			if allDay {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond) // Inclusive
+			}
+		case "BYDAY":
+			for d := range strings.SplitSeq(strings.ToUpper(v), ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					err = fmt.Errorf("unsupported BYDAY %q", d)
+					break
+				}
+				days = append(days, (int(wd)+6)%7)
+			}
+		case "WKST":
+			// Only matters for BYDAY with an INTERVAL, and is rarely not MO
+		default:
+			err = fmt.Errorf("unsupported %s", k)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case freq != "DAILY" && freq != "WEEKLY":
+		return nil, fmt.Errorf("unsupported FREQ %q", freq)
+	case freq == "DAILY" && days != nil:
+		return nil, errors.New("unsupported BYDAY with FREQ=DAILY")
+	case count > 0 && !until.IsZero():
+		return nil, errors.New("both COUNT and UNTIL")
+	case count > 0 || !until.IsZero():
+		limit = time.Time{}
+	}
+
+	// Occurrences start on the same (local) time of day as the first, so
+	// that they follow changes to and from summer time
+	weekday := (int(ev.Start.Weekday()) + 6) % 7
+	if days == nil {
+		days = []int{weekday}
+	}
+	slices.Sort(days)
+	days = slices.Compact(days)
+	monday := ev.Start.AddDate(0, 0, -weekday)
+	step := 7 * interval
+	if freq == "DAILY" {
+		monday, days, step = ev.Start, []int{0}, interval
+	}
+	duration := ev.End.Sub(ev.Start)
+	nights := int(duration.Round(24*time.Hour) / (24 * time.Hour)) // For all-day events
+
+	var out []Event
+	for n, period := 0, 0; ; period++ {
+		for _, d := range days {
+			start := monday.AddDate(0, 0, period*step+d)
+			switch {
+			case start.Before(ev.Start):
+				continue
+			case count > 0 && n == count,
+				!until.IsZero() && start.After(until),
+				!limit.IsZero() && start.After(limit):
+				return out, nil
+			case len(out) == maxOccurrences:
+				return out, fmt.Errorf("more than %d occurrences", maxOccurrences)
+			}
+			n++
+			if slices.ContainsFunc(except, start.Equal) {
+				continue
+			}
+			e := ev
+			e.Start, e.End = start, start.Add(duration)
+			if e.AllDay {
+				e.End = start.AddDate(0, 0, nights)
+			}
+			out = append(out, e)
+		}
+	}
+}
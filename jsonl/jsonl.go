@@ -0,0 +1,126 @@
+// Package jsonl persists records as JSON lines, one per line, in files which
+// are appended to and occasionally rewritten
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// File is a file of JSON lines, safe for concurrent use within a process
+type File struct {
+	mu sync.Mutex
+	fn string
+}
+
+// New returns a File backed by the named file, which need not exist yet
+func New(fn string) *File {
+	return &File{fn: fn}
+}
+
+// Name returns the name of the backing file
+func (f *File) Name() string {
+	return f.fn
+}
+
+// Append writes each value as a line at the end of the file, creating it if
+// needed
+func (f *File) Append(vs ...any) error {
+	buf, err := marshal(vs)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fh, err := os.OpenFile(f.fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fh.Write(buf); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// Each calls fn with every line in the file, oldest first, stopping at the
+// first error. A missing file is treated as empty.
+func (f *File) Each(fn func(line []byte) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.each(fn)
+}
+
+// each is Each, for callers which already hold mu
+func (f *File) each(fn func(line []byte) error) error {
+	fh, err := os.Open(f.fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	s := bufio.NewScanner(fh)
+	for s.Scan() {
+		if err := fn(s.Bytes()); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Rewrite replaces the file with the values which fn passes to emit. fn may
+// read the existing lines with each meanwhile, as with Each. The new file is
+// written alongside and then renamed over the old one, which is left
+// untouched if fn fails. Appends wait until Rewrite is done.
+func (f *File) Rewrite(fn func(each func(func(line []byte) error) error, emit func(v any) error) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp := f.fn + ".tmp"
+	fh, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fh)
+	emit := func(v any) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	err = fn(f.each, emit)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f.fn)
+}
+
+// marshal encodes values as JSON lines
+func marshal(vs []any) ([]byte, error) {
+	var buf []byte
+	for _, v := range vs {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(buf, b...), '\n')
+	}
+	return buf, nil
+}
@@ -0,0 +1,78 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "test.jsonl"))
+	read := func() []int {
+		var out []int
+		err := f.Each(func(line []byte) error {
+			var v int
+			if err := json.Unmarshal(line, &v); err != nil {
+				return err
+			}
+			out = append(out, v)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if got := read(); got != nil {
+		t.Fatalf("missing file: want empty got %v", got)
+	}
+	if err := f.Append(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(3); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("want [1 2 3] got %v", got)
+	}
+
+	// Keep the odd values
+	err := f.Rewrite(func(each func(func([]byte) error) error, emit func(any) error) error {
+		return each(func(line []byte) error {
+			var v int
+			if err := json.Unmarshal(line, &v); err != nil {
+				return err
+			}
+			if v%2 == 1 {
+				return emit(v)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); !slices.Equal(got, []int{1, 3}) {
+		t.Fatalf("after Rewrite: want [1 3] got %v", got)
+	}
+
+	// A failed rewrite leaves the file alone
+	boom := errors.New("boom")
+	err = f.Rewrite(func(each func(func([]byte) error) error, emit func(any) error) error {
+		emit(5)
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("want boom got %v", err)
+	}
+	if got := read(); !slices.Equal(got, []int{1, 3}) {
+		t.Fatalf("after failed Rewrite: want [1 3] got %v", got)
+	}
+	if _, err := os.Stat(f.Name() + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
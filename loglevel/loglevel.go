@@ -0,0 +1,91 @@
+// Package loglevel lets the daemon's log level be raised and lowered while it
+// runs, e.g. to capture debug messages from a misbehaving daemon without
+// restarting it and losing the fault.
+package loglevel
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+// Control is a slog.Leveler whose level may be changed, for a while or until
+// changed again
+type Control struct {
+	mu    sync.Mutex
+	level slog.LevelVar
+	base  slog.Level  // Restored when a temporary level expires
+	until time.Time   // When the temporary level expires, or zero
+	timer *time.Timer // Restores base at until
+}
+
+// New returns a Control at the given level
+func New(level slog.Level) *Control {
+	c := &Control{base: level}
+	c.level.Set(level)
+	return c
+}
+
+// Level implements slog.Leveler
+func (c *Control) Level() slog.Level {
+	return c.level.Level()
+}
+
+// Set changes the level. If d is positive, the level reverts after d to
+// what it was before any temporary change, otherwise the change is
+// permanent.
+func (c *Control) Set(level slog.Level, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.level.Set(level)
+	if d <= 0 {
+		c.base, c.until = level, time.Time{}
+		slog.Info("Log level changed", "level", level)
+		return
+	}
+	until := time.Now().Add(d)
+	c.until = until
+	c.timer = time.AfterFunc(d, panics.Func("loglevel", func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.until.Equal(until) { // Else superseded by another Set
+			c.restore()
+		}
+	}))
+	slog.Info("Log level changed", "level", level, "for", d, "then", c.base)
+}
+
+// Restore ends a temporary change of level, see Set
+func (c *Control) Restore() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restore()
+}
+
+// restore implements Restore, with mu held
+func (c *Control) restore() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.until.IsZero() {
+		return
+	}
+	c.level.Set(c.base)
+	c.until = time.Time{}
+	slog.Info("Log level restored", "level", c.base)
+}
+
+// Temporary returns when a temporary level expires, see Set, or false if
+// the level is not temporary
+func (c *Control) Temporary() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.until, !c.until.IsZero()
+}
@@ -0,0 +1,32 @@
+package loglevel
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestControl(t *testing.T) {
+	c := New(slog.LevelInfo)
+	c.Set(slog.LevelDebug, time.Hour)
+	if c.Level() != slog.LevelDebug {
+		t.Fatalf("got %v", c.Level())
+	}
+	if _, ok := c.Temporary(); !ok {
+		t.Error("want temporary")
+	}
+	c.Restore()
+	if c.Level() != slog.LevelInfo {
+		t.Errorf("not restored: %v", c.Level())
+	}
+
+	c.Set(slog.LevelWarn, 0)
+	c.Set(slog.LevelDebug, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if c.Level() != slog.LevelWarn {
+		t.Errorf("did not revert to permanent level: %v", c.Level())
+	}
+	if _, ok := c.Temporary(); ok {
+		t.Error("still temporary")
+	}
+}
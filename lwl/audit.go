@@ -0,0 +1,67 @@
+package lwl
+
+import (
+	"context"
+	"time"
+)
+
+// CommandRecord describes a command performed with Client.Do, for auditing
+type CommandRecord struct {
+	Time    time.Time     `json:"time"`            // When the command was sent
+	Source  string        `json:"source"`          // What issued the command, see WithSource
+	Command string        `json:"command"`         // As transmitted, e.g. "!R1D1F1"
+	Result  string        `json:"result"`          // "ok", "err", "timeout", "dry-run" or "held-off"
+	Error   string        `json:"error,omitempty"` // Reason for failure, if any
+	Latency time.Duration `json:"latency"`         // Time taken to get a response, or give up
+}
+
+// Auditor receives a record of every command performed with Client.Do
+type Auditor interface {
+	Audit(CommandRecord)
+}
+
+// SetAuditor sends a record of every command performed to a. Use nil to stop.
+func (c *Client) SetAuditor(a Auditor) {
+	if a == nil {
+		c.auditor.Store(nil)
+		return
+	}
+	c.auditor.Store(&a)
+}
+
+func (c *Client) audit(ctx context.Context, cmd Command, outcome commandOutcome, latency time.Duration, err error) {
+	a := c.auditor.Load()
+	if a == nil {
+		return
+	}
+	rec := CommandRecord{
+		Time:    time.Now().Add(-latency),
+		Source:  Source(ctx),
+		Command: cmd.String(),
+		Result:  outcome.String(),
+		Latency: latency,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	(*a).Audit(rec)
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context which attributes commands performed with it to
+// the given source, e.g. "cli" or "heating". Sources acting for someone or
+// something in particular name it after a colon, e.g. "rule:dusk" or
+// "http:home-assistant", so that conflicts between automations can be traced
+// in the audit log, the log and the statistics.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// Source returns the source attributed by WithSource, or "unknown"
+func Source(ctx context.Context) string {
+	if s, ok := ctx.Value(sourceKey{}).(string); ok {
+		return s
+	}
+	return "unknown"
+}
@@ -0,0 +1,150 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+// SetAutoOff makes the device switch itself off the given time after it is
+// switched on or dimmed, e.g. for a bathroom fan. Switching it off in the
+// meantime, by any means the LWL sees (see Registry.Watch), cancels this.
+// Zero disables auto-off.
+func (d *Device) SetAutoOff(after time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.autoOff = after
+	if after == 0 {
+		d.cancelAutoOff()
+	}
+}
+
+// AutoOff returns the delay set by SetAutoOff
+func (d *Device) AutoOff() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.autoOff
+}
+
+// armAutoOff (re)starts the auto-off timer, if enabled. d.mu must be held.
+func (d *Device) armAutoOff() {
+	d.cancelAutoOff()
+	if d.autoOff == 0 {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d.autoOff, panics.Func("auto-off", func() {
+		d.mu.Lock()
+		if d.offTimer != t {
+			d.mu.Unlock()
+			return // Superseded
+		}
+		d.offTimer = nil
+		d.mu.Unlock()
+
+		slog.Info("Auto-off", "device", d)
+		if err := d.Off(WithSource(context.Background(), "auto-off")); err != nil {
+			slog.Error("Auto-off failed", "device", d, "err", err)
+		}
+	}))
+	d.offTimer = t
+}
+
+// cancelAutoOff stops any pending auto-off. d.mu must be held.
+func (d *Device) cancelAutoOff() {
+	if d.offTimer != nil {
+		d.offTimer.Stop()
+		d.offTimer = nil
+	}
+}
+
+// observe updates the assumed state of the device from a 433T message, i.e.
+// a command sent by the LWL whether or not we asked for it, confirming it
+func (d *Device) observe(fn string, param int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch fn {
+	case "on":
+		if !d.on {
+			d.on = true
+			d.armAutoOff()
+		}
+	case "dim":
+		d.on = true
+		if param >= dimMin && param <= dimMax {
+			d.level = param
+		}
+		if d.offTimer == nil {
+			d.armAutoOff()
+		}
+	case "off", "allOff":
+		d.on = false
+		d.cancelAutoOff()
+	default:
+		return
+	}
+	d.setReported()
+}
+
+// Watch follows the commands the LWL transmits, including those sent by
+// other apps and hosts, keeping the assumed state of each device up to date
+// until the context is done. In particular, a device switched off elsewhere
+// no longer has an auto-off pending. Room reads are followed too, to learn
+// which room each heating device is paired to, see Slot.
+func (r *Registry) Watch(ctx context.Context) {
+	msgs := r.c.Events(ctx)
+	for m := range msgs {
+		if m.Pkt == "room" && m.Fn == "read" {
+			r.learnSlot(m)
+			continue
+		}
+		if m.Pkt != "433T" || m.Room == 0 {
+			continue
+		}
+		if m.Fn == "allOff" {
+			room := fmt.Sprintf("R%d", m.Room)
+			for _, d := range r.Devices() {
+				if d.Room() == room {
+					d.observe(m.Fn, 0)
+				}
+			}
+			continue
+		}
+		if m.Dev != 0 {
+			r.Device(fmt.Sprintf("R%dD%d", m.Room, m.Dev)).observe(m.Fn, m.Param)
+		}
+	}
+}
+
+// ApplyAutoOff parses a list of device=duration pairs, e.g.
+// "bathroom_fan=10m,R2D1=1h", and sets the auto-off of each device
+func (r *Registry) ApplyAutoOff(s string) error {
+	if s == "" {
+		return nil
+	}
+	var errs []error
+	for item := range strings.SplitSeq(s, ",") {
+		name, after, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			errs = append(errs, fmt.Errorf("auto-off should look like bathroom_fan=10m, got %q", item))
+			continue
+		}
+		d, err := r.Resolve(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dur, err := time.ParseDuration(after)
+		if err != nil || dur <= 0 {
+			errs = append(errs, fmt.Errorf("invalid auto-off for %s: %q", name, after))
+			continue
+		}
+		d.SetAutoOff(dur)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package lwl
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAutoOff(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	c.SetDryRun(true)
+	reg := NewRegistry(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reg.Watch(ctx)
+	for c.Subscriptions() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	d := reg.Device("R1D1")
+	d.SetAutoOff(50 * time.Millisecond)
+	if err := d.On(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if d.State().On {
+		t.Fatal("not switched off automatically")
+	}
+
+	// Switched off elsewhere, then on again by another app, which must arm
+	// the timer afresh rather than leave the original pending
+	if err := d.On(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.dispatch(Response{Trans: 1, Pkt: "433T", Fn: "off", Room: 1, Dev: 1})
+	time.Sleep(10 * time.Millisecond)
+	d.mu.Lock()
+	pending := d.offTimer != nil
+	d.mu.Unlock()
+	if pending || d.State().On {
+		t.Fatal("auto-off still pending after switched off elsewhere")
+	}
+	c.dispatch(Response{Trans: 2, Pkt: "433T", Fn: "on", Room: 1, Dev: 1})
+	time.Sleep(100 * time.Millisecond)
+	if d.State().On {
+		t.Fatal("not switched off automatically after switched on elsewhere")
+	}
+}
+
+func TestApplyAutoOff(t *testing.T) {
+	reg := NewRegistry(&Client{})
+	if err := reg.SetAlias("R2D1", "bathroom_fan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.ApplyAutoOff("bathroom_fan=10m, R3D1=1h"); err != nil {
+		t.Fatal(err)
+	}
+	if got := reg.Device("R2D1").AutoOff(); got != 10*time.Minute {
+		t.Errorf("bathroom_fan: want 10m got %v", got)
+	}
+	if got := reg.Device("R3D1").AutoOff(); got != time.Hour {
+		t.Errorf("R3D1: want 1h got %v", got)
+	}
+	for _, bad := range []string{"R1D1", "R1D1=soon", "R1D1=-1m", "nonesuch=1m"} {
+		if err := reg.ApplyAutoOff(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package lwl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ArgType is the kind of value a named command takes as an argument
+type ArgType int
+
+const (
+	ArgDevice ArgType = iota // Room+Device identifier, e.g. "R1D1"
+	ArgRoom                  // Room identifier, e.g. "R1"
+	ArgInt                   // Integer within Arg.Min-Arg.Max
+	ArgFloat                 // Number within Arg.Min-Arg.Max
+	ArgTemp                  // Celsius, within TempMin-TempMax
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case ArgDevice:
+		return "device"
+	case ArgRoom:
+		return "room"
+	case ArgInt:
+		return "int"
+	case ArgFloat:
+		return "float"
+	case ArgTemp:
+		return "temp"
+	default:
+		return fmt.Sprintf("ArgType(%d)", int(t))
+	}
+}
+
+// UnmarshalText parses the name of an ArgType, e.g. "device"
+func (t *ArgType) UnmarshalText(b []byte) error {
+	for _, v := range []ArgType{ArgDevice, ArgRoom, ArgInt, ArgFloat, ArgTemp} {
+		if v.String() == string(b) {
+			*t = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown argument type %q, want device, room, int, float or temp", b)
+}
+
+// Arg describes an argument of a named command
+type Arg struct {
+	Name string  `yaml:"name"`
+	Type ArgType `yaml:"type"`
+	Min  float64 `yaml:"min"` // Of ArgInt and ArgFloat, inclusive
+	Max  float64 `yaml:"max"`
+}
+
+// example returns a valid value of the argument
+func (a Arg) example() any {
+	switch a.Type {
+	case ArgDevice:
+		return "R1D1"
+	case ArgRoom:
+		return "R1"
+	case ArgInt:
+		return int(a.Min)
+	case ArgTemp:
+		return FormatTemp(TempMin)
+	default:
+		return a.Min
+	}
+}
+
+// parse converts s to the value passed to Command.New
+func (a Arg) parse(s string) (any, error) {
+	switch a.Type {
+	case ArgDevice, ArgRoom:
+		if !ValidID(s) || strings.Contains(s, "D") != (a.Type == ArgDevice) {
+			return nil, fmt.Errorf("%s: want a %s identifier, e.g. R1 or R1D1, got %q", a.Name, a.Type, s)
+		}
+		return s, nil
+	case ArgInt:
+		n, err := strconv.Atoi(s)
+		if err != nil || float64(n) < a.Min || float64(n) > a.Max {
+			return nil, fmt.Errorf("%s: want an integer %v-%v, got %q", a.Name, a.Min, a.Max, s)
+		}
+		return n, nil
+	case ArgFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < a.Min || f > a.Max {
+			return nil, fmt.Errorf("%s: want a number %v-%v, got %q", a.Name, a.Min, a.Max, s)
+		}
+		return f, nil
+	case ArgTemp:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < TempMin || f > TempMax {
+			return nil, fmt.Errorf("%s: want a temperature %v-%v, got %q", a.Name, TempMin, TempMax, s)
+		}
+		return FormatTemp(f), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown argument type %v", a.Name, a.Type)
+	}
+}
+
+// CommandInfo describes a Command known by a stable name, so that callers
+// such as CLIs and HTTP handlers can choose commands at run time
+type CommandInfo struct {
+	Name        string // e.g. "dim"
+	Description string
+	Args        []Arg
+	Command     *Command // Template, see Build
+}
+
+// Usage returns the name and arguments, e.g. "dim <device> <level>"
+func (ci CommandInfo) Usage() string {
+	out := []string{ci.Name}
+	for _, a := range ci.Args {
+		out = append(out, "<"+a.Name+">")
+	}
+	return strings.Join(out, " ")
+}
+
+// Build checks and converts args, returning a Command ready to send
+func (ci CommandInfo) Build(args ...string) (*Command, error) {
+	if len(args) != len(ci.Args) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d: usage: %s", ci.Name, len(ci.Args), len(args), ci.Usage())
+	}
+	opts := make([]any, len(args))
+	for i, a := range ci.Args {
+		v, err := a.parse(args[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ci.Name, err)
+		}
+		opts[i] = v
+	}
+	return ci.Command.New(opts...), nil
+}
+
+var (
+	argDevice = Arg{Name: "device", Type: ArgDevice}
+	argRoom   = Arg{Name: "room", Type: ArgRoom}
+)
+
+// Guards catalog, which RegisterCommand may add to
+var catalogMu sync.RWMutex
+
+// catalog holds every named command, ordered by name
+var catalog = []CommandInfo{
+	{Name: "all_off", Description: "Turn off every device in a room", Args: []Arg{argRoom}, Command: &CmdAllOff},
+	{Name: "close", Description: "Close a relay", Args: []Arg{argDevice}, Command: &CmdClose},
+	{Name: "colour", Description: "Set the colour of an LED colour changing product", Args: []Arg{argDevice, {Name: "colour", Type: ArgInt, Min: 1, Max: 20}}, Command: &CmdLEDColourSet},
+	{Name: "colour_cycle", Description: "Move a colour changing product to its next cycling mode", Args: []Arg{argDevice}, Command: &CmdLEDColourCycle},
+	{Name: "deregister", Description: "Unpair this host from the LightwaveLink", Command: &CmdDeregister},
+	{Name: "dim", Description: "Set the brightness of a dimmer", Args: []Arg{argDevice, {Name: "level", Type: ArgInt, Min: 1, Max: 32}}, Command: &CmdSetDimmer},
+	{Name: "dusk_dawn", Description: "Report the dusk and dawn times used by timers", Command: &CmdHubDuskDawn},
+	{Name: "heating_status", Description: "Ask a heating device to report its status", Args: []Arg{argRoom}, Command: &CmdHeatingStatus},
+	{Name: "hub_info", Description: "Report the LightwaveLink's firmware, uptime and settings", Command: &CmdHubCall},
+	{Name: "hub_ui_bright", Description: "Turn on the LightwaveLink's LED, or brighten its screen", Command: &CmdSetHubUIBright},
+	{Name: "hub_ui_dim", Description: "Turn off the LightwaveLink's LED, or dim its screen", Command: &CmdSetHubUIDim},
+	{Name: "lock_full", Description: "Prevent a device from being switched manually or by RF", Args: []Arg{argDevice}, Command: &CmdLockFull},
+	{Name: "lock_partial", Description: "Prevent a device from being switched manually", Args: []Arg{argDevice}, Command: &CmdLockPartial},
+	{Name: "mood_recall", Description: "Restore the devices in a room to a stored mood", Args: []Arg{argRoom, {Name: "mood", Type: ArgInt, Min: 1, Max: 5}}, Command: &CmdMoodRecall},
+	{Name: "mood_store", Description: "Store the state of the devices in a room as a mood", Args: []Arg{argRoom, {Name: "mood", Type: ArgInt, Min: 1, Max: 5}}, Command: &CmdMoodStore},
+	{Name: "off", Description: "Turn off a device", Args: []Arg{argDevice}, Command: &CmdOff},
+	{Name: "on", Description: "Turn on a device", Args: []Arg{argDevice}, Command: &CmdOn},
+	{Name: "open", Description: "Open a relay", Args: []Arg{argDevice}, Command: &CmdOpen},
+	{Name: "pair", Description: "Put the LightwaveLink in linking mode for a heating or energy device", Args: []Arg{argRoom}, Command: &CmdPairDevice},
+	{Name: "register", Description: "Pair this host with the LightwaveLink", Command: &CmdRegister},
+	{Name: "room_info", Description: "Ask the device paired to a room to report its product information", Args: []Arg{argRoom}, Command: &CmdQueryRadiator},
+	{Name: "rooms", Description: "Report which rooms heating devices are paired to", Command: &CmdQueryRadiators},
+	{Name: "set_location", Description: "Set the latitude and longitude used for dusk and dawn", Args: []Arg{{Name: "lat", Type: ArgFloat, Min: -90, Max: 90}, {Name: "long", Type: ArgFloat, Min: -180, Max: 180}}, Command: &CmdSetLocation},
+	{Name: "set_target", Description: "Set the target temperature of a heating device", Args: []Arg{argRoom, {Name: "temp", Type: ArgTemp}}, Command: &CmdSetTarget},
+	{Name: "set_timezone", Description: "Set the LightwaveLink's offset from GMT, in hours", Args: []Arg{{Name: "offset", Type: ArgInt, Min: -12, Max: 14}}, Command: &CmdSetTimezone},
+	{Name: "stop", Description: "Stop a relay", Args: []Arg{argDevice}, Command: &CmdStop},
+	{Name: "unlock", Description: "Allow a locked device to be switched from all inputs", Args: []Arg{argDevice}, Command: &CmdUnlock},
+	{Name: "unpair", Description: "Make the LightwaveLink forget the device paired to a room", Args: []Arg{argRoom}, Command: &CmdUnpairDevice},
+}
+
+// Commands returns every named command, ordered by name
+func Commands() []CommandInfo {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return slices.Clone(catalog)
+}
+
+// LookupCommand returns the command with the given name, e.g. "on"
+func LookupCommand(name string) (CommandInfo, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	i, found := searchCatalog(name)
+	if !found {
+		return CommandInfo{}, false
+	}
+	return catalog[i], true
+}
+
+// searchCatalog returns where name is, or would be, in catalog
+func searchCatalog(name string) (int, bool) {
+	return slices.BinarySearchFunc(catalog, name, func(ci CommandInfo, name string) int {
+		return strings.Compare(ci.Name, name)
+	})
+}
+
+// Matches acceptable names of commands
+var commandNameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// CommandSpec defines a command at run time, e.g. one supported by the LWL's
+// firmware but not (yet) by this package. For example, a TRV accepts 50-60
+// as valve positions rather than temperatures:
+//
+//	name: valve_position
+//	description: Set the position of a radiator valve
+//	format: "!%sF*tP%d"
+//	pkt: 868R
+//	fn: ack
+//	args:
+//	  - {name: room, type: room}
+//	  - {name: position, type: int, min: 50, max: 60}
+type CommandSpec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Format      string `yaml:"format"` // As Printf, with a verb per arg
+	Pkt         string `yaml:"pkt"`    // Expected Response.Pkt, optional
+	Fn          string `yaml:"fn"`     // Expected Response.Fn, optional
+	Args        []Arg  `yaml:"args"`
+}
+
+// Check returns every problem found with the spec, joined
+func (s CommandSpec) Check() error {
+	var errs []error
+	if !commandNameRegexp.MatchString(s.Name) {
+		errs = append(errs, fmt.Errorf("name should be lower case letters, digits and _, got %q", s.Name))
+	}
+	if s.Format == "" {
+		errs = append(errs, errors.New("missing format"))
+	}
+	opts := make([]any, len(s.Args))
+	for i, a := range s.Args {
+		if a.Name == "" {
+			errs = append(errs, fmt.Errorf("arg %d: missing name", i+1))
+		}
+		if (a.Type == ArgInt || a.Type == ArgFloat) && a.Min > a.Max {
+			errs = append(errs, fmt.Errorf("arg %d (%s): min %v above max %v", i+1, a.Name, a.Min, a.Max))
+		}
+		opts[i] = a.example()
+	}
+	cmd := Command{cmd: s.Format, opts: opts}
+	if err := cmd.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("format does not match args: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterCommand adds a command to those returned by Commands and
+// LookupCommand. Built-in commands cannot be replaced.
+func RegisterCommand(s CommandSpec) error {
+	if err := s.Check(); err != nil {
+		return fmt.Errorf("command %q: %w", s.Name, err)
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	i, found := searchCatalog(s.Name)
+	if found {
+		return fmt.Errorf("command %q: already defined", s.Name)
+	}
+	ci := CommandInfo{
+		Name:        s.Name,
+		Description: s.Description,
+		Args:        s.Args,
+		Command:     &Command{cmd: s.Format, pkt: s.Pkt, fn: s.Fn, legacyOnly: s.Pkt == "" && s.Fn == ""},
+	}
+	catalog = slices.Insert(catalog, i, ci)
+	return nil
+}
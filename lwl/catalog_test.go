@@ -0,0 +1,121 @@
+package lwl
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCatalogSorted(t *testing.T) {
+	if !slices.IsSortedFunc(catalog, func(a, b CommandInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	}) {
+		t.Fatal("catalog must be ordered by name, for LookupCommand")
+	}
+	for _, ci := range catalog {
+		if got, ok := LookupCommand(ci.Name); !ok || got.Command != ci.Command {
+			t.Errorf("LookupCommand(%q) = %v, %v", ci.Name, got.Name, ok)
+		}
+	}
+	if _, ok := LookupCommand("nonsense"); ok {
+		t.Error("want nonsense to be unknown")
+	}
+}
+
+func TestCatalogBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string // Rendered command, or "" for an error
+	}{
+		{"on", []string{"R1D2"}, "!R1D2F1"},
+		{"off", []string{"R80D16"}, "!R80D16F0"},
+		{"dim", []string{"R1D2", "16"}, "!R1D2FdP16"},
+		{"hub_info", nil, "@H"},
+		{"set_target", []string{"R7", "17.4"}, "!R7F*tP17.5"},
+		{"mood_recall", []string{"R3", "4"}, "!R3FmP4"},
+		{"set_location", []string{"52.18", "0.21"}, `!FqP"52.180000,0.210000"`},
+		{"set_timezone", []string{"-5"}, "!FzP-5"},
+
+		{"on", nil, ""},                       // Too few
+		{"hub_info", []string{"R1"}, ""},      // Too many
+		{"on", []string{"R1"}, ""},            // Room, not device
+		{"all_off", []string{"R1D1"}, ""},     // Device, not room
+		{"on", []string{"R81D1"}, ""},         // Out of range
+		{"dim", []string{"R1D1", "33"}, ""},   // Out of range
+		{"dim", []string{"R1D1", "half"}, ""}, // Not a number
+		{"set_target", []string{"R7", "41"}, ""},
+	}
+	for _, tt := range tests {
+		ci, ok := LookupCommand(tt.name)
+		if !ok {
+			t.Fatalf("unknown command %q", tt.name)
+		}
+		cmd, err := ci.Build(tt.args...)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%s %q: want error, got %v", tt.name, tt.args, cmd)
+		case tt.want != "" && err != nil:
+			t.Errorf("%s %q: %v", tt.name, tt.args, err)
+		case tt.want != "" && cmd.String() != tt.want:
+			t.Errorf("%s %q: want %q got %q", tt.name, tt.args, tt.want, cmd)
+		}
+	}
+}
+
+func TestCatalogUsage(t *testing.T) {
+	ci, _ := LookupCommand("dim")
+	if got, want := ci.Usage(), "dim <device> <level>"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	s := CommandSpec{
+		Name:   "test_valve_position",
+		Format: "!%sF*tP%d",
+		Pkt:    "868R",
+		Fn:     "ack",
+		Args:   []Arg{{Name: "room", Type: ArgRoom}, {Name: "position", Type: ArgInt, Min: 50, Max: 60}},
+	}
+	if err := RegisterCommand(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterCommand(s); err == nil {
+		t.Fatal("want error registering twice")
+	}
+
+	ci, ok := LookupCommand(s.Name)
+	if !ok {
+		t.Fatal("not found")
+	}
+	cmd, err := ci.Build("R7", "55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cmd.String(), "!R7F*tP55"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+	if !cmd.IsResponse(Response{Pkt: "868R", Fn: "ack"}) {
+		t.Fatal("want ack to be the response")
+	}
+	if _, err := ci.Build("R7", "40"); err == nil {
+		t.Fatal("want error for out of range position")
+	}
+}
+
+func TestRegisterCommandInvalid(t *testing.T) {
+	for _, s := range []CommandSpec{
+		{Name: "on", Format: "!%sF1", Args: []Arg{argDevice}}, // Built in
+		{Name: "Bad Name", Format: "@H"},
+		{Name: "test_no_format"},
+		{Name: "test_too_few_args", Format: "!%sFdP%d", Args: []Arg{argDevice}},
+		{Name: "test_too_many_args", Format: "@H", Args: []Arg{argDevice}},
+		{Name: "test_wrong_type", Format: "!R1D1FdP%d", Args: []Arg{argDevice}},
+		{Name: "test_range", Format: "!FzP%d", Args: []Arg{{Name: "n", Type: ArgInt, Min: 2, Max: 1}}},
+	} {
+		if err := RegisterCommand(s); err == nil {
+			t.Errorf("%s: want error", s.Name)
+		}
+	}
+}
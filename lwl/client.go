@@ -4,30 +4,35 @@ package lwl
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
 )
 
 const lwlServerPort = 9760 // We send to this address ...
 const lwlClientPort = 9761 // ... and listen for responses on this one
 
-type errNotJSON struct {
-	msg string
-}
+// Typical response time is ~25-30ms (from WriteToUDP() returning to
+// c.Listen() picking up a JSON response), but the LWL seems to be unable to
+// process requests faster than every 100ms. This is the fastest pace; it
+// slows while the LWL appears overloaded, see pacer.
+const sendInterval = 125 * time.Millisecond
 
-func (e errNotJSON) Error() string {
-	return e.msg
-}
+// CommandTimeout is how long Do waits for a reply when the caller's context
+// has no deadline. Callers which set their own deadline for a command should
+// usually allow this long too.
+const CommandTimeout = 5 * time.Second
 
 // Response holds a decoded JSON message from the LWL. Not all fields are used
 // by all LWL messages.
@@ -40,9 +45,6 @@ func (e errNotJSON) Error() string {
 //	*!{"trans":14674,"mac":"20:3B:85","time":1767297488,"pkt":"room","fn":"summary","stat0":255,"stat1":7,"stat2":0,"stat3":0,"stat4":0,"stat5":0,"stat6":0,"stat7":0,"stat8":0,"stat9":0}
 //	*!{"trans":14819,"mac":"20:3B:85","time":1767307528,"pkt":"room","fn":"read","slot":10,"serial":"D88002","prod":"valve"}
 //	*!{"trans":93136,"mac":"20:3B:85","time":1776726001,"pkt":"868R","fn":"statusPush","prod":"valve","serial":"24C702","type":"temp","batt":3.03,"ver":58,"state":"run","cTemp":19.4,"cTarg":19.0,"output":0,"nTarg":17.0,"nSlot":"00:00","prof":1}
-//
-// FIXME: Payload is sometimes a number, othertimes a string!
-//
 //	*!{"trans":93150,"mac":"20:3B:85","time":1776726215,"pkt":"868R","fn":"ack","status":"success","attempts":1,"packet":208,"type":"log","payload":208}
 type Response struct {
 	// Common to all
@@ -51,14 +53,14 @@ type Response struct {
 	Time  int32  `json:"time"`  // Timestamp of the transaction in LWL "local" Unixtime (i.e. if Link is set to UTC+2, this time will be UNIX + (3600*2))
 
 	// errors
-	Pkt     string `json:"pkt"` // Packet. "system", "error", "433T" to indicate a 433MHz transmission (i.e. LWL to Device), or "868R" to indicate 868MHz radio being received
-	Fn      string `json:"fn"`  // Function. "error", "system", "on", "off", "dim", "fullLock", "manualLock", "unlock", "open", "close", "stop", "ledColour", "ledColourCycle", "allOff", "moodStore", "moodRecall", "read"
-	Payload string `json:"payload"`
+	Pkt     string `json:"pkt"`     // Packet. "system", "error", "433T" to indicate a 433MHz transmission (i.e. LWL to Device), or "868R" to indicate 868MHz radio being received
+	Fn      string `json:"fn"`      // Function. "error", "system", "on", "off", "dim", "fullLock", "manualLock", "unlock", "open", "close", "stop", "ledColour", "ledColourCycle", "allOff", "moodStore", "moodRecall", "read"
+	Payload any    `json:"payload"` // Usually a string, but a number (the packet number) in acks
 
 	// pkt:433T (LWL stating that it is sending a command to a device via 433 MHz transmission)
-	Room  int    `json:"room"`  // The room number that the command was sent to, 0-80 (inc.)
-	Dev   string `json:"dev"`   // The device number that the command was sent to
-	Param string `json:"Param"` // Not in every packet. The parameter for the function, if the function requires a parameter (i.e. dim, mood slot)
+	Room  int `json:"room"`  // The room number that the command was sent to, 0-80 (inc.)
+	Dev   int `json:"dev"`   // The device number that the command was sent to
+	Param int `json:"param"` // Not in every packet. The parameter for the function, if the function requires a parameter (i.e. dim, mood slot)
 
 	// type:link (e.g. !F*p)
 	Type     string `json:"type"`     // "link" or "unlink"
@@ -93,15 +95,51 @@ type Response struct {
 	Stat7 uint8 `json:"stat7"` // Bitfile indicating which slows are in use. LSB=R57, MSB=R64
 	Stat8 uint8 `json:"stat8"` // Bitfile indicating which slows are in use. LSB=R65, MSB=R72
 	Stat9 uint8 `json:"stat9"` // Bitfile indicating which slows are in use. LSB=R73, MSB=R80
+	Slot  int   `json:"slot"`  // Of fn:read, the room the heating device (Serial) is paired to
+
+	// pkt:868R fn:statusPush (heating device reporting its status)
+	Batt   float32 `json:"batt"`   // Battery level in volts, 0.00-4.00. 3V or more is full, less than 2.40V is low
+	Ver    int32   `json:"ver"`    // Firmware version of the device
+	State  string  `json:"state"`  // "run", "man", "frost", "comf", "away", "boost", "calib", "hday", "stby"
+	CTemp  float32 `json:"cTemp"`  // Current temperature in Celsius
+	CTarg  float32 `json:"cTarg"`  // Current target temperature in Celsius
+	Output int32   `json:"output"` // Valve: % open, Thermostat: 0 (off) or 100 (on), Electric Switch: 0 (off) or 1 (on)
+	NTarg  float32 `json:"nTarg"`  // Next target temperature, from the time in NSlot
+	NSlot  string  `json:"nSlot"`  // Time of next scheduled change, "HH:MM"
+	Prof   int32   `json:"prof"`   // Running profile. 1-7 are Monday-Sunday
+
+	// pkt:868T (LWL transmitting to a heating device) and pkt:868R fn:ack (the
+	// device acknowledging)
+	Packet   int32   `json:"packet"`   // Radio packet ID, which the ack refers to
+	Status   string  `json:"status"`   // Of an ack: "success" or "fail"
+	Attempts int32   `json:"attempts"` // Of a successful ack: transmissions needed, 1-5
+	Temp     float32 `json:"temp"`     // Of setTarget: the target temperature sent
+	Minutes  int32   `json:"minutes"`  // Of setTarget
+
+	// pkt:868R fn:meterData (energy monitor reporting usage)
+	CUse   int32 `json:"cUse"`   // Current usage in Watts
+	TodUse int32 `json:"todUse"` // Usage so far today in Watt-hours
 
 	// Internal
-	json string // Original message, before it was decoded
+	Src     net.IP `json:"-"` // Address the message was received from
+	Replay  bool   `json:"-"` // Re-emitted from a capture, not live, see Replay
+	Echo    bool   `json:"-"` // Of a 433T, reports transmitting a command this Client sent moments ago, rather than e.g. a sensor's
+	Decoder string `json:"-"` // Name of the Decoder which understood the message, see RegisterDecoder
+	Decoded any    `json:"-"` // The message, as decoded by Decoder
+	json    string // Original message, before it was decoded
+	packet  bool   // The message has a packet field, which may be 0
 }
 
 func (r *Response) String() string {
 	return r.json
 }
 
+// HasPacket reports whether the message carries a radio packet ID. The LWL
+// numbers packets from 0, so Packet alone cannot tell.
+func (r Response) HasPacket() bool {
+	return r.packet || r.Packet != 0
+}
+
 // LogValue implements slog.LogValuer.
 func (r Response) LogValue() slog.Value {
 	return slog.StringValue(r.String())
@@ -118,15 +156,22 @@ type Client struct {
 	tid atomic.Int32 // Transaction ID (hub monotonically increases this in JSON responses)
 
 	// Discovered at runtime
-	addr net.UDPAddr // Unicast address of LWL
-	mac  string      // MAC address of LWL
+	addr        net.UDPAddr            // Unicast address of LWL
+	mac         atomic.Pointer[string] // Source MAC prefix of commands, see SetMAC
+	macAuto     atomic.Bool            // Relearn mac when addr changes
+	hubMAC      atomic.Pointer[string] // Of the LWL we talk to, see SetHubMAC
+	addrWatches []chan net.IP          // Notified when addr changes, see NotifyHubAddr
+	// Protects addr and addrWatches
+	addrLock  sync.Mutex
+	found     chan struct{} // Closed once addr is a unicast address, see WaitForHub
+	foundOnce sync.Once
 
 	con *net.UDPConn // UDP connection for LAN traffic
 
 	// Outstanding transactions keyed on sid. Legacy format messages from the LWL
 	// with a matching sid will be written to the channel. Use Subscribe() to
 	// add, Unsubscribe() to remove.
-	pendingJSON   map[string]chan Response
+	pendingJSON   map[string]*subscription
 	pendingLegacy map[string]chan string
 	// Protects pending
 	pendingLock sync.Mutex
@@ -134,30 +179,126 @@ type Client struct {
 	// Serialises transmission
 	sendLock sync.Mutex
 
+	// Optional recording of traffic, see Capture
+	capture atomic.Pointer[PcapWriter]
+
+	// Optional forwarding of received traffic, see SetProxy
+	proxy atomic.Pointer[Proxy]
+
+	// Optional recording of commands, see SetAuditor
+	auditor atomic.Pointer[Auditor]
+
+	// Devices recently commanded, see Response.Echo
+	echoes echoes
+
+	// Detected firmware version, see Firmware and Quirks
+	fw atomic.Pointer[Firmware]
+
+	// Offset of the LWL's clock from ours, see ClockOffset
+	clockOffset atomic.Int64 // Nanoseconds
+	clockKnown  atomic.Bool
+
+	// Most recently reported uptime, to detect reboots, see NotifyReboot
+	uptimeLock    sync.Mutex
+	uptime        time.Duration
+	boot          time.Time // Estimated from uptime
+	rebootWatches []chan Reboot
+
+	// Validate and log commands, but don't send them, see SetDryRun
+	dryRun atomic.Bool
+
+	// Stop automations undoing manual commands, see SetHoldOff
+	holdOff atomic.Pointer[holdOff]
+
+	// Messages not understood, see Quarantine
+	quarantine quarantine
+
+	// Log every message sent and received until this time, see Trace
+	traceUntil atomic.Int64 // Unix nanoseconds
+
+	// Shrink buffers and skip verbose dumps, see SetLowMemory
+	lowMemory atomic.Bool
+
+	// Health, see LastHeard and Registered
+	heard      atomic.Int64 // Unix nanoseconds of the most recent valid message
+	registered atomic.Int32 // One of registration*
+
+	// Interval between commands, see SendInterval
+	pacer pacer
+
 	// Metrics
-	latencyStatsLock sync.Mutex
-	latencyStats     map[string]*LatencyStats
+	stats       *StatsRegistry // Command latency, and counters below
+	resultsLock sync.Mutex
+	results     map[string]*CommandResults
+	sock        socketCounters
+	dropped     atomic.Int64 // Messages not delivered to slow subscribers, see Overflow
+	invalid     atomic.Int64 // Messages which failed Response.Validate
+	strict      atomic.Bool  // Discard invalid messages, see SetStrict
+	rfLock      sync.Mutex
+	rfPackets   map[int32]string // Packet ID -> heating device ID, awaiting ack
+	rf          map[string]*RFStats
 }
 
-// New returns a Client
+// ErrNotRegistered is returned when the LWL refuses a command because this
+// host has not been paired with it, see EnsureRegistered
+var ErrNotRegistered = errors.New("not registered with LightwaveLink")
+
+// ErrNoHubAddr is returned when a command cannot be sent because the address
+// of the LWL is not known
+var ErrNoHubAddr = errors.New("LightwaveLink address not known")
+
+// New returns a Client, and panics if it is unable to listen for the LWL
 func New() *Client {
-	con, err := net.ListenUDP("udp4", &net.UDPAddr{Port: lwlClientPort})
+	c, err := Open()
 	if err != nil {
 		panic(err)
 	}
+	return c
+}
+
+// Open returns a Client, or an error if unable to listen for the LWL (e.g.
+// because another process is using the port)
+func Open() (*Client, error) {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{Port: lwlClientPort})
+	if err != nil {
+		return nil, err
+	}
+	return newClient(con, net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort}), nil
+}
+
+// Connect returns a Client which sends commands directly to the given
+// address, rather than discovering the LWL by broadcast, and which listens on
+// an ephemeral port. This is mainly for use with a simulated LWL, see package
+// lwltest.
+func Connect(hub *net.UDPAddr) (*Client, error) {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{IP: hub.IP})
+	if err != nil {
+		return nil, err
+	}
+	return newClient(con, *hub), nil
+}
 
-	c := Client{
-		addr: net.UDPAddr{
-			IP:   net.IPv4bcast,
-			Port: lwlServerPort,
-		},
-		con: con,
+func newClient(con *net.UDPConn, hub net.UDPAddr) *Client {
+	c := &Client{
+		addr:  hub,
+		con:   con,
+		found: make(chan struct{}),
 
-		pendingJSON:   make(map[string]chan Response),
+		pendingJSON:   make(map[string]*subscription),
 		pendingLegacy: make(map[string]chan string),
-		latencyStats:  make(map[string]*LatencyStats),
+		stats:         NewStatsRegistry(),
+		results:       make(map[string]*CommandResults),
 	}
-	return &c
+	c.registerCounters()
+	if !hub.IP.Equal(net.IPv4bcast) {
+		c.markFound()
+	}
+	return c
+}
+
+// Close stops the client listening, causing Listen to return
+func (c *Client) Close() error {
+	return c.con.Close()
 }
 
 // Subscribe to Response and (if sid is non-empty) ACK/NACK messages.
@@ -165,27 +306,72 @@ func New() *Client {
 // Returns a sequence ID which can be used with Unsubscribe.
 //
 // If the input sid is an empty string, one will be allocated.
+//
+// Messages which do not fit in chr are dropped, see SubscribeWith.
 func (c *Client) Subscribe(sid string, chr chan Response, chs chan string) string {
+	return c.SubscribeWith(sid, chr, chs, DropNewest)
+}
+
+// SubscribeWith is Subscribe, with a choice of what happens when chr is full
+func (c *Client) SubscribeWith(sid string, chr chan Response, chs chan string, o Overflow) string {
+	return c.subscribe(sid, chr, chs, o, "")
+}
+
+// subscribe is SubscribeWith, noting the command (if any) the subscriber is
+// waiting for a reply to
+func (c *Client) subscribe(sid string, chr chan Response, chs chan string, o Overflow, origin string) string {
 	if len(sid) == 0 {
 		sid = fmt.Sprintf("%d", c.sid.Add(1))
 	}
 	c.pendingLock.Lock()
 	defer c.pendingLock.Unlock()
-	c.pendingJSON[sid] = chr
+	if old, ok := c.pendingJSON[sid]; ok {
+		old.remove()
+	}
+	s := newSubscription(chr, o)
+	s.origin = origin
+	c.pendingJSON[sid] = s
 	c.pendingLegacy[sid] = chs
 	return sid
 }
 
+// SubscribeContext subscribes to every JSON message until the context is
+// done, at which point the subscription is removed and the returned channel
+// (of the given buffer size) is closed. This suits long-lived consumers, which
+// can range over the channel, and cannot leak the subscription.
+func (c *Client) SubscribeContext(ctx context.Context, size int, o Overflow) <-chan Response {
+	ch := make(chan Response, size)
+	s := newSubscription(ch, o)
+	s.owned = true
+
+	sid := fmt.Sprintf("%d", c.sid.Add(1))
+	c.pendingLock.Lock()
+	c.pendingJSON[sid] = s
+	c.pendingLock.Unlock()
+
+	context.AfterFunc(ctx, func() { c.Unsubscribe(sid) })
+	return ch
+}
+
 // Unsubscribe undoes Subscribe()
 func (c *Client) Unsubscribe(sid string) {
 	c.pendingLock.Lock()
 	defer c.pendingLock.Unlock()
+	if s, ok := c.pendingJSON[sid]; ok {
+		s.remove()
+	}
 	delete(c.pendingJSON, sid)
 	delete(c.pendingLegacy, sid)
 }
 
 // Render internal state as a string
 func (c *Client) String() string {
+	if c.lowMemory.Load() {
+		c.pendingLock.Lock()
+		defer c.pendingLock.Unlock()
+		return fmt.Sprintf("lwl.Client(sid: %v, addr: %v, pendingJSON: %d, pendingLegacy: %d)",
+			c.sid.Load(), c.hubAddr(), len(c.pendingJSON), len(c.pendingLegacy))
+	}
 	return spew.Sprintf(`
 lwl.Client(
   sid:           %v
@@ -195,13 +381,14 @@ lwl.Client(
 )
 `,
 		c.sid.Load(),
-		c.addr,
+		c.hubAddr(),
 		c.pendingJSON,
 		c.pendingLegacy,
 	)
 }
 
-// Listen captures traffic from the LWL and writes it to all subscribers
+// Listen captures traffic from the LWL and writes it to all subscribers, until
+// Close is called
 func (c *Client) Listen() {
 	var b = make([]byte, 1024)
 	for {
@@ -210,59 +397,230 @@ func (c *Client) Listen() {
 			if errors.Is(err, os.ErrDeadlineExceeded) {
 				continue
 			}
-			panic(err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			c.sock.readErrors.Add(1)
+			slog.Error("Failed to read from UDP socket", "err", err)
+			time.Sleep(sendInterval) // Don't spin if the error persists
+			continue
+		}
+		c.receive(b[:i], addr)
+	}
+}
+
+// receive handles a message received from addr
+func (c *Client) receive(b []byte, addr *net.UDPAddr) {
+	c.sock.packetsIn.Add(1)
+	c.sock.bytesIn.Add(int64(len(b)))
+	if p := c.capture.Load(); p != nil {
+		local := &net.UDPAddr{IP: localIPFor(addr.IP), Port: lwlClientPort}
+		if err := p.WritePacket(time.Now(), addr, local, b); err != nil {
+			slog.Error("Failed to capture packet", "err", err)
 		}
+	}
+	c.forward(b)
+
+	msg := string(b)
+	c.trace("in", addr, msg)
 
-		msg := string(b[:i])
-
-		if errJSON := c.handleJSON(msg); errJSON != nil {
-			if _, ok := errJSON.(errNotJSON); ok {
-				// Not JSON. Try legacy
-				if errLegacy := c.handleLegacy(msg); errLegacy != nil {
-					// Uh-ho. No idea what this is
-					slog.Warn("Unable to parse message as either JSON or Legacy:",
-						"msg", msg,
-						"errJSON", errJSON,
-						"errLegacy", errLegacy,
-					)
-					continue // Abandon processing of this message
-				}
-			} else {
-				// Was JSON, but invalid in some way
-				slog.Error("Bad JSON", "errJSON", errJSON, "msg", msg)
+	if errJSON := c.handleJSON(msg, addr.IP); errJSON != nil {
+		if errors.Is(errJSON, errOtherHub) {
+			slog.Debug("Ignoring message from another LightwaveLink", "src", addr, "msg", msg)
+			return
+		}
+		if errors.Is(errJSON, wire.ErrNotJSON) {
+			// Not JSON. Try legacy
+			if errLegacy := c.handleLegacy(msg); errLegacy != nil {
+				// Uh-ho. No idea what this is
+				slog.Warn("Unable to parse message as either JSON or Legacy:",
+					"msg", msg,
+					"errJSON", errJSON,
+					"errLegacy", errLegacy,
+				)
+				c.quarantineMsg("unparseable", "unparseable", msg)
+				return // Abandon processing of this message
 			}
+			c.markHeard()
+			if c.isFound() {
+				// Legacy messages do not identify the LWL, so only the first
+				// may tell us where it is
+				return
+			}
+		} else if errors.Is(errJSON, errInvalid) {
+			// Discarded in strict mode, and already quarantined by validate
+			slog.Debug("Discarded invalid message", "err", errJSON, "msg", msg)
+			return
+		} else {
+			// Was JSON, but malformed in some way
+			slog.Error("Bad JSON", "errJSON", errJSON, "msg", msg)
+			c.quarantineMsg("malformed JSON", errJSON.Error(), msg)
+			return
+		}
+	}
+
+	// Valid message, we'll talk to this LWL from now on
+	c.setHubIP(addr.IP)
+	c.markHeard()
+}
+
+// Capture records all traffic sent and received to p. Use nil to stop
+// recording.
+func (c *Client) Capture(p *PcapWriter) {
+	c.capture.Store(p)
+}
+
+// hubAddr returns the address commands are sent to
+func (c *Client) hubAddr() net.UDPAddr {
+	c.addrLock.Lock()
+	defer c.addrLock.Unlock()
+	return c.addr
+}
+
+// setHubIP updates the address commands are sent to, notifying watchers if it
+// has changed from a previously known unicast address
+func (c *Client) setHubIP(ip net.IP) {
+	c.addrLock.Lock()
+	defer c.addrLock.Unlock()
+
+	old := c.addr.IP
+	if old.Equal(ip) {
+		return
+	}
+	c.addr.IP = ip
+	if c.macAuto.Load() {
+		c.learnMAC(ip)
+	}
+
+	if old.Equal(net.IPv4bcast) {
+		slog.Info("Found LightwaveLink", "ip", ip)
+		c.markFound()
+		return
+	}
+	slog.Warn("LightwaveLink address changed", "old", old, "new", ip)
+	for _, ch := range c.addrWatches {
+		// Non-blocking write to channel
+		select {
+		case ch <- ip:
+		default:
+		}
+	}
+}
+
+// markFound wakes anything blocked in WaitForHub
+func (c *Client) markFound() {
+	c.foundOnce.Do(func() { close(c.found) })
+}
+
+// isFound reports whether the LWL has been located, see WaitForHub
+func (c *Client) isFound() bool {
+	select {
+	case <-c.found:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForHub blocks until the LWL has been located, so that commands are
+// unicast to it rather than broadcast. Until then it broadcasts CmdHubCall
+// every second to prompt a reply. Listen must be running.
+func (c *Client) WaitForHub(ctx context.Context) error {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.found:
+			return nil
+		default:
+		}
+		sid := fmt.Sprintf("%d", c.sid.Add(1))
+		if err := c.sendRaw(string(wire.MarshalLegacy(wire.Legacy{Seq: sid, Payload: CmdHubCall.String()}))); err != nil {
+			return err
+		}
+		select {
+		case <-c.found:
+			return nil
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+}
+
+// NotifyHubAddr causes the new IP address of the LWL to be written to ch
+// whenever it changes (e.g. due to a DHCP lease renewal). Writes are
+// non-blocking, so ch should be buffered.
+func (c *Client) NotifyHubAddr(ch chan net.IP) {
+	c.addrLock.Lock()
+	defer c.addrLock.Unlock()
+	c.addrWatches = append(c.addrWatches, ch)
+}
+
+// Rediscover periodically broadcasts CmdHubCall until the context is done.
+// The LWL's reply lets Listen notice if its address has changed, since
+// otherwise we would keep unicasting to a stale address until the LWL
+// happened to send us something.
+func (c *Client) Rediscover(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
 
-		// Valid message, we'll talk to this LWL from now on
-		c.addr.IP = addr.IP
+	bcast := net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort}
+	for {
+		select {
+		case <-t.C:
+			sid := fmt.Sprintf("%d", c.sid.Add(1))
+			if err := c.sendRawTo(string(wire.MarshalLegacy(wire.Legacy{Seq: sid, Payload: CmdHubCall.String()})), &bcast); err != nil {
+				slog.Warn("Failed to broadcast for LightwaveLink", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-// handleJSON decodes a message into a Response, and writes it to all subscribers
-func (c *Client) handleJSON(msg string) error {
+// handleJSON decodes a message received from src into a Response, and writes
+// it to all subscribers. Messages from an LWL other than ours are discarded
+// with errOtherHub.
+func (c *Client) handleJSON(msg string, src net.IP) error {
 	r, err := c.parseJSON(msg)
 	if err != nil {
 		return err
 	}
-
-	if r.Trans <= c.tid.Load() {
-		// Duplicate message, discard
+	r.Src = src
+	if !c.fromHub(r) {
+		return errOtherHub
+	}
+	if r.Trans > 0 && r.Trans <= c.tid.Load() {
+		// Duplicate message, discard. (A missing trans is left to validate.)
 		return nil
 	}
+	if err := c.validate(&r); err != nil {
+		return err
+	}
 
 	// Record that we've seen this transaction ID
-	c.tid.Store(r.Trans)
+	if r.Trans > 0 {
+		c.tid.Store(r.Trans)
+	}
 
-	// Feed message to subscribers, if able
-	c.pendingLock.Lock()
-	for _, chr := range c.pendingJSON {
-		select {
-		case chr <- r:
-		default:
-			// Means we were unable to write to the channel (full?)
-		}
+	if r.Fn == "hubCall" && r.Fw != "" {
+		c.detectFirmware(r.Fw)
 	}
-	c.pendingLock.Unlock()
+	if r.Fn == "hubCall" && r.Uptime > 0 {
+		c.trackUptime(r.Uptime, time.Now())
+	}
+	if r.Pkt == "433T" {
+		r.Echo = c.echoes.match(r, time.Now())
+	}
+	c.checkKnown(r)
+	c.trackRF(r)
+	c.trackClock(r, time.Now())
+	if r.Fn == "nonRegistered" || (r.Type == "link" && r.Msg == "success") {
+		c.setRegistered(r.Fn != "nonRegistered")
+	}
+
+	c.dispatch(r)
 
 	return nil
 }
@@ -276,6 +634,12 @@ func (c *Client) handleLegacy(msg string) error {
 		return err
 	}
 
+	// E.g. ?V="N2.94D", in reply to CmdRegister when already paired
+	if v, found := strings.CutPrefix(payload, "?V="); found {
+		c.detectFirmware(v)
+	}
+	c.trackRegistration(payload)
+
 	// Write message to legacy subscribers
 	c.pendingLock.Lock()
 	waiter, ok := c.pendingLegacy[sid]
@@ -290,77 +654,68 @@ func (c *Client) handleLegacy(msg string) error {
 	return nil
 }
 
-// Parse JSON response
-func (c *Client) parseJSON(msg string) (Response, error) {
-	if !strings.HasPrefix(msg, "*") {
-		return Response{}, errNotJSON{msg: "not JSON: Does not start with literal asterisk"}
-	}
-	// JSON response
-	// e.g. *!{"trans":12090,"mac":"20:3B:85","time":1766967067,"pkt":"error","fn":"nonRegistered","payload":"Not yet registered. See LightwaveLink"}
-	if len(msg) < 2 {
-		return Response{}, errors.New("invalid JSON: Message not long enough")
-	}
-
-	b := []byte(msg)
-	var r Response
-	err := json.Unmarshal(b[2:], &r)
+// parseLegacy decodes a legacy reply into its sid and payload
+func (c *Client) parseLegacy(msg string) (string, string, error) {
+	m, err := wire.UnmarshalLegacy([]byte(msg))
 	if err != nil {
-		return r, fmt.Errorf("failed to parse JSON: %w", err)
+		return "", "", err
 	}
-	r.json = msg
-	return r, nil
+	return m.Seq, m.Payload, nil
 }
 
-func (c *Client) parseLegacy(msg string) (string, string, error) {
-	// Legacy response
-	// e.g. ERR,1,"Not yet registered. Send !F*p to register"
-	sid, payload, found := strings.Cut(msg, ",")
-	if !found {
-		return "", "", fmt.Errorf("unable to parse legacy message: %v", msg)
-	}
-	payload = strings.TrimSpace(payload)
-	return sid, payload, nil
+func (c *Client) sendRaw(msg string) error {
+	addr := c.hubAddr()
+	return c.sendRawTo(msg, &addr)
 }
 
-func (c *Client) sendRaw(msg string) {
+func (c *Client) sendRawTo(msg string, addr *net.UDPAddr) error {
+	if addr.IP == nil || addr.Port == 0 {
+		return ErrNoHubAddr
+	}
 	c.sendLock.Lock()
-	c.con.WriteToUDP([]byte(msg), &c.addr)
-	slog.Debug("sendRaw", "msg", msg)
+	if _, err := c.con.WriteToUDP([]byte(msg), addr); err != nil {
+		c.sendLock.Unlock()
+		c.sock.writeErrors.Add(1)
+		return fmt.Errorf("send to %v: %w", addr, err)
+	}
+	c.sock.packetsOut.Add(1)
+	c.sock.bytesOut.Add(int64(len(msg)))
+	if p := c.capture.Load(); p != nil {
+		local := &net.UDPAddr{IP: localIPFor(addr.IP), Port: lwlClientPort}
+		if err := p.WritePacket(time.Now(), local, addr, []byte(msg)); err != nil {
+			slog.Error("Failed to capture packet", "err", err)
+		}
+	}
+	slog.Debug("sendRaw", "msg", msg, "addr", addr)
+	c.trace("out", addr, msg)
 	// Rate limit sending, to avoid collisions
+	interval := c.pacer.current()
 	go func() {
-		// Typical response time is ~25-30ms (from WriteToUDP() returning to
-		// c.Listen() picking up a JSON response), but the LWL seems to be unable
-		// to process requests faster than every 100ms.
-		time.Sleep(125 * time.Millisecond)
+		time.Sleep(interval)
 		c.sendLock.Unlock()
 	}()
-
+	return nil
 }
 
 // Send transmits a payload to the LWL, and returns the sequence ID (sid) of
 // the request. If a non-nil channel is provided, it will be subscribed to
-// replies; the caller is responsible for calling Unsubscribe().
-func (c *Client) Send(payload string, chr chan Response, chs chan string) string {
-	var out []string
-
+// replies; the caller is responsible for calling Unsubscribe(), unless an
+// error is returned, meaning the payload never left this host.
+func (c *Client) Send(payload string, chr chan Response, chs chan string) (string, error) {
 	// Generate new sid, atomically
 	sid := fmt.Sprintf("%d", c.sid.Add(1))
-
-	if len(c.mac) > 0 {
-		out = append(out, fmt.Sprintf(":%s", c.mac))
-	}
-	out = append(out, sid)
-	out = append(out, payload)
-
-	msg := strings.Join(out, ",")
+	msg := string(wire.MarshalLegacy(wire.Legacy{MAC: c.MAC(), Seq: sid, Payload: payload}))
 
 	if chr != nil && chs != nil {
-		c.Subscribe(sid, chr, chs)
+		c.subscribe(sid, chr, chs, DropNewest, payload)
 	}
 
-	c.sendRaw(msg)
+	if err := c.sendRaw(msg); err != nil {
+		c.Unsubscribe(sid)
+		return sid, err
+	}
 
-	return sid
+	return sid, nil
 }
 
 // DoLegacy sends a given payload, and then waits for a non-JSON response from
@@ -368,13 +723,21 @@ func (c *Client) Send(payload string, chr chan Response, chs chan string) string
 func (c *Client) DoLegacy(payload string) string {
 	chr := make(chan Response)
 	chs := make(chan string)
-	sid := c.Send(payload, chr, chs)
+	sid, err := c.Send(payload, chr, chs)
+	if err != nil {
+		slog.Error("Failed to send", "payload", payload, "err", err)
+		return ""
+	}
 
 	defer c.Unsubscribe(sid)
 
 	select {
 	case reply := <-chr:
-		spew.Dump(reply)
+		if c.lowMemory.Load() {
+			slog.Debug("DoLegacy got JSON", "reply", &reply)
+		} else {
+			spew.Dump(reply)
+		}
 		return ""
 	case reply := <-chs:
 		return reply
@@ -384,64 +747,202 @@ func (c *Client) DoLegacy(payload string) string {
 }
 
 func (c *Client) sampleCommandLatency(cmd Command, t time.Duration) {
-	c.latencyStatsLock.Lock()
-	defer c.latencyStatsLock.Unlock()
+	c.stats.Latency(cmd.cmd).Sample(t)
+	if id := cmd.target(); id != "" {
+		c.stats.Latency(deviceLatencyPrefix + id).Sample(t)
+	}
+}
+
+// registerCounters adds the Client's counters to its StatsRegistry
+func (c *Client) registerCounters() {
+	for name, n := range map[string]*atomic.Int64{
+		"dropped":             &c.dropped,
+		"invalid":             &c.invalid,
+		"socket.packets_in":   &c.sock.packetsIn,
+		"socket.bytes_in":     &c.sock.bytesIn,
+		"socket.packets_out":  &c.sock.packetsOut,
+		"socket.bytes_out":    &c.sock.bytesOut,
+		"socket.read_errors":  &c.sock.readErrors,
+		"socket.write_errors": &c.sock.writeErrors,
+	} {
+		c.stats.CounterFunc(name, n.Load)
+	}
+	c.stats.CounterFunc("subscriptions", func() int64 { return int64(c.Subscriptions()) })
+	c.stats.Counter(rebootCounter) // Reported as zero until the first reboot
+	c.stats.CounterFunc(sendIntervalGauge, func() int64 { return c.SendInterval().Milliseconds() })
+	c.stats.CounterFunc(clockOffsetGauge, func() int64 {
+		offset, _ := c.ClockOffset()
+		return int64(offset.Round(time.Second) / time.Second)
+	})
+}
 
-	ls, ok := c.latencyStats[cmd.cmd]
+// StatsRegistry returns the registry holding the Client's command latencies
+// and counters, to which callers may add their own
+func (c *Client) StatsRegistry() *StatsRegistry {
+	return c.stats
+}
+
+// Prefix of the counters of commands sent for each source (see WithSource),
+// e.g. "commands.source.rule:dusk"
+const sourceCounterPrefix = "commands.source."
+
+func (c *Client) recordResult(ctx context.Context, cmd Command, outcome commandOutcome) {
+	c.stats.Counter(sourceCounterPrefix + Source(ctx)).Add(1)
+
+	c.resultsLock.Lock()
+	defer c.resultsLock.Unlock()
+
+	r, ok := c.results[cmd.cmd]
 	if !ok {
-		ls = NewLatencyStats(cmd.cmd)
-		c.latencyStats[cmd.cmd] = ls
+		r = &CommandResults{}
+		c.results[cmd.cmd] = r
+	}
+	r.record(outcome)
+}
+
+// Results returns a copy of the outcome counts of each command performed with
+// Do(), keyed on the command's format string (e.g. "!%sFdP%d")
+func (c *Client) Results() map[string]CommandResults {
+	c.resultsLock.Lock()
+	defer c.resultsLock.Unlock()
+
+	out := make(map[string]CommandResults, len(c.results))
+	for k, v := range c.results {
+		out[k] = *v
 	}
-	ls.Sample(t)
+	return out
 }
 
 // Stats reports the min/mean/max times for seen commands to get a (non-error)
-// response from the LWL.
+// response from the LWL, and how many succeeded, failed or timed out.
 //
 // The report is intended for human consumption.
 func (c *Client) Stats() string {
-	c.latencyStatsLock.Lock()
-	defer c.latencyStatsLock.Unlock()
+	snap := c.stats.Snapshot()
+	s := make([]string, 0, len(snap.Latency))
+	for _, v := range snap.Latency {
+		s = append(s, v.String())
+	}
 
-	s := make([]string, len(c.latencyStats))
+	results := c.Results()
+	for _, k := range slices.Sorted(maps.Keys(results)) {
+		s = append(s, fmt.Sprintf("%s: %v", k, results[k]))
+	}
 
-	for _, v := range c.latencyStats {
-		s = append(s, v.String())
+	s = append(s, fmt.Sprintf("Subscriptions: %d", c.Subscriptions()))
+	s = append(s, fmt.Sprintf("Dropped (slow subscribers): %d", c.dropped.Load()))
+	s = append(s, fmt.Sprintf("Invalid messages: %d", c.invalid.Load()))
+	s = append(s, c.SocketStats().String())
+	if p := c.proxy.Load(); p != nil {
+		s = append(s, p.String())
+	}
+
+	rf := c.RFStats()
+	for _, k := range slices.Sorted(maps.Keys(rf)) {
+		s = append(s, fmt.Sprintf("%s RF: %v", k, rf[k]))
 	}
 
 	out := strings.Join(s, "\n")
 	return out
 }
 
-// Do performs a command and returns the response, or an error.
-func (c *Client) Do(ctx context.Context, cmd Command) (Response, error) {
+// Do performs a command and returns the response, or an error. It gives up
+// after CommandTimeout unless ctx has a deadline of its own.
+func (c *Client) Do(ctx context.Context, cmd Command) (r Response, err error) {
+	parent := ctx
+	ctx, cancel := withDoTimeout(ctx)
+	defer cancel()
+	cmd = withContextText(ctx, cmd)
+	if err := cmd.validate(); err != nil {
+		return Response{}, err
+	}
+	if err := c.checkHoldOff(ctx, cmd); err != nil {
+		c.audit(ctx, cmd, outcomeHeldOff, 0, err)
+		return Response{}, err
+	}
+	if c.isDryRun(ctx) {
+		slog.Info("Dry run, not sending", "cmd", cmd, "source", Source(ctx))
+		c.audit(ctx, cmd, outcomeDryRun, 0, nil)
+		return Response{}, nil
+	}
+
+	slog.Debug("Do", "cmd", cmd, "source", Source(ctx))
+	c.echoes.note(cmd, time.Now()) // Before sending, as the echo may arrive first
 	chr := make(chan Response, 10)
 	chs := make(chan string, 10)
-	sid := c.Send(cmd.String(), chr, chs)
+	sid, err := c.Send(cmd.String(), chr, chs)
+	if err != nil {
+		c.recordResult(ctx, cmd, outcomeErr)
+		c.audit(ctx, cmd, outcomeErr, 0, err)
+		return Response{}, err
+	}
 	defer c.Unsubscribe(sid)
 
 	// Send() is rate-limited, but returns as soon as transmission is complete,
 	// so start timing from when it returns.
 	start := time.Now()
 
-	select {
-	case msg := <-chs:
-		slog.Debug("Do", "msg", &msg)
-		if strings.TrimSpace(msg) != "OK" {
-			return Response{}, fmt.Errorf("Unexpected (legacy) response to command: %s", msg)
+	isResponse := cmd.IsResponse
+	if ack := newRFAck(cmd); ack != nil {
+		isResponse = ack.matches
+	}
+
+	outcome := outcomeTimeout
+	defer func() {
+		c.recordResult(ctx, cmd, outcome)
+		c.audit(ctx, cmd, outcome, time.Since(start), err)
+		c.pace(outcome, err, parent.Err() == nil)
+		if outcome == outcomeOK {
+			c.noteManual(ctx, cmd)
 		}
-	case r := <-chr:
-		slog.Debug("Do", "r", &r)
-		if cmd.IsResponse(r) {
+	}()
+
+	// The LWL acknowledges commands with a legacy "OK", and (for most
+	// commands) also sends a JSON response. These can arrive in either order,
+	// and other JSON traffic may arrive in the meantime.
+	for {
+		select {
+		case msg := <-chs:
+			slog.Debug("Do", "msg", &msg)
+			msg = strings.TrimSpace(msg)
+			switch {
+			case strings.Contains(msg, "Not yet registered"):
+				outcome = outcomeErr
+				return Response{}, ErrNotRegistered
+			case strings.HasPrefix(msg, "ERR,6,"):
+				outcome = outcomeErr
+				return Response{}, fmt.Errorf("%w: %s", ErrTransmitFail, msg)
+			case msg != "OK":
+				outcome = outcomeErr
+				return Response{}, fmt.Errorf("unexpected (legacy) response to command: %s", msg)
+			case !cmd.expectsJSON() || c.Quirks().NoJSON:
+				c.sampleCommandLatency(cmd, time.Since(start))
+				outcome = outcomeOK
+				return Response{}, nil
+			}
+		case r := <-chr:
 			slog.Debug("Do", "r", &r)
-			c.sampleCommandLatency(cmd, time.Since(start))
-			return r, nil
+			switch {
+			case isResponse(r):
+				c.sampleCommandLatency(cmd, time.Since(start))
+				outcome = outcomeOK
+				return r, nil
+			case r.Fn == "nonRegistered":
+				outcome = outcomeErr
+				return r, ErrNotRegistered
+			}
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
 		}
-	case <-ctx.Done():
-		return Response{}, ctx.Err()
 	}
+}
 
-	return Response{}, nil
+// withDoTimeout applies CommandTimeout to ctx, unless it already has a deadline
+func withDoTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, CommandTimeout)
 }
 
 // EnsureRegistered checks if the LWL accepts commands from the current host,
@@ -449,9 +950,11 @@ func (c *Client) Do(ctx context.Context, cmd Command) (Response, error) {
 func (c *Client) EnsureRegistered() {
 	chr := make(chan Response, 10)
 	chs := make(chan string, 10)
-	sid := c.Send(CmdRegister.String(), chr, chs)
-
+	sid := c.Subscribe("", chr, chs)
 	defer c.Unsubscribe(sid)
+	if err := c.sendRaw(string(wire.MarshalLegacy(wire.Legacy{Seq: sid, Payload: CmdRegister.String()}))); err != nil {
+		slog.Warn("Failed to send pairing request, will retry", "err", err)
+	}
 
 	t := time.NewTimer(time.Second)
 	pairingRequired := true
@@ -480,7 +983,9 @@ func (c *Client) EnsureRegistered() {
 			}
 		case <-t.C:
 			slog.Debug("Timeout. Resending pairing request")
-			c.sendRaw(fmt.Sprintf("%s,%v", sid, CmdRegister))
+			if err := c.sendRaw(string(wire.MarshalLegacy(wire.Legacy{Seq: sid, Payload: CmdRegister.String()}))); err != nil {
+				slog.Warn("Failed to send pairing request, will retry", "err", err)
+			}
 			t.Reset(10 * time.Second) // LWL pairing ends after ~15s
 		}
 	}
@@ -489,8 +994,6 @@ func (c *Client) EnsureRegistered() {
 // QueryAllRadiators queries the LWL for a list of paired devices, then
 // requests the status of each.
 func (c *Client) QueryAllRadiators(ctx context.Context) error {
-	chr := make(chan Response, 10)
-	c.Subscribe("", chr, nil)
 	r, err := c.Do(ctx, CmdQueryRadiators)
 	if err != nil {
 		slog.Error("Failed to query radiators: %w")
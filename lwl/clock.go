@@ -0,0 +1,62 @@
+package lwl
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Name of the clock offset gauge in a Client's StatsRegistry, in seconds
+const clockOffsetGauge = "hub.clock_offset_seconds"
+
+// clockOffset returns how far the LWL's clock (from Response.Time, which is
+// in its local time) is ahead of ours.
+//
+// The LWL's timestamps are offset by its time zone and daylight saving, which
+// are whole hours, so these are removed by rounding to the nearest hour. This
+// is only accurate while the clocks are within half an hour of each other,
+// which is plenty to notice drift.
+func clockOffset(lwlTime int32, now time.Time) time.Duration {
+	raw := time.Unix(int64(lwlTime), 0).Sub(now)
+	return raw - raw.Round(time.Hour)
+}
+
+// trackClock records the offset of the LWL's clock from a message just
+// received from it
+func (c *Client) trackClock(r Response, now time.Time) {
+	if r.Time <= 0 {
+		return
+	}
+	c.clockOffset.Store(int64(clockOffset(r.Time, now)))
+	c.clockKnown.Store(true)
+}
+
+// ClockOffset returns how far the LWL's clock is ahead of ours (negative if
+// behind), as of the last message received from it, or false if nothing has
+// been received yet. It is accurate to about a second, since the LWL
+// timestamps messages in whole seconds.
+//
+// A drifting clock makes the LWL's dusk and dawn timers fire at the wrong
+// time, see WatchClock.
+func (c *Client) ClockOffset() (time.Duration, bool) {
+	return time.Duration(c.clockOffset.Load()), c.clockKnown.Load()
+}
+
+// WatchClock checks the LWL's clock every interval until the context is done,
+// and logs a warning if it is out by more than threshold. It does not correct
+// the clock: no command to set it has been confirmed.
+func (c *Client) WatchClock(ctx context.Context, interval, threshold time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			offset, ok := c.ClockOffset()
+			if ok && offset.Abs() > threshold {
+				slog.Warn("LightwaveLink clock has drifted", "offset", offset, "threshold", threshold)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package lwl
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClockOffset(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		lwl  time.Time
+		want time.Duration
+	}{
+		{now, 0},
+		{now.Add(42 * time.Second), 42 * time.Second},
+		{now.Add(-3 * time.Minute), -3 * time.Minute},
+		{now.Add(time.Hour + 5*time.Second), 5 * time.Second},       // BST
+		{now.Add(-5*time.Hour - 10*time.Second), -10 * time.Second}, // GMT-5
+	} {
+		if got := clockOffset(int32(tt.lwl.Unix()), now); got != tt.want {
+			t.Errorf("%v: want %v got %v", tt.lwl.Sub(now), tt.want, got)
+		}
+	}
+}
+
+func TestTrackClock(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	if _, ok := c.ClockOffset(); ok {
+		t.Fatal("want offset unknown before any message")
+	}
+	now := time.Now().Truncate(time.Second)
+	c.trackClock(Response{Time: int32(now.Add(time.Hour - 90*time.Second).Unix())}, now)
+	if got, ok := c.ClockOffset(); !ok || got != -90*time.Second {
+		t.Fatalf("want -90s, got %v %v", got, ok)
+	}
+	if got := c.StatsRegistry().Snapshot().Counters[clockOffsetGauge]; got != -90 {
+		t.Fatalf("want gauge of -90, got %d", got)
+	}
+}
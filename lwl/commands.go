@@ -13,6 +13,7 @@ type Command struct {
 	pkt        string              // Expected Response.Pkt
 	fn         string              // Expected Response.Fn
 	match      func(Response) bool // Custom IsResponse implementation, optional
+	text       string              // Text for the LW500's screen, see WithText
 }
 
 // New returns a Command with parameters.
@@ -22,14 +23,14 @@ type Command struct {
 // used to match responses, e.g. detecting a status message from a specific
 // device.
 func (c *Command) New(opts ...any) *Command {
-	out := c
+	out := *c // Copy, so the package-level template is left untouched
 	out.opts = opts
-	return out
+	return &out
 }
 
 // String returns a rendered comand, ready to Send
 func (c *Command) String() string {
-	return fmt.Sprintf(c.cmd, c.opts...)
+	return fmt.Sprintf(c.cmd, c.opts...) + c.text
 }
 
 // LogValue implements slog.LogValuer.
@@ -56,6 +57,12 @@ func (c *Command) IsResponse(r Response) bool {
 	}
 }
 
+// expectsJSON reports whether IsResponse can ever match, i.e. whether a JSON
+// response (rather than just a legacy "OK") should be waited for
+func (c *Command) expectsJSON() bool {
+	return c.match != nil || c.fn != "" || c.pkt != ""
+}
+
 // CmdRegister will pair the current LAN host (identified by MAC address) with
 // LWL. If already paired LWL will response with a legacy message containing
 // it's version, e.g. "?V=\"N2.94D\""
@@ -257,6 +264,31 @@ var CmdPairDevice = Command{cmd: "!%sF*L"}
 //   - string  Room identifier, e.g. R1
 var CmdUnpairDevice = Command{cmd: "!%sF*xU"}
 
+// CmdHeatingStatus asks a heating device to report its status. The LWL
+// announces the transmission, then reports the device's acknowledgement
+// (which is the response), followed by the device's statusPush. Args:
+//
+//	id string: Heating device, e.g. "R8"
+//
+//	->: 123,!R8F*r
+//	<-: *!{"trans":718,"mac":"20:04:96","time":1475325023,"pkt":"868T","fn":"getStatus","room":8,"packet":202}
+//	<-: 123,OK
+//	<-: *!{"trans":719,"mac":"20:04:96","time":1475325032,"pkt":"868R","fn":"ack","status":"fail","packet":202}
+var CmdHeatingStatus = Command{cmd: "!%sF*r", pkt: "868R", fn: "ack"}
+
+// CmdSetTarget sets the target temperature of a heating device, until its
+// schedule next changes. The response is the device's acknowledgement, as
+// for CmdHeatingStatus. Args:
+//
+//	id   string: Heating device, e.g. "R7"
+//	temp string: Celsius, see FormatTemp
+//
+//	->: 123,!R7F*tP17
+//	<-: *!{"trans":691,"mac":"03:34:BC","time":1475323582,"pkt":"868T","fn":"setTarget","room":7,"temp":17.0,"minutes":0,"packet":191}
+//	<-: 123,OK
+//	<-: *!{"trans":692,"mac":"20:04:96","time":1475323584,"pkt":"868R","fn":"ack","status":"success","attempts":1,"packet":191}
+var CmdSetTarget = Command{cmd: "!%sF*tP%s", pkt: "868R", fn: "ack"}
+
 // CmdQueryRadiators finds which radiator ("room") numbers have been allocated.
 //
 //	->: 5,@R
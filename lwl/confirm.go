@@ -0,0 +1,176 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Times On or Off is sent to a confirmed device before giving up, see
+// SetConfirm
+const confirmAttempts = 3
+
+// How long a heating device has to report its status after CmdHeatingStatus
+const statusTimeout = 10 * time.Second
+
+// ConfirmTimeout is how long On or Off may take for a device with
+// SetConfirm, if every attempt to confirm it times out. Callers with shorter
+// deadlines get fewer chances of a response.
+const ConfirmTimeout = confirmAttempts * statusTimeout
+
+// ErrNotConfirmed is returned when a device does not report the state it was
+// switched to
+var ErrNotConfirmed = errors.New("device did not confirm its state")
+
+// SwitchConfirmer is implemented by HubClients which can ask a device whether
+// it is on. *Client implements it for heating switches.
+type SwitchConfirmer interface {
+	// ConfirmSwitch returns ErrNotConfirmed if the device (e.g. "R7") does
+	// not report being on (or off, if on is false)
+	ConfirmSwitch(ctx context.Context, id string, on bool) error
+}
+
+var _ SwitchConfirmer = (*Client)(nil)
+
+// HeatingStatus asks a heating device (e.g. "R7") to report its status,
+// returning its statusPush. Devices report by serial number, so the serial
+// paired to the room is looked up first with CmdQueryRadiator.
+func (c *Client) HeatingStatus(ctx context.Context, id string) (Response, error) {
+	info, err := c.Do(ctx, *CmdQueryRadiator.New(id))
+	if err != nil {
+		return Response{}, err
+	}
+	if info.Serial == "" {
+		return Response{}, fmt.Errorf("%s: no heating device paired", id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	msgs := c.Events(ctx) // Before asking, so the statusPush cannot be missed
+	r, err := c.Do(ctx, *CmdHeatingStatus.New(id))
+	if err != nil {
+		return Response{}, err
+	}
+	if r.Fn == "ack" && r.Status != "success" {
+		return Response{}, fmt.Errorf("%s: %w", id, ErrNoAck)
+	}
+	for {
+		select {
+		case m := <-msgs:
+			if m.Fn == "statusPush" && m.Serial == info.Serial {
+				return m, nil
+			}
+		case <-ctx.Done():
+			return Response{}, fmt.Errorf("%s: no status reported: %w", id, ctx.Err())
+		}
+	}
+}
+
+// ConfirmSwitch implements SwitchConfirmer for heating switches, i.e. the
+// Electric Switch (LW934) and Boiler Switch (LW920), whose statusPush reports
+// their output. Dry runs are always confirmed.
+func (c *Client) ConfirmSwitch(ctx context.Context, id string, on bool) error {
+	if c.isDryRun(ctx) {
+		return nil
+	}
+	r, err := c.HeatingStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	if (r.Output != 0) != on {
+		return fmt.Errorf("%s: output %d: %w", id, r.Output, ErrNotConfirmed)
+	}
+	return nil
+}
+
+// SetConfirm makes On and Off ask the device for its state afterwards, and
+// resend the command if it does not match, giving at-least-once semantics for
+// critical loads like heaters. After confirmAttempts tries they return
+// ErrNotConfirmed.
+//
+// Only devices which report their state can be confirmed, i.e. heating
+// switches, which are addressed by room alone (e.g. "R7"), and only if the
+// HubClient implements SwitchConfirmer.
+func (d *Device) SetConfirm(confirm bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.confirm = confirm
+}
+
+// Confirm returns the setting of SetConfirm
+func (d *Device) Confirm() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.confirm
+}
+
+// send sends On or Off, confirming the result if enabled. confirmed is true
+// if the device reported the new state.
+func (d *Device) send(ctx context.Context, cmd *Command, on bool) (confirmed bool, err error) {
+	sc, ok := d.c.(SwitchConfirmer)
+	if !ok || !d.Confirm() {
+		_, err := d.c.Do(ctx, *cmd)
+		return false, err
+	}
+	for attempt := 1; ; attempt++ {
+		actx, cancel := shareDeadline(ctx, confirmAttempts-attempt+1)
+		_, err := d.c.Do(actx, *cmd)
+		if err != nil && actx.Err() == nil {
+			cancel()
+			return false, err // Not sent, e.g. held off
+		}
+		if err == nil {
+			err = sc.ConfirmSwitch(actx, d.id, on)
+		}
+		cancel()
+		if err == nil {
+			return true, nil
+		}
+		if ctx.Err() != nil || attempt == confirmAttempts {
+			if !errors.Is(err, ErrNotConfirmed) {
+				err = fmt.Errorf("%w: %w", ErrNotConfirmed, err)
+			}
+			return false, err
+		}
+		slog.Warn("Device did not confirm its state, resending", "device", d, "on", on, "attempt", attempt, "err", err)
+	}
+}
+
+// shareDeadline returns a context with an equal share of what remains of
+// ctx's deadline, if it has one, so that a device which is slow to respond
+// leaves time for the remaining attempts
+func shareDeadline(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remaining))
+}
+
+// ApplyConfirm parses a list of heating switches, e.g. "heater,R7", and
+// enables SetConfirm on each
+func (r *Registry) ApplyConfirm(s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, ok := r.c.(SwitchConfirmer); !ok {
+		return errors.New("devices cannot be confirmed via this client")
+	}
+	var errs []error
+	for name := range strings.SplitSeq(s, ",") {
+		d, err := r.Resolve(strings.TrimSpace(name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if strings.Contains(d.ID(), "D") {
+			errs = append(errs, fmt.Errorf("%s is not a heating switch, which are addressed by room, e.g. R7", d.ID()))
+			continue
+		}
+		d.SetConfirm(true)
+	}
+	return errors.Join(errs...)
+}
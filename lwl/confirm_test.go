@@ -0,0 +1,94 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// confirmingHub is a fakeHub whose devices report a state, which only
+// changes once a command has been sent ignore times
+type confirmingHub struct {
+	fakeHub
+	ignore int
+	hang   int // Times ConfirmSwitch waits until the context is done
+	on     bool
+}
+
+func (f *confirmingHub) Do(ctx context.Context, cmd Command) (Response, error) {
+	f.fakeHub.Do(ctx, cmd)
+	if f.ignore > 0 {
+		f.ignore--
+		return Response{}, nil
+	}
+	f.on = cmd.String() == "!R7F1"
+	return Response{}, nil
+}
+
+func (f *confirmingHub) ConfirmSwitch(ctx context.Context, id string, on bool) error {
+	if f.hang > 0 {
+		f.hang--
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if f.on != on {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+func TestConfirm(t *testing.T) {
+	f := &confirmingHub{ignore: 1}
+	d := NewRegistry(f).Device("R7")
+	d.SetConfirm(true)
+
+	if err := d.On(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"!R7F1", "!R7F1"}; !slices.Equal(f.sent, want) {
+		t.Fatalf("want %q got %q", want, f.sent)
+	}
+
+	f.ignore, f.sent = confirmAttempts, nil
+	if err := d.Off(t.Context()); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("want ErrNotConfirmed, got %v", err)
+	}
+	if len(f.sent) != confirmAttempts {
+		t.Fatalf("want %d attempts, got %q", confirmAttempts, f.sent)
+	}
+	if !d.State().On {
+		t.Fatal("want device still assumed on")
+	}
+
+	// A device slow to respond leaves time to try again within the deadline
+	f.ignore, f.hang, f.sent = 0, 1, nil
+	ctx, cancel := context.WithTimeout(t.Context(), 300*time.Millisecond)
+	defer cancel()
+	if err := d.Off(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.sent) != 2 {
+		t.Fatalf("want 2 attempts, got %q", f.sent)
+	}
+}
+
+func TestApplyConfirm(t *testing.T) {
+	reg := NewRegistry(&Client{})
+	if err := reg.SetAlias("R7", "heater"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.ApplyConfirm("heater"); err != nil {
+		t.Fatal(err)
+	}
+	if !reg.Device("R7").Confirm() {
+		t.Error("heater: want confirm")
+	}
+	if err := reg.ApplyConfirm("R1D1"); err == nil {
+		t.Error("R1D1: want error, not a heating switch")
+	}
+	if err := NewRegistry(&fakeHub{}).ApplyConfirm("R7"); err == nil {
+		t.Error("want error, fakeHub cannot confirm")
+	}
+}
@@ -0,0 +1,115 @@
+package lwl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// Decoder parses messages from the LWL which this package does not
+// understand, e.g. from niche LightwaveRF products, so that they can be
+// supported without modifying it. See RegisterDecoder.
+type Decoder interface {
+	// Decode is given each JSON message, without its "*!" prefix, before it
+	// is decoded into a Response. It returns the message decoded and true if
+	// it understands it, or false to leave it to other decoders.
+	Decode(msg []byte) (v any, ok bool, err error)
+}
+
+// DecoderFunc adapts a function to a Decoder
+type DecoderFunc func(msg []byte) (any, bool, error)
+
+// Decode calls f(msg)
+func (f DecoderFunc) Decode(msg []byte) (any, bool, error) {
+	return f(msg)
+}
+
+type namedDecoder struct {
+	name string
+	d    Decoder
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   []namedDecoder // In the order registered
+)
+
+// RegisterDecoder adds a Decoder, which is offered each message from the LWL
+// after those registered before it. The first to understand a message sets
+// Response.Decoded and Response.Decoder; the message is still decoded into
+// the rest of the Response, for its common fields (trans, mac, pkt, fn and
+// so on), but is not quarantined as unknown (see Client.Quarantine).
+// Decoders must be safe to call from multiple goroutines.
+func RegisterDecoder(name string, d Decoder) error {
+	if name == "" || d == nil {
+		return errors.New("decoder needs a name")
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if slices.ContainsFunc(decoders, func(nd namedDecoder) bool { return nd.name == name }) {
+		return fmt.Errorf("decoder %q: already registered", name)
+	}
+	decoders = append(decoders, namedDecoder{name, d})
+	return nil
+}
+
+// decode offers a JSON message to each Decoder in turn, returning the name
+// of the first to understand it and its result. Decoders which fail are
+// logged and skipped, as are those which panic.
+func decode(msg string) (string, any, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if len(decoders) == 0 || !wire.IsJSON([]byte(msg)) {
+		return "", nil, false
+	}
+	b := []byte(msg[len(wire.JSONPrefix):])
+	for _, nd := range decoders {
+		v, ok, err := nd.decode(b)
+		if err != nil {
+			slog.Warn("Decoder failed", "decoder", nd.name, "msg", msg, "err", err)
+			continue
+		}
+		if ok {
+			return nd.name, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// decode calls the Decoder, turning a panic into an error so that a broken
+// Decoder cannot take down the Client
+func (nd namedDecoder) decode(msg []byte) (v any, ok bool, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			v, ok, err = nil, false, fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return nd.d.Decode(msg)
+}
+
+// parseJSON decodes a JSON message, see wire.UnmarshalJSON, having first
+// offered it to each Decoder
+func (c *Client) parseJSON(msg string) (Response, error) {
+	var r Response
+	name, v, ok := decode(msg)
+	err := wire.UnmarshalJSON([]byte(msg), &r)
+	var te *json.UnmarshalTypeError
+	if ok && errors.As(err, &te) {
+		// The decoder understands fields which Response has with another
+		// type, but the rest of Response is still filled in
+		err = nil
+	}
+	if err != nil {
+		return r, err
+	}
+	r.json = msg
+	r.packet = strings.Contains(msg, `"packet":`)
+	r.Decoder, r.Decoded = name, v
+	return r, nil
+}
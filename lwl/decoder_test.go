@@ -0,0 +1,70 @@
+package lwl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+)
+
+// testBlind is a message from a product this package does not know
+type testBlind struct {
+	Position string `json:"position"`
+	Tilt     int    `json:"tilt"`
+}
+
+func TestDecoder(t *testing.T) {
+	blinds := DecoderFunc(func(msg []byte) (any, bool, error) {
+		var m struct {
+			Pkt string `json:"pkt"`
+			testBlind
+		}
+		if err := json.Unmarshal(msg, &m); err != nil || m.Pkt != "testBlind" {
+			return nil, false, nil
+		}
+		return m.testBlind, true, nil
+	})
+	broken := DecoderFunc(func(msg []byte) (any, bool, error) {
+		return nil, false, errors.New("broken")
+	})
+	panics := DecoderFunc(func(msg []byte) (any, bool, error) {
+		panic("oops")
+	})
+	if err := RegisterDecoder("test_broken", broken); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDecoder("test_panics", panics); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDecoder("test_blinds", blinds); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDecoder("test_blinds", blinds); err == nil {
+		t.Error("want error registering twice")
+	}
+
+	c := newClient(nil, net.UDPAddr{})
+	ch := c.SubscribeContext(context.Background(), 2, Block)
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort}
+	for _, msg := range []string{
+		`*!{"trans":1,"mac":"20:3B:85","time":1767129953,"pkt":"testBlind","fn":"report","room":1,"position":"half","tilt":45,"output":"closing"}`,
+		`*!{"trans":2,"mac":"20:3B:85","time":1767129953,"pkt":"433T","fn":"on","room":1,"dev":2}`,
+	} {
+		c.receive([]byte(msg), src)
+	}
+
+	r := <-ch
+	if r.Decoder != "test_blinds" || r.Decoded != (testBlind{Position: "half", Tilt: 45}) {
+		t.Errorf("got %s %+v", r.Decoder, r.Decoded)
+	}
+	if r.Trans != 1 || r.Pkt != "testBlind" || r.Room != 1 {
+		t.Errorf("common fields not decoded: %+v", r)
+	}
+	if r := <-ch; r.Decoder != "" || r.Decoded != nil || r.Fn != "on" {
+		t.Errorf("decoded by a plugin: %+v", r)
+	}
+	if q := c.Quarantine(); len(q) != 0 {
+		t.Errorf("quarantined %+v", q)
+	}
+}
@@ -0,0 +1,315 @@
+package lwl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dimMin = 1  // Dimmest level accepted by CmdSetDimmer
+	dimMax = 32 // Brightest level accepted by CmdSetDimmer
+)
+
+// Device is a lighting or power peripheral, addressed by its Room+Device
+// identifier (e.g. "R1D1").
+//
+// The LWL cannot report the state of most devices, so Device remembers the
+// last state it commanded instead. Heating switches can, see SetConfirm.
+type Device struct {
+	c     HubClient
+	id    string // Room+Device identifier, e.g. R1D1
+	alias string // Human-friendly name, see Registry.SetAlias
+
+	mu        sync.Mutex
+	on        bool
+	level     int      // Last dim level sent, or 0 if unknown
+	lock      LockMode // Last lock mode sent
+	commanded bool     // on and level have been sent by us
+	reported  *report  // State most recently reported by the LWL, or nil
+
+	autoOff  time.Duration // Switch off this long after on, see SetAutoOff
+	offTimer *time.Timer   // Pending auto-off
+	confirm  bool          // Check the device's state after On and Off, see SetConfirm
+}
+
+// LockMode describes whether a device will accept manual and/or RF control
+type LockMode int
+
+const (
+	Unlocked    LockMode = iota // Accepts control from all inputs
+	LockPartial                 // Cannot be switched manually, but can be via RF (LWL, remotes, PIRs, etc)
+	LockFull                    // Cannot be switched manually or via RF until unlocked
+)
+
+func (m LockMode) String() string {
+	switch m {
+	case Unlocked:
+		return "unlocked"
+	case LockPartial:
+		return "partial"
+	case LockFull:
+		return "full"
+	default:
+		return fmt.Sprintf("LockMode(%d)", int(m))
+	}
+}
+
+// NewDevice returns a Device which sends commands via c
+func NewDevice(c HubClient, id string) *Device {
+	return &Device{c: c, id: id}
+}
+
+// ID returns the Room+Device identifier, e.g. R1D1
+func (d *Device) ID() string {
+	return d.id
+}
+
+// Name returns the alias of the device if it has one, otherwise its
+// identifier
+func (d *Device) Name() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.alias != "" {
+		return d.alias
+	}
+	return d.id
+}
+
+// LogValue implements slog.LogValuer.
+func (d *Device) LogValue() slog.Value {
+	name := d.Name()
+	if name == d.id {
+		return slog.StringValue(d.id)
+	}
+	return slog.StringValue(fmt.Sprintf("%s (%s)", name, d.id))
+}
+
+// Room returns the Room part of the device's identifier, e.g. R1
+func (d *Device) Room() string {
+	return roomOf(d.id)
+}
+
+// roomOf returns the Room part of a Room+Device identifier
+func roomOf(id string) string {
+	room, _, _ := strings.Cut(id, "D")
+	return room
+}
+
+// DeviceState is the assumed state of a Device at a point in time
+type DeviceState struct {
+	ID         string // Room+Device identifier, e.g. R1D1
+	On         bool
+	Level      int // Dim level, or 0 if unknown
+	Confidence Confidence
+}
+
+// Confidence is how sure a Device is of its state
+type Confidence int
+
+const (
+	StateUnknown    Confidence = iota // Neither commanded nor reported since we started
+	StateOptimistic                   // Commanded by us, but not (yet) reported by the LWL
+	StateConfirmed                    // Reported by the LWL, or by the device itself
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case StateUnknown:
+		return "unknown"
+	case StateOptimistic:
+		return "optimistic"
+	case StateConfirmed:
+		return "confirmed"
+	default:
+		return fmt.Sprintf("Confidence(%d)", int(c))
+	}
+}
+
+// report is the state of a device as reported by the LWL
+type report struct {
+	on    bool
+	level int
+}
+
+// State returns the state last commanded or reported. It is confirmed if the
+// LWL has reported transmitting it, e.g. by echoing our command as a 433T
+// message, or if the device itself reported it, see SetConfirm.
+func (d *Device) State() DeviceState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := DeviceState{ID: d.id, On: d.on, Level: d.level}
+	switch {
+	case d.reported != nil && *d.reported == (report{d.on, d.level}):
+		st.Confidence = StateConfirmed
+	case d.commanded || d.reported != nil:
+		st.Confidence = StateOptimistic
+	}
+	return st
+}
+
+// setReported records that the current state has been reported. d.mu must
+// be held.
+func (d *Device) setReported() {
+	d.reported = &report{d.on, d.level}
+}
+
+// Level returns the last dim level sent to the device, or 0 if unknown
+func (d *Device) Level() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.level
+}
+
+// LockMode returns the last lock mode sent to the device. Devices are
+// assumed to be Unlocked until told otherwise.
+func (d *Device) LockMode() LockMode {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lock
+}
+
+// Lock sets the lock mode of the device, e.g. to act as a child-lock.
+//
+// Note that locked dimmers will still turn on for 5 minutes when switched
+// manually, as a safety feature.
+func (d *Device) Lock(ctx context.Context, mode LockMode) error {
+	var cmd *Command
+	switch mode {
+	case Unlocked:
+		cmd = CmdUnlock.New(d.id)
+	case LockPartial:
+		cmd = CmdLockPartial.New(d.id)
+	case LockFull:
+		cmd = CmdLockFull.New(d.id)
+	default:
+		return fmt.Errorf("unknown lock mode: %v", mode)
+	}
+	if _, err := d.c.Do(ctx, *cmd); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lock = mode
+	return nil
+}
+
+// On turns the device on. Dimmers return to their previous level.
+func (d *Device) On(ctx context.Context) error {
+	confirmed, err := d.send(ctx, CmdOn.New(d.id), true)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.on, d.commanded = true, true
+	if confirmed {
+		d.setReported()
+	}
+	d.armAutoOff()
+	return nil
+}
+
+// Off turns the device off
+func (d *Device) Off(ctx context.Context) error {
+	confirmed, err := d.send(ctx, CmdOff.New(d.id), false)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.on, d.commanded = false, true
+	if confirmed {
+		d.setReported()
+	}
+	d.cancelAutoOff()
+	return nil
+}
+
+// Dim sets the brightness of a dimmer, 1-32 (inc.)
+func (d *Device) Dim(ctx context.Context, level int) error {
+	if level < dimMin || level > dimMax {
+		return fmt.Errorf("dim level out of range %d-%d: %d", dimMin, dimMax, level)
+	}
+	if _, err := d.c.Do(ctx, *CmdSetDimmer.New(d.id, level)); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.on, d.level, d.commanded = true, level, true
+	d.armAutoOff()
+	return nil
+}
+
+// FadeTo steps a dimmer from its current level to the given level over
+// (approximately) the given duration. The LWL has no transition command of its
+// own, so this sends a CmdSetDimmer per step.
+//
+// Steps are never sent faster than the LWL can accept them; if the duration
+// is too short to visit every level, levels are skipped instead. If the
+// current level is unknown (or the device is off) the fade starts from the
+// dimmest level.
+func (d *Device) FadeTo(ctx context.Context, level int, duration time.Duration) error {
+	if level < dimMin || level > dimMax {
+		return fmt.Errorf("dim level out of range %d-%d: %d", dimMin, dimMax, level)
+	}
+
+	d.mu.Lock()
+	from := d.level
+	if !d.on || from == 0 {
+		from = dimMin
+	}
+	d.mu.Unlock()
+
+	steps, interval := fadeSteps(from, level, duration)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for i, step := range steps {
+		if err := d.Dim(ctx, step); err != nil {
+			return err
+		}
+		if i == len(steps)-1 {
+			break
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// fadeSteps returns the dim levels to visit when fading from one level to
+// another, and the interval between them. The final step is always the target
+// level.
+func fadeSteps(from, to int, duration time.Duration) ([]int, time.Duration) {
+	distance := to - from
+	direction := 1
+	if distance < 0 {
+		distance = -distance
+		direction = -1
+	}
+	if distance == 0 {
+		return []int{to}, sendInterval
+	}
+
+	// Largest number of steps we can send in the time available
+	n := distance
+	if limit := int(duration / sendInterval); limit < n {
+		n = max(limit, 1)
+	}
+
+	steps := make([]int, 0, n)
+	for i := 1; i <= n; i++ {
+		steps = append(steps, from+direction*(distance*i/n))
+	}
+
+	interval := max(duration/time.Duration(n), sendInterval)
+	return steps, interval
+}
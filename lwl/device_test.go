@@ -0,0 +1,102 @@
+package lwl
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFadeSteps(t *testing.T) {
+	table := []struct {
+		n        string        // name of the test
+		from, to int           // dim levels
+		d        time.Duration // fade duration
+		steps    []int         // Expected steps
+		interval time.Duration // Expected interval
+	}{
+		{
+			n:        `same`,
+			from:     16,
+			to:       16,
+			d:        time.Second,
+			steps:    []int{16},
+			interval: sendInterval,
+		},
+		{
+			n:        `up, slow`,
+			from:     1,
+			to:       5,
+			d:        4 * time.Second,
+			steps:    []int{2, 3, 4, 5},
+			interval: time.Second,
+		},
+		{
+			n:        `down, slow`,
+			from:     5,
+			to:       1,
+			d:        4 * time.Second,
+			steps:    []int{4, 3, 2, 1},
+			interval: time.Second,
+		},
+		{
+			n:        `up, rate limited`,
+			from:     1,
+			to:       32,
+			d:        4 * sendInterval,
+			steps:    []int{8, 16, 24, 32},
+			interval: sendInterval,
+		},
+		{
+			n:        `instant`,
+			from:     32,
+			to:       1,
+			d:        0,
+			steps:    []int{1},
+			interval: sendInterval,
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.n, func(t *testing.T) {
+			steps, interval := fadeSteps(test.from, test.to, test.d)
+			if !slices.Equal(steps, test.steps) {
+				t.Errorf("steps: want %v got %v", test.steps, steps)
+			}
+			if interval != test.interval {
+				t.Errorf("interval: want %v got %v", test.interval, interval)
+			}
+		})
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	d := NewRegistry(&fakeHub{}).Device("R1D1")
+	if got := d.State().Confidence; got != StateUnknown {
+		t.Fatalf("new device: want unknown got %v", got)
+	}
+	if err := d.Dim(t.Context(), 16); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.State().Confidence; got != StateOptimistic {
+		t.Fatalf("after dim: want optimistic got %v", got)
+	}
+	d.observe("dim", 16) // The LWL's echo
+	if got := d.State().Confidence; got != StateConfirmed {
+		t.Fatalf("after echo: want confirmed got %v", got)
+	}
+	if err := d.Off(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.State().Confidence; got != StateOptimistic {
+		t.Fatalf("after off without echo: want optimistic got %v", got)
+	}
+
+	// Echoes may arrive before the command returns
+	d.observe("on", 0)
+	if err := d.On(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.State().Confidence; got != StateConfirmed {
+		t.Fatalf("after early echo: want confirmed got %v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package lwl
+
+import (
+	"strings"
+)
+
+// Prefix of the names of per-device LatencyStats in a Client's StatsRegistry,
+// e.g. "device.R1D2"
+const deviceLatencyPrefix = "device."
+
+// target returns the device or room a command is addressed to, e.g. R1D2 or
+// R7, or "" if it has none (e.g. @H)
+func (c *Command) target() string {
+	if len(c.opts) == 0 {
+		return ""
+	}
+	id, ok := c.opts[0].(string)
+	if !ok || !idRegexp.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// DeviceLatency returns the round-trip times of commands performed with Do,
+// for each device or room they were addressed to, keyed by ID, e.g. "R1D2".
+//
+// For heating devices (868 MHz) this is the time until the device
+// acknowledged the command, including any retransmissions by the LWL, so
+// devices at the edge of its radio range stand out as slow or erratic. Other
+// devices do not acknowledge, so their times only show how long the LWL
+// took to transmit.
+func (c *Client) DeviceLatency() map[string]LatencySnapshot {
+	out := make(map[string]LatencySnapshot)
+	for _, s := range c.stats.Snapshot().Latency {
+		if id, ok := strings.CutPrefix(s.Name, deviceLatencyPrefix); ok {
+			out[id] = s
+		}
+	}
+	return out
+}
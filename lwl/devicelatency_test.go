@@ -0,0 +1,42 @@
+package lwl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestDeviceLatency(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, cmd := range []*lwl.Command{
+		lwl.CmdOn.New("R1D2"),
+		lwl.CmdOff.New("R1D2"),
+		lwl.CmdOn.New("R3D1"),
+		&lwl.CmdHubCall, // No device
+	} {
+		if _, err := c.Do(ctx, *cmd); err != nil {
+			t.Fatal(cmd, err)
+		}
+	}
+
+	got := c.DeviceLatency()
+	if len(got) != 2 || got["R1D2"].Samples != 2 || got["R3D1"].Samples != 1 {
+		t.Fatalf("want 2 samples for R1D2 and 1 for R3D1, got %+v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package lwl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SetDryRun enables or disables dry-run mode for every command performed with
+// Do. In dry-run mode commands are validated, logged and audited, and
+// reported as successful (so Device state is updated), but never transmitted.
+func (c *Client) SetDryRun(enabled bool) {
+	c.dryRun.Store(enabled)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context which causes commands performed with it to be
+// dry runs, see SetDryRun
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was made by WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	return ctx.Value(dryRunKey{}) != nil
+}
+
+// isDryRun reports whether a command performed with ctx should be a dry run
+func (c *Client) isDryRun(ctx context.Context) bool {
+	return c.dryRun.Load() || IsDryRun(ctx)
+}
+
+// validate checks that a command renders correctly, e.g. that it was given
+// the right number and type of parameters
+func (c *Command) validate() error {
+	if s := c.String(); strings.Contains(s, "%!") {
+		return fmt.Errorf("invalid parameters for command %q: %s", c.cmd, s)
+	}
+	return nil
+}
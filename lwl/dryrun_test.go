@@ -0,0 +1,34 @@
+package lwl
+
+import (
+	"context"
+	"testing"
+)
+
+// auditFunc adapts a function to an Auditor
+type auditFunc func(CommandRecord)
+
+func (f auditFunc) Audit(r CommandRecord) { f(r) }
+
+func TestDryRun(t *testing.T) {
+	c := &Client{} // Not listening, so any transmission would fail
+	var records []CommandRecord
+	c.SetAuditor(auditFunc(func(r CommandRecord) { records = append(records, r) }))
+
+	ctx := WithDryRun(context.Background())
+	d := NewDevice(c, "R1D1")
+	if err := d.Dim(ctx, 16); err != nil {
+		t.Fatal(err)
+	}
+	if st := d.State(); !st.On || st.Level != 16 {
+		t.Fatalf("want state cache to reflect dry run, got %+v", st)
+	}
+	if len(records) != 1 || records[0].Result != "dry-run" || records[0].Command != "!R1D1FdP16" {
+		t.Fatalf("want one dry-run audit record, got %+v", records)
+	}
+
+	// Still validated
+	if _, err := c.Do(ctx, *CmdOn.New()); err == nil {
+		t.Fatal("want error for missing parameter")
+	}
+}
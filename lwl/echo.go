@@ -0,0 +1,49 @@
+package lwl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How long after a Client commands a device the LWL's report of transmitting
+// to it is taken to be the echo of that command, see Response.Echo
+const echoWindow = 2 * time.Second
+
+// echoes are the devices and rooms a Client has recently commanded
+type echoes struct {
+	mu   sync.Mutex
+	sent map[string]time.Time // Device or room ID -> when last commanded
+}
+
+// note records that cmd has been sent
+func (e *echoes) note(cmd Command, now time.Time) {
+	id := cmd.target()
+	if id == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sent == nil {
+		e.sent = make(map[string]time.Time)
+	}
+	e.sent[id] = now
+}
+
+// match reports whether a 433T message reports transmitting a command
+// recently sent to its device or room
+func (e *echoes) match(r Response, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range []string{fmt.Sprintf("R%dD%d", r.Room, r.Dev), fmt.Sprintf("R%d", r.Room)} {
+		at, ok := e.sent[id]
+		if !ok {
+			continue
+		}
+		if now.Sub(at) < echoWindow {
+			return true
+		}
+		delete(e.sent, id)
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package lwl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestEcho(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	events := c.Events(t.Context())
+	next := func() lwl.Response {
+		for {
+			select {
+			case r := <-events:
+				if r.Pkt == "433T" {
+					return r
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no 433T")
+			}
+		}
+	}
+
+	if _, err := c.Do(t.Context(), *lwl.CmdOn.New("R1D2")); err != nil {
+		t.Fatal(err)
+	}
+	if r := next(); !r.Echo {
+		t.Errorf("want the report of our own command to be an echo, got %v", &r)
+	}
+
+	// e.g. a PIR
+	if err := h.Push(map[string]any{"pkt": "433T", "fn": "on", "room": 3, "dev": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if r := next(); r.Echo {
+		t.Errorf("want a sensor's report not to be an echo, got %v", &r)
+	}
+}
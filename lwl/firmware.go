@@ -0,0 +1,49 @@
+package lwl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Matches firmware versions, e.g. N2.94D
+var firmwareRegexp = regexp.MustCompile(`^([NUV])([0-9]+)\.([0-9]+)([A-Z]*)$`)
+
+// Firmware is a decoded LWL firmware version, as reported by CmdHubCall
+// (Response.Fw) or CmdRegister (?V="N2.94D").
+type Firmware struct {
+	Model string // Hardware model, "LW930" (no screen) or "LW500" (with screen)
+	Major int    // e.g. 2
+	Minor int    // e.g. 94
+	Build string // e.g. "D". Simply incremented each release, not alpha/beta/etc
+	raw   string
+}
+
+// ParseFirmware decodes a firmware version string, e.g. "N2.94D". The prefix
+// letter denotes the model: N for LW930, U or V for LW500.
+func ParseFirmware(s string) (Firmware, error) {
+	m := firmwareRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Firmware{}, fmt.Errorf("unrecognised firmware version: %q", s)
+	}
+	f := Firmware{Build: m[4], raw: s}
+	switch m[1] {
+	case "N":
+		f.Model = "LW930"
+	default:
+		f.Model = "LW500"
+	}
+	f.Major, _ = strconv.Atoi(m[2])
+	f.Minor, _ = strconv.Atoi(m[3])
+	return f, nil
+}
+
+// AtLeast reports whether the firmware is the given version or newer,
+// ignoring the build letter
+func (f Firmware) AtLeast(major, minor int) bool {
+	return f.Major > major || (f.Major == major && f.Minor >= minor)
+}
+
+func (f Firmware) String() string {
+	return f.raw
+}
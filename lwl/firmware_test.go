@@ -0,0 +1,41 @@
+package lwl_test
+
+import (
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestParseFirmware(t *testing.T) {
+	table := []struct {
+		in           string
+		model        string
+		major, minor int
+		build        string
+	}{
+		{in: "N2.94D", model: "LW930", major: 2, minor: 94, build: "D"},
+		{in: "U2.93J", model: "LW500", major: 2, minor: 93, build: "J"},
+		{in: "V2.91", model: "LW500", major: 2, minor: 91},
+	}
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			f, err := lwl.ParseFirmware(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Model != test.model || f.Major != test.major || f.Minor != test.minor || f.Build != test.build {
+				t.Fatalf("ParseFirmware(%q) = %+v", test.in, f)
+			}
+			if f.String() != test.in {
+				t.Fatalf("String() = %q, want %q", f.String(), test.in)
+			}
+		})
+	}
+
+	if _, err := lwl.ParseFirmware("2.94"); err == nil {
+		t.Fatal("ParseFirmware() should reject versions without a model prefix")
+	}
+	if f, _ := lwl.ParseFirmware("N2.94D"); !f.AtLeast(2, 92) || f.AtLeast(2, 95) || !f.AtLeast(1, 99) {
+		t.Fatal("AtLeast() compared versions incorrectly")
+	}
+}
@@ -0,0 +1,53 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Group is a user-defined collection of devices, e.g. "Downstairs". Unlike
+// CmdAllOff, which only affects a single room, a group may span rooms.
+//
+// Commands are sent to each device in turn, paced by the Client's rate
+// limiting. A failure for one device does not prevent the remaining devices
+// from being commanded.
+type Group struct {
+	Name    string
+	Devices []*Device
+}
+
+// NewGroup returns a Group of the given devices
+func NewGroup(name string, devices ...*Device) *Group {
+	return &Group{Name: name, Devices: devices}
+}
+
+// On turns on every device in the group
+func (g *Group) On(ctx context.Context) error {
+	return g.each(ctx, func(d *Device) error { return d.On(ctx) })
+}
+
+// Off turns off every device in the group
+func (g *Group) Off(ctx context.Context) error {
+	return g.each(ctx, func(d *Device) error { return d.Off(ctx) })
+}
+
+// Dim sets the brightness of every device in the group, 1-32 (inc.)
+func (g *Group) Dim(ctx context.Context, level int) error {
+	return g.each(ctx, func(d *Device) error { return d.Dim(ctx, level) })
+}
+
+// each calls fn for each device, stopping early only if ctx is done
+func (g *Group) each(ctx context.Context, fn func(*Device) error) error {
+	var errs []error
+	for _, d := range g.Devices {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := fn(d); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,60 @@
+package lwl
+
+import (
+	"strings"
+	"time"
+)
+
+// Values of Client.registered
+const (
+	registrationUnknown int32 = iota
+	registrationYes
+	registrationNo
+)
+
+// LastHeard returns when a message was last received from the LWL, or the
+// zero Time if none has been
+func (c *Client) LastHeard() time.Time {
+	ns := c.heard.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// Registered reports whether the LWL accepts commands from this host, as of
+// its most recent reply. known is false until the LWL has replied to a
+// command.
+func (c *Client) Registered() (registered, known bool) {
+	switch c.registered.Load() {
+	case registrationYes:
+		return true, true
+	case registrationNo:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func (c *Client) markHeard() {
+	c.heard.Store(time.Now().UnixNano())
+}
+
+func (c *Client) setRegistered(yes bool) {
+	if yes {
+		c.registered.Store(registrationYes)
+	} else {
+		c.registered.Store(registrationNo)
+	}
+}
+
+// trackRegistration notes whether a legacy reply shows this host is paired:
+// "OK" or a firmware version if so, or an error saying it is not
+func (c *Client) trackRegistration(payload string) {
+	switch {
+	case strings.Contains(payload, "Not yet registered"):
+		c.setRegistered(false)
+	case payload == "OK", strings.HasPrefix(payload, "?V="):
+		c.setRegistered(true)
+	}
+}
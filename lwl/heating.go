@@ -0,0 +1,48 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Range of target temperatures accepted by heating devices, in Celsius. (A
+// TRV also accepts 50-60 as valve positions, which are not temperatures.)
+const (
+	TempMin = 0.0
+	TempMax = 40.0
+)
+
+// ErrNoAck is returned when a heating device did not acknowledge a command,
+// e.g. because it is out of range or its batteries are flat
+var ErrNoAck = errors.New("heating device did not acknowledge")
+
+// FormatTemp formats a target temperature for CmdSetTarget, rounded to the
+// nearest half degree, e.g. "17" or "5.5"
+func FormatTemp(temp float64) string {
+	return strconv.FormatFloat(math.Round(temp*2)/2, 'f', -1, 64)
+}
+
+// SetTarget sets the target temperature of a heating device (e.g. "R7"),
+// returning ErrNoAck if the device did not acknowledge it
+func (c *Client) SetTarget(ctx context.Context, id string, temp float64) error {
+	return setTarget(ctx, c, id, temp)
+}
+
+// setTarget implements SetTarget via any HubClient
+func setTarget(ctx context.Context, hc HubClient, id string, temp float64) error {
+	if temp < TempMin || temp > TempMax {
+		return fmt.Errorf("target temperature out of range %v-%v: %v", TempMin, TempMax, temp)
+	}
+	r, err := hc.Do(ctx, *CmdSetTarget.New(id, FormatTemp(temp)))
+	if err != nil {
+		return err
+	}
+	// No ack at all (e.g. a dry run, or firmware without JSON) is not a failure
+	if r.Fn == "ack" && r.Status != "success" {
+		return fmt.Errorf("%s: %w", id, ErrNoAck)
+	}
+	return nil
+}
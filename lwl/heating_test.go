@@ -0,0 +1,18 @@
+package lwl
+
+import "testing"
+
+func TestFormatTemp(t *testing.T) {
+	table := map[float64]string{
+		17:    "17",
+		22.5:  "22.5",
+		5.5:   "5.5",
+		19.24: "19",
+		19.26: "19.5",
+	}
+	for in, want := range table {
+		if got := FormatTemp(in); got != want {
+			t.Errorf("%v: want %q got %q", in, want, got)
+		}
+	}
+}
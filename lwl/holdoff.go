@@ -0,0 +1,145 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrHeldOff is returned by Do for an automation's command which conflicts
+// with a recent manual command, see SetHoldOff
+var ErrHeldOff = errors.New("held off by a recent manual command")
+
+// HeldOffError is returned for a command which is held off, saying by what
+// and until when. It matches ErrHeldOff.
+type HeldOffError struct {
+	Manual string    // The manual command, as transmitted, e.g. "!R1D1F0"
+	By     string    // Its source
+	At     time.Time // When it was sent
+	Until  time.Time
+}
+
+func (e HeldOffError) Error() string {
+	return fmt.Sprintf("%v (%s by %s at %s)", ErrHeldOff, e.Manual, e.By, e.At.Format(time.TimeOnly))
+}
+
+func (e HeldOffError) Is(target error) bool {
+	return target == ErrHeldOff
+}
+
+// HoldOff is a policy which stops automations from undoing what somebody has
+// just done by hand, e.g. "I turned this light off, don't let the dusk rule
+// turn it back on for 2 hours"
+type HoldOff struct {
+	Duration  time.Duration // How long a manual command holds off automations
+	Manual    []string      // Sources of manual commands, e.g. "http", see SourceMatches
+	Automatic []string      // Sources of the automations held off, e.g. "rule"
+}
+
+// ParseSources parses a comma separated list of sources, e.g. "cli,http",
+// for use in HoldOff
+func ParseSources(s string) []string {
+	var out []string
+	for f := range strings.SplitSeq(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// SourceMatches reports whether source is want, or want followed by the name
+// of what it acted for (see WithSource), e.g. "rule:dusk" matches "rule"
+func SourceMatches(source, want string) bool {
+	rest, ok := strings.CutPrefix(source, want)
+	return ok && (rest == "" || rest[0] == ':' || rest[0] == '/')
+}
+
+func matchesAny(source string, wants []string) bool {
+	for _, w := range wants {
+		if SourceMatches(source, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdOff is the state of a HoldOff policy
+type holdOff struct {
+	HoldOff
+	mu     sync.Mutex
+	manual map[string]manualCommand // Device or room ID -> most recent
+}
+
+// manualCommand is a command sent to a device or room by a manual source
+type manualCommand struct {
+	cmd    string // As transmitted, e.g. "!R1D1F0"
+	source string
+	at     time.Time
+}
+
+// SetHoldOff applies a policy to every command performed with Do: a command
+// to a device or room from one of h.Automatic sources fails with a
+// HeldOffError if, within h.Duration, a different command was successfully
+// sent to it, or to its room, from one of h.Manual. A zero Duration disables
+// the policy.
+func (c *Client) SetHoldOff(h HoldOff) {
+	if h.Duration <= 0 {
+		c.holdOff.Store(nil)
+		return
+	}
+	c.holdOff.Store(&holdOff{HoldOff: h, manual: make(map[string]manualCommand)})
+}
+
+// checkHoldOff returns a HeldOffError if cmd should not be sent now, see
+// SetHoldOff
+func (c *Client) checkHoldOff(ctx context.Context, cmd Command) error {
+	h := c.holdOff.Load()
+	id := cmd.target()
+	if h == nil || id == "" {
+		return nil
+	}
+	source := Source(ctx)
+	if !matchesAny(source, h.Automatic) {
+		return nil
+	}
+	// A device is also held off by a command to its whole room, e.g. all off
+	ids := []string{id}
+	if room, _, ok := strings.Cut(id, "D"); ok {
+		ids = append(ids, room)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range ids {
+		last, ok := h.manual[id]
+		if !ok || last.cmd == cmd.String() || time.Since(last.at) >= h.Duration {
+			continue
+		}
+		err := HeldOffError{Manual: last.cmd, By: last.source, At: last.at, Until: last.at.Add(h.Duration)}
+		slog.Debug("Automation held off by manual command", "cmd", cmd, "source", source,
+			"manual", err.Manual, "by", err.By, "until", err.Until.Format(time.TimeOnly))
+		return err
+	}
+	return nil
+}
+
+// noteManual records cmd, if it is a manual command to a device or room which
+// succeeded, see SetHoldOff
+func (c *Client) noteManual(ctx context.Context, cmd Command) {
+	h := c.holdOff.Load()
+	id := cmd.target()
+	if h == nil || id == "" {
+		return
+	}
+	source := Source(ctx)
+	if !matchesAny(source, h.Manual) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.manual[id] = manualCommand{cmd: cmd.String(), source: source, at: time.Now()}
+}
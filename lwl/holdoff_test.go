@@ -0,0 +1,84 @@
+package lwl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestHoldOff(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+	c.SetHoldOff(lwl.HoldOff{
+		Duration:  time.Hour,
+		Manual:    lwl.ParseSources("cli, http"),
+		Automatic: lwl.ParseSources("rule"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	manual := lwl.WithSource(ctx, "http:alice")
+	rule := lwl.WithSource(ctx, "rule:dusk/scene:evening")
+	for _, tt := range []struct {
+		ctx     context.Context
+		cmd     *lwl.Command
+		heldOff bool
+		failed  bool // Rejected by the hub
+	}{
+		{rule, lwl.CmdOn.New("R1D2"), false, false},    // No manual command yet
+		{manual, lwl.CmdOff.New("R1D2"), false, false}, // Starts the hold-off
+		{rule, lwl.CmdOn.New("R1D2"), true, false},     // Conflicts
+		{rule, lwl.CmdOff.New("R1D2"), false, false},   // Agrees
+		{rule, lwl.CmdOn.New("R1D3"), false, false},    // Another device
+		{manual, lwl.CmdAllOff.New("R2"), false, false},
+		{rule, lwl.CmdOn.New("R2D1"), true, false},     // In a room turned off
+		{manual, lwl.CmdOpen.New("R3D1"), false, true}, // The hub does not understand it
+		{rule, lwl.CmdOn.New("R3D1"), false, false},    // So it does not hold off
+		{lwl.WithSource(ctx, "auto-off"), lwl.CmdOn.New("R1D2"), false, false},
+	} {
+		_, err := c.Do(tt.ctx, *tt.cmd)
+		if got := errors.Is(err, lwl.ErrHeldOff); got != tt.heldOff || (err != nil && !got) != tt.failed {
+			t.Errorf("%s from %s: %v", tt.cmd, lwl.Source(tt.ctx), err)
+		}
+	}
+
+	var heldOff lwl.HeldOffError
+	if _, err := c.Do(rule, *lwl.CmdOn.New("R1D2")); !errors.As(err, &heldOff) || heldOff.By != "http:alice" || time.Until(heldOff.Until) < 59*time.Minute {
+		t.Errorf("got %v, want held off for an hour by alice", err)
+	}
+
+	c.SetHoldOff(lwl.HoldOff{})
+	if _, err := c.Do(rule, *lwl.CmdOn.New("R1D2")); err != nil {
+		t.Errorf("hold-off disabled: %v", err)
+	}
+}
+
+func TestSourceMatches(t *testing.T) {
+	for _, tt := range []struct {
+		source, want string
+		match        bool
+	}{
+		{"rule", "rule", true},
+		{"rule:dusk", "rule", true},
+		{"rule:dusk/scene:evening", "rule:dusk", true},
+		{"rules", "rule", false},
+		{"http:alice", "rule", false},
+	} {
+		if got := lwl.SourceMatches(tt.source, tt.want); got != tt.match {
+			t.Errorf("SourceMatches(%q, %q) = %v", tt.source, tt.want, got)
+		}
+	}
+}
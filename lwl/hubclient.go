@@ -0,0 +1,42 @@
+package lwl
+
+import "context"
+
+// Size of the channel returned by Client.Events, and in low-memory mode
+const (
+	eventsBuffer       = 100
+	lowMemEventsBuffer = 16
+)
+
+// HubClient is the part of Client needed to command devices and follow what
+// the LWL reports. Code which depends on HubClient rather than *Client can be
+// tested with a fake, without a simulated LWL.
+type HubClient interface {
+	// Do sends a command and waits for the LWL's reply, see Client.Do
+	Do(ctx context.Context, cmd Command) (Response, error)
+
+	// Subscribe delivers replies to the given sequence ID (or allocates one
+	// if sid is empty), see Client.Subscribe
+	Subscribe(sid string, chr chan Response, chs chan string) string
+
+	// Unsubscribe undoes Subscribe
+	Unsubscribe(sid string)
+
+	// Events delivers every JSON message from the LWL until ctx is done
+	Events(ctx context.Context) <-chan Response
+
+	// Close releases the connection to the LWL
+	Close() error
+}
+
+var _ HubClient = (*Client)(nil)
+
+// Events is SubscribeContext with a buffer suited to long-running consumers.
+// When they fall behind, the oldest undelivered messages are dropped.
+func (c *Client) Events(ctx context.Context) <-chan Response {
+	size := eventsBuffer
+	if c.lowMemory.Load() {
+		size = lowMemEventsBuffer
+	}
+	return c.SubscribeContext(ctx, size, DropOldest)
+}
@@ -0,0 +1,52 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeHub is a HubClient which records the commands sent to it, as a
+// downstream application might in its own tests
+type fakeHub struct {
+	sent []string
+	err  error
+}
+
+func (f *fakeHub) Do(ctx context.Context, cmd Command) (Response, error) {
+	f.sent = append(f.sent, cmd.String())
+	return Response{}, f.err
+}
+
+func (f *fakeHub) Subscribe(sid string, chr chan Response, chs chan string) string { return sid }
+func (f *fakeHub) Unsubscribe(sid string)                                          {}
+func (f *fakeHub) Events(ctx context.Context) <-chan Response                      { return nil }
+func (f *fakeHub) Close() error                                                    { return nil }
+
+func TestHubClientFake(t *testing.T) {
+	f := &fakeHub{}
+	d := NewRegistry(f).Device("R1D2")
+
+	if err := d.On(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Dim(t.Context(), 16); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"!R1D2F1", "!R1D2FdP16"}; !slices.Equal(f.sent, want) {
+		t.Fatalf("want %q got %q", want, f.sent)
+	}
+	if s := d.State(); !s.On || s.Level != 16 {
+		t.Fatalf("want on at 16, got %+v", s)
+	}
+
+	// Failures leave the assumed state alone
+	f.err = errors.New("no reply")
+	if err := d.Off(t.Context()); err == nil {
+		t.Fatal("want error")
+	}
+	if !d.State().On {
+		t.Fatal("want device still assumed on")
+	}
+}
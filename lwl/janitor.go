@@ -0,0 +1,54 @@
+package lwl
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Janitor removes subscriptions awaiting replies to commands (see Send and
+// Do) which are older than ttl, until the context is done, logging each as a
+// leak along with the command it was made for. This protects long-running
+// processes from callers which forget to Unsubscribe. Other subscriptions
+// are exempt, as they may be long-lived: those made with SubscribeContext
+// are removed with their context, and those made with Subscribe are up to
+// their owner.
+func (c *Client) Janitor(ctx context.Context, ttl time.Duration) {
+	t := time.NewTicker(ttl / 2)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			c.expire(now, ttl)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// expire removes subscriptions awaiting replies made before now-ttl,
+// returning how many
+func (c *Client) expire(now time.Time, ttl time.Duration) int {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	n := 0
+	for sid, s := range c.pendingJSON {
+		if s.origin == "" || now.Sub(s.created) < ttl {
+			continue
+		}
+		slog.Warn("Subscription leaked, removing", "sid", sid, "cmd", s.origin, "age", now.Sub(s.created).Round(time.Second))
+		s.remove()
+		delete(c.pendingJSON, sid)
+		delete(c.pendingLegacy, sid)
+		n++
+	}
+	return n
+}
+
+// Subscriptions returns the number of live subscriptions. If this keeps
+// growing, something is forgetting to Unsubscribe, see Janitor.
+func (c *Client) Subscriptions() int {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	return len(c.pendingJSON)
+}
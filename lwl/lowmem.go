@@ -0,0 +1,16 @@
+package lwl
+
+// SetLowMemory shrinks the buffers the client keeps, for hosts with little
+// RAM such as a Raspberry Pi Zero: channels returned by Events hold 16
+// messages rather than 100, so a slow consumer drops messages sooner, the
+// quarantine holds 10, and String and DoLegacy no longer spew whole
+// structures. See BenchmarkLowMemory for the effect on RSS.
+func (c *Client) SetLowMemory(low bool) {
+	c.lowMemory.Store(low)
+	c.quarantine.mu.Lock()
+	defer c.quarantine.mu.Unlock()
+	c.quarantine.size = quarantineSize
+	if low {
+		c.quarantine.size = lowMemQuarantineSize
+	}
+}
@@ -0,0 +1,237 @@
+// Package lwltest provides a simulated LightwaveRF Link (LWL), and a harness
+// for testing clients against it with scripted scenarios
+package lwltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// Matches device commands, e.g. "!R1D2F1" or "!R1D2FdP16|Line 1|Line 2"
+var deviceCmdRegexp = regexp.MustCompile(`^!R([0-9]+)D([0-9]+)F([01]|dP([0-9]+))`)
+
+// Matches heating commands, e.g. "!R7F*r" or "!R7F*tP21.5"
+var heatingCmdRegexp = regexp.MustCompile(`^!R([0-9]+)F\*(r|tP([0-9.]+))`)
+
+// Matches commands to every device in a room, e.g. "!R1Fa"
+var allOffRegexp = regexp.MustCompile(`^!R([0-9]+)Fa`)
+
+// Hub simulates an LWL on the loopback interface. It replies to commands as
+// the LWL does, with a legacy acknowledgement and (where appropriate) JSON.
+type Hub struct {
+	con *net.UDPConn
+
+	mu         sync.Mutex
+	fw         string
+	registered bool
+	trans      int32
+	packet     int32
+	peer       *net.UDPAddr // Most recent client, to which pushes are sent
+	received   []string     // Commands received, without sid
+}
+
+// NewHub returns a Hub listening on an ephemeral loopback port, running
+// firmware N2.94D, with which clients are already registered. Use
+// lwl.Connect(h.Addr()) to talk to it.
+func NewHub() (*Hub, error) {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	h := &Hub{con: con, fw: "N2.94D", registered: true}
+	go h.serve()
+	return h, nil
+}
+
+// Addr returns the address commands should be sent to
+func (h *Hub) Addr() *net.UDPAddr {
+	return h.con.LocalAddr().(*net.UDPAddr)
+}
+
+// Close stops the hub
+func (h *Hub) Close() error {
+	return h.con.Close()
+}
+
+// SetFirmware changes the firmware version the hub reports, e.g. "N2.91"
+func (h *Hub) SetFirmware(fw string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fw = fw
+}
+
+// SetRegistered changes whether the hub accepts commands from clients
+func (h *Hub) SetRegistered(registered bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.registered = registered
+}
+
+// Received returns the commands received so far, without their sids
+func (h *Hub) Received() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.received...)
+}
+
+// Push sends a JSON message to the most recent client, as if a device had
+// reported something. The common fields (trans, mac and time) are added.
+func (h *Hub) Push(fields map[string]any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.peer == nil {
+		return errors.New("no client has contacted the hub yet")
+	}
+	return h.sendJSON(fields)
+}
+
+// Generate pushes copies of a message at the given rate (messages per
+// second) until the context is done or count messages have been sent (if
+// count > 0). It returns the number sent. This provides synthetic load, e.g.
+// 1000 statusPush messages per second, far beyond what a real LWL produces.
+func (h *Hub) Generate(ctx context.Context, rate, count int, fields map[string]any) (int, error) {
+	t := time.NewTicker(time.Second / time.Duration(rate))
+	defer t.Stop()
+	sent := 0
+	for count <= 0 || sent < count {
+		select {
+		case <-ctx.Done():
+			return sent, nil
+		case <-t.C:
+			if err := h.Push(fields); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (h *Hub) serve() {
+	b := make([]byte, 1024)
+	for {
+		n, addr, err := h.con.ReadFromUDP(b)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("Simulated hub failed", "err", err)
+			}
+			return
+		}
+		h.handle(string(b[:n]), addr)
+	}
+}
+
+// handle replies to a command, e.g. "3,@H" or ":205678,3,@H"
+func (h *Hub) handle(msg string, from *net.UDPAddr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	m, err := wire.UnmarshalLegacy([]byte(msg))
+	if err != nil {
+		return
+	}
+	sid, cmd := m.Seq, m.Payload
+	h.peer = from
+	h.received = append(h.received, cmd)
+
+	switch {
+	case cmd == "!F*p" && h.registered:
+		h.sendLegacy(sid, fmt.Sprintf("?V=%q", h.fw))
+		return
+	case cmd == "!F*p":
+		h.registered = true // As if the button were pressed immediately
+		h.sendLegacy(sid, `ERR,2,"Not yet registered. See LightwaveLink"`)
+		h.sendJSON(map[string]any{"type": "link", "prod": "lwl", "pairType": "local", "msg": "success", "class": "", "serial": ""})
+		return
+	case !h.registered:
+		h.sendLegacy(sid, `ERR,2,"Not yet registered. See LightwaveLink"`)
+		h.sendJSON(map[string]any{"pkt": "error", "fn": "nonRegistered", "payload": "Not yet registered. See LightwaveLink"})
+		return
+	}
+
+	// JSON responses were introduced in 2.92
+	hasJSON := !strings.HasPrefix(h.fw, "N2.91") && !strings.HasPrefix(h.fw, "N2.90")
+	send := func(fields map[string]any) {
+		if hasJSON {
+			h.sendJSON(fields)
+		}
+	}
+
+	switch m, hm := deviceCmdRegexp.FindStringSubmatch(cmd), heatingCmdRegexp.FindStringSubmatch(cmd); {
+	case cmd == "@H":
+		h.sendLegacy(sid, "OK")
+		send(map[string]any{"pkt": "system", "fn": "hubCall", "type": "hub", "prod": "lwl", "fw": h.fw, "uptime": 1000, "timeZone": 0, "ip": "127.0.0.1"})
+	case cmd == "@R":
+		h.sendLegacy(sid, "OK")
+		send(map[string]any{"pkt": "room", "fn": "summary", "stat0": 0})
+	case strings.HasPrefix(cmd, "@L"):
+		h.sendLegacy(sid, "OK")
+	case m != nil:
+		fields := map[string]any{"pkt": "433T", "fn": "on", "room": atoi(m[1]), "dev": atoi(m[2])}
+		switch {
+		case m[3] == "0":
+			fields["fn"] = "off"
+		case m[4] != "":
+			fields["fn"] = "dim"
+			fields["param"] = atoi(m[4])
+		}
+		send(fields)
+		h.sendLegacy(sid, "OK")
+	case hm != nil:
+		h.packet = (h.packet + 1) % 256
+		fields := map[string]any{"pkt": "868T", "fn": "getStatus", "room": atoi(hm[1]), "packet": h.packet}
+		if hm[2] != "r" {
+			temp, _ := strconv.ParseFloat(hm[3], 64)
+			fields["fn"] = "setTarget"
+			fields["temp"] = temp
+			fields["minutes"] = 0
+		}
+		send(fields)
+		h.sendLegacy(sid, "OK")
+		send(map[string]any{"pkt": "868R", "fn": "ack", "status": "success", "attempts": 1, "packet": h.packet})
+	case allOffRegexp.MatchString(cmd):
+		send(map[string]any{"pkt": "433T", "fn": "allOff", "room": atoi(allOffRegexp.FindStringSubmatch(cmd)[1])})
+		h.sendLegacy(sid, "OK")
+	default:
+		h.sendLegacy(sid, `ERR,1,"Unknown command"`)
+	}
+}
+
+func (h *Hub) sendLegacy(sid, payload string) {
+	h.write(string(wire.MarshalLegacy(wire.Legacy{Seq: sid, Payload: payload})) + "\r\n")
+}
+
+// sendJSON adds the common fields to a message and sends it
+func (h *Hub) sendJSON(fields map[string]any) error {
+	h.trans++
+	msg := map[string]any{"trans": h.trans, "mac": "20:3B:85", "time": time.Now().Unix()}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	b, err := wire.MarshalJSON(msg)
+	if err != nil {
+		return err
+	}
+	return h.write(string(b))
+}
+
+func (h *Hub) write(msg string) error {
+	_, err := h.con.WriteToUDP([]byte(msg), h.peer)
+	return err
+}
+
+// atoi converts digits matched by a regexp
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
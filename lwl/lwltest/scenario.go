@@ -0,0 +1,177 @@
+package lwltest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// How long expectations wait, unless given "within"
+const defaultWithin = time.Second
+
+// Scenario is a script of steps exercising a Client against a Hub. Each line
+// is one step; blank lines and lines starting with # are ignored:
+//
+//	send <command>                        Send a command, e.g. "send @H"
+//	reply <text> [within <duration>]      Expect a legacy reply to the last command, e.g. "reply OK"
+//	expect <fn> [<key>=<value>...] [within <duration>]
+//	                                      Expect a JSON message with the given fn (or pkt, or type), and fields
+//	push <JSON>                           Make the hub send a message, e.g. a statusPush
+//	hub firmware <version>                Change the hub's firmware, e.g. "hub firmware N2.91"
+//	hub registered <true|false>           Change whether the hub accepts commands
+//	sleep <duration>
+type Scenario struct {
+	Name  string
+	Steps []Step
+}
+
+// Step is one line of a Scenario
+type Step struct {
+	Line   int
+	Verb   string
+	Args   []string
+	Within time.Duration // For reply and expect
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("line %d: %s %s", s.Line, s.Verb, strings.Join(s.Args, " "))
+}
+
+// ParseScenario reads a script, see Scenario
+func ParseScenario(name string, r io.Reader) (*Scenario, error) {
+	sc := &Scenario{Name: name}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verb, rest, _ := strings.Cut(line, " ")
+		step := Step{Line: n, Verb: verb, Within: defaultWithin}
+
+		switch verb {
+		case "send", "push":
+			// Take the rest verbatim, as commands and JSON may contain spaces
+			step.Args = []string{strings.TrimSpace(rest)}
+		case "reply", "expect", "hub", "sleep":
+			step.Args = strings.Fields(rest)
+			if i := len(step.Args) - 2; i >= 0 && step.Args[i] == "within" && verb != "hub" {
+				d, err := time.ParseDuration(step.Args[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+				}
+				step.Within, step.Args = d, step.Args[:i]
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown step %q", name, n, verb)
+		}
+		if len(step.Args) == 0 || step.Args[0] == "" {
+			return nil, fmt.Errorf("%s:%d: %s needs an argument", name, n, verb)
+		}
+		sc.Steps = append(sc.Steps, step)
+	}
+	return sc, s.Err()
+}
+
+// Run performs each step against a client connected to the hub, failing the
+// test at the first step which does not go as expected
+func (sc *Scenario) Run(t testing.TB, c *lwl.Client, h *Hub) {
+	t.Helper()
+	msgs := make(chan lwl.Response, 100)
+	sid := c.Subscribe("", msgs, nil)
+	defer c.Unsubscribe(sid)
+
+	var replies chan string // To the most recent send
+	for _, step := range sc.Steps {
+		switch step.Verb {
+		case "send":
+			replies = make(chan string, 10)
+			if _, err := c.Send(step.Args[0], make(chan lwl.Response, 10), replies); err != nil {
+				t.Fatalf("%s: %v: %v", sc.Name, step, err)
+			}
+
+		case "reply":
+			if replies == nil {
+				t.Fatalf("%s: %v: nothing has been sent", sc.Name, step)
+			}
+			want := strings.Join(step.Args, " ")
+			select {
+			case got := <-replies:
+				if got = strings.TrimSpace(got); got != want {
+					t.Fatalf("%s: %v: got %q", sc.Name, step, got)
+				}
+			case <-time.After(step.Within):
+				t.Fatalf("%s: %v: timed out", sc.Name, step)
+			}
+
+		case "expect":
+			deadline := time.After(step.Within)
+		wait:
+			for {
+				select {
+				case r := <-msgs:
+					if matches(r, step.Args) {
+						break wait
+					}
+				case <-deadline:
+					t.Fatalf("%s: %v: timed out", sc.Name, step)
+				}
+			}
+
+		case "push":
+			var fields map[string]any
+			if err := json.Unmarshal([]byte(step.Args[0]), &fields); err != nil {
+				t.Fatalf("%s: %v: %v", sc.Name, step, err)
+			}
+			if err := h.Push(fields); err != nil {
+				t.Fatalf("%s: %v: %v", sc.Name, step, err)
+			}
+
+		case "hub":
+			if len(step.Args) != 2 {
+				t.Fatalf("%s: %v: want setting and value", sc.Name, step)
+			}
+			switch step.Args[0] {
+			case "firmware":
+				h.SetFirmware(step.Args[1])
+			case "registered":
+				h.SetRegistered(step.Args[1] == "true")
+			default:
+				t.Fatalf("%s: %v: unknown hub setting", sc.Name, step)
+			}
+
+		case "sleep":
+			d, err := time.ParseDuration(step.Args[0])
+			if err != nil {
+				t.Fatalf("%s: %v: %v", sc.Name, step, err)
+			}
+			time.Sleep(d)
+		}
+	}
+}
+
+// matches reports whether a message has the given fn (or pkt, or type), and
+// each of the given key=value fields
+func matches(r lwl.Response, args []string) bool {
+	if r.Fn != args[0] && r.Pkt != args[0] && r.Type != args[0] {
+		return false
+	}
+	var fields map[string]any
+	if err := wire.UnmarshalJSON([]byte(r.String()), &fields); err != nil {
+		return false
+	}
+	for _, kv := range args[1:] {
+		k, v, _ := strings.Cut(kv, "=")
+		if fmt.Sprint(fields[k]) != v {
+			return false
+		}
+	}
+	return true
+}
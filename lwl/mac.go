@@ -0,0 +1,95 @@
+package lwl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// MACAuto may be passed to SetMAC to use the MAC address of the network
+// interface this host uses to reach the LWL
+const MACAuto = "auto"
+
+// ParseMAC validates a source MAC for the message prefix, returning it in
+// the form the LWL expects: the last 3 octets, upper case, colon separated,
+// e.g. "0A:1B:2C". A full 6 octet address is also accepted.
+func ParseMAC(s string) (string, error) {
+	octets := strings.Split(s, ":")
+	if len(octets) != 3 && len(octets) != 6 {
+		return "", fmt.Errorf("invalid MAC %q, should look like 0A:1B:2C", s)
+	}
+	octets = octets[len(octets)-3:]
+	for _, o := range octets {
+		if _, err := hex.DecodeString(o); err != nil || len(o) != 2 {
+			return "", fmt.Errorf("invalid MAC %q, should look like 0A:1B:2C", s)
+		}
+	}
+	return strings.ToUpper(strings.Join(octets, ":")), nil
+}
+
+// SetMAC sets the source MAC prepended to commands (":0A:1B:2C,sid,cmd").
+// Some firmware only sends directed replies to hosts which identify
+// themselves this way. Use MACAuto to learn it from this host's network
+// interface, or "" (the default) to omit the prefix.
+func (c *Client) SetMAC(s string) error {
+	if s == MACAuto {
+		c.macAuto.Store(true)
+		addr := c.hubAddr()
+		c.learnMAC(addr.IP)
+		return nil
+	}
+	c.macAuto.Store(false)
+	if s == "" {
+		c.mac.Store(nil)
+		return nil
+	}
+	mac, err := ParseMAC(s)
+	if err != nil {
+		return err
+	}
+	c.mac.Store(&mac)
+	return nil
+}
+
+// MAC returns the source MAC prepended to commands, or "" if none
+func (c *Client) MAC() string {
+	if p := c.mac.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// learnMAC sets the source MAC from the interface used to reach hub
+func (c *Client) learnMAC(hub net.IP) {
+	hw, err := hostMAC(localIPFor(hub))
+	if err != nil {
+		slog.Warn("Unable to learn source MAC", "err", err)
+		return
+	}
+	mac, _ := ParseMAC(hw.String())
+	if old := c.mac.Swap(&mac); old == nil || *old != mac {
+		slog.Info("Source MAC", "mac", mac)
+	}
+}
+
+// hostMAC returns the hardware address of the interface with the given IP
+func hostMAC(ip net.IP) (net.HardwareAddr, error) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range ifs {
+		addrs, err := i.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) && len(i.HardwareAddr) == 6 {
+				return i.HardwareAddr, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no interface with address %v has a MAC", ip)
+}
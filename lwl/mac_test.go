@@ -0,0 +1,55 @@
+package lwl
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseMAC(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		{"0a:1b:2c", "0A:1B:2C"},
+		{"00:11:22:0a:1b:2c", "0A:1B:2C"},
+		{"0A:1B", ""},
+		{"0A:1B:2G", ""},
+		{"0A:1B:2CC", ""},
+		{"0A-1B-2C", ""},
+	}
+	for _, tt := range table {
+		got, err := ParseMAC(tt.in)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: want error got %q", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: want %q got %q, %v", tt.in, tt.want, got, err)
+		}
+	}
+}
+
+func TestSendMAC(t *testing.T) {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer con.Close()
+	c := newClient(con, *con.LocalAddr().(*net.UDPAddr))
+	if err := c.SetMAC("0a:1b:2c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Send("@H", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	n, _, err := con.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, ":0A:1B:2C,") || !strings.HasSuffix(got, ",@H") {
+		t.Errorf("want :0A:1B:2C,sid,@H got %q", got)
+	}
+}
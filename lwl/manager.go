@@ -0,0 +1,151 @@
+package lwl
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// errOtherHub is returned when a message was sent by an LWL other than the
+// one a Client talks to
+var errOtherHub = errors.New("message from another LightwaveLink")
+
+// SetHubMAC restricts the Client to the LWL with the given MAC, as reported
+// in its JSON messages (e.g. "20:3B:85"). Otherwise the Client adopts the
+// first LWL it hears from, which on a LAN with several LWLs may be the wrong
+// one; see also Manager.
+func (c *Client) SetHubMAC(mac string) {
+	mac = strings.ToUpper(mac)
+	c.hubMAC.Store(&mac)
+}
+
+// HubMAC returns the MAC of the LWL the Client talks to, or "" if not yet
+// known
+func (c *Client) HubMAC() string {
+	if p := c.hubMAC.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// fromHub reports whether r was sent by our LWL, adopting its sender as our
+// LWL if we have yet to hear from one
+func (c *Client) fromHub(r Response) bool {
+	if r.Mac == "" {
+		return true
+	}
+	mac := strings.ToUpper(r.Mac)
+	if c.hubMAC.CompareAndSwap(nil, &mac) {
+		slog.Info("LightwaveLink MAC", "mac", mac, "ip", r.Src)
+		return true
+	}
+	return c.HubMAC() == mac
+}
+
+// Manager talks to several LWLs on the same LAN. Only one process can listen
+// for replies, so the Manager listens on their behalf and routes each message
+// to a Client for the LWL which sent it, creating one as each LWL is heard
+// from.
+type Manager struct {
+	con *net.UDPConn
+
+	mu   sync.Mutex
+	hubs map[string]*Client // Keyed on LWL MAC, e.g. "20:3B:85"
+	byIP map[string]*Client // Keyed on LWL IP, for legacy messages which lack a MAC
+}
+
+// OpenManager returns a Manager, or an error if it is unable to listen for
+// LWLs
+func OpenManager() (*Manager, error) {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{Port: lwlClientPort})
+	if err != nil {
+		return nil, err
+	}
+	return newManager(con), nil
+}
+
+func newManager(con *net.UDPConn) *Manager {
+	return &Manager{
+		con:  con,
+		hubs: make(map[string]*Client),
+		byIP: make(map[string]*Client),
+	}
+}
+
+// Close stops the Manager listening, causing Listen to return. Its Clients
+// share its connection, so should not be closed themselves.
+func (m *Manager) Close() error {
+	return m.con.Close()
+}
+
+// Listen routes traffic from LWLs to their Clients, until Close is called.
+// The Clients' own Listen methods must not be used.
+func (m *Manager) Listen() {
+	var b = make([]byte, 1024)
+	for {
+		i, addr, err := m.con.ReadFromUDP(b)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			panic(err)
+		}
+		m.route(b[:i], addr)
+	}
+}
+
+// route passes a message from addr to the Client for the LWL which sent it
+func (m *Manager) route(b []byte, addr *net.UDPAddr) {
+	var peek struct {
+		Mac string `json:"mac"`
+	}
+	wire.UnmarshalJSON(b, &peek) // Errors are reported by the Client
+
+	m.mu.Lock()
+	c, ok := m.byIP[addr.IP.String()]
+	if peek.Mac != "" {
+		mac := strings.ToUpper(peek.Mac)
+		c, ok = m.hubs[mac]
+		if !ok {
+			c = newClient(m.con, net.UDPAddr{IP: addr.IP, Port: lwlServerPort})
+			c.SetHubMAC(mac)
+			m.hubs[mac] = c
+			slog.Info("Found LightwaveLink", "mac", mac, "ip", addr.IP)
+		}
+		m.byIP[addr.IP.String()] = c
+		ok = true
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		slog.Debug("Message from unknown LightwaveLink", "src", addr, "msg", string(b))
+		return
+	}
+	c.receive(b, addr)
+}
+
+// Hub returns the Client for the LWL with the given MAC, or false if it has
+// not been heard from
+func (m *Manager) Hub(mac string) (*Client, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.hubs[strings.ToUpper(mac)]
+	return c, ok
+}
+
+// Hubs returns the MACs of the LWLs heard from so far, in order
+func (m *Manager) Hubs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.hubs))
+}
@@ -0,0 +1,63 @@
+package lwl
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestClientOtherHub(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort})
+	ours := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort}
+	theirs := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 3), Port: lwlServerPort}
+
+	c.receive([]byte(`*!{"trans":1,"mac":"20:3B:85","fn":"hubCall"}`), ours)
+	c.receive([]byte(`*!{"trans":2,"mac":"20:04:96","fn":"hubCall"}`), theirs)
+	c.receive([]byte(`3,OK`), theirs)
+
+	if got := c.HubMAC(); got != "20:3B:85" {
+		t.Errorf("want hub MAC 20:3B:85 got %q", got)
+	}
+	if got := c.hubAddr(); !got.IP.Equal(ours.IP) {
+		t.Errorf("want hub address %v got %v", ours.IP, got.IP)
+	}
+}
+
+func TestManager(t *testing.T) {
+	m := newManager(nil)
+	a := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort}
+	b := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 3), Port: lwlServerPort}
+
+	m.route([]byte(`3,OK`), a) // Unknown, dropped
+	m.route([]byte(`*!{"trans":10,"mac":"20:3B:85","fn":"hubCall"}`), a)
+	m.route([]byte(`*!{"trans":10,"mac":"20:04:96","fn":"hubCall"}`), b)
+	if got, want := m.Hubs(), []string{"20:04:96", "20:3B:85"}; !slices.Equal(got, want) {
+		t.Fatalf("want hubs %v got %v", want, got)
+	}
+
+	ca, _ := m.Hub("20:3b:85")
+	cb, _ := m.Hub("20:04:96")
+	chr := ca.SubscribeContext(context.Background(), 10, DropNewest)
+	chs := make(chan string, 1)
+	ca.Subscribe("5", make(chan Response, 1), chs)
+
+	// Transaction numbers are per LWL, so b's must not be discarded as
+	// duplicates of a's
+	m.route([]byte(`*!{"trans":11,"mac":"20:3B:85","fn":"statusPush"}`), a)
+	m.route([]byte(`*!{"trans":11,"mac":"20:04:96","fn":"statusPush"}`), b)
+	m.route([]byte(`5,OK`), a)
+
+	if r := <-chr; !r.Src.Equal(a.IP) || r.Mac != "20:3B:85" {
+		t.Errorf("want message from %v got %v from %v", a.IP, &r, r.Src)
+	}
+	if len(chr) != 0 {
+		t.Errorf("want no further messages, got %d", len(chr))
+	}
+	if got := <-chs; got != "OK" {
+		t.Errorf("want legacy OK got %q", got)
+	}
+	if got := cb.tid.Load(); got != 11 {
+		t.Errorf("want b's transaction 11 got %d", got)
+	}
+}
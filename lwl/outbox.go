@@ -0,0 +1,309 @@
+package lwl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long each queued command has to be sent when the Outbox is flushed
+const outboxTimeout = 5 * time.Second
+
+// ErrQueued is returned by Outbox.Do when a command could not be sent, but
+// has been queued to send once the LWL is reachable again
+var ErrQueued = errors.New("queued until the LightwaveLink is reachable")
+
+// OutboxItem is a command waiting in an Outbox, by its name in the catalog
+// (see LookupCommand), so that it can be persisted
+type OutboxItem struct {
+	Name    string    `json:"name"` // e.g. "set_target"
+	Args    []string  `json:"args,omitempty"`
+	Queued  time.Time `json:"queued"`
+	Expires time.Time `json:"expires"` // Discarded, rather than sent, after this
+}
+
+// equal reports whether two items are the same queued command
+func (it OutboxItem) equal(other OutboxItem) bool {
+	return it.Name == other.Name && slices.Equal(it.Args, other.Args) && it.Queued.Equal(other.Queued)
+}
+
+// Outbox is a HubClient which queues commands that could not be sent because
+// the LWL was unreachable, e.g. a heating schedule change entered while it
+// was offline, and sends them when it is reachable again, see Run.
+//
+// Only commands given a maximum age are queued, so that e.g. a light toggled
+// hours ago is not switched when the LWL comes back. The queue is optionally
+// persisted to a file, so that it survives restarts.
+type Outbox struct {
+	c      *Client
+	fn     string                   // File the queue is persisted to, or ""
+	maxAge map[string]time.Duration // By catalog name, e.g. "set_target"
+
+	flushLock sync.Mutex // Held while sending queued commands, to keep them in order
+	mu        sync.Mutex
+	items     []OutboxItem
+}
+
+var _ HubClient = (*Outbox)(nil)
+
+// NewOutbox returns an Outbox sending via c, which queues the named commands
+// (e.g. "set_target") for up to the given ages. If fn is not empty, the
+// queue is persisted there, and loaded from there if it exists.
+func NewOutbox(c *Client, fn string, maxAge map[string]time.Duration) (*Outbox, error) {
+	o := &Outbox{c: c, fn: fn, maxAge: maxAge}
+	if fn == "" {
+		return o, nil
+	}
+	data, err := os.ReadFile(fn)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return o, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &o.items); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return o, nil
+}
+
+// ParseMaxAge parses a list of command=duration pairs, e.g.
+// "set_target=12h,on=1m", for NewOutbox
+func ParseMaxAge(s string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration)
+	if s == "" {
+		return out, nil
+	}
+	var errs []error
+	for item := range strings.SplitSeq(s, ",") {
+		name, after, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			errs = append(errs, fmt.Errorf("max age should look like set_target=12h, got %q", item))
+			continue
+		}
+		if _, ok := LookupCommand(name); !ok {
+			errs = append(errs, fmt.Errorf("unknown command: %q", name))
+			continue
+		}
+		d, err := time.ParseDuration(after)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("invalid max age for %s: %q", name, after))
+			continue
+		}
+		out[name] = d
+	}
+	return out, errors.Join(errs...)
+}
+
+// unreachable reports whether err, from a command sent at the given time,
+// means the command never reached the LWL, rather than the LWL rejecting it.
+// A command which timed out only counts if nothing has been heard from the
+// LWL since: if it has, the command may well have arrived with only its reply
+// lost, and sending it again could repeat it.
+func (o *Outbox) unreachable(err error, sent time.Time) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded): // Also a net.Error
+		return !o.c.LastHeard().After(sent)
+	case errors.Is(err, ErrNoHubAddr), errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+// catalogName returns the catalog name and arguments of a command, if it is
+// in the catalog
+func catalogName(cmd Command) (string, []string, bool) {
+	for _, ci := range Commands() {
+		if ci.Command.cmd != cmd.cmd || len(ci.Args) != len(cmd.opts) {
+			continue
+		}
+		args := make([]string, len(cmd.opts))
+		for i, v := range cmd.opts {
+			args[i] = fmt.Sprint(v)
+		}
+		return ci.Name, args, true
+	}
+	return "", nil, false
+}
+
+// Do sends a command via the Client. If the LWL is unreachable, and the
+// command has a maximum age, it is queued and ErrQueued is returned. It
+// replaces any queued command of the same name to the same device, which it
+// supersedes.
+func (o *Outbox) Do(ctx context.Context, cmd Command) (Response, error) {
+	sent := time.Now()
+	r, err := o.c.Do(ctx, cmd)
+	if err == nil || !o.unreachable(err, sent) || cmd.text != "" {
+		return r, err
+	}
+	name, args, ok := catalogName(cmd)
+	maxAge := o.maxAge[name]
+	if !ok || maxAge == 0 {
+		return r, err
+	}
+	now := time.Now()
+	o.mu.Lock()
+	o.items = slices.DeleteFunc(o.items, func(it OutboxItem) bool {
+		return it.Name == name && slices.Equal(it.Args[:min(1, len(it.Args))], args[:min(1, len(args))])
+	})
+	o.items = append(o.items, OutboxItem{Name: name, Args: args, Queued: now, Expires: now.Add(maxAge)})
+	saveErr := o.saveLocked()
+	o.mu.Unlock()
+	if saveErr != nil {
+		slog.Error("Failed to save outbox", "fn", o.fn, "err", saveErr)
+	}
+	slog.Warn("LightwaveLink unreachable, queued command", "cmd", cmd, "expires", now.Add(maxAge), "err", err)
+	return r, fmt.Errorf("%w: %w", ErrQueued, err)
+}
+
+// Pending returns the queued commands, oldest first
+func (o *Outbox) Pending() []OutboxItem {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxItem(nil), o.items...)
+}
+
+// Flush sends the queued commands in order, discarding those which have
+// expired or which the LWL rejects. It stops at the first which cannot be
+// sent because the LWL is still unreachable, or when the context is done
+// (e.g. on shutdown), leaving it and those after it queued.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.flushLock.Lock()
+	defer o.flushLock.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		o.mu.Lock()
+		if len(o.items) == 0 {
+			o.mu.Unlock()
+			return nil
+		}
+		it := o.items[0]
+		o.mu.Unlock()
+
+		sent := time.Now()
+		if err := o.send(ctx, it); err != nil && (ctx.Err() != nil || o.unreachable(err, sent)) {
+			return err
+		}
+
+		o.mu.Lock()
+		// Do may have superseded it meanwhile, moving those after it
+		if i := slices.IndexFunc(o.items, it.equal); i >= 0 {
+			o.items = slices.Delete(o.items, i, i+1)
+		}
+		err := o.saveLocked()
+		o.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("save outbox: %w", err)
+		}
+	}
+}
+
+// send sends a queued command, unless it has expired. Errors other than
+// the LWL being unreachable are logged, since the command is discarded
+// unless ctx is done.
+func (o *Outbox) send(ctx context.Context, it OutboxItem) error {
+	if time.Now().After(it.Expires) {
+		slog.Warn("Discarded expired command from outbox", "name", it.Name, "args", it.Args, "queued", it.Queued)
+		return nil
+	}
+	ci, ok := LookupCommand(it.Name)
+	if !ok {
+		slog.Error("Discarded unknown command from outbox", "name", it.Name)
+		return nil
+	}
+	cmd, err := ci.Build(it.Args...)
+	if err != nil {
+		slog.Error("Discarded invalid command from outbox", "err", err)
+		return nil
+	}
+	dctx, cancel := context.WithTimeout(ctx, outboxTimeout)
+	defer cancel()
+	sent := time.Now()
+	if _, err := o.c.Do(dctx, *cmd); err != nil {
+		if !o.unreachable(err, sent) && ctx.Err() == nil {
+			slog.Error("LightwaveLink failed command from outbox", "cmd", cmd, "err", err)
+		}
+		return err
+	}
+	slog.Info("Sent command from outbox", "cmd", cmd, "queued", it.Queued)
+	return nil
+}
+
+// Run flushes the queue every interval, while it is not empty, until the
+// context is done
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if len(o.Pending()) == 0 {
+				continue
+			}
+			if err := o.Flush(ctx); err != nil {
+				slog.Debug("Outbox not flushed", "pending", len(o.Pending()), "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// saveLocked persists the queue, if enabled. o.mu must be held.
+func (o *Outbox) saveLocked() error {
+	if o.fn == "" {
+		return nil
+	}
+	buf, err := json.MarshalIndent(o.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := o.fn + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.fn)
+}
+
+// Subscribe implements HubClient
+func (o *Outbox) Subscribe(sid string, chr chan Response, chs chan string) string {
+	return o.c.Subscribe(sid, chr, chs)
+}
+
+// Unsubscribe implements HubClient
+func (o *Outbox) Unsubscribe(sid string) {
+	o.c.Unsubscribe(sid)
+}
+
+// Events implements HubClient
+func (o *Outbox) Events(ctx context.Context) <-chan Response {
+	return o.c.Events(ctx)
+}
+
+// Close implements HubClient
+func (o *Outbox) Close() error {
+	return o.c.Close()
+}
+
+// SetTarget is Client.SetTarget, queueing the command if the LWL is
+// unreachable
+func (o *Outbox) SetTarget(ctx context.Context, id string, temp float64) error {
+	return setTarget(ctx, o, id, temp)
+}
+
+// ConfirmSwitch implements SwitchConfirmer
+func (o *Outbox) ConfirmSwitch(ctx context.Context, id string, on bool) error {
+	return o.c.ConfirmSwitch(ctx, id, on)
+}
@@ -0,0 +1,114 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{}) // No hub address, so unreachable
+	fn := filepath.Join(t.TempDir(), "outbox.json")
+	maxAge, err := ParseMaxAge("set_target=1h, on=1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOutbox(c, fn, maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := o.Do(t.Context(), *CmdSetTarget.New("R7", "17")); !errors.Is(err, ErrQueued) || !errors.Is(err, ErrNoHubAddr) {
+		t.Fatalf("want ErrQueued and ErrNoHubAddr, got %v", err)
+	}
+	if err := o.SetTarget(t.Context(), "R7", 18); !errors.Is(err, ErrQueued) {
+		t.Fatalf("want ErrQueued, got %v", err)
+	}
+	if err := NewDevice(o, "R1D1").Off(t.Context()); errors.Is(err, ErrQueued) {
+		t.Fatal("off has no max age, so should not be queued")
+	}
+	if err := NewDevice(o, "R1D1").On(t.Context()); !errors.Is(err, ErrQueued) {
+		t.Fatalf("want on queued, got %v", err)
+	}
+
+	// Reloaded from the file, with the second target superseding the first
+	o, err = NewOutbox(c, fn, maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, it := range o.Pending() {
+		got = append(got, append([]string{it.Name}, it.Args...))
+	}
+	want := [][]string{{"set_target", "R7", "18"}, {"on", "R1D1"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("want %q got %q", want, got)
+	}
+
+	if err := o.Flush(t.Context()); !errors.Is(err, ErrNoHubAddr) {
+		t.Fatalf("want ErrNoHubAddr while unreachable, got %v", err)
+	}
+	if n := len(o.Pending()); n != 2 {
+		t.Fatalf("want 2 still pending, got %d", n)
+	}
+
+	// Shutting down keeps the queue
+	c.SetDryRun(true) // Reachable, in effect
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := o.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want Canceled, got %v", err)
+	}
+	if n := len(o.Pending()); n != 2 {
+		t.Fatalf("want 2 still pending after cancelling, got %d", n)
+	}
+
+	o.items[1].Expires = time.Now().Add(-time.Second)
+	if err := o.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(o.Pending()); n != 0 {
+		t.Fatalf("want none pending, got %d", n)
+	}
+	if o, _ = NewOutbox(c, fn, maxAge); len(o.Pending()) != 0 {
+		t.Fatal("want empty outbox saved")
+	}
+}
+
+func TestOutboxUnreachable(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	o, err := NewOutbox(c, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := time.Now()
+	if !o.unreachable(context.DeadlineExceeded, sent) {
+		t.Error("want a timeout unreachable while the LWL is silent")
+	}
+	c.markHeard()
+	if o.unreachable(context.DeadlineExceeded, sent) {
+		t.Error("want a timeout reachable once the LWL has been heard, since it may have received the command")
+	}
+	if !o.unreachable(ErrNoHubAddr, sent) || o.unreachable(ErrNotRegistered, sent) {
+		t.Error("want only ErrNoHubAddr unreachable")
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	for _, s := range []string{"set_target", "bogus=1h", "on=-1m", "on=soon"} {
+		if _, err := ParseMaxAge(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
+
+func TestCatalogName(t *testing.T) {
+	name, args, ok := catalogName(*CmdSetDimmer.New("R1D1", 16))
+	if !ok || name != "dim" || !slices.Equal(args, []string{"R1D1", "16"}) {
+		t.Fatalf("want dim R1D1 16, got %v %q %v", name, args, ok)
+	}
+}
@@ -0,0 +1,209 @@
+package lwl
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+type overflowPolicy int
+
+const (
+	dropNewest overflowPolicy = iota
+	dropOldest
+	block
+	ring
+)
+
+// Overflow determines what happens to a message when a subscriber's channel
+// is full, see SubscribeWith. Different consumers want different trade-offs:
+// a logger may prefer the latest messages, while a controller may prefer to
+// see every message, even late.
+type Overflow struct {
+	policy overflowPolicy
+	size   int // Of the ring
+}
+
+var (
+	// DropNewest discards the message which does not fit. This is the
+	// default, used by Subscribe.
+	DropNewest = Overflow{policy: dropNewest}
+
+	// DropOldest discards the oldest message in the channel to make room
+	DropOldest = Overflow{policy: dropOldest}
+
+	// Block waits until there is room. This delays delivery to every other
+	// subscriber (and the processing of further messages) until then, so
+	// the subscriber must keep up.
+	Block = Overflow{policy: block}
+)
+
+// Ring queues messages which do not fit in the channel, up to size, beyond
+// which the oldest queued message is discarded. Unlike Block, a slow
+// subscriber does not delay others. Sizes below 1 are taken to be 1.
+func Ring(size int) Overflow {
+	return Overflow{policy: ring, size: max(size, 1)}
+}
+
+func (o Overflow) String() string {
+	switch o.policy {
+	case dropNewest:
+		return "DropNewest"
+	case dropOldest:
+		return "DropOldest"
+	case block:
+		return "Block"
+	default:
+		return fmt.Sprintf("Ring(%d)", o.size)
+	}
+}
+
+// subscription delivers messages to a subscriber's channel according to its
+// Overflow policy
+type subscription struct {
+	ch       chan Response
+	overflow Overflow
+	done     chan struct{} // Closed by Unsubscribe
+	owned    bool          // Close ch when removed, see SubscribeContext
+	created  time.Time
+	origin   string // Command the subscription awaits a reply to, if any, see Janitor
+
+	// Held for reading while delivering, so that the subscription is not
+	// removed meanwhile, and nothing is delivered once it has been
+	sendMu sync.RWMutex
+	closed bool
+
+	// Ring only
+	mu    sync.Mutex
+	queue []Response
+	wake  chan struct{}
+}
+
+func newSubscription(ch chan Response, o Overflow) *subscription {
+	s := &subscription{ch: ch, overflow: o, done: make(chan struct{}), created: time.Now()}
+	if o.policy == ring && ch != nil {
+		s.wake = make(chan struct{}, 1)
+		panics.Go("subscription", s.drain)
+	}
+	return s
+}
+
+// deliver passes r to the subscriber, returning false if it was dropped
+func (s *subscription) deliver(r Response) bool {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	switch {
+	case s.closed:
+		return true // Unsubscribed since dispatch took its copy
+	case s.ch == nil:
+		return true // Only interested in legacy messages
+	case s.overflow.policy == ring:
+		return s.enqueue(r) // Even if there is room, to preserve order
+	}
+	select {
+	case s.ch <- r:
+		return true
+	default:
+	}
+
+	switch s.overflow.policy {
+	case dropOldest:
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- r:
+		default: // Consumer is racing us; give up
+		}
+		return false
+	case block:
+		select {
+		case s.ch <- r:
+			return true
+		case <-s.done:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// enqueue adds r to the ring, discarding the oldest message if it is full
+func (s *subscription) enqueue(r Response) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := true
+	if len(s.queue) >= s.overflow.size {
+		s.queue = s.queue[1:]
+		kept = false
+	}
+	s.queue = append(s.queue, r)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return kept
+}
+
+// drain feeds queued messages into the channel as room becomes available,
+// until Unsubscribe
+func (s *subscription) drain() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		r := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		s.sendMu.RLock()
+		select {
+		case s.ch <- r:
+		case <-s.done:
+			s.sendMu.RUnlock()
+			return
+		}
+		s.sendMu.RUnlock()
+	}
+}
+
+// remove stops delivery, releasing any blocked sends, and closes the channel
+// if the subscription owns it
+func (s *subscription) remove() {
+	close(s.done) // Releases blocked sends, so sendMu can be taken
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.closed = true
+	if s.owned {
+		close(s.ch)
+	}
+}
+
+// dispatch delivers r to every subscriber, outside of pendingLock so that
+// Block subscribers cannot prevent Subscribe/Unsubscribe
+func (c *Client) dispatch(r Response) {
+	c.pendingLock.Lock()
+	subs := make([]*subscription, 0, len(c.pendingJSON))
+	for _, s := range c.pendingJSON {
+		subs = append(subs, s)
+	}
+	c.pendingLock.Unlock()
+
+	for _, s := range subs {
+		if !s.deliver(r) {
+			c.dropped.Add(1)
+			slog.Debug("Subscriber too slow, dropped message", "overflow", s.overflow, "trans", r.Trans)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package lwl
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		overflow Overflow
+		want     []int32 // Transactions received, after sending 1-7 to a channel of 2
+	}{
+		{overflow: DropNewest, want: []int32{1, 2}},
+		{overflow: DropOldest, want: []int32{6, 7}},
+		{overflow: Ring(2), want: []int32{1, 2, 3, 6, 7}}, // 3 is in flight, waiting for room in the channel
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow.String(), func(t *testing.T) {
+			c := newClient(nil, net.UDPAddr{})
+			ch := make(chan Response, 2)
+			sid := c.SubscribeWith("", ch, nil, tt.overflow)
+			defer c.Unsubscribe(sid)
+
+			for i := range int32(7) {
+				c.dispatch(Response{Trans: i + 1})
+				time.Sleep(10 * time.Millisecond) // Let the ring drain
+			}
+
+			var got []int32
+		loop:
+			for {
+				select {
+				case r := <-ch:
+					got = append(got, r.Trans)
+				case <-time.After(50 * time.Millisecond):
+					break loop
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	ch := make(chan Response) // Unbuffered, so every delivery must wait
+	sid := c.SubscribeWith("", ch, nil, Block)
+
+	go func() {
+		for i := range int32(3) {
+			c.dispatch(Response{Trans: i + 1})
+		}
+	}()
+	for i := range int32(3) {
+		if r := <-ch; r.Trans != i+1 {
+			t.Fatalf("want %d got %d", i+1, r.Trans)
+		}
+	}
+
+	// Unsubscribing releases a blocked delivery
+	done := make(chan struct{})
+	go func() {
+		c.dispatch(Response{Trans: 4})
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.Unsubscribe(sid)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch still blocked after Unsubscribe")
+	}
+}
+
+func TestSubscribeContext(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.SubscribeContext(ctx, 1, Block)
+
+	// Deliveries racing with cancellation must not panic
+	go func() {
+		for i := range int32(100) {
+			c.dispatch(Response{Trans: i + 1})
+		}
+	}()
+	if r := <-ch; r.Trans != 1 {
+		t.Fatalf("want 1 got %d", r.Trans)
+	}
+	cancel()
+
+	timeout := time.After(time.Second)
+	for open := true; open; {
+		select {
+		case _, open = <-ch:
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	if n := len(c.pendingJSON); n != 0 {
+		t.Fatalf("want no subscriptions, got %d", n)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	c.subscribe("", make(chan Response, 1), nil, DropNewest, "!R1D1F1")
+	c.SubscribeContext(context.Background(), 1, DropNewest)
+	c.Subscribe("", make(chan Response, 1), nil)
+	if n := c.Subscriptions(); n != 3 {
+		t.Fatalf("want 3 subscriptions, got %d", n)
+	}
+
+	if n := c.expire(time.Now(), time.Minute); n != 0 {
+		t.Fatalf("expired %d fresh subscriptions", n)
+	}
+	if n := c.expire(time.Now().Add(time.Hour), time.Minute); n != 1 {
+		t.Fatalf("want 1 expired, got %d", n)
+	}
+	if n := c.Subscriptions(); n != 2 {
+		t.Fatalf("SubscribeContext and Subscribe subscriptions should be exempt, got %d live", n)
+	}
+}
+
+func TestOverflowUnsubscribed(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	ch := make(chan Response, 2)
+	sid := c.SubscribeWith("", ch, nil, Ring(0)) // Taken to be 1
+	c.dispatch(Response{Trans: 1})
+	if r := <-ch; r.Trans != 1 {
+		t.Fatalf("want 1 got %d", r.Trans)
+	}
+
+	c.Unsubscribe(sid)
+	c.dispatch(Response{Trans: 2})
+	select {
+	case r := <-ch:
+		t.Fatalf("delivered %d after Unsubscribe", r.Trans)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,87 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Slowest the send interval is allowed to become, see pacer
+const maxSendInterval = 2 * time.Second
+
+// Consecutive successes needed before the send interval is reduced again
+const paceRecovery = 10
+
+// Name of the send interval gauge in a Client's StatsRegistry, in
+// milliseconds
+const sendIntervalGauge = "send.interval_ms"
+
+// ErrTransmitFail is returned by Do when the LWL replies that it failed to
+// transmit a command, e.g. ERR,6,"Transmit fail", as it does when overloaded
+var ErrTransmitFail = errors.New("LightwaveLink failed to transmit")
+
+// pacer adapts the interval between commands to how the LWL copes. When it is
+// overloaded it fails to transmit, or does not reply, so each of those
+// doubles the interval (up to maxSendInterval); it then recovers by a quarter
+// after every paceRecovery successes, back to sendInterval.
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration // Zero until first slowed, meaning sendInterval
+	ok       int           // Successes since the interval last changed
+}
+
+// current returns the interval to leave after sending a command
+func (p *pacer) current() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return max(p.interval, sendInterval)
+}
+
+// record adjusts the interval after a command, returning the new interval
+// and whether it changed
+func (p *pacer) record(overloaded bool) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	was := max(p.interval, sendInterval)
+	switch {
+	case overloaded:
+		p.interval, p.ok = min(was*2, maxSendInterval), 0
+	case was > sendInterval:
+		p.ok++
+		if p.ok < paceRecovery {
+			return was, false
+		}
+		p.interval, p.ok = max(was-was/4, sendInterval), 0
+	default:
+		return was, false
+	}
+	return p.interval, p.interval != was
+}
+
+// pace adapts the send interval to the outcome of a command performed by Do.
+// ErrTransmitFail, and Do's own timeouts (ownTimeout), suggest the LWL is
+// overloaded. Other errors (e.g. an unknown command) do not, nor do timeouts
+// set by the caller, which may be too short for the LWL however it copes.
+func (c *Client) pace(outcome commandOutcome, err error, ownTimeout bool) {
+	var overloaded bool
+	switch outcome {
+	case outcomeOK:
+	case outcomeErr:
+		overloaded = errors.Is(err, ErrTransmitFail)
+	case outcomeTimeout:
+		overloaded = ownTimeout && errors.Is(err, context.DeadlineExceeded)
+	default:
+		return
+	}
+	if interval, changed := c.pacer.record(overloaded); changed {
+		slog.Info("Adjusted pace of commands to LightwaveLink", "interval", interval, "overloaded", overloaded)
+	}
+}
+
+// SendInterval returns the current interval between commands sent to the
+// LWL, which grows while it appears overloaded, see Do
+func (c *Client) SendInterval() time.Duration {
+	return c.pacer.current()
+}
@@ -0,0 +1,59 @@
+package lwl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPacer(t *testing.T) {
+	var p pacer
+	if _, changed := p.record(false); changed || p.current() != sendInterval {
+		t.Fatalf("want %v unchanged, got %v", sendInterval, p.current())
+	}
+	for _, want := range []string{"250ms", "500ms", "1s", "2s", "2s"} {
+		p.record(true)
+		if got := p.current().String(); got != want {
+			t.Fatalf("after overload: want %s got %s", want, got)
+		}
+	}
+	for i := 1; i < paceRecovery; i++ {
+		if _, changed := p.record(false); changed {
+			t.Fatalf("changed after %d successes", i)
+		}
+	}
+	if got, changed := p.record(false); !changed || got.String() != "1.5s" {
+		t.Fatalf("want 1.5s after recovery, got %v", got)
+	}
+	for range 100 * paceRecovery {
+		p.record(false)
+	}
+	if got := p.current(); got != sendInterval {
+		t.Fatalf("want %v after full recovery, got %v", sendInterval, got)
+	}
+}
+
+func TestPace(t *testing.T) {
+	table := []struct {
+		outcome commandOutcome
+		err     error
+		own     bool
+		slowed  bool
+	}{
+		{outcomeOK, nil, true, false},
+		{outcomeErr, fmt.Errorf("%w: ERR,6,\"Transmit fail\"", ErrTransmitFail), true, true},
+		{outcomeErr, fmt.Errorf("unexpected (legacy) response to command: ERR,1,\"Unknown command\""), true, false},
+		{outcomeErr, ErrNotRegistered, true, false},
+		{outcomeTimeout, context.DeadlineExceeded, true, true},
+		{outcomeTimeout, context.DeadlineExceeded, false, false}, // The caller's deadline
+		{outcomeTimeout, context.Canceled, false, false},
+		{outcomeDryRun, nil, true, false},
+	}
+	for _, tc := range table {
+		var c Client
+		c.pace(tc.outcome, tc.err, tc.own)
+		if slowed := c.SendInterval() > sendInterval; slowed != tc.slowed {
+			t.Errorf("%v %v: want slowed %v", tc.outcome, tc.err, tc.slowed)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package lwl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	pcapMagic     = 0xa1b2c3d4 // Microsecond resolution timestamps
+	pcapSnapLen   = 65535
+	pcapLinkRaw   = 101 // LINKTYPE_RAW: Packets begin with an IPv4 header
+	pcapLinkEth   = 1   // LINKTYPE_ETHERNET, as captured by e.g. tcpdump
+	ethHeaderLen  = 14
+	ipv4HeaderLen = 20
+	udpHeaderLen  = 8
+)
+
+// PcapWriter records LWL traffic as a libpcap capture file, so recordings can
+// be examined with Wireshark (and its LightwaveRF dissectors).
+//
+// Only UDP payloads are known to us, so IPv4 and UDP headers are synthesised
+// from the addresses and ports involved.
+type PcapWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	id uint16 // IPv4 identification field, incremented per packet
+}
+
+// NewPcapWriter writes a pcap file header to w, and returns a PcapWriter
+// ready to record packets
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	var hdr [24]byte
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], 2) // Version major
+	binary.LittleEndian.PutUint16(hdr[6:], 4) // Version minor
+	// thiszone and sigfigs are always zero
+	binary.LittleEndian.PutUint32(hdr[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], pcapLinkRaw)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WritePacket records a single UDP datagram
+func (p *PcapWriter) WritePacket(t time.Time, src, dst *net.UDPAddr, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.id++
+	pkt := make([]byte, ipv4HeaderLen+udpHeaderLen+len(payload))
+
+	// IPv4
+	ip := pkt[:ipv4HeaderLen]
+	ip[0] = 0x45 // Version 4, 5x32-bit words
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(pkt)))
+	binary.BigEndian.PutUint16(ip[4:], p.id)
+	ip[8] = 64 // TTL
+	ip[9] = 17 // UDP
+	copy(ip[12:16], src.IP.To4())
+	copy(ip[16:20], dst.IP.To4())
+	binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+
+	// UDP. Checksum is optional for IPv4, so left as zero
+	udp := pkt[ipv4HeaderLen : ipv4HeaderLen+udpHeaderLen]
+	binary.BigEndian.PutUint16(udp[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLen+len(payload)))
+	copy(pkt[ipv4HeaderLen+udpHeaderLen:], payload)
+
+	// Record header
+	var rec [16]byte
+	binary.LittleEndian.PutUint32(rec[0:], uint32(t.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(t.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(pkt)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(pkt)))
+
+	if _, err := p.w.Write(rec[:]); err != nil {
+		return err
+	}
+	_, err := p.w.Write(pkt)
+	return err
+}
+
+// PcapReader reads UDP datagrams from a libpcap capture file, such as one
+// written by PcapWriter or captured with tcpdump. Other packets are skipped.
+type PcapReader struct {
+	r    io.Reader
+	link uint32
+}
+
+// NewPcapReader reads a pcap file header from r, and returns a PcapReader
+// ready to read packets
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if m := binary.LittleEndian.Uint32(hdr[0:]); m != pcapMagic {
+		return nil, fmt.Errorf("not a little-endian microsecond pcap file (magic %x)", m)
+	}
+	link := binary.LittleEndian.Uint32(hdr[20:])
+	if link != pcapLinkRaw && link != pcapLinkEth {
+		return nil, fmt.Errorf("unsupported pcap link type %d", link)
+	}
+	return &PcapReader{r: r, link: link}, nil
+}
+
+// ReadPacket returns the next UDP datagram, or io.EOF at the end of the file
+func (p *PcapReader) ReadPacket() (t time.Time, src, dst *net.UDPAddr, payload []byte, err error) {
+	for {
+		var rec [16]byte
+		if _, err := io.ReadFull(p.r, rec[:]); err != nil {
+			return t, nil, nil, nil, err
+		}
+		t = time.Unix(int64(binary.LittleEndian.Uint32(rec[0:])), int64(binary.LittleEndian.Uint32(rec[4:]))*1000)
+		pkt := make([]byte, binary.LittleEndian.Uint32(rec[8:]))
+		if _, err := io.ReadFull(p.r, pkt); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF // Truncated record
+			}
+			return t, nil, nil, nil, err
+		}
+
+		if p.link == pcapLinkEth {
+			if len(pkt) < ethHeaderLen || binary.BigEndian.Uint16(pkt[12:]) != 0x0800 {
+				continue // Not IPv4
+			}
+			pkt = pkt[ethHeaderLen:]
+		}
+		if len(pkt) < ipv4HeaderLen || pkt[0]>>4 != 4 || pkt[9] != 17 {
+			continue // Not IPv4 UDP
+		}
+		ihl := int(pkt[0]&0x0f) * 4
+		if len(pkt) < ihl+udpHeaderLen {
+			continue
+		}
+		udp := pkt[ihl:]
+		src = &net.UDPAddr{IP: net.IP(pkt[12:16]), Port: int(binary.BigEndian.Uint16(udp[0:]))}
+		dst = &net.UDPAddr{IP: net.IP(pkt[16:20]), Port: int(binary.BigEndian.Uint16(udp[2:]))}
+		n := min(int(binary.BigEndian.Uint16(udp[4:])), len(udp))
+		return t, src, dst, udp[udpHeaderLen:n], nil
+	}
+}
+
+// ipv4Checksum returns the header checksum, assuming the checksum field is
+// currently zero
+func ipv4Checksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// localIPFor returns the IP address of the interface this host would use to
+// reach the given address. No packets are sent.
+func localIPFor(remote net.IP) net.IP {
+	con, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: remote, Port: lwlServerPort})
+	if err != nil {
+		return net.IPv4zero
+	}
+	defer con.Close()
+	return con.LocalAddr().(*net.UDPAddr).IP
+}
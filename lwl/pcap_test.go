@@ -0,0 +1,84 @@
+package lwl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 200), Port: lwlClientPort}
+	dst := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 71), Port: lwlServerPort}
+	payload := []byte("1,@H")
+	if err := p.WritePacket(time.Unix(1767106420, 5000), src, dst, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if len(b) != 24+16+ipv4HeaderLen+udpHeaderLen+len(payload) {
+		t.Fatalf("unexpected capture length: %d", len(b))
+	}
+	if got := binary.LittleEndian.Uint32(b[0:]); got != pcapMagic {
+		t.Fatalf("bad magic: %x", got)
+	}
+	if got := binary.LittleEndian.Uint32(b[24+4:]); got != 5 {
+		t.Fatalf("bad timestamp microseconds: %d", got)
+	}
+
+	ip := b[24+16:]
+	if ipv4Checksum(ip[:ipv4HeaderLen]) != 0 {
+		t.Fatal("IPv4 header checksum does not verify")
+	}
+	if !net.IP(ip[12:16]).Equal(src.IP) || !net.IP(ip[16:20]).Equal(dst.IP) {
+		t.Fatalf("bad addresses: %v -> %v", net.IP(ip[12:16]), net.IP(ip[16:20]))
+	}
+	udp := ip[ipv4HeaderLen:]
+	if binary.BigEndian.Uint16(udp[0:]) != lwlClientPort || binary.BigEndian.Uint16(udp[2:]) != lwlServerPort {
+		t.Fatal("bad ports")
+	}
+	if !bytes.Equal(udp[udpHeaderLen:], payload) {
+		t.Fatalf("bad payload: %q", udp[udpHeaderLen:])
+	}
+}
+
+func TestPcapReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 71), Port: lwlServerPort}
+	dst := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 200), Port: lwlClientPort}
+	when := time.Unix(1767106420, 5000)
+	for _, msg := range []string{"1,OK", "2,OK"} {
+		if err := w.WritePacket(when, src, dst, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewPcapReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1,OK", "2,OK"} {
+		got, s, d, payload, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(when) || s.String() != src.String() || d.String() != dst.String() || string(payload) != want {
+			t.Errorf("want %v %v->%v %q got %v %v->%v %q", when, src, dst, want, got, s, d, payload)
+		}
+	}
+	if _, _, _, _, err := r.ReadPacket(); err != io.EOF {
+		t.Errorf("want EOF got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package lwl
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+// Packets queued per Proxy endpoint, beyond which they are dropped
+const proxyQueue = 100
+
+// Proxy forwards packets, unaltered, to UDP endpoints, such as existing tools
+// (e.g. a Node-RED flow) which used to listen for the LWL themselves. Their
+// source address is this host, rather than the LWL. Each endpoint has its own
+// queue, so one which is slow or unreachable delays neither the Client nor
+// the others.
+type Proxy struct {
+	con       *net.UDPConn
+	endpoints []*proxyEndpoint
+	wg        sync.WaitGroup
+
+	mu     sync.RWMutex // Protects closed, so Write never sends on a closed queue
+	closed bool
+}
+
+// proxyEndpoint is a destination of a Proxy
+type proxyEndpoint struct {
+	addr    *net.UDPAddr
+	queue   chan []byte
+	sent    atomic.Int64
+	dropped atomic.Int64 // Queue full, or failed to send
+}
+
+// NewProxy returns a Proxy forwarding to each endpoint, of the form
+// host:port, or just a port on this host, e.g. "9762" or "nodered.local:9761".
+// Use Client.SetProxy to forward packets received from the LWL, and Close
+// when done.
+func NewProxy(endpoints ...string) (*Proxy, error) {
+	p := &Proxy{}
+	for _, e := range endpoints {
+		if e = strings.TrimSpace(e); !strings.Contains(e, ":") {
+			e = "127.0.0.1:" + e
+		}
+		addr, err := net.ResolveUDPAddr("udp4", e)
+		if err == nil && addr.Port == 0 {
+			err = errors.New("missing port")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("proxy endpoint %q: %w", e, err)
+		}
+		p.endpoints = append(p.endpoints, &proxyEndpoint{addr: addr, queue: make(chan []byte, proxyQueue)})
+	}
+	con, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	p.con = con
+	for _, e := range p.endpoints {
+		p.wg.Add(1)
+		panics.Go("proxy", func() { p.run(e) })
+	}
+	return p, nil
+}
+
+// run sends an endpoint's queued packets, until Close
+func (p *Proxy) run(e *proxyEndpoint) {
+	defer p.wg.Done()
+	for b := range e.queue {
+		if _, err := p.con.WriteToUDP(b, e.addr); err != nil {
+			e.dropped.Add(1)
+			slog.Debug("Failed to forward packet", "dst", e.addr, "err", err)
+			continue
+		}
+		e.sent.Add(1)
+	}
+}
+
+// Write queues a copy of b for every endpoint, dropping it for those whose
+// queues are full
+func (p *Proxy) Write(b []byte) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	b = append([]byte(nil), b...) // The caller reuses its buffer
+	for _, e := range p.endpoints {
+		select {
+		case e.queue <- b:
+		default:
+			e.dropped.Add(1)
+		}
+	}
+}
+
+// Close stops forwarding, once queued packets have been sent
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	for _, e := range p.endpoints {
+		close(e.queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return p.con.Close()
+}
+
+// String returns the packets sent to, and dropped for, each endpoint
+func (p *Proxy) String() string {
+	s := make([]string, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		s = append(s, fmt.Sprintf("Proxy %v: sent %d, dropped %d", e.addr, e.sent.Load(), e.dropped.Load()))
+	}
+	return strings.Join(s, "\n")
+}
+
+// SetProxy forwards every packet received from the LWL to p. Use nil to
+// stop.
+func (c *Client) SetProxy(p *Proxy) {
+	c.proxy.Store(p)
+}
+
+// forward passes a received packet to the Proxy, if any
+func (c *Client) forward(b []byte) {
+	if p := c.proxy.Load(); p != nil {
+		p.Write(b)
+	}
+}
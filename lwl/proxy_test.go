@@ -0,0 +1,61 @@
+package lwl
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	var consumers []*net.UDPConn
+	var endpoints []string
+	for range 2 {
+		con, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer con.Close()
+		consumers = append(consumers, con)
+		endpoints = append(endpoints, con.LocalAddr().String())
+	}
+
+	for _, e := range []string{"nonsense", "localhost:", "70000"} {
+		if _, err := NewProxy(e); err == nil {
+			t.Errorf("NewProxy(%q) should fail", e)
+		}
+	}
+	// A bare port is on this host
+	_, port, _ := net.SplitHostPort(endpoints[1])
+	endpoints[1] = port
+	p, err := NewProxy(endpoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(nil, net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort})
+	c.SetProxy(p)
+
+	msg := []byte(`*!{"trans":1,"mac":"20:3B:85","fn":"hubCall"}`)
+	c.receive(msg, &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort})
+	copy(msg, "XXXX") // Proxy must have taken a copy
+
+	b := make([]byte, 1024)
+	for _, con := range consumers {
+		con.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := con.ReadFromUDP(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b[:n]); !strings.HasPrefix(got, `*!{"trans":1`) {
+			t.Errorf("%v received %q", con.LocalAddr(), got)
+		}
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p.Write(msg) // Ignored once closed
+	if got := p.String(); strings.Count(got, "sent 1, dropped 0") != 2 {
+		t.Errorf("stats = %q", got)
+	}
+}
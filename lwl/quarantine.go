@@ -0,0 +1,144 @@
+package lwl
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl/wire"
+)
+
+// How many messages the quarantine holds, see Quarantine, and in low-memory
+// mode
+const (
+	quarantineSize       = 100
+	lowMemQuarantineSize = 10
+)
+
+// Most messages kept for each reason, see QuarantinedMessage
+const quarantineSamples = 3
+
+// QuarantinedMessage is a message from the LWL which could not be parsed, or
+// which this package does not fully understand, with others like it: of the
+// same pkt, fn and field names, quarantined for the same kind of reason
+type QuarantinedMessage struct {
+	Time   time.Time `json:"time"`   // When the first was received
+	Reason string    `json:"reason"` // Of the first, e.g. "unknown pkt/fn: room/delete"
+	Msgs   []string  `json:"msgs"`   // The first few and the most recent, as received, with MACs anonymised
+	Count  int       `json:"count"`  // Times received
+	key    string
+}
+
+// quarantine holds the most recent distinct QuarantinedMessages, see
+// Client.Quarantine
+type quarantine struct {
+	mu   sync.Mutex
+	msgs []QuarantinedMessage // Oldest first
+	size int                  // Most held, or quarantineSize if 0
+}
+
+// Matches the MAC of the LWL in a JSON message, and whole MACs anywhere, see
+// anonymise
+var (
+	macFieldRegexp = regexp.MustCompile(`"mac":"[^"]*"`)
+	macRegexp      = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b`)
+)
+
+// anonymise replaces MACs in a message, including the LWL's own (e.g.
+// 20:3B:85) if known, so that it may be shared
+func anonymise(msg, hubMAC string) string {
+	msg = macFieldRegexp.ReplaceAllString(strings.TrimSpace(msg), `"mac":"00:00:00"`)
+	msg = macRegexp.ReplaceAllString(msg, "00:00:00:00:00:00")
+	if hubMAC != "" {
+		msg = regexp.MustCompile(`(?i)`+regexp.QuoteMeta(hubMAC)).ReplaceAllString(msg, "00:00:00")
+	}
+	return msg
+}
+
+// add quarantines a message. key identifies the kind of reason, and the pkt,
+// fn and field names of the message, e.g. "invalid 868R/statusPush {batt,
+// ...}", so that messages differing only in their values are counted
+// together, and one chatty device does not crowd out the rest. A few of the
+// messages are kept, with hubMAC and other MACs anonymised.
+func (q *quarantine) add(key, reason, msg, hubMAC string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, m := range q.msgs {
+		if m.key != key {
+			continue
+		}
+		m.Count++
+		if len(m.Msgs) < quarantineSamples {
+			m.Msgs = append(m.Msgs, anonymise(msg, hubMAC))
+		} else {
+			m.Msgs[len(m.Msgs)-1] = anonymise(msg, hubMAC)
+		}
+		q.msgs[i] = m
+		return
+	}
+	size := cmp.Or(q.size, quarantineSize)
+	if len(q.msgs) >= size {
+		q.msgs = slices.Delete(q.msgs, 0, len(q.msgs)-size+1)
+	}
+	q.msgs = append(q.msgs, QuarantinedMessage{Time: time.Now(), Reason: reason, Msgs: []string{anonymise(msg, hubMAC)}, Count: 1, key: key})
+}
+
+// quarantineMsg quarantines a message from the LWL, see quarantine.add
+func (c *Client) quarantineMsg(key, reason, msg string) {
+	c.quarantine.add(key, reason, msg, c.HubMAC())
+}
+
+// fieldNames returns the names of the fields of a JSON message, sorted, e.g.
+// "fn,mac,pkt,room,time,trans"
+func fieldNames(msg string) string {
+	var fields map[string]json.RawMessage
+	if err := wire.UnmarshalJSON([]byte(msg), &fields); err != nil {
+		return ""
+	}
+	return strings.Join(slices.Sorted(maps.Keys(fields)), ",")
+}
+
+// Quarantine returns the messages from the LWL which could not be parsed, or
+// had a pkt, fn or field which this package does not know, oldest first.
+// Messages are anonymised, so that they may be shared to help support more
+// of the protocol.
+func (c *Client) Quarantine() []QuarantinedMessage {
+	c.quarantine.mu.Lock()
+	defer c.quarantine.mu.Unlock()
+	out := slices.Clone(c.quarantine.msgs)
+	for i := range out {
+		out[i].Msgs = slices.Clone(out[i].Msgs)
+	}
+	return out
+}
+
+// checkKnown quarantines a valid JSON message if its type or any of its
+// fields is not known, see messageTypes. Messages understood by a Decoder are
+// known.
+func (c *Client) checkKnown(r Response) {
+	if r.Decoder != "" {
+		return
+	}
+	names := fieldNames(r.json)
+	if _, ok := messageTypeOf(r); !ok {
+		reason := fmt.Sprintf("unknown pkt/fn: %s/%s", r.Pkt, r.Fn)
+		c.quarantineMsg(reason+" {"+names+"}", reason, r.json)
+		return
+	}
+	var unknown []string
+	for name := range strings.SplitSeq(names, ",") {
+		if _, ok := responseFields()[name]; !ok && name != "" {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		reason := fmt.Sprintf("unknown fields of %s/%s: %s", r.Pkt, r.Fn, strings.Join(unknown, ", "))
+		c.quarantineMsg(reason, reason, r.json)
+	}
+}
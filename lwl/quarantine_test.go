@@ -0,0 +1,52 @@
+package lwl
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQuarantine(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort}
+	for _, msg := range []string{
+		`*!{"trans":1,"mac":"20:3B:85","time":1767129953,"pkt":"433T","fn":"on","room":1,"dev":2}`,                // Known
+		`*!{"trans":2,"mac":"20:3B:85","time":1767129953,"pkt":"room","fn":"delete","slot":3}`,                    // Unknown fn
+		`*!{"trans":3,"mac":"20:3B:85","time":1767129953,"pkt":"room","fn":"delete","slot":4}`,                    // Counted with the above
+		`*!{"trans":4,"mac":"20:3B:85","time":1767129953,"pkt":"433T","fn":"on","room":1,"dev":2,"colour":"red"}`, // Unknown field
+		`*!{"trans":5,"mac":"20:3B:85","time":1767129953,"pkt":"433T","fn":"on","room":81,"dev":2}`,               // Invalid
+		`*!{"trans":6,"mac":"20:3B:85","time":1767129953,"pkt":"433T","fn":"on","room":82,"dev":2}`,               // Counted with the above
+		`~garbage from 20:3b:85 and AA:BB:CC:DD:EE:FF`,
+		`~more garbage`,
+		`~yet more garbage`,
+		`~the most recent garbage`,
+	} {
+		c.receive([]byte(msg), src)
+	}
+
+	got := c.Quarantine()
+	if len(got) != 4 {
+		t.Fatalf("got %d messages, want 4: %+v", len(got), got)
+	}
+	if got[0].Reason != "unknown pkt/fn: room/delete" || got[0].Count != 2 || len(got[0].Msgs) != 2 {
+		t.Errorf("unknown fn: %+v", got[0])
+	}
+	if got[1].Reason != "unknown fields of 433T/on: colour" {
+		t.Errorf("unknown field: %+v", got[1])
+	}
+	if !strings.HasPrefix(got[2].Reason, "invalid 433T/on: room 81") || got[2].Count != 2 {
+		t.Errorf("invalid: %+v", got[2])
+	}
+	want := []string{"~garbage from 00:00:00 and 00:00:00:00:00:00", "~more garbage", "~the most recent garbage"}
+	if got[3].Reason != "unparseable" || got[3].Count != 4 || !slices.Equal(got[3].Msgs, want) {
+		t.Errorf("garbage: %+v", got[3])
+	}
+	for _, m := range got {
+		for _, msg := range m.Msgs {
+			if strings.Contains(msg, "20:3B:85") {
+				t.Errorf("MAC not anonymised: %s", msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package lwl
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/meermanr/LightwaveRF-go/buildinfo"
+)
+
+// Quirks describes how a firmware version departs from the documented
+// protocol. The zero value means none, i.e. behaves as documented.
+type Quirks struct {
+	// Only legacy "sid,OK" replies are sent. JSON responses were added in
+	// 2.92, so commands which normally wait for one are satisfied by OK.
+	NoJSON bool
+
+	// The LWL is an LW930, rather than an LW500, so screen commands only
+	// change its LED and text sent with commands is ignored. Depends on the
+	// model rather than the version.
+	NoScreen bool
+}
+
+// quirksTable lists known firmware versions, newest first. Each entry applies
+// to its version and later, up to the entry before it.
+var quirksTable = []struct {
+	major, minor int
+	quirks       Quirks
+}{
+	{major: 2, minor: 92}, // Reference version, see testdata/N2.94D.txt
+	{major: 0, minor: 0, quirks: Quirks{NoJSON: true}},
+}
+
+// QuirksFor returns the quirks of a firmware version
+func QuirksFor(f Firmware) Quirks {
+	var q Quirks
+	for _, e := range quirksTable {
+		if f.AtLeast(e.major, e.minor) {
+			q = e.quirks
+			break
+		}
+	}
+	q.NoScreen = f.Model != "LW500"
+	return q
+}
+
+// Quirks returns the quirks of the LWL's firmware. Until its version is
+// known, none are assumed.
+func (c *Client) Quirks() Quirks {
+	if f, ok := c.Firmware(); ok {
+		return QuirksFor(f)
+	}
+	return Quirks{}
+}
+
+// Firmware returns the LWL's firmware version, which is detected from its
+// replies to CmdRegister and CmdHubCall, or false if not yet known
+func (c *Client) Firmware() (Firmware, bool) {
+	if f := c.fw.Load(); f != nil {
+		return *f, true
+	}
+	return Firmware{}, false
+}
+
+// SetFirmware overrides detection of the LWL's firmware version, adjusting
+// parsing and command formats to suit
+func (c *Client) SetFirmware(f Firmware) {
+	if old := c.fw.Swap(&f); old == nil || *old != f {
+		slog.Info("LightwaveLink firmware", "fw", f, "model", f.Model, "quirks", QuirksFor(f), "build", buildinfo.Get())
+	}
+}
+
+// detectFirmware updates Quirks from a firmware version seen in a reply, e.g.
+// "N2.94D" or `"N2.94D"` (quoted, as in the legacy ?V= reply)
+func (c *Client) detectFirmware(s string) {
+	f, err := ParseFirmware(strings.Trim(s, `"`))
+	if err != nil {
+		slog.Debug("Unable to detect firmware", "err", err)
+		return
+	}
+	c.SetFirmware(f)
+}
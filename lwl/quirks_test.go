@@ -0,0 +1,61 @@
+package lwl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestQuirks feeds each fixture (messages as sent by a given firmware
+// version, see testdata/README) through the client, checking each parses and
+// the firmware is detected
+func TestQuirks(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Quirks{
+		"N2.91":  {NoJSON: true, NoScreen: true},
+		"N2.94D": {NoScreen: true},
+	}
+	for _, fn := range fixtures {
+		version := strings.TrimSuffix(filepath.Base(fn), ".txt")
+		t.Run(version, func(t *testing.T) {
+			q, known := want[version]
+			if !known {
+				t.Fatalf("no expected quirks for fixture %s", fn)
+			}
+			f, err := os.Open(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			c := Client{}
+			s := bufio.NewScanner(f)
+			for s.Scan() {
+				msg := s.Text()
+				var err error
+				if strings.HasPrefix(msg, "*") {
+					err = c.handleJSON(msg, nil)
+				} else {
+					err = c.handleLegacy(msg)
+				}
+				if err != nil {
+					t.Errorf("%s: %v", msg, err)
+				}
+			}
+			if err := s.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if c.fw.Load() == nil {
+				t.Fatal("firmware not detected")
+			}
+			if got := c.Quirks(); got != q {
+				t.Fatalf("want %+v got %+v", q, got)
+			}
+		})
+	}
+}
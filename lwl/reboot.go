@@ -0,0 +1,59 @@
+package lwl
+
+import (
+	"log/slog"
+	"time"
+)
+
+// A reboot is detected when the LWL's boot time (now, less its uptime) moves
+// later by more than this, allowing for delays in delivering its reply
+const rebootSlack = time.Minute
+
+// Name of the reboot counter in a Client's StatsRegistry
+const rebootCounter = "hub.reboots"
+
+// Reboot describes a restart of the LWL, detected from the uptime it reports
+// in reply to CmdHubCall, e.g. when polled by Rediscover
+type Reboot struct {
+	Time           time.Time     // When the reboot was detected
+	PreviousUptime time.Duration // Last uptime reported before the reboot
+	Uptime         time.Duration // First uptime reported after the reboot
+}
+
+// NotifyReboot causes a Reboot to be written to ch whenever the LWL is found
+// to have restarted, e.g. due to a flaky power supply. Writes are
+// non-blocking, so ch should be buffered.
+func (c *Client) NotifyReboot(ch chan Reboot) {
+	c.uptimeLock.Lock()
+	defer c.uptimeLock.Unlock()
+	c.rebootWatches = append(c.rebootWatches, ch)
+}
+
+// trackUptime compares the LWL's reported uptime (in seconds) with the
+// previous report, to detect reboots. This catches a reboot even if the LWL
+// has since been up longer than it was beforehand, unlike simply checking
+// whether the uptime fell.
+func (c *Client) trackUptime(seconds int32, now time.Time) {
+	uptime := time.Duration(seconds) * time.Second
+	boot := now.Add(-uptime)
+
+	c.uptimeLock.Lock()
+	defer c.uptimeLock.Unlock()
+
+	prev, prevBoot := c.uptime, c.boot
+	c.uptime, c.boot = uptime, boot
+	if prevBoot.IsZero() || !boot.After(prevBoot.Add(rebootSlack)) {
+		return
+	}
+
+	c.stats.Counter(rebootCounter).Add(1)
+	slog.Warn("LightwaveLink rebooted", "previous_uptime", prev, "uptime", uptime)
+	r := Reboot{Time: now, PreviousUptime: prev, Uptime: uptime}
+	for _, ch := range c.rebootWatches {
+		// Non-blocking write to channel
+		select {
+		case ch <- r:
+		default:
+		}
+	}
+}
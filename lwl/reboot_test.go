@@ -0,0 +1,49 @@
+package lwl
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrackUptime(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	ch := make(chan Reboot, 10)
+	c.NotifyReboot(ch)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range []struct {
+		after  time.Duration // Since start
+		uptime int32
+	}{
+		{0, 1000},
+		{5 * time.Minute, 1302},              // 2s out, e.g. uptime rounding
+		{10 * time.Minute, 60},               // Uptime fell
+		{15 * time.Minute, 360},              // Still up
+		{5 * time.Hour, 4 * 3600},            // Rebooted, but up longer than before
+		{5*time.Hour + 5*time.Minute, 14700}, // Still up
+	} {
+		c.trackUptime(p.uptime, start.Add(p.after))
+	}
+
+	want := []Reboot{
+		{Time: start.Add(10 * time.Minute), PreviousUptime: 1302 * time.Second, Uptime: time.Minute},
+		{Time: start.Add(5 * time.Hour), PreviousUptime: 6 * time.Minute, Uptime: 4 * time.Hour},
+	}
+	for _, w := range want {
+		select {
+		case got := <-ch:
+			if got != w {
+				t.Errorf("want %+v got %+v", w, got)
+			}
+		default:
+			t.Fatalf("want %+v, got nothing", w)
+		}
+	}
+	if len(ch) != 0 {
+		t.Errorf("want no more reboots, got %+v", <-ch)
+	}
+	if n := c.StatsRegistry().Snapshot().Counters[rebootCounter]; n != 2 {
+		t.Errorf("want 2 reboots counted, got %d", n)
+	}
+}
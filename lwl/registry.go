@@ -0,0 +1,211 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matches Room or Room+Device identifiers, e.g. R1 or R1D1
+var idRegexp = regexp.MustCompile(`^R([0-9]+)(?:D([0-9]+))?$`)
+
+// Matches acceptable aliases, which are also used in topic names and URLs
+var aliasRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidID reports whether id is a well-formed Room (R1-R80) or Room+Device
+// (R1D1-R80D16) identifier.
+func ValidID(id string) bool {
+	m := idRegexp.FindStringSubmatch(id)
+	if m == nil {
+		return false
+	}
+	if room, _ := strconv.Atoi(m[1]); room < 1 || room > 80 {
+		return false
+	}
+	if m[2] == "" {
+		return true
+	}
+	dev, _ := strconv.Atoi(m[2])
+	return dev >= 1 && dev <= 16
+}
+
+// Registry holds the Devices commanded through a Client, so that the state
+// assumed for each device is shared by everything commanding it.
+type Registry struct {
+	c HubClient
+
+	mu      sync.Mutex
+	devices map[string]*Device // Room+Device identifier -> Device
+	aliases map[string]string  // Alias -> Room+Device identifier, or serial
+	serials map[string]string  // Serial -> Room the device is paired to, see Slot
+}
+
+// NewRegistry returns an empty Registry of devices commanded via c
+func NewRegistry(c HubClient) *Registry {
+	return &Registry{
+		c:       c,
+		devices: make(map[string]*Device),
+		aliases: make(map[string]string),
+		serials: make(map[string]string),
+	}
+}
+
+// Device returns the Device with the given Room+Device identifier (e.g.
+// "R1D1"), adding it to the registry if not seen before.
+func (r *Registry) Device(id string) *Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.devices[id]
+	if !ok {
+		d = NewDevice(r.c, id)
+		r.devices[id] = d
+	}
+	return d
+}
+
+// ValidAlias reports whether s may be used as an alias, see SetAlias
+func ValidAlias(s string) bool {
+	return aliasRegexp.MatchString(s) && !idRegexp.MatchString(s)
+}
+
+// SetAlias gives a device a human-friendly name, e.g. "kitchen_ceiling" for
+// R1D1, which may be used in place of its identifier. A heating device may be
+// given by serial, e.g. 24C702, in which case the alias follows it if it is
+// re-paired into another room, see Slot.
+//
+// Aliases must be unique, may only contain letters, digits, underscores and
+// hyphens, and must not themselves look like an identifier.
+func (r *Registry) SetAlias(id, alias string) error {
+	serial := ValidSerial(id)
+	if !ValidID(id) && !serial {
+		return fmt.Errorf("invalid identifier for alias %q: %q", alias, id)
+	}
+	if !ValidAlias(alias) {
+		return fmt.Errorf("invalid alias for %s: %q", id, alias)
+	}
+	if serial {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if other, ok := r.aliases[alias]; ok && other != id {
+			return fmt.Errorf("alias %q already used by %s", alias, other)
+		}
+		maps.DeleteFunc(r.aliases, func(_, other string) bool { return other == id })
+		r.aliases[alias] = id
+		return nil
+	}
+
+	d := r.Device(id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if other, ok := r.aliases[alias]; ok && other != id {
+		return fmt.Errorf("alias %q already used by %s", alias, other)
+	}
+	r.aliases[alias] = id
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.alias != "" && d.alias != alias {
+		delete(r.aliases, d.alias)
+	}
+	d.alias = alias
+	return nil
+}
+
+// Resolve returns the Device with the given alias or identifier, or the room
+// a heating or energy device is paired to, given its serial or an alias for
+// its serial
+func (r *Registry) Resolve(name string) (*Device, error) {
+	r.mu.Lock()
+	id, ok := r.aliases[name]
+	if ok && ValidSerial(id) {
+		name = id
+		ok = false
+	}
+	if !ok {
+		id, ok = r.serials[name]
+	}
+	r.mu.Unlock()
+	if ok {
+		return r.Device(id), nil
+	}
+	if ValidSerial(name) {
+		return nil, fmt.Errorf("room of device %s not known yet", name)
+	}
+	if !ValidID(name) {
+		return nil, fmt.Errorf("unknown device: %q", name)
+	}
+	return r.Device(name), nil
+}
+
+// Devices returns every device in the registry, ordered by identifier
+func (r *Registry) Devices() []*Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]*Device, 0, len(r.devices))
+	for _, d := range r.devices {
+		out = append(out, d)
+	}
+	slices.SortFunc(out, func(a, b *Device) int {
+		return strings.Compare(a.id, b.id)
+	})
+	return out
+}
+
+// Snapshot is the assumed state of the devices in a room, see
+// Registry.Snapshot
+type Snapshot []DeviceState
+
+// Snapshot captures the assumed state of the devices in a room (e.g. "R1"),
+// for later use with Restore. Devices whose state has been neither commanded
+// nor reported are left out, as they would otherwise be restored to off.
+//
+// This is a software alternative to CmdMoodStore: it is not limited to five
+// slots, and can be restored via any hub.
+func (r *Registry) Snapshot(room string) Snapshot {
+	var out Snapshot
+	for _, d := range r.Devices() {
+		if d.Room() != room {
+			continue
+		}
+		if st := d.State(); st.Confidence != StateUnknown {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// Restore commands the devices in a room to the states captured by Snapshot.
+// Devices in the snapshot which are not in the given room, or whose state was
+// unknown, are ignored.
+func (r *Registry) Restore(ctx context.Context, room string, snap Snapshot) error {
+	var errs []error
+	for _, s := range snap {
+		if roomOf(s.ID) != room || s.Confidence == StateUnknown {
+			continue
+		}
+		d := r.Device(s.ID)
+
+		var err error
+		switch {
+		case !s.On:
+			err = d.Off(ctx)
+		case s.Level > 0:
+			err = d.Dim(ctx, s.Level)
+		default:
+			err = d.On(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,142 @@
+package lwl
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestRegistrySnapshot(t *testing.T) {
+	r := NewRegistry(nil)
+
+	commanded := func(id string, on bool, level int) {
+		d := r.Device(id)
+		d.on, d.level, d.commanded = on, level, true
+	}
+	commanded("R1D1", true, 16)
+	commanded("R1D2", false, 0)
+	r.Device("R1D3")            // Never seen, so its state is unknown
+	commanded("R10D1", true, 0) // Not in R1, despite the prefix
+	commanded("R2D1", true, 0)
+
+	want := Snapshot{
+		{ID: "R1D1", On: true, Level: 16, Confidence: StateOptimistic},
+		{ID: "R1D2", On: false, Confidence: StateOptimistic},
+	}
+	got := r.Snapshot("R1")
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+
+	if got := r.Snapshot("R3"); len(got) != 0 {
+		t.Fatalf("want empty snapshot, got %v", got)
+	}
+}
+
+func TestRegistryRestore(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	c.SetDryRun(true)
+	r := NewRegistry(c)
+
+	snap := Snapshot{
+		{ID: "R1D1", On: true, Level: 16, Confidence: StateConfirmed},
+		{ID: "R1D2", On: false, Confidence: StateOptimistic},
+		{ID: "R1D3", Confidence: StateUnknown},
+	}
+	if err := r.Restore(context.Background(), "R1", snap); err != nil {
+		t.Fatal(err)
+	}
+	if st := r.Device("R1D1").State(); !st.On || st.Level != 16 {
+		t.Errorf("R1D1 not dimmed: %+v", st)
+	}
+	if st := r.Device("R1D2").State(); st.On || st.Confidence == StateUnknown {
+		t.Errorf("R1D2 not switched off: %+v", st)
+	}
+	if st := r.Device("R1D3").State(); st.Confidence != StateUnknown {
+		t.Errorf("R1D3 of unknown state was commanded: %+v", st)
+	}
+}
+
+func TestRegistryAliases(t *testing.T) {
+	r := NewRegistry(nil)
+
+	if err := r.SetAlias("R1D1", "kitchen_ceiling"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Resolve("kitchen_ceiling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID() != "R1D1" || d.Name() != "kitchen_ceiling" {
+		t.Fatalf("resolved wrong device: %v", d.LogValue())
+	}
+	if d, _ := r.Resolve("R1D1"); d.Name() != "kitchen_ceiling" {
+		t.Fatalf("identifier lookup lost alias: %v", d.LogValue())
+	}
+
+	bad := []struct {
+		n         string // name of the test
+		id, alias string
+	}{
+		{n: `duplicate`, id: "R1D2", alias: "kitchen_ceiling"},
+		{n: `bad id`, id: "kitchen", alias: "kitchen_wall"},
+		{n: `room out of range`, id: "R81D1", alias: "attic"},
+		{n: `device out of range`, id: "R1D17", alias: "attic"},
+		{n: `alias looks like id`, id: "R1D2", alias: "R1D3"},
+		{n: `alias has spaces`, id: "R1D2", alias: "kitchen wall"},
+	}
+	for _, test := range bad {
+		t.Run(test.n, func(t *testing.T) {
+			if err := r.SetAlias(test.id, test.alias); err == nil {
+				t.Fatalf("SetAlias(%q, %q) should have failed", test.id, test.alias)
+			}
+		})
+	}
+
+	if _, err := r.Resolve("nonexistent"); err == nil {
+		t.Fatal("Resolve of unknown alias should have failed")
+	}
+}
+
+func TestRegistrySerials(t *testing.T) {
+	r := NewRegistry(nil)
+	if _, err := r.Resolve("24C702"); err == nil {
+		t.Fatal("resolved a serial not yet seen")
+	}
+	r.learnSlot(Response{Pkt: "room", Fn: "read", Slot: 7, Serial: "24C702"})
+	if d, err := r.Resolve("24C702"); err != nil || d.ID() != "R7" {
+		t.Fatalf("got %v, %v", d, err)
+	}
+
+	// Re-paired into another room, whose previous device was unpaired
+	r.learnSlot(Response{Pkt: "room", Fn: "read", Slot: 3, Serial: "D88002"})
+	r.learnSlot(Response{Pkt: "room", Fn: "read", Slot: 3, Serial: "24C702"})
+	if room, _ := r.Slot("24C702"); room != "R3" {
+		t.Errorf("moved to %s, want R3", room)
+	}
+	if room, ok := r.Slot("D88002"); ok {
+		t.Errorf("replaced device still in %s", room)
+	}
+
+	// Aliases for serials follow the device
+	if err := r.SetAlias("24C702", "lounge_valve"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := r.Resolve("lounge_valve"); err != nil || d.ID() != "R3" {
+		t.Errorf("got %v, %v", d, err)
+	}
+	r.learnSlot(Response{Pkt: "room", Fn: "read", Slot: 5, Serial: "24C702"})
+	if d, err := r.Resolve("lounge_valve"); err != nil || d.ID() != "R5" {
+		t.Errorf("after re-pairing got %v, %v", d, err)
+	}
+	if err := r.SetAlias("D88002", "lounge_valve"); err == nil {
+		t.Error("alias reused for another serial")
+	}
+	if err := r.SetAlias("AB1234", "hall_valve"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Resolve("hall_valve"); err == nil {
+		t.Error("resolved a serial not yet seen, by alias")
+	}
+}
@@ -0,0 +1,59 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Replay re-emits the JSON messages the LWL sent in a capture (see Capture)
+// between from and to, calling emit with each in turn. Messages are paced as
+// they were originally, but speed times faster. A zero from or to leaves
+// that end of the range open.
+//
+// Emitted Responses have Replay set. Legacy replies are skipped, as they only
+// make sense to whoever sent the original command.
+func Replay(ctx context.Context, p *PcapReader, from, to time.Time, speed float64, emit func(Response)) (int, error) {
+	var c Client // Only for parsing
+	var last time.Time
+	n := 0
+	for {
+		t, src, _, payload, err := p.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if src.Port != lwlServerPort || (!from.IsZero() && t.Before(from)) {
+			continue
+		}
+		if !to.IsZero() && t.After(to) {
+			return n, nil
+		}
+		r, err := c.parseJSON(string(payload))
+		if err != nil {
+			continue
+		}
+		r.Src = src.IP
+		r.Replay = true
+
+		if !last.IsZero() && speed > 0 {
+			select {
+			case <-time.After(time.Duration(float64(t.Sub(last)) / speed)):
+			case <-ctx.Done():
+				return n, ctx.Err()
+			}
+		}
+		last = t
+		emit(r)
+		n++
+	}
+}
+
+// Replay re-emits messages from a capture to the Client's subscribers, e.g.
+// to test automation against real traffic. See the Replay function.
+func (c *Client) Replay(ctx context.Context, p *PcapReader, from, to time.Time, speed float64) (int, error) {
+	return Replay(ctx, p, from, to, speed, c.dispatch)
+}
@@ -0,0 +1,61 @@
+package lwl
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 71), Port: lwlServerPort}
+	host := &net.UDPAddr{IP: net.IPv4(192, 168, 4, 200), Port: lwlClientPort}
+	t0 := time.Unix(1767106420, 0)
+	packets := []struct {
+		src, dst *net.UDPAddr
+		at       time.Duration
+		msg      string
+	}{
+		{hub, host, 0, `*!{"trans":1,"fn":"statusPush"}`}, // Before from
+		{host, hub, time.Second, `5,@H`},                  // Sent by us
+		{hub, host, 2 * time.Second, `5,OK`},              // Legacy
+		{hub, host, 3 * time.Second, `*!{"trans":2,"fn":"hubCall"}`},
+		{hub, host, 13 * time.Second, `*!{"trans":3,"fn":"statusPush"}`},
+		{hub, host, time.Hour, `*!{"trans":4,"fn":"statusPush"}`}, // After to
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(t0.Add(p.at), p.src, p.dst, []byte(p.msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := NewPcapReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(nil, net.UDPAddr{})
+	ch := c.SubscribeContext(context.Background(), 10, DropNewest)
+	start := time.Now()
+	n, err := c.Replay(context.Background(), p, t0.Add(time.Second), t0.Add(time.Minute), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("want 2 replayed got %d", n)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("10s at 100x should take 100ms, took %v", d)
+	}
+	for _, want := range []int32{2, 3} {
+		r := <-ch
+		if r.Trans != want || !r.Replay || !r.Src.Equal(hub.IP) {
+			t.Errorf("want replay of %d from %v, got %v (replay %v) from %v", want, hub.IP, &r, r.Replay, r.Src)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNoReusePort is returned by OpenShared where the OS cannot share a port
+var ErrNoReusePort = errors.New("sharing a UDP port is not supported on this OS")
+
+// OpenShared is Open, but lets other programs on this host listen for the
+// LWL too, provided they also set SO_REUSEADDR or SO_REUSEPORT. Every
+// listener receives the LWL's broadcasts, but the OS delivers each unicast
+// reply to only one of them, so those consumers which need every message
+// should instead be sent them by SetProxy. Returns ErrNoReusePort where the
+// OS does not support sharing.
+func OpenShared() (*Client, error) {
+	lc := net.ListenConfig{Control: reusePort}
+	pc, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf(":%d", lwlClientPort))
+	if err != nil {
+		return nil, err
+	}
+	return newClient(pc.(*net.UDPConn), net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort}), nil
+}
@@ -0,0 +1,25 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestReusePort(t *testing.T) {
+	lc := net.ListenConfig{Control: reusePort}
+	a, err := lc.ListenPacket(context.Background(), "udp4", "127.0.0.1:0")
+	if errors.Is(err, ErrNoReusePort) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := lc.ListenPacket(context.Background(), "udp4", a.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("second listener on %v: %v", a.LocalAddr(), err)
+	}
+	b.Close()
+}
@@ -0,0 +1,138 @@
+package lwl
+
+import (
+	"fmt"
+)
+
+// The LWL retries 868 MHz transmissions to heating devices up to this many
+// times, until the device acknowledges
+const rfMaxAttempts = 5
+
+// Number of recent 868 MHz transmissions remembered while awaiting their
+// acknowledgement. The LWL numbers packets 0-255.
+const rfPacketsMax = 256
+
+// RFStats summarises the acknowledgements of 868 MHz transmissions to a
+// heating device, as a measure of its radio link quality. (433 MHz devices,
+// such as lights and sockets, do not acknowledge.)
+type RFStats struct {
+	Acked    int64 // Acknowledged by the device
+	Failed   int64 // Not acknowledged after rfMaxAttempts
+	Attempts int64 // Total transmissions needed for Acked packets
+}
+
+// AckRate returns the fraction of packets acknowledged, from 0 to 1
+func (s RFStats) AckRate() float64 {
+	if s.Acked+s.Failed == 0 {
+		return 0
+	}
+	return float64(s.Acked) / float64(s.Acked+s.Failed)
+}
+
+// MeanAttempts returns the mean number of transmissions needed for a packet
+// to be acknowledged. 1 is perfect; values near rfMaxAttempts suggest the
+// device is at the edge of the LWL's range.
+func (s RFStats) MeanAttempts() float64 {
+	if s.Acked == 0 {
+		return 0
+	}
+	return float64(s.Attempts) / float64(s.Acked)
+}
+
+func (s RFStats) String() string {
+	return fmt.Sprintf("Acked=%d Failed=%d AckRate=%.0f%% MeanAttempts=%.1f",
+		s.Acked, s.Failed, 100*s.AckRate(), s.MeanAttempts())
+}
+
+// trackRF records the outcome of 868 MHz transmissions. The LWL announces
+// each transmission (pkt:868T) with its room and packet number, and later
+// reports the acknowledgement (pkt:868R, fn:ack) by packet number, and
+// sometimes room.
+func (c *Client) trackRF(r Response) {
+	if !r.HasPacket() {
+		return
+	}
+	c.rfLock.Lock()
+	defer c.rfLock.Unlock()
+	if c.rfPackets == nil {
+		c.rfPackets = make(map[int32]string)
+		c.rf = make(map[string]*RFStats)
+	}
+
+	switch {
+	case r.Pkt == "868T" && r.Room > 0:
+		if len(c.rfPackets) >= rfPacketsMax {
+			clear(c.rfPackets) // Acks which never arrived
+		}
+		c.rfPackets[r.Packet] = fmt.Sprintf("R%d", r.Room)
+	case r.Pkt == "868R" && r.Fn == "ack":
+		id, ok := c.rfPackets[r.Packet]
+		if !ok {
+			return // Sent before we started listening, or by another LWL
+		}
+		if r.Room > 0 && id != fmt.Sprintf("R%d", r.Room) {
+			return // Packet number reused, e.g. after wrapping
+		}
+		delete(c.rfPackets, r.Packet)
+		s, ok := c.rf[id]
+		if !ok {
+			s = &RFStats{}
+			c.rf[id] = s
+		}
+		if r.Status == "success" {
+			s.Acked++
+			s.Attempts += int64(r.Attempts)
+		} else {
+			s.Failed++
+		}
+	}
+}
+
+// RFStats returns a copy of the radio statistics of each heating device,
+// keyed by ID, e.g. "R7"
+func (c *Client) RFStats() map[string]RFStats {
+	c.rfLock.Lock()
+	defer c.rfLock.Unlock()
+	out := make(map[string]RFStats, len(c.rf))
+	for k, v := range c.rf {
+		out[k] = *v
+	}
+	return out
+}
+
+// rfAck matches a heating command to the acknowledgement from its device,
+// rather than whichever ack arrives first. The LWL announces the
+// transmission (pkt:868T) with its room and packet number, and the ack
+// (pkt:868R, fn:ack) carries the packet number, so an ack is only taken once
+// the announcement for the command's room has been seen.
+type rfAck struct {
+	room      int
+	packet    int32
+	announced bool
+}
+
+// newRFAck returns a matcher for the ack to cmd, or nil if cmd is not
+// answered by one
+func newRFAck(cmd Command) *rfAck {
+	if cmd.pkt != "868R" || cmd.fn != "ack" || len(cmd.opts) == 0 {
+		return nil
+	}
+	id, _ := cmd.opts[0].(string)
+	var room int
+	if _, err := fmt.Sscanf(id, "R%d", &room); err != nil {
+		return nil
+	}
+	return &rfAck{room: room}
+}
+
+// matches reports whether r is the ack, noting the announcement if r is it
+func (a *rfAck) matches(r Response) bool {
+	switch {
+	case r.Pkt == "868T" && r.Room == a.room && r.HasPacket():
+		a.packet, a.announced = r.Packet, true
+	case r.Pkt == "868R" && r.Fn == "ack":
+		return a.announced && r.HasPacket() && r.Packet == a.packet &&
+			(r.Room == 0 || r.Room == a.room)
+	}
+	return false
+}
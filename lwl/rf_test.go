@@ -0,0 +1,80 @@
+package lwl
+
+import (
+	"testing"
+)
+
+func TestTrackRF(t *testing.T) {
+	c := Client{}
+	for _, r := range []Response{
+		{Pkt: "868T", Fn: "setTarget", Room: 7, Packet: 191},
+		{Pkt: "868T", Fn: "getStatus", Room: 8, Packet: 202},
+		{Pkt: "868R", Fn: "ack", Status: "success", Attempts: 1, Packet: 191},
+		{Pkt: "868R", Fn: "ack", Status: "fail", Packet: 202},
+		{Pkt: "868T", Fn: "getStatus", Room: 8, Packet: 203},
+		{Pkt: "868R", Fn: "ack", Status: "success", Attempts: 4, Packet: 203},
+		{Pkt: "868R", Fn: "ack", Status: "success", Attempts: 1, Packet: 99}, // Unknown packet
+		{Pkt: "868T", Fn: "setTarget", Room: 7, Packet: 0, packet: true},
+		{Pkt: "868R", Fn: "ack", Status: "success", Attempts: 2, Packet: 0, Room: 8, packet: true}, // Another room's
+		{Pkt: "868R", Fn: "ack", Status: "success", Attempts: 2, Packet: 0, packet: true},
+	} {
+		c.trackRF(r)
+	}
+
+	got := c.RFStats()
+	want := map[string]RFStats{
+		"R7": {Acked: 2, Attempts: 3},
+		"R8": {Acked: 1, Failed: 1, Attempts: 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s: want %v got %v", id, w, got[id])
+		}
+	}
+	if r := got["R8"].AckRate(); r != 0.5 {
+		t.Errorf("R8: want AckRate 0.5 got %v", r)
+	}
+}
+
+func TestHasPacket(t *testing.T) {
+	c := Client{}
+	for msg, want := range map[string]bool{
+		`*!{"trans":1,"pkt":"868R","fn":"ack","status":"success","attempts":1,"packet":0}`: true,
+		`*!{"trans":2,"pkt":"868R","fn":"statusPush","serial":"24C702"}`:                   false,
+	} {
+		r, err := c.parseJSON(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.HasPacket(); got != want {
+			t.Errorf("%s: want HasPacket %v got %v", msg, want, got)
+		}
+	}
+}
+
+func TestRFAck(t *testing.T) {
+	ack := newRFAck(*CmdSetTarget.New("R7", "17"))
+	if ack == nil {
+		t.Fatal("no matcher for setTarget")
+	}
+	if newRFAck(*CmdHubCall.New()) != nil {
+		t.Error("matcher for a command without an ack")
+	}
+	for _, tt := range []struct {
+		r    Response
+		want bool
+	}{
+		{Response{Pkt: "868R", Fn: "ack", Status: "success", Packet: 190}, false}, // Before our announcement
+		{Response{Pkt: "868T", Fn: "setTarget", Room: 8, Packet: 191}, false},     // Another valve's
+		{Response{Pkt: "868T", Fn: "setTarget", Room: 7, Packet: 0, packet: true}, false},
+		{Response{Pkt: "868R", Fn: "ack", Status: "success", Packet: 191}, false}, // The other valve's ack
+		{Response{Pkt: "868R", Fn: "ack", Status: "success", Packet: 0, packet: true}, true},
+	} {
+		if got := ack.matches(tt.r); got != tt.want {
+			t.Errorf("%+v: want %v got %v", tt.r, tt.want, got)
+		}
+	}
+}
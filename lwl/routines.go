@@ -0,0 +1,35 @@
+package lwl
+
+import (
+	"context"
+	"time"
+)
+
+// SunriseAlarm waits until shortly before the wake time, then ramps a dimmer
+// from its dimmest to its brightest level over the given period, reaching full
+// brightness at the wake time.
+//
+// If the ramp should already have started, it runs over whatever remains of
+// the period.
+func SunriseAlarm(ctx context.Context, d *Device, wake time.Time, period time.Duration) error {
+	if err := sleepUntil(ctx, wake.Add(-period)); err != nil {
+		return err
+	}
+	if err := d.Dim(ctx, dimMin); err != nil {
+		return err
+	}
+	return d.FadeTo(ctx, dimMax, time.Until(wake))
+}
+
+// sleepUntil blocks until the given time, or the context is done
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
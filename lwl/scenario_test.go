@@ -0,0 +1,99 @@
+package lwl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+// TestScenarios runs each script in testdata/scenarios against a simulated
+// LWL, see lwltest.Scenario
+func TestScenarios(t *testing.T) {
+	scripts, err := filepath.Glob("testdata/scenarios/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range scripts {
+		name := strings.TrimSuffix(filepath.Base(fn), ".txt")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			sc, err := lwltest.ParseScenario(fn, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := lwltest.NewHub()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+			c, err := lwl.Connect(h.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			go c.Listen()
+
+			sc.Run(t, c, h)
+		})
+	}
+}
+
+// BenchmarkListen measures the whole receive path (UDP, parsing and
+// dispatch) under synthetic load of 1000 messages per second from the
+// simulated LWL. Target: no messages lost.
+func BenchmarkListen(b *testing.B) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	// The hub only pushes to clients it has heard from
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Do(ctx, lwl.CmdHubCall); err != nil {
+		b.Fatal(err)
+	}
+
+	msgs := make(chan lwl.Response, 1000)
+	c.Subscribe("", msgs, nil)
+	received := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-msgs:
+				n++
+			case <-time.After(100 * time.Millisecond):
+				received <- n
+				return
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	push := map[string]any{"pkt": "868R", "fn": "statusPush", "prod": "valve", "serial": "24C702", "batt": 3.03, "cTemp": 19.4}
+	if _, err := h.Generate(context.Background(), 1000, b.N, push); err != nil {
+		b.Fatal(err)
+	}
+	n := <-received
+	b.StopTimer()
+	b.ReportMetric(float64(b.N-n), "lost")
+}
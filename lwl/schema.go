@@ -0,0 +1,140 @@
+package lwl
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// messageType describes one kind of JSON message sent by the LWL
+type messageType struct {
+	name         string
+	pkt, fn, typ string   // Identify the message. Empty matches anything.
+	fields       []string // JSON names of fields, besides the common ones
+	required     []string // Subset of fields which must be non-zero
+}
+
+// commonFields are in every JSON message. Validate checks them separately.
+var commonFields = []string{"trans", "mac", "time", "pkt", "fn"}
+
+// messageTypes lists the known JSON messages. The first match applies, so
+// more specific entries come first.
+var messageTypes = []messageType{
+	{name: "433T", pkt: "433T", fields: []string{"room", "dev", "param"}},
+	{name: "statusPush", fn: "statusPush", required: []string{"serial", "prod"},
+		fields: []string{"prod", "serial", "type", "batt", "ver", "state", "cTemp", "cTarg", "output", "nTarg", "nSlot", "prof"}},
+	{name: "meterData", fn: "meterData", required: []string{"serial"},
+		fields: []string{"prod", "serial", "type", "cUse", "todUse"}},
+	{name: "hubCall", fn: "hubCall", required: []string{"fw"},
+		fields: []string{"type", "prod", "fw", "uptime", "timeZone", "lat", "long", "tmrs", "evns", "macs", "ip", "devs"}},
+	{name: "ack", fn: "ack", required: []string{"status"},
+		fields: []string{"status", "attempts", "packet", "type", "payload"}},
+	{name: "868T", pkt: "868T", required: []string{"packet"},
+		fields: []string{"room", "packet", "temp", "minutes"}},
+	{name: "roomSummary", pkt: "room", fn: "summary",
+		fields: []string{"stat0", "stat1", "stat2", "stat3", "stat4", "stat5", "stat6", "stat7", "stat8", "stat9"}},
+	{name: "roomRead", pkt: "room", fn: "read", required: []string{"serial"},
+		fields: []string{"slot", "serial", "prod"}},
+	{name: "duskDawn", pkt: "duskDawn", required: []string{"duskTime", "dawnTime"},
+		fields: []string{"duskTime", "dawnTime"}},
+	{name: "nonRegistered", fn: "nonRegistered", required: []string{"payload"},
+		fields: []string{"payload"}},
+	{name: "link", typ: "link",
+		fields: []string{"type", "prod", "pairType", "msg", "class", "serial"}},
+}
+
+// matches reports whether r is of this type
+func (mt messageType) matches(r Response) bool {
+	return (mt.pkt == "" || mt.pkt == r.Pkt) &&
+		(mt.fn == "" || mt.fn == r.Fn) &&
+		(mt.typ == "" || mt.typ == r.Type)
+}
+
+// messageTypeOf returns the type of r, or false if it is not known
+func messageTypeOf(r Response) (messageType, bool) {
+	for _, mt := range messageTypes {
+		if mt.matches(r) {
+			return mt, true
+		}
+	}
+	return messageType{}, false
+}
+
+// responseFields maps the JSON names of Response's fields to their indices
+var responseFields = sync.OnceValue(func() map[string]int {
+	out := make(map[string]int)
+	t := reflect.TypeFor[Response]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			out[name] = i
+		}
+	}
+	return out
+})
+
+// field returns the value of the field of r with the given JSON name
+func (r Response) field(name string) reflect.Value {
+	return reflect.ValueOf(r).Field(responseFields()[name])
+}
+
+// Field returns the value of the field with the given JSON name, e.g.
+// "cTemp", or false if there is no such field
+func (r Response) Field(name string) (any, bool) {
+	if _, ok := responseFields()[name]; !ok {
+		return nil, false
+	}
+	return r.field(name).Interface(), true
+}
+
+// Schema returns a JSON Schema (draft 2020-12) describing the known JSON
+// messages from the LWL, as written to subscribers and logs (without the
+// "*!" prefix). Each message type is under "$defs".
+func Schema() ([]byte, error) {
+	defs := make(map[string]any, len(messageTypes))
+	var refs []any
+	for _, mt := range messageTypes {
+		props := make(map[string]any)
+		for _, name := range slices.Concat(commonFields, mt.fields) {
+			props[name] = jsonType(reflect.TypeFor[Response]().Field(responseFields()[name]).Type)
+		}
+		required := []string{"trans", "mac", "time"}
+		for _, id := range [...][2]string{{"pkt", mt.pkt}, {"fn", mt.fn}, {"type", mt.typ}} {
+			if id[1] != "" {
+				props[id[0]] = map[string]any{"const": id[1]}
+				required = append(required, id[0])
+			}
+		}
+		defs[mt.name] = map[string]any{
+			"type":       "object",
+			"properties": props,
+			"required":   append(required, mt.required...),
+		}
+		refs = append(refs, map[string]any{"$ref": "#/$defs/" + mt.name})
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "LightwaveLink JSON message",
+		"anyOf":   refs,
+		"$defs":   defs,
+	}, "", "  ")
+}
+
+// jsonType returns the JSON Schema for values of a Go type
+func jsonType(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	default:
+		return map[string]any{} // Anything, e.g. Payload
+	}
+}
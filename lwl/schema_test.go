@@ -0,0 +1,40 @@
+package lwl
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	for _, mt := range messageTypes {
+		for _, name := range append(append([]string{}, mt.fields...), mt.required...) {
+			if _, ok := responseFields()[name]; !ok {
+				t.Errorf("%s: Response has no field %q", mt.name, name)
+			}
+		}
+	}
+
+	b, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Defs map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+			Required   []string                  `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	sp := doc.Defs["statusPush"]
+	if got := sp.Properties["fn"]["const"]; got != "statusPush" {
+		t.Errorf("statusPush fn: want const statusPush got %v", got)
+	}
+	if got := sp.Properties["cTemp"]["type"]; got != "number" {
+		t.Errorf("statusPush cTemp: want number got %v", got)
+	}
+	if len(doc.Defs) != len(messageTypes) {
+		t.Errorf("want %d definitions got %d", len(messageTypes), len(doc.Defs))
+	}
+}
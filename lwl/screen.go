@@ -0,0 +1,64 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Number of characters which fit on each line of the LW500's screen
+const screenWidth = 16
+
+// ErrNoScreen is returned when screen-specific features are requested of an
+// LWL without one, i.e. an LW930
+var ErrNoScreen = errors.New("LightwaveLink has no screen")
+
+// HasScreen reports whether the LWL has a screen (i.e. is an LW500), and
+// whether that is known yet, see Firmware
+func (c *Client) HasScreen() (has, known bool) {
+	f, ok := c.Firmware()
+	return ok && !QuirksFor(f).NoScreen, ok
+}
+
+// WithText returns a copy of a device command which also displays two lines
+// of text on the screen of an LW500, e.g. the name of the device and what was
+// done to it. Lines are truncated to fit. An LW930 ignores the text.
+//
+//	->: 373,!R4D3FdP16|Side Lamp|Half Brightness
+func (c *Command) WithText(line1, line2 string) *Command {
+	out := *c
+	out.text = "|" + screenLine(line1) + "|" + screenLine(line2)
+	return &out
+}
+
+// screenLine makes s suitable for display, by removing characters which
+// would corrupt the command and truncating it to fit on a line
+func screenLine(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '|' || r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return s[:min(len(s), screenWidth)]
+}
+
+// screenTextKey is the context key for WithScreenText
+type screenTextKey struct{}
+
+// WithScreenText returns a context which causes device commands done with it
+// to display the given text on an LW500's screen, see Command.WithText
+func WithScreenText(ctx context.Context, line1, line2 string) context.Context {
+	return context.WithValue(ctx, screenTextKey{}, [2]string{line1, line2})
+}
+
+// withContextText returns cmd with the text from WithScreenText, if any, and
+// if cmd is a device command (the only kind which accepts text)
+func withContextText(ctx context.Context, cmd Command) Command {
+	lines, ok := ctx.Value(screenTextKey{}).([2]string)
+	if !ok || cmd.text != "" || !strings.HasPrefix(cmd.cmd, "!%s") {
+		return cmd
+	}
+	return *cmd.WithText(lines[0], lines[1])
+}
@@ -0,0 +1,37 @@
+package lwl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithText(t *testing.T) {
+	tests := []struct {
+		name         string
+		line1, line2 string
+		want         string
+	}{
+		{name: "Short", line1: "Side Lamp", line2: "Half Brightness", want: "!R4D3FdP16|Side Lamp|Half Brightness"},
+		{name: "Truncated", line1: "Kitchen ceiling lights", line2: "", want: "!R4D3FdP16|Kitchen ceiling |"},
+		{name: "Sanitised", line1: "a|b\nc", line2: "café", want: "!R4D3FdP16|abc|caf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CmdSetDimmer.New("R4D3", 16).WithText(tt.line1, tt.line2).String()
+			if got != tt.want {
+				t.Fatalf("want %q got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Text from the context applies to device commands only
+	ctx := WithScreenText(context.Background(), "Side Lamp", "On")
+	cmd := withContextText(ctx, *CmdOn.New("R4D3"))
+	if got, want := cmd.String(), "!R4D3F1|Side Lamp|On"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+	cmd = withContextText(ctx, CmdHubCall)
+	if got, want := cmd.String(), "@H"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
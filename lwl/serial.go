@@ -0,0 +1,47 @@
+package lwl
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// Matches the serial numbers of heating and energy devices, e.g. 24C702
+var serialRegexp = regexp.MustCompile(`^[0-9A-F]{6}$`)
+
+// ValidSerial reports whether s is a well-formed serial number of a heating
+// or energy device, e.g. "24C702"
+func ValidSerial(s string) bool {
+	return serialRegexp.MatchString(s)
+}
+
+// Slot returns the room (e.g. "R7") the heating or energy device with the
+// given serial is paired to, as last reported by the LWL, see Watch
+func (r *Registry) Slot(serial string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room, ok := r.serials[serial]
+	return room, ok
+}
+
+// learnSlot records the room a device is paired to, from a room read (see
+// Client.QueryAllRadiators). A device re-paired into another room keeps its
+// serial, so anything identifying it by serial follows it.
+func (r *Registry) learnSlot(m Response) {
+	if m.Serial == "" || m.Slot == 0 {
+		return
+	}
+	room := fmt.Sprintf("R%d", m.Slot)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if was, ok := r.serials[m.Serial]; ok && was != room {
+		slog.Info("Heating device moved to another room", "serial", m.Serial, "from", was, "to", room)
+	}
+	for serial, other := range r.serials {
+		if other == room && serial != m.Serial {
+			delete(r.serials, serial) // Unpaired, and its room reused
+		}
+	}
+	r.serials[m.Serial] = room
+}
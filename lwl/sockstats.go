@@ -0,0 +1,53 @@
+package lwl
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// SocketStats counts the traffic on a Client's UDP socket, to help
+// investigate lost packets without a packet capture
+type SocketStats struct {
+	PacketsIn   int64
+	BytesIn     int64
+	PacketsOut  int64
+	BytesOut    int64
+	ReadErrors  int64
+	WriteErrors int64
+	Drops       int64 // Discarded by the OS because the receive buffer was full, if DropsKnown
+	DropsKnown  bool  // Whether the OS reports Drops (only Linux does)
+}
+
+func (s SocketStats) String() string {
+	drops := "unknown"
+	if s.DropsKnown {
+		drops = fmt.Sprint(s.Drops)
+	}
+	return fmt.Sprintf("Socket: in=%d packets (%d bytes) out=%d packets (%d bytes) read errors=%d write errors=%d OS drops=%s",
+		s.PacketsIn, s.BytesIn, s.PacketsOut, s.BytesOut, s.ReadErrors, s.WriteErrors, drops)
+}
+
+// socketCounters accumulates SocketStats
+type socketCounters struct {
+	packetsIn, bytesIn   atomic.Int64
+	packetsOut, bytesOut atomic.Int64
+	readErrors           atomic.Int64
+	writeErrors          atomic.Int64
+}
+
+// SocketStats returns the traffic counted on the Client's socket since it
+// was opened
+func (c *Client) SocketStats() SocketStats {
+	s := SocketStats{
+		PacketsIn:   c.sock.packetsIn.Load(),
+		BytesIn:     c.sock.bytesIn.Load(),
+		PacketsOut:  c.sock.packetsOut.Load(),
+		BytesOut:    c.sock.bytesOut.Load(),
+		ReadErrors:  c.sock.readErrors.Load(),
+		WriteErrors: c.sock.writeErrors.Load(),
+	}
+	if c.con != nil {
+		s.Drops, s.DropsKnown = socketDrops(c.con)
+	}
+	return s
+}
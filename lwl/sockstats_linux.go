@@ -0,0 +1,57 @@
+package lwl
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketDrops returns the number of datagrams the kernel discarded for con,
+// from the "drops" column of /proc/net/udp (or udp6), found by the socket's
+// inode since other sockets may share its port
+func socketDrops(con *net.UDPConn) (int64, bool) {
+	rc, err := con.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var st unix.Stat_t
+	var serr error
+	if err := rc.Control(func(fd uintptr) { serr = unix.Fstat(int(fd), &st) }); err != nil || serr != nil {
+		return 0, false
+	}
+	inode := strconv.FormatUint(st.Ino, 10)
+
+	for _, fn := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		if drops, ok := procDrops(fn, inode); ok {
+			return drops, true
+		}
+	}
+	return 0, false
+}
+
+// procDrops returns the drops column of the socket with the given inode in a
+// /proc/net/udp format file
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+//	 1: 00000000:2621 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 91811 2 0000000000000000 0
+func procDrops(fn, inode string) (int64, bool) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 13 || fields[9] != inode {
+			continue
+		}
+		drops, err := strconv.ParseInt(fields[12], 10, 64)
+		return drops, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,24 @@
+package lwl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcDrops(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "udp")
+	data := `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  1: 00000000:2621 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 91811 2 0000000000000000 0
+  2: 00000000:2621 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 91812 2 0000000000000000 17
+`
+	if err := os.WriteFile(fn, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if drops, ok := procDrops(fn, "91812"); !ok || drops != 17 {
+		t.Errorf("want 17, got %d %v", drops, ok)
+	}
+	if _, ok := procDrops(fn, "1"); ok {
+		t.Error("want unknown inode not found")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package lwl
+
+import "net"
+
+func socketDrops(con *net.UDPConn) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,45 @@
+package lwl_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestSocketStats(t *testing.T) {
+	h, err := lwltest.NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Do(ctx, lwl.CmdHubCall); err != nil {
+		t.Fatal(err)
+	}
+
+	s := c.SocketStats()
+	if s.PacketsOut != 1 || s.BytesOut != int64(len("1,@H")) {
+		t.Errorf("want 1 packet of 4 bytes out, got %+v", s)
+	}
+	if s.PacketsIn < 2 || s.BytesIn == 0 { // Legacy OK and JSON hubCall
+		t.Errorf("want at least 2 packets in, got %+v", s)
+	}
+	if s.ReadErrors != 0 || s.WriteErrors != 0 {
+		t.Errorf("want no errors, got %+v", s)
+	}
+	if runtime.GOOS == "linux" && (!s.DropsKnown || s.Drops != 0) {
+		t.Errorf("want 0 drops reported by Linux, got %+v", s)
+	}
+}
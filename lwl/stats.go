@@ -1,12 +1,16 @@
 package lwl
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
 	"sync"
 	"time"
 )
 
-// LatencyStats maintains statistics (min/mean/max duration)
+// LatencyStats maintains statistics (min/mean/max duration, standard
+// deviation and jitter)
 type LatencyStats struct {
 	mu    sync.RWMutex
 	name  string // Identify to print in .String()
@@ -14,6 +18,15 @@ type LatencyStats struct {
 	total time.Duration
 	min   time.Duration
 	max   time.Duration
+
+	// Welford's algorithm, so the variance is accurate without keeping every
+	// sample. In float64 nanoseconds.
+	mean float64
+	m2   float64 // Sum of squared differences from the mean
+
+	// Smoothed difference between consecutive samples, as RFC 3550 jitter
+	jitter float64
+	last   time.Duration
 }
 
 // NewLatencyStats returns a *LatencyStats
@@ -37,16 +50,97 @@ func (l *LatencyStats) Sample(t time.Duration) {
 	if t > l.max {
 		l.max = t
 	}
+
+	x := float64(t)
+	delta := x - l.mean
+	l.mean += delta / float64(l.count)
+	l.m2 += delta * (x - l.mean)
+
+	if l.count > 1 {
+		d := math.Abs(float64(t - l.last))
+		l.jitter += (d - l.jitter) / 16
+	}
+	l.last = t
 }
 
-func (l *LatencyStats) String() string {
+// LatencySnapshot is a copy of LatencyStats at one moment, which is safe to
+// pass around without locking
+type LatencySnapshot struct {
+	Name    string
+	Samples int64
+	Min     time.Duration
+	Mean    time.Duration // Zero if no samples
+	Max     time.Duration
+	StdDev  time.Duration // Sample standard deviation, zero if fewer than two samples
+	Jitter  time.Duration // Smoothed difference between consecutive samples
+}
+
+// Snapshot returns the current statistics
+func (l *LatencyStats) Snapshot() LatencySnapshot {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	var mean time.Duration // Rely on zero-value if no samples
+	s := LatencySnapshot{
+		Name:    l.name,
+		Samples: l.count,
+		Min:     l.min,
+		Max:     l.max,
+		Jitter:  time.Duration(l.jitter),
+	}
 	if l.count > 0 {
-		mean = time.Duration(l.total.Nanoseconds() / l.count)
+		s.Mean = time.Duration(l.total.Nanoseconds() / l.count)
+	}
+	if l.count > 1 {
+		s.StdDev = time.Duration(math.Sqrt(l.m2 / float64(l.count-1)))
+	}
+	return s
+}
+
+// LogValue implements slog.LogValuer, logging a snapshot as a group.
+func (l *LatencyStats) LogValue() slog.Value {
+	return l.Snapshot().LogValue()
+}
+
+// MarshalJSON implements json.Marshaler, encoding a snapshot.
+func (l *LatencyStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Snapshot())
+}
+
+// LogValue implements slog.LogValuer.
+func (s LatencySnapshot) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", s.Name),
+		slog.Int64("samples", s.Samples),
+		slog.Duration("min", s.Min),
+		slog.Duration("mean", s.Mean),
+		slog.Duration("max", s.Max),
+		slog.Duration("stddev", s.StdDev),
+		slog.Duration("jitter", s.Jitter),
+	)
+}
+
+// MarshalJSON implements json.Marshaler, with durations in (fractional)
+// milliseconds, which are easier to graph than nanoseconds.
+func (s LatencySnapshot) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
 	}
+	return json.Marshal(struct {
+		Name    string  `json:"name"`
+		Samples int64   `json:"samples"`
+		Min     float64 `json:"min_ms"`
+		Mean    float64 `json:"mean_ms"`
+		Max     float64 `json:"max_ms"`
+		StdDev  float64 `json:"stddev_ms"`
+		Jitter  float64 `json:"jitter_ms"`
+	}{s.Name, s.Samples, ms(s.Min), ms(s.Mean), ms(s.Max), ms(s.StdDev), ms(s.Jitter)})
+}
+
+func (l *LatencyStats) String() string {
+	return l.Snapshot().String()
+}
+
+func (s LatencySnapshot) String() string {
 	return fmt.Sprintf(
 		`
 %s:
@@ -54,11 +148,64 @@ func (l *LatencyStats) String() string {
       Max: %v
      Mean: %v
       Min: %v
+   StdDev: %v
+   Jitter: %v
 `,
-		l.name,
-		l.count,
-		l.max,
-		mean,
-		l.min,
+		s.Name,
+		s.Samples,
+		s.Max,
+		s.Mean,
+		s.Min,
+		s.StdDev,
+		s.Jitter,
 	)
 }
+
+type commandOutcome int
+
+const (
+	outcomeOK commandOutcome = iota
+	outcomeErr
+	outcomeTimeout
+	outcomeDryRun  // Not sent, see SetDryRun
+	outcomeHeldOff // Not sent, see SetHoldOff
+)
+
+func (o commandOutcome) String() string {
+	switch o {
+	case outcomeOK:
+		return "ok"
+	case outcomeErr:
+		return "err"
+	case outcomeDryRun:
+		return "dry-run"
+	case outcomeHeldOff:
+		return "held-off"
+	default:
+		return "timeout"
+	}
+}
+
+// CommandResults counts the outcomes of performing a command, so that
+// commands rejected by a particular firmware (or a regression after an
+// update) stand out.
+type CommandResults struct {
+	OK      int64 // Acknowledged, and if expected a JSON response was received
+	Err     int64 // Rejected by the LWL, e.g. ERR,6,"Transmit fail"
+	Timeout int64 // No response before the context was done
+}
+
+func (r *CommandResults) record(outcome commandOutcome) {
+	switch outcome {
+	case outcomeOK:
+		r.OK++
+	case outcomeErr:
+		r.Err++
+	case outcomeTimeout:
+		r.Timeout++
+	}
+}
+
+func (r CommandResults) String() string {
+	return fmt.Sprintf("OK=%d ERR=%d Timeout=%d", r.OK, r.Err, r.Timeout)
+}
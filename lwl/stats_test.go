@@ -1,6 +1,9 @@
 package lwl_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
 	"strings"
 	"sync"
 	"testing"
@@ -83,3 +86,55 @@ func TestLatencyStats_ConcurrentSamples(t *testing.T) {
 		}
 	}
 }
+
+func TestLatencyStats_JSON(t *testing.T) {
+	ls := lwl.NewLatencyStats("json")
+	ls.Sample(time.Millisecond * 100)
+	ls.Sample(time.Millisecond * 200)
+	ls.Sample(time.Millisecond * 300)
+	b, err := json.Marshal(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"json","samples":3,"min_ms":100,"mean_ms":200,"max_ms":300,"stddev_ms":100,"jitter_ms":12.109375}`
+	if string(b) != want {
+		t.Fatalf("want %s got %s", want, b)
+	}
+}
+
+func TestLatencyStats_LogValue(t *testing.T) {
+	ls := lwl.NewLatencyStats("log")
+	ls.Sample(time.Millisecond * 314)
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("stats", "latency", ls)
+	s := buf.String()
+	for _, v := range []string{"latency.name=log", "latency.samples=1", "latency.min=314ms", "latency.mean=314ms", "latency.max=314ms"} {
+		if !strings.Contains(s, v) {
+			t.Fatal("log did not include", v, "\n", s)
+		}
+	}
+}
+
+func TestLatencyStats_StdDevAndJitter(t *testing.T) {
+	// Alternating fast and slow replies have the same mean as steady ones,
+	// but are told apart by the standard deviation and jitter
+	steady := lwl.NewLatencyStats("steady")
+	bimodal := lwl.NewLatencyStats("bimodal")
+	for i := range 100 {
+		steady.Sample(time.Millisecond * 200)
+		bimodal.Sample(time.Millisecond * time.Duration(100+200*(i%2)))
+	}
+	s, b := steady.Snapshot(), bimodal.Snapshot()
+	if s.Mean != b.Mean {
+		t.Fatalf("want equal means, got %v and %v", s.Mean, b.Mean)
+	}
+	if s.StdDev != 0 || s.Jitter != 0 {
+		t.Errorf("want steady to have no deviation or jitter, got %+v", s)
+	}
+	if b.StdDev < 100*time.Millisecond || b.StdDev > 101*time.Millisecond {
+		t.Errorf("want bimodal stddev ~100ms, got %v", b.StdDev)
+	}
+	if b.Jitter < 190*time.Millisecond || b.Jitter > 200*time.Millisecond {
+		t.Errorf("want bimodal jitter ~200ms, got %v", b.Jitter)
+	}
+}
@@ -0,0 +1,134 @@
+package lwl
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatsRegistry holds named latency statistics and counters, so that they can
+// be reported together, see Report
+type StatsRegistry struct {
+	mu       sync.Mutex
+	latency  map[string]*LatencyStats
+	counters map[string]*atomic.Int64
+	funcs    map[string]func() int64
+}
+
+// NewStatsRegistry returns an empty *StatsRegistry
+func NewStatsRegistry() *StatsRegistry {
+	return &StatsRegistry{
+		latency:  make(map[string]*LatencyStats),
+		counters: make(map[string]*atomic.Int64),
+		funcs:    make(map[string]func() int64),
+	}
+}
+
+// Latency returns the LatencyStats with the given name, creating it if needed
+func (r *StatsRegistry) Latency(name string) *LatencyStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ls, ok := r.latency[name]
+	if !ok {
+		ls = NewLatencyStats(name)
+		r.latency[name] = ls
+	}
+	return ls
+}
+
+// Counter returns the counter with the given name, creating it if needed
+func (r *StatsRegistry) Counter(name string) *atomic.Int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.counters[name]
+	if !ok {
+		n = new(atomic.Int64)
+		r.counters[name] = n
+	}
+	return n
+}
+
+// CounterFunc registers a counter maintained elsewhere, which is read by
+// calling fn whenever a snapshot is taken. It replaces any existing counter
+// with the same name.
+func (r *StatsRegistry) CounterFunc(name string, fn func() int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.counters, name)
+	r.funcs[name] = fn
+}
+
+// StatsSnapshot is a copy of every statistic in a StatsRegistry at one moment
+type StatsSnapshot struct {
+	Time     time.Time         `json:"time"`
+	Latency  []LatencySnapshot `json:"latency"` // Ordered by name
+	Counters map[string]int64  `json:"counters"`
+}
+
+// Snapshot returns the current value of every statistic
+func (r *StatsRegistry) Snapshot() StatsSnapshot {
+	r.mu.Lock()
+	latency := slices.Collect(maps.Values(r.latency))
+	counters := make(map[string]int64, len(r.counters)+len(r.funcs))
+	for k, v := range r.counters {
+		counters[k] = v.Load()
+	}
+	funcs := maps.Clone(r.funcs)
+	r.mu.Unlock()
+
+	// Call out without the lock held, in case fn takes locks of its own
+	for k, fn := range funcs {
+		counters[k] = fn()
+	}
+	s := StatsSnapshot{
+		Time:     time.Now(),
+		Latency:  make([]LatencySnapshot, 0, len(latency)),
+		Counters: counters,
+	}
+	for _, ls := range latency {
+		s.Latency = append(s.Latency, ls.Snapshot())
+	}
+	slices.SortFunc(s.Latency, func(a, b LatencySnapshot) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return s
+}
+
+// LogValue implements slog.LogValuer.
+func (s StatsSnapshot) LogValue() slog.Value {
+	latency := make([]slog.Attr, 0, len(s.Latency))
+	for _, ls := range s.Latency {
+		latency = append(latency, slog.Any(ls.Name, ls))
+	}
+	counters := make([]slog.Attr, 0, len(s.Counters))
+	for _, k := range slices.Sorted(maps.Keys(s.Counters)) {
+		counters = append(counters, slog.Int64(k, s.Counters[k]))
+	}
+	return slog.GroupValue(
+		slog.Attr{Key: "latency", Value: slog.GroupValue(latency...)},
+		slog.Attr{Key: "counters", Value: slog.GroupValue(counters...)},
+	)
+}
+
+// Report calls fn with a snapshot every interval, until ctx is done. It
+// blocks, so is usually run in its own goroutine.
+func (r *StatsRegistry) Report(ctx context.Context, interval time.Duration, fn func(StatsSnapshot)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fn(r.Snapshot())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package lwl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestStatsRegistry_Snapshot(t *testing.T) {
+	r := lwl.NewStatsRegistry()
+	r.Latency("b").Sample(time.Millisecond)
+	r.Latency("a").Sample(time.Millisecond * 3)
+	r.Latency("b").Sample(time.Millisecond * 3)
+	r.Counter("sent").Add(2)
+	r.Counter("sent").Add(1)
+	r.CounterFunc("queued", func() int64 { return 7 })
+
+	s := r.Snapshot()
+	if len(s.Latency) != 2 || s.Latency[0].Name != "a" || s.Latency[1].Name != "b" {
+		t.Fatalf("want latency of a and b, in order, got %+v", s.Latency)
+	}
+	if got := s.Latency[1]; got.Samples != 2 || got.Mean != time.Millisecond*2 {
+		t.Errorf("want 2 samples with mean 2ms, got %+v", got)
+	}
+	if s.Counters["sent"] != 3 || s.Counters["queued"] != 7 {
+		t.Errorf("want sent=3 queued=7, got %v", s.Counters)
+	}
+}
+
+func TestStatsRegistry_Report(t *testing.T) {
+	r := lwl.NewStatsRegistry()
+	r.Counter("n").Add(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan lwl.StatsSnapshot, 1)
+	done := make(chan struct{})
+	go func() {
+		r.Report(ctx, time.Millisecond, func(s lwl.StatsSnapshot) {
+			select {
+			case got <- s:
+			default: // Not yet read
+			}
+		})
+		close(done)
+	}()
+	if s := <-got; s.Counters["n"] != 1 {
+		t.Errorf("want n=1, got %v", s.Counters)
+	}
+	cancel()
+	<-done
+}
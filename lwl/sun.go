@@ -0,0 +1,75 @@
+package lwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DuskDawn asks the LWL for today's dusk and dawn, as used by its timers
+func (c *Client) DuskDawn(ctx context.Context) (dusk, dawn time.Time, err error) {
+	r, err := c.Do(ctx, CmdHubDuskDawn)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return lwlTime(r.DuskTime), lwlTime(r.DawnTime), nil
+}
+
+// HubLocation asks the LWL for the latitude and longitude it uses for dusk
+// and dawn, which are both zero if it has not been set, see CmdSetLocation
+func (c *Client) HubLocation(ctx context.Context) (lat, long float64, err error) {
+	r, err := c.Do(ctx, CmdHubCall)
+	if err != nil {
+		return 0, 0, err
+	}
+	return float64(r.Lat), float64(r.Long), nil
+}
+
+// DuskDawnFallback returns a function like DuskDawn which, if the LWL's
+// location has not been set or it fails to report dusk and dawn, calculates
+// them for the given latitude and longitude instead (see SunTimes), so that
+// rules active between dusk and dawn still work.
+func (c *Client) DuskDawnFallback(lat, long float64) func(ctx context.Context) (dusk, dawn time.Time, err error) {
+	return func(ctx context.Context) (dusk, dawn time.Time, err error) {
+		dusk, dawn, err = c.hubDuskDawn(ctx)
+		if err == nil {
+			return dusk, dawn, nil
+		}
+		slog.Warn("Calculating dusk and dawn locally", "reason", err, "lat", lat, "long", long)
+
+		sunrise, sunset, ok := SunTimes(time.Now(), lat, long)
+		if !ok {
+			return time.Time{}, time.Time{}, fmt.Errorf("the sun does not rise or set today at %v,%v", lat, long)
+		}
+		return sunset, sunrise, nil
+	}
+}
+
+// hubDuskDawn is like DuskDawn, but returns an error if the LWL's location has
+// not been set, since its dusk and dawn would then be meaningless
+func (c *Client) hubDuskDawn(ctx context.Context) (dusk, dawn time.Time, err error) {
+	lat, long, err := c.HubLocation(ctx)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if lat == 0 && long == 0 {
+		return time.Time{}, time.Time{}, errors.New("LightwaveLink location not set")
+	}
+	r, err := c.Do(ctx, CmdHubDuskDawn)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if r.DuskTime == 0 || r.DawnTime == 0 {
+		return time.Time{}, time.Time{}, errors.New("LightwaveLink did not report dusk and dawn")
+	}
+	return lwlTime(r.DuskTime), lwlTime(r.DawnTime), nil
+}
+
+// lwlTime converts an LWL "local" Unix time (i.e. offset by its time zone) to
+// a Time, assuming the LWL and this host are in the same time zone
+func lwlTime(v int32) time.Time {
+	t := time.Unix(int64(v), 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+}
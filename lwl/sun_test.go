@@ -0,0 +1,35 @@
+package lwl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/lwl/lwltest"
+)
+
+func TestDuskDawnFallback(t *testing.T) {
+	h, err := lwltest.NewHub() // Reports no location
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := lwl.Connect(h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go c.Listen()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	dusk, dawn, err := c.DuskDawnFallback(52.18, 0.21)(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunrise, sunset, _ := lwl.SunTimes(time.Now(), 52.18, 0.21)
+	if !dusk.Equal(sunset) || !dawn.Equal(sunrise) {
+		t.Fatalf("want calculated %v-%v, got %v-%v", sunset, sunrise, dusk, dawn)
+	}
+}
@@ -0,0 +1,71 @@
+package lwl
+
+import (
+	"math"
+	"time"
+)
+
+// Angle of the sun's centre below the horizon at sunrise and sunset, allowing
+// for its radius and atmospheric refraction
+const sunZenith = 90.833
+
+// SunTimes calculates sunrise and sunset on the given day (in its location)
+// at a latitude and longitude, in degrees, using the algorithm from the
+// Almanac for Computers (1990). It is accurate to a minute or two, which is
+// plenty for switching lights. ok is false if the sun does not rise or set
+// that day, e.g. within the Arctic circle.
+func SunTimes(day time.Time, lat, long float64) (sunrise, sunset time.Time, ok bool) {
+	sunrise, ok1 := sunEvent(day, lat, long, true)
+	sunset, ok2 := sunEvent(day, lat, long, false)
+	return sunrise, sunset, ok1 && ok2
+}
+
+// sunEvent returns sunrise (rising) or sunset on the given day
+func sunEvent(day time.Time, lat, long float64, rising bool) (time.Time, bool) {
+	sin := func(deg float64) float64 { return math.Sin(deg * math.Pi / 180) }
+	cos := func(deg float64) float64 { return math.Cos(deg * math.Pi / 180) }
+	tan := func(deg float64) float64 { return math.Tan(deg * math.Pi / 180) }
+	deg := func(rad float64) float64 { return rad * 180 / math.Pi }
+	norm := func(v, max float64) float64 {
+		v = math.Mod(v, max)
+		if v < 0 {
+			v += max
+		}
+		return v
+	}
+
+	// Approximate time of the event, in days since the start of the year
+	lngHour := long / 15
+	t := float64(day.YearDay()) + (18-lngHour)/24
+	if rising {
+		t = float64(day.YearDay()) + (6-lngHour)/24
+	}
+
+	// The sun's mean anomaly and true longitude
+	m := 0.9856*t - 3.289
+	l := norm(m+1.916*sin(m)+0.020*sin(2*m)+282.634, 360)
+
+	// Right ascension, in the same quadrant as l, in hours
+	ra := norm(deg(math.Atan(0.91764*tan(l))), 360)
+	ra += math.Floor(l/90)*90 - math.Floor(ra/90)*90
+	ra /= 15
+
+	// Declination, and then the local hour angle
+	sinDec := 0.39782 * sin(l)
+	cosDec := math.Cos(math.Asin(sinDec))
+	cosH := (cos(sunZenith) - sinDec*sin(lat)) / (cosDec * cos(lat))
+	if cosH > 1 || cosH < -1 {
+		return time.Time{}, false // Never rises, or never sets
+	}
+	h := deg(math.Acos(cosH))
+	if rising {
+		h = 360 - h
+	}
+	h /= 15
+
+	// Local mean time of the event, then UTC
+	ut := norm(h+ra-0.06571*t-6.622-lngHour, 24)
+	y, mo, d := day.Date()
+	midnight := time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+	return midnight.Add(time.Duration(ut * float64(time.Hour))).In(day.Location()), true
+}
@@ -0,0 +1,46 @@
+package lwl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, tt := range []struct {
+		day             time.Time
+		lat, long       float64
+		sunrise, sunset string
+	}{
+		// As reported by an LWL in Cambridge, see CmdHubDuskDawn
+		{time.Date(2026, 1, 7, 12, 0, 0, 0, london), 52.18, 0.21, "08:06", "16:04"},
+		{time.Date(2026, 6, 21, 12, 0, 0, 0, london), 51.51, -0.13, "04:43", "21:21"},
+		{time.Date(2026, 3, 20, 12, 0, 0, 0, newYork), 40.71, -74.0, "06:59", "19:07"},
+	} {
+		sunrise, sunset, ok := SunTimes(tt.day, tt.lat, tt.long)
+		if !ok {
+			t.Fatalf("%v: want sunrise and sunset", tt.day)
+		}
+		for _, c := range []struct {
+			got  time.Time
+			want string
+		}{{sunrise, tt.sunrise}, {sunset, tt.sunset}} {
+			want, _ := time.ParseInLocation("2006-01-02 15:04", tt.day.Format(time.DateOnly)+" "+c.want, tt.day.Location())
+			if d := c.got.Sub(want).Abs(); d > 2*time.Minute {
+				t.Errorf("%v at %v,%v: want %s got %v", tt.day.Format(time.DateOnly), tt.lat, tt.long, c.want, c.got)
+			}
+		}
+	}
+
+	// Midnight sun in Svalbard
+	if _, _, ok := SunTimes(time.Date(2026, 6, 21, 12, 0, 0, 0, time.UTC), 78.2, 15.6); ok {
+		t.Error("want no sunset in Svalbard at midsummer")
+	}
+}
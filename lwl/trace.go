@@ -0,0 +1,38 @@
+package lwl
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Trace logs every message sent to and received from the LWL, whatever the
+// log level, for the given duration, e.g. to capture what a misbehaving hub
+// is saying without restarting. A duration of 0 stops tracing.
+func (c *Client) Trace(d time.Duration) {
+	if d <= 0 {
+		c.traceUntil.Store(0)
+		return
+	}
+	c.traceUntil.Store(time.Now().Add(d).UnixNano())
+}
+
+// TraceUntil returns when tracing stops, see Trace, or false if it is not
+// tracing
+func (c *Client) TraceUntil() (time.Time, bool) {
+	until := time.Unix(0, c.traceUntil.Load())
+	return until, time.Now().Before(until)
+}
+
+// trace logs a message sent ("out") to or received ("in") from addr, if
+// tracing. It is given to the default handler directly, as slog.Info would
+// drop it if the level were above INFO.
+func (c *Client) trace(dir string, addr *net.UDPAddr, msg string) {
+	if _, ok := c.TraceUntil(); !ok {
+		return
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "Trace", 0)
+	r.AddAttrs(slog.String("dir", dir), slog.Any("addr", addr), slog.String("msg", msg))
+	slog.Default().Handler().Handle(context.Background(), r)
+}
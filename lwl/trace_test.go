@@ -0,0 +1,28 @@
+package lwl
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Traced messages are logged even when the level is above INFO
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	c := newClient(nil, net.UDPAddr{})
+	c.trace("in", nil, "not traced")
+	c.Trace(time.Minute)
+	c.trace("in", nil, "traced")
+	c.Trace(0)
+	c.trace("in", nil, "no longer traced")
+
+	if got := buf.String(); strings.Count(got, "msg=Trace") != 1 || !strings.Contains(got, "msg=traced") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package lwl
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// The LWL's clock is set from the internet, so timestamps outside this range
+// (allowing for its local time zone) suggest a corrupt packet
+var (
+	minTime = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxSkew = 48 * time.Hour
+)
+
+// Validate checks that r has the fields required by its pkt and fn (see
+// Schema), a plausible timestamp, and values within range. It returns every
+// problem found, joined.
+func (r Response) Validate() error {
+	var errs []error
+	need := func(ok bool, field string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("missing %s", field))
+		}
+	}
+
+	need(r.Trans > 0, "trans")
+	need(r.Mac != "", "mac")
+	need(r.Fn != "" || r.Type != "", "fn or type")
+	if t := time.Unix(int64(r.Time), 0); t.Before(minTime) || t.After(time.Now().Add(maxSkew)) {
+		errs = append(errs, fmt.Errorf("implausible time %v", t.UTC()))
+	}
+
+	if mt, ok := messageTypeOf(r); ok {
+		for _, name := range mt.required {
+			if name == "packet" {
+				need(r.HasPacket(), name) // Packets are numbered from 0
+				continue
+			}
+			need(!r.field(name).IsZero(), name)
+		}
+	}
+	if r.Room < 0 || r.Room > 80 {
+		errs = append(errs, fmt.Errorf("room %d out of range 0-80", r.Room))
+	}
+	if r.Batt < 0 || r.Batt > 4 {
+		errs = append(errs, fmt.Errorf("battery %.2fV out of range 0-4", r.Batt))
+	}
+	return errors.Join(errs...)
+}
+
+// SetStrict enables strict parsing, which discards JSON messages that fail
+// Response.Validate rather than passing them on to subscribers. Either way,
+// failures are logged; see also Stats.
+func (c *Client) SetStrict(strict bool) {
+	c.strict.Store(strict)
+}
+
+// errInvalid is returned by validate in strict mode
+var errInvalid = errors.New("invalid response")
+
+// validate applies Response.Validate, returning an error only in strict mode.
+// Invalid responses are quarantined either way.
+func (c *Client) validate(r *Response) error {
+	err := r.Validate()
+	if err == nil {
+		return nil
+	}
+	c.invalid.Add(1)
+	reason := fmt.Sprintf("invalid %s/%s: %v", r.Pkt, r.Fn, strings.ReplaceAll(err.Error(), "\n", "; "))
+	c.quarantineMsg(fmt.Sprintf("invalid %s/%s {%s}", r.Pkt, r.Fn, fieldNames(r.json)), reason, r.json)
+	if c.strict.Load() {
+		return fmt.Errorf("%w: %w", errInvalid, err)
+	}
+	slog.Debug("Invalid response", "err", err, "r", r)
+	return nil
+}
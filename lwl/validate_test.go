@@ -0,0 +1,110 @@
+package lwl
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Every JSON message in the firmware fixtures should be valid
+func TestValidateFixtures(t *testing.T) {
+	fns, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Client{}
+	for _, fn := range fns {
+		f, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if !strings.HasPrefix(s.Text(), "*") {
+				continue
+			}
+			r, err := c.parseJSON(s.Text())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Validate(); err != nil {
+				t.Errorf("%s: %s: %v", fn, s.Text(), err)
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	table := []struct {
+		msg, want string
+	}{
+		{`*!{"mac":"20:3B:85","time":1767297488,"fn":"summary"}`, "missing trans"},
+		{`*!{"trans":1,"mac":"20:3B:85","fn":"summary"}`, "implausible time"},
+		{`*!{"trans":1,"mac":"20:3B:85","time":1767297488}`, "missing fn or type"},
+		{`*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"868R","fn":"statusPush","prod":"valve"}`, "missing serial"},
+		{`*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"433T","fn":"on","room":81}`, "room 81"},
+		{`*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"system","fn":"hubCall"}`, "missing fw"},
+	}
+	c := Client{}
+	for _, tt := range table {
+		r, err := c.parseJSON(tt.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: want %q got %v", tt.msg, tt.want, err)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{})
+	ch := c.SubscribeContext(t.Context(), 10, DropNewest)
+	bad := `*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"868R","fn":"statusPush"}`
+
+	if err := c.handleJSON(bad, nil); err != nil {
+		t.Fatalf("lenient: %v", err)
+	}
+	if len(ch) != 1 {
+		t.Fatal("lenient: invalid message not passed on")
+	}
+
+	c.SetStrict(true)
+	bad = strings.Replace(bad, `"trans":1`, `"trans":2`, 1)
+	if err := c.handleJSON(bad, nil); err == nil {
+		t.Fatal("strict: invalid message accepted")
+	}
+	if len(ch) != 1 {
+		t.Fatal("strict: invalid message passed on")
+	}
+	if got := c.invalid.Load(); got != 2 {
+		t.Errorf("want 2 invalid got %d", got)
+	}
+
+	// A duplicate is discarded before it is validated, so not counted again
+	lenient := strings.Replace(bad, `"trans":2`, `"trans":1`, 1)
+	if err := c.handleJSON(lenient, nil); err != nil {
+		t.Fatalf("duplicate: %v", err)
+	}
+	if got := c.invalid.Load(); got != 2 {
+		t.Errorf("duplicate counted: want 2 invalid got %d", got)
+	}
+}
+
+func TestStrictHubAddr(t *testing.T) {
+	c := newClient(nil, net.UDPAddr{IP: net.IPv4bcast, Port: lwlServerPort})
+	c.SetStrict(true)
+	from := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: lwlServerPort}
+	c.receive([]byte(`*!{"trans":1,"mac":"20:3B:85","time":1767297488,"pkt":"868R","fn":"statusPush"}`), from)
+	if c.isFound() {
+		t.Fatal("invalid message located the LWL")
+	}
+	c.receive([]byte(`*!{"trans":2,"mac":"20:3B:85","time":1767297488,"pkt":"868T","fn":"setTarget","room":7,"packet":0}`), from)
+	if !c.isFound() {
+		t.Fatal("valid message with packet 0 did not locate the LWL")
+	}
+}
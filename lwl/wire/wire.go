@@ -0,0 +1,98 @@
+// Package wire encodes and decodes the messages exchanged with a LightwaveRF
+// Link (LWL), independently of how they are sent.
+//
+// The LWL speaks two formats over UDP. Legacy messages are comma-separated
+// text, used for commands and their immediate replies:
+//
+//	123,!R1D1F1                    Command, with sequence ID 123
+//	:20:3B:85,123,!R1D1F1          Command, from a client identified by MAC
+//	123,OK                         Reply
+//	123,ERR,2,"Not yet registered. See LightwaveLink"
+//
+// JSON messages are a JSON object after the literal prefix "*!", and are
+// broadcast by firmware N2.92 onwards to report what the LWL did:
+//
+//	*!{"trans":14619,"mac":"20:3B:85","time":1767288212,"pkt":"system","fn":"hubCall",...}
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix of every JSON message
+const JSONPrefix = "*!"
+
+// ErrNotJSON is returned by UnmarshalJSON for a message without JSONPrefix,
+// which is likely to be Legacy
+var ErrNotJSON = errors.New("not JSON: does not start with " + JSONPrefix)
+
+// Legacy is a message in the comma-separated format
+type Legacy struct {
+	MAC     string // Of commands, the client's MAC (if it has one), e.g. "20:3B:85"
+	Seq     string // Sequence ID, chosen by the client and echoed in replies
+	Payload string // e.g. "!R1D1F1" or "OK"
+}
+
+// IsJSON reports whether b has JSONPrefix
+func IsJSON(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(JSONPrefix))
+}
+
+// MarshalLegacy returns the encoding of m
+func MarshalLegacy(m Legacy) []byte {
+	var b []byte
+	if m.MAC != "" {
+		b = append(b, ':')
+		b = append(b, m.MAC...)
+		b = append(b, ',')
+	}
+	b = append(b, m.Seq...)
+	b = append(b, ',')
+	return append(b, m.Payload...)
+}
+
+// UnmarshalLegacy decodes a legacy message. Surrounding whitespace, such as
+// the CRLF ending replies, is removed from the payload.
+func UnmarshalLegacy(b []byte) (Legacy, error) {
+	var m Legacy
+	s := string(b)
+	if rest, found := strings.CutPrefix(s, ":"); found {
+		mac, rest, found := strings.Cut(rest, ",")
+		if !found || mac == "" {
+			return Legacy{}, fmt.Errorf("unable to parse legacy message: missing sequence ID after MAC: %q", s)
+		}
+		m.MAC, s = mac, rest
+	}
+	seq, payload, found := strings.Cut(s, ",")
+	if !found {
+		return Legacy{}, fmt.Errorf("unable to parse legacy message: %q", b)
+	}
+	m.Seq = seq
+	m.Payload = strings.TrimSpace(payload)
+	return m, nil
+}
+
+// MarshalJSON returns the JSON encoding of v, with JSONPrefix
+func MarshalJSON(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(JSONPrefix), b...), nil
+}
+
+// UnmarshalJSON decodes a JSON message into v, returning ErrNotJSON if b
+// does not have JSONPrefix
+func UnmarshalJSON(b []byte, v any) error {
+	if !IsJSON(b) {
+		return ErrNotJSON
+	}
+	if err := json.Unmarshal(b[len(JSONPrefix):], v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return nil
+}
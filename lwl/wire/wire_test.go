@@ -0,0 +1,128 @@
+package wire
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLegacy(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Legacy
+		out  string // Re-encoding, if different from in
+	}{
+		{"command", "123,!R1D1F1", Legacy{Seq: "123", Payload: "!R1D1F1"}, ""},
+		{"command with MAC", ":20:3B:85,7,@H", Legacy{MAC: "20:3B:85", Seq: "7", Payload: "@H"}, ""},
+		{"reply", "123,OK\r\n", Legacy{Seq: "123", Payload: "OK"}, "123,OK"},
+		{"error reply", `5,ERR,2,"Not yet registered. See LightwaveLink"`, Legacy{Seq: "5", Payload: `ERR,2,"Not yet registered. See LightwaveLink"`}, ""},
+		{"firmware reply", `9,?V="N2.94D"`, Legacy{Seq: "9", Payload: `?V="N2.94D"`}, ""},
+		{"text with commas", "1,!R1D1FdP16|Line 1, and more|Line 2", Legacy{Seq: "1", Payload: "!R1D1FdP16|Line 1, and more|Line 2"}, ""},
+		{"empty payload", "1,", Legacy{Seq: "1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalLegacy([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %+v got %+v", tt.want, got)
+			}
+			out := tt.out
+			if out == "" {
+				out = tt.in
+			}
+			if b := MarshalLegacy(got); string(b) != out {
+				t.Fatalf("re-encoded: want %q got %q", out, b)
+			}
+		})
+	}
+}
+
+func TestLegacyInvalid(t *testing.T) {
+	for _, in := range []string{"", "OK", ":20:3B:85", ":,1,@H", ":20:3B:85,@H"} {
+		if m, err := UnmarshalLegacy([]byte(in)); err == nil {
+			t.Errorf("%q: want error, got %+v", in, m)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type msg struct {
+		Trans   int32   `json:"trans"`
+		Fn      string  `json:"fn"`
+		CTemp   float32 `json:"cTemp"`
+		Payload any     `json:"payload"`
+	}
+	in := `*!{"trans":93136,"mac":"20:3B:85","fn":"statusPush","cTemp":19.4,"payload":208}`
+
+	var got msg
+	if err := UnmarshalJSON([]byte(in), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := msg{Trans: 93136, Fn: "statusPush", CTemp: 19.4, Payload: 208.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v got %+v", want, got)
+	}
+
+	b, err := MarshalJSON(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsJSON(b) {
+		t.Fatalf("missing prefix: %s", b)
+	}
+	var again msg
+	if err := UnmarshalJSON(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Fatalf("round trip: want %+v got %+v", want, again)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	tests := []struct {
+		in      string
+		notJSON bool
+	}{
+		{"", true},
+		{"123,OK", true},
+		{"*", true},
+		{`{"trans":1}`, true},
+		{"*!", false},
+		{"*!{", false},
+		{`*!{"trans":"one"}`, false},
+	}
+	for _, tt := range tests {
+		var v struct {
+			Trans int32 `json:"trans"`
+		}
+		err := UnmarshalJSON([]byte(tt.in), &v)
+		if err == nil {
+			t.Errorf("%q: want error", tt.in)
+			continue
+		}
+		if errors.Is(err, ErrNotJSON) != tt.notJSON {
+			t.Errorf("%q: want ErrNotJSON %v, got %v", tt.in, tt.notJSON, err)
+		}
+	}
+}
+
+func FuzzUnmarshalLegacy(f *testing.F) {
+	f.Add("123,OK")
+	f.Add(":20:3B:85,7,@H")
+	f.Fuzz(func(t *testing.T, in string) {
+		m, err := UnmarshalLegacy([]byte(in))
+		if err != nil {
+			return
+		}
+		// Anything decoded must survive a round trip
+		again, err := UnmarshalLegacy(MarshalLegacy(m))
+		if err != nil || again != m {
+			t.Fatalf("%q: decoded %+v, round trip %+v %v", in, m, again, err)
+		}
+	})
+}
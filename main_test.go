@@ -97,3 +97,31 @@ func TestIsRegistered(t *testing.T) {
 	t.Log("Logging test")
 
 }
+
+func TestParseLocation(t *testing.T) {
+	lat, long, ok, err := parseLocation("52.18, -0.21")
+	if err != nil || !ok || lat != 52.18 || long != -0.21 {
+		t.Errorf("got %v, %v, %v, %v", lat, long, ok, err)
+	}
+	if _, _, ok, err := parseLocation(""); ok || err != nil {
+		t.Errorf("want no location, got %v, %v", ok, err)
+	}
+	for _, s := range []string{"52.18", "x,0", "91,0", "0,181"} {
+		if _, _, _, err := parseLocation(s); err == nil {
+			t.Errorf("parseLocation(%q) should fail", s)
+		}
+	}
+}
+
+func TestParsePprof(t *testing.T) {
+	for in, want := range map[string]string{"6060": "localhost:6060", "127.0.0.1:6060": "127.0.0.1:6060", "[::1]:6060": "[::1]:6060"} {
+		if got, err := parsePprof(in); got != want || err != nil {
+			t.Errorf("parsePprof(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, s := range []string{":6060", "0.0.0.0:6060", "192.168.1.5:6060", "pi.local:6060", "x", "70000"} {
+		if _, err := parsePprof(s); err == nil {
+			t.Errorf("parsePprof(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package notify delivers notifications, holding back non-critical ones
+// during quiet hours
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+)
+
+// QuietHours is a daily period (e.g. overnight) during which non-critical
+// notifications are held back
+type QuietHours struct {
+	start, end time.Duration // Offset from midnight. Start > end means the period spans midnight
+}
+
+// ParseQuietHours parses a period such as "22:00-07:00". An empty string
+// means there are no quiet hours.
+func ParseQuietHours(s string) (*QuietHours, error) {
+	if s == "" {
+		return nil, nil
+	}
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return nil, fmt.Errorf("quiet hours should look like 22:00-07:00, got %q", s)
+	}
+	start, err := timeofday.Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timeofday.Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	return &QuietHours{start: start, end: end}, nil
+}
+
+// Contains reports whether t falls within the quiet hours. A nil QuietHours
+// never contains anything.
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil || q.start == q.end {
+		return false
+	}
+	return timeofday.Within(timeofday.SinceMidnight(t), q.start, q.end)
+}
+
+// notification is a message held back until quiet hours end
+type notification struct {
+	msg  string
+	args []any
+}
+
+// Notifier logs notifications, queuing non-critical ones during quiet hours
+type Notifier struct {
+	quiet *QuietHours
+
+	mu      sync.Mutex
+	pending []notification
+}
+
+// New returns a Notifier observing the given quiet hours, which may be nil
+func New(quiet *QuietHours) *Notifier {
+	return &Notifier{quiet: quiet}
+}
+
+// Notify logs a non-critical notification now, or once quiet hours end
+func (n *Notifier) Notify(msg string, args ...any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.quiet.Contains(time.Now()) {
+		slog.Debug("Quiet hours, queuing notification", "msg", msg)
+		n.pending = append(n.pending, notification{msg: msg, args: args})
+		return
+	}
+	slog.Warn(msg, args...)
+}
+
+// Flush logs any queued notifications, if quiet hours are over
+func (n *Notifier) Flush(now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.quiet.Contains(now) {
+		return
+	}
+	for _, p := range n.pending {
+		slog.Warn(p.msg, p.args...)
+	}
+	n.pending = nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	at := func(hh, mm int) time.Time {
+		return time.Date(2026, 10, 15, hh, mm, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		period string
+		quiet  []time.Time
+		loud   []time.Time
+	}{
+		{
+			name:   "Overnight",
+			period: "22:00-07:00",
+			quiet:  []time.Time{at(22, 0), at(23, 59), at(0, 0), at(6, 59)},
+			loud:   []time.Time{at(7, 0), at(12, 0), at(21, 59)},
+		},
+		{
+			name:   "Daytime",
+			period: "09:30-17:00",
+			quiet:  []time.Time{at(9, 30), at(16, 59)},
+			loud:   []time.Time{at(9, 29), at(17, 0), at(0, 0)},
+		},
+		{
+			name:   "None",
+			period: "",
+			loud:   []time.Time{at(0, 0), at(12, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuietHours(tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, ts := range tt.quiet {
+				if !q.Contains(ts) {
+					t.Errorf("%s should be quiet", ts.Format("15:04"))
+				}
+			}
+			for _, ts := range tt.loud {
+				if q.Contains(ts) {
+					t.Errorf("%s should not be quiet", ts.Format("15:04"))
+				}
+			}
+		})
+	}
+
+	for _, bad := range []string{"22:00", "22:00-7am", "25:00-07:00"} {
+		if _, err := ParseQuietHours(bad); err == nil {
+			t.Errorf("ParseQuietHours(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package occupancy estimates which areas of a home are occupied from the
+// messages a LightwaveRF Link (LWL) reports when PIR motion sensors trigger.
+// An area stays occupied until no motion has been seen for its decay time:
+//
+//   - name: landing
+//     sensors: [R3D1]
+//     decay: 5m
+//
+// Changes are passed to rules as events, e.g. when: {pkt: occupancy, fn:
+// vacant, area: landing}.
+package occupancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"github.com/meermanr/LightwaveRF-go/sensor"
+)
+
+// Decay used if an area does not set its own
+const defaultDecay = 5 * time.Minute
+
+// Pkt identifies occupancy events, like Response.Pkt for messages from the LWL
+const Pkt = "occupancy"
+
+// Functions of occupancy events, like Response.Fn
+const (
+	FnOccupied = "occupied" // Motion in a vacant area
+	FnVacant   = "vacant"   // No motion for the area's decay time
+)
+
+// Fields are those of an Event besides pkt and fn, which rules may match, see
+// rules.Check
+var Fields = []string{"area"}
+
+// Area is a part of the home with one or more PIRs, as configured in YAML
+type Area struct {
+	Name    string        `yaml:"name"`
+	Sensors []string      `yaml:"sensors"` // Room+Device the PIRs are paired to, e.g. R3D1, or a room, e.g. R3, for any device in it
+	Decay   time.Duration `yaml:"decay"`   // How long the area stays occupied after motion. Optional, defaults to 5m.
+}
+
+// Load reads areas from a YAML file
+func Load(fn string) ([]Area, error) {
+	var as []Area
+	if err := sensor.Load(fn, &as); err != nil {
+		return nil, err
+	}
+	return as, Check(as)
+}
+
+// Check returns every problem found with the areas, joined
+func Check(as []Area) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, a := range as {
+		bad := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("area %d (%s): %s", i+1, a.Name, fmt.Sprintf(format, args...)))
+		}
+		switch {
+		case a.Name == "":
+			bad("missing name")
+		case seen[a.Name]:
+			bad("duplicate name")
+		}
+		seen[a.Name] = true
+
+		if len(a.Sensors) == 0 {
+			bad("missing sensors")
+		}
+		for _, id := range a.Sensors {
+			if !lwl.ValidID(id) {
+				bad("invalid sensor %q, should be like R3D1 or R3", id)
+			}
+		}
+		if a.Decay < 0 {
+			bad("negative decay")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Event is a change in an area's occupancy. It is a rules.Event, with fields
+// "pkt" (always Pkt), "fn" and "area".
+type Event struct {
+	Fn   string
+	Area string
+}
+
+// Field implements rules.Event
+func (e Event) Field(name string) (any, bool) {
+	switch name {
+	case "pkt":
+		return Pkt, true
+	case "fn":
+		return e.Fn, true
+	case "area":
+		return e.Area, true
+	}
+	return nil, false
+}
+
+// LogValue implements slog.LogValuer.
+func (e Event) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("pkt", Pkt), slog.String("fn", e.Fn), slog.String("area", e.Area))
+}
+
+// State is the estimated occupancy of an area
+type State struct {
+	Occupied bool      `json:"occupied"`
+	Motion   time.Time `json:"motion,omitzero"` // Most recent
+}
+
+// Estimator tracks the occupancy of areas from PIR messages
+type Estimator struct {
+	areas  []Area
+	notify func(Event) // Called with each Event, e.g. rules.Engine.Handle
+
+	mu    sync.Mutex
+	state map[string]*area
+}
+
+// area is the state of an Area, and its pending decay
+type area struct {
+	State
+	decay *time.Timer
+}
+
+// NewEstimator returns an Estimator for areas, which should have passed
+// Check, calling notify with each resulting Event. Every area starts vacant.
+func NewEstimator(as []Area, notify func(Event)) *Estimator {
+	e := &Estimator{areas: as, notify: notify, state: make(map[string]*area)}
+	for _, a := range as {
+		e.state[a.Name] = &area{}
+	}
+	return e
+}
+
+// Run observes each message until the channel is closed or the context is
+// done
+func (e *Estimator) Run(ctx context.Context, msgs <-chan lwl.Response) {
+	sensor.Run(ctx, msgs, e.Observe)
+}
+
+// Observe marks the areas of a PIR which triggered as occupied, restarting
+// their decay. Other messages are ignored, see sensor.Report. PIRs report
+// motion as switching on the device they are paired to, e.g. {pkt: 433T, fn:
+// on, room: 3, dev: 1}.
+func (e *Estimator) Observe(r lwl.Response, now time.Time) {
+	id, ok := sensor.Report(r)
+	if !ok || r.Fn != "on" {
+		return
+	}
+	room := fmt.Sprintf("R%d", r.Room)
+	for _, a := range e.areas {
+		if slices.Contains(a.Sensors, id) || slices.Contains(a.Sensors, room) {
+			e.motion(a, now)
+		}
+	}
+}
+
+// motion marks an area occupied, and schedules it to become vacant
+func (e *Estimator) motion(a Area, now time.Time) {
+	decay := a.Decay
+	if decay == 0 {
+		decay = defaultDecay
+	}
+
+	e.mu.Lock()
+	s := e.state[a.Name]
+	was := s.Occupied
+	s.Occupied, s.Motion = true, now
+	if s.decay != nil {
+		s.decay.Stop()
+	}
+	s.decay = time.AfterFunc(decay, panics.Func("occupancy", func() {
+		e.mu.Lock()
+		if !s.Motion.Equal(now) {
+			e.mu.Unlock()
+			return // Superseded by later motion
+		}
+		s.Occupied, s.decay = false, nil
+		e.mu.Unlock()
+		e.emit(Event{Fn: FnVacant, Area: a.Name})
+	}))
+	e.mu.Unlock()
+
+	if !was {
+		e.emit(Event{Fn: FnOccupied, Area: a.Name})
+	}
+}
+
+func (e *Estimator) emit(ev Event) {
+	slog.Info("Occupancy", "event", ev)
+	if e.notify != nil {
+		e.notify(ev)
+	}
+}
+
+// State returns the occupancy of each area, by name
+func (e *Estimator) State() map[string]State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]State, len(e.state))
+	for name, s := range e.state {
+		out[name] = s.State
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package occupancy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/rules"
+)
+
+func TestCheck(t *testing.T) {
+	err := Check([]Area{
+		{Name: "landing", Sensors: []string{"R3D1"}},
+		{Name: "landing", Sensors: []string{"R3D99"}, Decay: -time.Minute},
+		{Name: "hall"},
+	})
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	for _, want := range []string{"duplicate name", "invalid sensor", "negative decay", "missing sensors"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	events := make(chan Event, 10)
+	e := NewEstimator([]Area{
+		{Name: "landing", Sensors: []string{"R3D1"}, Decay: 50 * time.Millisecond},
+		{Name: "upstairs", Sensors: []string{"R3"}, Decay: time.Hour},
+	}, func(ev Event) { events <- ev })
+
+	pir := lwl.Response{Pkt: "433T", Fn: "on", Room: 3, Dev: 1}
+	e.Observe(lwl.Response{Pkt: "433T", Fn: "off", Room: 3, Dev: 1}, time.Now())            // Not motion
+	e.Observe(lwl.Response{Pkt: "433T", Fn: "on", Room: 3, Dev: 1, Echo: true}, time.Now()) // We switched on R3D1
+	e.Observe(pir, time.Now())
+	e.Observe(pir, time.Now()) // Already occupied
+
+	want := []Event{{FnOccupied, "landing"}, {FnOccupied, "upstairs"}, {FnVacant, "landing"}}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Fatalf("want %v got %v", w, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("want %v, got nothing", w)
+		}
+	}
+	st := e.State()
+	if st["landing"].Occupied || !st["upstairs"].Occupied {
+		t.Fatalf("want only upstairs occupied, got %+v", st)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("want no more events, got %v", ev)
+	default:
+	}
+}
+
+func TestRule(t *testing.T) {
+	rs := []rules.Rule{{
+		Name: "Landing empty",
+		When: map[string]string{"pkt": "occupancy", "fn": "vacant", "area": "landing"},
+		Then: rules.Action{Device: "R3D2", Action: "off"},
+	}}
+	if err := rules.Check(rs, Fields...); err != nil {
+		t.Fatal(err)
+	}
+}
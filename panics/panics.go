@@ -0,0 +1,40 @@
+// Package panics lets goroutines and timer callbacks started within other
+// packages report panics, e.g. as bug reports (see
+// bugreport.Bundler.ReportPanics), rather than crash the daemon. Until a
+// handler is set, e.g. in tests and lwlctl, panics crash as usual.
+package panics
+
+import "sync/atomic"
+
+var handler atomic.Pointer[func(name string, v any)]
+
+// SetHandler sets the function given panics recovered by Go and Func, with
+// the name of what panicked, or with nil lets panics crash again
+func SetHandler(f func(name string, v any)) {
+	if f == nil {
+		handler.Store(nil)
+		return
+	}
+	handler.Store(&f)
+}
+
+// Go runs f in a goroutine, see Func
+func Go(name string, f func()) {
+	go Func(name, f)()
+}
+
+// Func wraps f, e.g. for time.AfterFunc, so that if it panics the handler is
+// called and f abandoned, rather than the daemon crashing. f should leave
+// nothing locked or half done that the rest of the daemon relies on.
+func Func(name string, f func()) func() {
+	return func() {
+		defer func() {
+			if h := handler.Load(); h != nil {
+				if v := recover(); v != nil {
+					(*h)(name, v)
+				}
+			}
+		}()
+		f()
+	}
+}
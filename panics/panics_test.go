@@ -0,0 +1,36 @@
+package panics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGo(t *testing.T) {
+	got := make(chan string, 1)
+	SetHandler(func(name string, v any) { got <- name + ": " + v.(string) })
+	defer SetHandler(nil)
+
+	Go("test", func() { panic("boom") })
+	select {
+	case s := <-got:
+		if s != "test: boom" {
+			t.Errorf("got %q", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic not handled")
+	}
+
+	time.AfterFunc(0, Func("timer", func() { panic("bang") }))
+	if s := <-got; s != "timer: bang" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestUnhandled(t *testing.T) {
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Errorf("want panic to propagate, got %v", v)
+		}
+	}()
+	Func("test", func() { panic("boom") })()
+}
@@ -0,0 +1,201 @@
+// Package presence tracks who is at home, from the geofencing of phone apps
+// such as OwnTracks or iOS Shortcuts, so that rules can act when e.g. the
+// last person leaves:
+//
+//   - name: Everyone left
+//     when: {pkt: presence, fn: allAway}
+//     then: {device: hall_lights, action: "off"}
+package presence
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Pkt identifies presence events, like Response.Pkt for messages from the LWL
+const Pkt = "presence"
+
+// Functions of presence events, like Response.Fn
+const (
+	FnArrive    = "arrive"    // Someone came home
+	FnLeave     = "leave"     // Someone left home
+	FnFirstHome = "firstHome" // Someone came home to an empty house, after FnArrive
+	FnAllAway   = "allAway"   // The last person left, after FnLeave
+)
+
+// Fields are those of an Event besides pkt and fn, which rules may match, see
+// rules.Check
+var Fields = []string{"person", "home"}
+
+// Event is a change in who is at home. It is a rules.Event, with fields
+// "pkt" (always Pkt), "fn", "person" and "home" (how many people are at home
+// afterwards).
+type Event struct {
+	Fn     string
+	Person string
+	Home   int
+}
+
+// Field implements rules.Event
+func (e Event) Field(name string) (any, bool) {
+	switch name {
+	case "pkt":
+		return Pkt, true
+	case "fn":
+		return e.Fn, true
+	case "person":
+		return e.Person, true
+	case "home":
+		return e.Home, true
+	}
+	return nil, false
+}
+
+// LogValue implements slog.LogValuer.
+func (e Event) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("pkt", Pkt),
+		slog.String("fn", e.Fn),
+		slog.String("person", e.Person),
+		slog.Int("home", e.Home),
+	)
+}
+
+// Update reports that someone entered or left a region, as posted by e.g. an
+// iOS Shortcuts automation:
+//
+//	{"person": "alice", "event": "leave", "region": "home"}
+type Update struct {
+	Person string `json:"person"`
+	Event  string `json:"event"` // "enter" or "leave"
+	Region string `json:"region"`
+}
+
+// Check returns an error if the update is incomplete
+func (u Update) Check() error {
+	switch {
+	case u.Person == "":
+		return fmt.Errorf("missing person")
+	case u.Event != "enter" && u.Event != "leave":
+		return fmt.Errorf("event should be enter or leave, got %q", u.Event)
+	}
+	return nil
+}
+
+// Tracker records who is at home, from Updates about the home region
+type Tracker struct {
+	region string      // Name of the home region, compared case-insensitively
+	notify func(Event) // Called with each Event, e.g. rules.Engine.Handle
+
+	mu   sync.Mutex
+	home map[string]bool // Person -> at home
+}
+
+// NewTracker returns a Tracker of who is in the named region (e.g. "home"),
+// calling notify with each resulting Event. Updates about other regions are
+// ignored.
+func NewTracker(region string, notify func(Event)) *Tracker {
+	return &Tracker{region: region, notify: notify, home: make(map[string]bool)}
+}
+
+// Apply records an update, returning the resulting events, which are also
+// passed to notify. Someone not seen before is assumed to have been away, so
+// the first update about them only causes an event if they are home.
+func (t *Tracker) Apply(u Update) ([]Event, error) {
+	if err := u.Check(); err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Region, t.region) {
+		slog.Debug("Presence update for another region", "update", u, "home", t.region)
+		return nil, nil
+	}
+	home := u.Event == "enter"
+
+	t.mu.Lock()
+	was := t.home[u.Person]
+	t.home[u.Person] = home
+	n := t.count()
+	t.mu.Unlock()
+
+	var out []Event
+	switch {
+	case home && !was:
+		out = append(out, Event{Fn: FnArrive, Person: u.Person, Home: n})
+		if n == 1 {
+			out = append(out, Event{Fn: FnFirstHome, Person: u.Person, Home: n})
+		}
+	case !home && was:
+		out = append(out, Event{Fn: FnLeave, Person: u.Person, Home: n})
+		if n == 0 {
+			out = append(out, Event{Fn: FnAllAway, Person: u.Person, Home: n})
+		}
+	}
+	for _, e := range out {
+		slog.Info("Presence", "event", e)
+		if t.notify != nil {
+			t.notify(e)
+		}
+	}
+	return out, nil
+}
+
+// count returns how many people are at home. The caller must hold mu.
+func (t *Tracker) count() int {
+	n := 0
+	for _, home := range t.home {
+		if home {
+			n++
+		}
+	}
+	return n
+}
+
+// People returns whether each person seen is at home, by name
+func (t *Tracker) People() map[string]bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.home)
+}
+
+// ownTracks is the subset of an OwnTracks message used here, see
+// https://owntracks.org/booklet/tech/json/
+type ownTracks struct {
+	Type      string   `json:"_type"`     // e.g. "transition" or "location"
+	Event     string   `json:"event"`     // For transitions, "enter" or "leave"
+	Desc      string   `json:"desc"`      // For transitions, the region's name
+	InRegions []string `json:"inregions"` // For locations, the regions currently in
+	TID       string   `json:"tid"`       // Tracker ID, e.g. initials
+}
+
+// DecodeOwnTracks decodes a message posted by OwnTracks in HTTP mode into an
+// Update of the home region. user is the X-Limit-U header, which identifies
+// the person; if it is empty the tracker ID is used. ok is false for messages
+// which say nothing about the home region, e.g. waypoints.
+func (t *Tracker) DecodeOwnTracks(data []byte, user string) (u Update, ok bool, err error) {
+	var m ownTracks
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Update{}, false, err
+	}
+	person := user
+	if person == "" {
+		person = m.TID
+	}
+	switch m.Type {
+	case "transition":
+		return Update{Person: person, Event: m.Event, Region: m.Desc}, strings.EqualFold(m.Desc, t.region), nil
+	case "location":
+		// Locations list the regions the phone is in, and omit them when in
+		// none, so each says whether the person is at home
+		event := "leave"
+		if slices.ContainsFunc(m.InRegions, func(r string) bool { return strings.EqualFold(r, t.region) }) {
+			event = "enter"
+		}
+		return Update{Person: person, Event: event, Region: t.region}, true, nil
+	}
+	return Update{}, false, nil
+}
@@ -0,0 +1,90 @@
+package presence
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/rules"
+)
+
+func TestTracker(t *testing.T) {
+	var got []string
+	tr := NewTracker("home", func(e Event) { got = append(got, e.Person+" "+e.Fn) })
+	for _, u := range []Update{
+		{Person: "alice", Event: "leave", Region: "home"}, // Not seen before, so assumed away
+		{Person: "alice", Event: "enter", Region: "Home"},
+		{Person: "bob", Event: "enter", Region: "home"},
+		{Person: "bob", Event: "enter", Region: "home"}, // No change
+		{Person: "bob", Event: "leave", Region: "work"}, // Ignored
+		{Person: "alice", Event: "leave", Region: "home"},
+		{Person: "bob", Event: "leave", Region: "home"},
+	} {
+		if _, err := tr.Apply(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"alice arrive", "alice firstHome", "bob arrive", "alice leave", "bob leave", "bob allAway"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %q got %q", want, got)
+	}
+	if p := tr.People(); len(p) != 2 || p["alice"] || p["bob"] {
+		t.Fatalf("want alice and bob away, got %v", p)
+	}
+
+	if _, err := tr.Apply(Update{Person: "alice", Event: "wander", Region: "home"}); err == nil {
+		t.Fatal("want error for unknown event")
+	}
+}
+
+func TestDecodeOwnTracks(t *testing.T) {
+	tr := NewTracker("home", nil)
+	for _, tt := range []struct {
+		msg, user string
+		want      Update
+		ok        bool
+	}{
+		{`{"_type":"transition","event":"enter","desc":"Home","tid":"al"}`, "alice", Update{"alice", "enter", "Home"}, true},
+		{`{"_type":"transition","event":"leave","desc":"Work","tid":"al"}`, "alice", Update{"alice", "leave", "Work"}, false},
+		{`{"_type":"location","inregions":["Work","home"],"tid":"bo"}`, "", Update{"bo", "enter", "home"}, true},
+		{`{"_type":"location","lat":52.18,"lon":0.21,"tid":"bo"}`, "bob", Update{"bob", "leave", "home"}, true},
+		{`{"_type":"waypoint","desc":"Home"}`, "bob", Update{}, false},
+	} {
+		got, ok, err := tr.DecodeOwnTracks([]byte(tt.msg), tt.user)
+		if err != nil || ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("%s: want %+v %v got %+v %v %v", tt.msg, tt.want, tt.ok, got, ok, err)
+		}
+	}
+}
+
+// light is a rules.Switch which records whether it is on
+type light struct{ on bool }
+
+func (l *light) On(ctx context.Context) error             { l.on = true; return nil }
+func (l *light) Off(ctx context.Context) error            { l.on = false; return nil }
+func (l *light) Dim(ctx context.Context, level int) error { return l.On(ctx) }
+
+func TestRule(t *testing.T) {
+	rs := []rules.Rule{{
+		Name: "Everyone left",
+		When: map[string]string{"pkt": "presence", "fn": "allAway", "home": "0"},
+		Then: rules.Action{Device: "R1D1", Action: "off"},
+	}}
+	if err := rules.Check(rs, Fields...); err != nil {
+		t.Fatal(err)
+	}
+	l := &light{on: true}
+	e := rules.NewEngine(rs, func(string) (rules.Switch, error) { return l, nil })
+	tr := NewTracker("home", func(ev Event) { e.Handle(t.Context(), ev) })
+
+	tr.Apply(Update{Person: "alice", Event: "enter", Region: "home"})
+	tr.Apply(Update{Person: "bob", Event: "enter", Region: "home"})
+	tr.Apply(Update{Person: "alice", Event: "leave", Region: "home"})
+	if !l.on {
+		t.Fatal("want light on while bob is home")
+	}
+	tr.Apply(Update{Person: "bob", Event: "leave", Region: "home"})
+	if l.on {
+		t.Fatal("want light off once everyone left")
+	}
+}
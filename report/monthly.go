@@ -0,0 +1,33 @@
+package report
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/energy"
+)
+
+// MailMonthly emails the previous month's report early on the first of each
+// month, until the context is done
+func MailMonthly(ctx context.Context, src Source, server, from string, to []string) {
+	for {
+		now := time.Now()
+		next := energy.Month.Next(energy.Month.Start(now)).Add(6 * time.Hour)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		r, err := Build(src, energy.Month.Back(next, 1))
+		if err == nil {
+			err = r.Mail(server, from, to)
+		}
+		if err != nil {
+			slog.Error("Failed to send monthly report", "to", to, "err", err)
+			continue
+		}
+		slog.Info("Sent monthly report", "to", to, "month", r.From.Format("2006-01"))
+	}
+}
@@ -0,0 +1,110 @@
+package report
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// funcs are available to both templates
+var funcs = map[string]any{
+	"month": func(t time.Time) string { return t.Format("January 2006") },
+	"days": func(d float64) string {
+		if math.IsInf(d, 1) {
+			return "?"
+		}
+		return strconvFloat(d, 0)
+	},
+	"float": strconvFloat,
+	"duration": func(d time.Duration) string {
+		return (d.Round(time.Hour)).String()
+	},
+}
+
+const textReport = `LightwaveRF report for {{month .From}}
+
+Energy
+{{- with .Energy}}
+  Used:  {{float .KWh 1}} kWh
+  Cost:  {{$.Tariff.Format .Cost}} (including standing charges)
+{{- else}}
+  No tariff configured
+{{- end}}
+
+Heating (hours with valve open)
+{{- range .Heating}}
+  {{printf "%-8s %-20s %6.1f" .Serial .Name .Hours}}
+{{- else}}
+  No valves reported
+{{- end}}
+
+Batteries (volts, days left)
+{{- range .Batteries}}
+  {{printf "%-8s %-20s %4.2f %s" .Serial (index $.Names .Serial) .Latest.Volts .Trend}} {{days .DaysRemaining}}
+{{- else}}
+  No readings
+{{- end}}
+
+Hub
+{{- if .Hub.Seen}}
+  Restarts: {{.Hub.Restarts}}
+  Uptime:   {{duration .Hub.Uptime}}
+{{- else}}
+  Not seen
+{{- end}}
+`
+
+const htmlReport = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>LightwaveRF report for {{month .From}}</title></head>
+<body>
+<h1>LightwaveRF report for {{month .From}}</h1>
+<h2>Energy</h2>
+{{- with .Energy}}
+<p>Used {{float .KWh 1}} kWh, costing {{$.Tariff.Format .Cost}} including standing charges.</p>
+{{- else}}
+<p>No tariff configured.</p>
+{{- end}}
+<h2>Heating</h2>
+<table>
+<tr><th>Serial</th><th>Name</th><th>Hours open</th></tr>
+{{- range .Heating}}
+<tr><td>{{.Serial}}</td><td>{{.Name}}</td><td>{{float .Hours 1}}</td></tr>
+{{- end}}
+</table>
+<h2>Batteries</h2>
+<table>
+<tr><th>Serial</th><th>Name</th><th>Volts</th><th>Trend</th><th>Days left</th></tr>
+{{- range .Batteries}}
+<tr><td>{{.Serial}}</td><td>{{index $.Names .Serial}}</td><td>{{float .Latest.Volts 2}}</td><td>{{.Trend}}</td><td>{{days .DaysRemaining}}</td></tr>
+{{- end}}
+</table>
+<h2>Hub</h2>
+{{- if .Hub.Seen}}
+<p>Restarted {{.Hub.Restarts}} times. Up for {{duration .Hub.Uptime}}.</p>
+{{- else}}
+<p>Not seen.</p>
+{{- end}}
+</body></html>
+`
+
+var (
+	textTemplate = template.Must(template.New("report").Funcs(funcs).Parse(textReport))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(funcs).Parse(htmlReport))
+)
+
+// Text writes the report as plain text
+func (r *Report) Text(w io.Writer) error {
+	return textTemplate.Execute(w, r)
+}
+
+// HTML writes the report as an HTML page
+func (r *Report) HTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+func strconvFloat(f float64, prec int) string {
+	return strconv.FormatFloat(f, 'f', prec, 64)
+}
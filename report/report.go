@@ -0,0 +1,132 @@
+// Package report summarises a month of energy use, heating, batteries and
+// hub uptime, as text, HTML or email
+package report
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+	"github.com/meermanr/LightwaveRF-go/energy"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+// Readings further apart than this leave a gap, rather than being assumed to
+// hold in between
+const maxGap = 30 * time.Minute
+
+// Report is a month's summary
+type Report struct {
+	From, To time.Time // The month
+
+	Energy    *energy.Usage // Nil if there is no tariff
+	Tariff    *energy.Tariff
+	Heating   []Heating
+	Batteries []battery.Summary
+	Hub       Hub
+	Names     map[string]string // Serial -> name
+}
+
+// Heating is the time a valve spent open during the month
+type Heating struct {
+	Serial string  `json:"serial"`
+	Name   string  `json:"name,omitempty"`
+	Hours  float64 `json:"hours"`
+}
+
+// Hub is the LWL's uptime during the month
+type Hub struct {
+	Restarts int           // Times the uptime fell
+	Uptime   time.Duration // Most recently reported
+	Seen     bool          // Whether any uptime was reported
+}
+
+// Source is what a report is built from. Tariff, History and Names are
+// optional.
+type Source struct {
+	Telemetry telemetry.Store
+	Tariff    *energy.Tariff
+	History   *battery.History
+	Names     func() map[string]string // Serial -> name, as configured now
+}
+
+// Build summarises the month containing t
+func Build(src Source, t time.Time) (*Report, error) {
+	from := energy.Month.Start(t)
+	r := &Report{From: from, To: energy.Month.Next(from), Tariff: src.Tariff, Names: map[string]string{}}
+	if src.Names != nil {
+		r.Names = src.Names()
+	}
+
+	if src.Tariff != nil {
+		usage, err := energy.Query(src.Telemetry, src.Tariff, from, from, energy.Month)
+		if err != nil {
+			return nil, err
+		}
+		r.Energy = &usage[0]
+	}
+
+	series, err := src.Telemetry.Series()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range series {
+		serial, ok := strings.CutSuffix(s, ".output")
+		if !ok {
+			continue
+		}
+		ps, err := src.Telemetry.Query(s, r.From, r.To)
+		if err != nil {
+			return nil, err
+		}
+		r.Heating = append(r.Heating, Heating{Serial: serial, Name: r.Names[serial], Hours: hoursOn(ps)})
+	}
+	slices.SortFunc(r.Heating, func(a, b Heating) int {
+		return cmp.Or(cmp.Compare(b.Hours, a.Hours), strings.Compare(a.Serial, b.Serial))
+	})
+
+	ps, err := src.Telemetry.Query(telemetry.HubUptime, r.From, r.To)
+	if err != nil {
+		return nil, err
+	}
+	r.Hub = hub(ps)
+
+	if src.History != nil {
+		readings, err := src.History.Load()
+		if err != nil {
+			return nil, err
+		}
+		readings = slices.DeleteFunc(readings, func(rd battery.Reading) bool {
+			return !rd.Time.Before(r.To)
+		})
+		r.Batteries = battery.Summarise(readings)
+	}
+	return r, nil
+}
+
+// hoursOn returns the time during which a valve's output was above zero
+func hoursOn(ps []telemetry.Point) float64 {
+	var on time.Duration
+	for i := 1; i < len(ps); i++ {
+		if ps[i-1].Value > 0 {
+			on += min(ps[i].Time.Sub(ps[i-1].Time), maxGap)
+		}
+	}
+	return math.Round(on.Hours()*10) / 10
+}
+
+// hub summarises the LWL's reported uptime
+func hub(ps []telemetry.Point) Hub {
+	var h Hub
+	for i, p := range ps {
+		if i > 0 && p.Value < ps[i-1].Value {
+			h.Restarts++
+		}
+		h.Uptime = time.Duration(p.Value) * time.Second
+		h.Seen = true
+	}
+	return h
+}
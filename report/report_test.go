@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/battery"
+	"github.com/meermanr/LightwaveRF-go/energy"
+	"github.com/meermanr/LightwaveRF-go/telemetry"
+)
+
+func TestReport(t *testing.T) {
+	tele := telemetry.NewMemory()
+	hist := battery.NewHistory(filepath.Join(t.TempDir(), "battery.jsonl"))
+	t0 := time.Date(2026, 9, 10, 0, 0, 0, 0, time.Local)
+	at := func(h float64) time.Time { return t0.Add(time.Duration(h * float64(time.Hour))) }
+	tele.Put(
+		telemetry.Point{Series: "A1B2C3.today", Time: at(12), Value: 30000},
+		telemetry.Point{Series: "24C702.output", Time: at(0), Value: 40},
+		telemetry.Point{Series: "24C702.output", Time: at(0.25), Value: 60},
+		telemetry.Point{Series: "24C702.output", Time: at(0.5), Value: 0},
+		telemetry.Point{Series: "24C702.output", Time: at(10), Value: 100}, // Gap, counted as maxGap
+		telemetry.Point{Series: "24C702.output", Time: at(20), Value: 0},
+		telemetry.Point{Series: telemetry.HubUptime, Time: at(1), Value: 7200},
+		telemetry.Point{Series: telemetry.HubUptime, Time: at(2), Value: 60},
+		telemetry.Point{Series: telemetry.HubUptime, Time: at(3), Value: 3660},
+		telemetry.Point{Series: telemetry.HubUptime, Time: t0.AddDate(0, 1, 0), Value: 10}, // Next month
+	)
+	hist.Append(battery.Reading{Serial: "24C702", Prod: "valve", Time: at(0), Volts: 2.9})
+
+	r, err := Build(Source{
+		Telemetry: tele,
+		Tariff:    &energy.Tariff{Currency: "£", Unit: 0.2},
+		History:   hist,
+		Names:     func() map[string]string { return map[string]string{"24C702": "Lounge"} },
+	}, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Heating) != 1 || r.Heating[0].Hours != 1 {
+		t.Errorf("heating = %+v, want 1 hour", r.Heating)
+	}
+	if r.Hub != (Hub{Restarts: 1, Uptime: time.Hour + time.Minute, Seen: true}) {
+		t.Errorf("hub = %+v", r.Hub)
+	}
+
+	var text bytes.Buffer
+	if err := r.Text(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"September 2026", "30.0 kWh", "£6.00", "Lounge", "2.90", "Restarts: 1"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("want %q in:\n%s", want, text.String())
+		}
+	}
+
+	msg, err := r.message("lwl@example.com", []string{"me@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Subject: LightwaveRF report for September 2026", "multipart/alternative", "text/plain", "text/html", "<td>Lounge</td>"} {
+		if !bytes.Contains(msg, []byte(want)) {
+			t.Errorf("want %q in message", want)
+		}
+	}
+}
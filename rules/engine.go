@@ -0,0 +1,341 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+)
+
+// Switch is something a rule can act on, such as an lwl.Device or lwl.Group
+type Switch interface {
+	On(ctx context.Context) error
+	Off(ctx context.Context) error
+	Dim(ctx context.Context, level int) error
+}
+
+// Event is something a rule can match, such as an lwl.Response or a change
+// of someone's presence. Field returns the value of a field by name, e.g.
+// "pkt", as used in Rule.When.
+type Event interface {
+	Field(name string) (any, bool)
+}
+
+// Engine evaluates rules against messages from the LWL, and other Events.
+// Why each rule did or did not fire is logged at debug level.
+type Engine struct {
+	// Resolve finds the device a rule acts on, e.g. lwl.Registry.Resolve
+	Resolve func(name string) (Switch, error)
+
+	// DuskDawn returns today's dusk and dawn, e.g. lwl.Client.DuskDawn.
+	// Required by rules active between dusk and dawn.
+	DuskDawn func(ctx context.Context) (dusk, dawn time.Time, err error)
+
+	// Recorded is called with each scene recorded, see Record, e.g. to save
+	// it with SaveScene. Optional.
+	Recorded func(Scene)
+
+	now    func() time.Time // For testing
+	posted chan Event       // See Post
+
+	mu        sync.Mutex
+	rules     []*state
+	scenes    map[string]*Scene              // By name, see SetScenes
+	running   map[string]*context.CancelFunc // Scenes in progress, by name
+	recording *recording                     // In progress, see Record
+	sun       struct {
+		day        string // Date dusk and dawn were fetched for, e.g. "2026-01-02"
+		dusk, dawn time.Time
+	}
+}
+
+// state is a rule, and what has become of it
+type state struct {
+	Rule
+	period    *period
+	enabled   bool
+	fired     time.Time   // Most recent
+	undo      *time.Timer // Pending switch off, see Rule.For
+	echo      []string    // IDs of the devices last commanded, whose echoes are ignored until echoUntil
+	echoUntil time.Time
+}
+
+// How long after a rule fires the LWL's echo of its command is ignored, so
+// that a rule cannot retrigger itself, e.g. when {pkt: 433T, room: 3} then
+// R3D1 on
+const echoWindow = 2 * time.Second
+
+// Events queued by Post, beyond which they are dropped
+const postQueue = 100
+
+// Status describes a rule, for reporting
+type Status struct {
+	Name    string    `json:"name"`
+	Enabled bool      `json:"enabled"`
+	Fired   time.Time `json:"fired,omitzero"` // Most recent
+	Active  bool      `json:"active"`         // Waiting to switch off, see Rule.For
+}
+
+// NewEngine returns an Engine for rules, which should have passed Check
+func NewEngine(rs []Rule, resolve func(name string) (Switch, error)) *Engine {
+	e := &Engine{Resolve: resolve, now: time.Now, posted: make(chan Event, postQueue)}
+	for _, r := range rs {
+		p, _ := parsePeriod(r.Between)
+		e.rules = append(e.rules, &state{Rule: r, period: p, enabled: r.Enabled == nil || *r.Enabled})
+	}
+	return e
+}
+
+// Run evaluates rules against each message, and each Event posted, until
+// the channel is closed or the context is done
+func (e *Engine) Run(ctx context.Context, msgs <-chan lwl.Response) {
+	for {
+		select {
+		case r, ok := <-msgs:
+			if !ok {
+				return
+			}
+			e.Handle(ctx, r)
+		case ev := <-e.posted:
+			e.Handle(ctx, ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Post queues an event for Run to handle, so that the caller, e.g. an HTTP
+// handler, need not wait for the rules it fires. The event is dropped if too
+// many are queued.
+func (e *Engine) Post(ev Event) {
+	select {
+	case e.posted <- ev:
+	default:
+		slog.Warn("Rules are too busy, dropped event", "event", ev)
+	}
+}
+
+// Handle evaluates rules against an event, e.g. a message from the LWL,
+// firing those which match, and records it if a recording is in progress.
+// Rules fired by replayed messages are dry runs.
+func (e *Engine) Handle(ctx context.Context, ev Event) {
+	e.record(ev)
+
+	e.mu.Lock()
+	rules := slices.Clone(e.rules)
+	e.mu.Unlock()
+
+	r, _ := ev.(lwl.Response)
+	if r.Replay {
+		ctx = lwl.WithDryRun(ctx) // Testing against old traffic, see lwl.Replay
+	}
+	for _, s := range rules {
+		if why := e.check(ctx, s, ev); why != "" {
+			slog.Debug("Rule did not fire", "rule", s.Name, "why", why, "msg", ev)
+			continue
+		}
+		slog.Info("Rule fired", "rule", s.Name, "then", s.Then, "for", s.For, "msg", ev, "replay", r.Replay)
+		switch err := e.fire(lwl.WithSource(ctx, "rule:"+s.Name), s); {
+		case errors.Is(err, lwl.ErrHeldOff):
+			slog.Info("Rule held off", "rule", s.Name, "then", s.Then, "why", err)
+		case err != nil:
+			slog.Error("Rule failed", "rule", s.Name, "then", s.Then, "err", err)
+		}
+	}
+}
+
+// check returns why a rule should not fire for an event, or "" if it should
+func (e *Engine) check(ctx context.Context, s *state, ev Event) string {
+	e.mu.Lock()
+	enabled := s.enabled
+	e.mu.Unlock()
+	if !enabled {
+		return "disabled"
+	}
+	if r, ok := ev.(lwl.Response); ok && r.Pkt == "433T" && e.isEcho(s, r) {
+		return "echo of its own command"
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.When)) {
+		got, _ := ev.Field(k)
+		if want := s.When[k]; fmt.Sprint(got) != want {
+			return fmt.Sprintf("%s is %v, want %s", k, got, want)
+		}
+	}
+	return e.outside(ctx, s.period)
+}
+
+// isEcho reports whether a 433T message is the LWL's echo of a command a
+// rule recently sent, e.g. to R3D1, or to a room (R3) containing the device
+func (e *Engine) isEcho(s *state, r lwl.Response) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.now().After(s.echoUntil) {
+		return false
+	}
+	return slices.Contains(s.echo, fmt.Sprintf("R%dD%d", r.Room, r.Dev)) || slices.Contains(s.echo, fmt.Sprintf("R%d", r.Room))
+}
+
+// switchIDs returns the IDs of the devices a Switch commands, e.g. R3D1,
+// where known
+func switchIDs(sw Switch) []string {
+	switch sw := sw.(type) {
+	case interface{ ID() string }:
+		return []string{sw.ID()}
+	case *lwl.Group:
+		var ids []string
+		for _, d := range sw.Devices {
+			ids = append(ids, d.ID())
+		}
+		return ids
+	}
+	return nil
+}
+
+// outside returns why now is outside a period, or "" if it is within it
+func (e *Engine) outside(ctx context.Context, p *period) string {
+	if p == nil {
+		return ""
+	}
+	now := e.now()
+	dusk, dawn, err := e.duskDawn(ctx, now)
+	if err != nil && (p.start.sun != "" || p.end.sun != "") {
+		return fmt.Sprintf("unable to find dusk and dawn: %v", err)
+	}
+	start, end := p.start.at(dusk, dawn), p.end.at(dusk, dawn)
+	if !timeofday.Within(timeofday.SinceMidnight(now), start, end) {
+		return fmt.Sprintf("outside %v (%v-%v)", p, fmtOffset(start), fmtOffset(end))
+	}
+	return ""
+}
+
+// fire performs a rule's action, and schedules it to be undone if required.
+// Scenes are run in the background, so that their waits do not hold up
+// other rules.
+func (e *Engine) fire(ctx context.Context, s *state) error {
+	if s.Then.Scene != "" {
+		e.mu.Lock()
+		s.fired = e.now()
+		e.mu.Unlock()
+		panics.Go("scene", func() {
+			if err := e.RunScene(context.WithoutCancel(ctx), s.Then.Scene); err != nil {
+				slog.Error("Rule failed", "rule", s.Name, "then", s.Then, "err", err)
+			}
+		})
+		return nil
+	}
+
+	sw, err := e.do(ctx, s.Then)
+	if sw == nil {
+		return err // No device, so nothing to undo
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s.fired = e.now()
+	s.echo, s.echoUntil = switchIDs(sw), s.fired.Add(echoWindow)
+	if s.For > 0 {
+		if s.undo != nil {
+			s.undo.Stop()
+		}
+		s.undo = time.AfterFunc(s.For, panics.Func("rule", func() {
+			e.mu.Lock()
+			s.undo = nil
+			e.mu.Unlock()
+			slog.Info("Rule expired", "rule", s.Name, "device", s.Then.Device)
+			if err := sw.Off(context.WithoutCancel(ctx)); err != nil {
+				slog.Error("Rule failed to switch off", "rule", s.Name, "device", s.Then.Device, "err", err)
+			}
+		}))
+	}
+	return err
+}
+
+// do performs an action on a device, returning the device
+func (e *Engine) do(ctx context.Context, a Action) (Switch, error) {
+	sw, err := e.Resolve(a.Device)
+	if err != nil {
+		return nil, err
+	}
+	switch a.Action {
+	case "on":
+		err = sw.On(ctx)
+	case "off":
+		err = sw.Off(ctx)
+	case "dim":
+		err = sw.Dim(ctx, a.Level)
+	}
+	return sw, err
+}
+
+// duskDawn returns today's dusk and dawn, asking the LWL at most once a day
+func (e *Engine) duskDawn(ctx context.Context, now time.Time) (dusk, dawn time.Time, err error) {
+	day := now.Format(time.DateOnly)
+	e.mu.Lock()
+	if e.sun.day == day {
+		defer e.mu.Unlock()
+		return e.sun.dusk, e.sun.dawn, nil
+	}
+	e.mu.Unlock()
+
+	if e.DuskDawn == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("no source of dusk and dawn times")
+	}
+	dusk, dawn, err = e.DuskDawn(ctx)
+	if err != nil {
+		return dusk, dawn, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sun.day, e.sun.dusk, e.sun.dawn = day, dusk, dawn
+	return dusk, dawn, nil
+}
+
+// SetEnabled enables or disables the named rule. Disabling a rule does not
+// cancel a pending switch off.
+func (e *Engine) SetEnabled(name string, enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.rules {
+		if s.Name == name {
+			s.enabled = enabled
+			slog.Info("Rule", "rule", name, "enabled", enabled)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such rule: %s", name)
+}
+
+// Rules returns the status of each rule, in configuration order
+func (e *Engine) Rules() []Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Status, 0, len(e.rules))
+	for _, s := range e.rules {
+		out = append(out, Status{Name: s.Name, Enabled: s.enabled, Fired: s.fired, Active: s.undo != nil})
+	}
+	return out
+}
+
+// at returns the offset from midnight of an endpoint, given today's dusk and
+// dawn
+func (e endpoint) at(dusk, dawn time.Time) time.Duration {
+	switch e.sun {
+	case "dusk":
+		return timeofday.SinceMidnight(dusk)
+	case "dawn":
+		return timeofday.SinceMidnight(dawn)
+	}
+	return e.offset
+}
+
+func fmtOffset(d time.Duration) string {
+	return endpoint{offset: d}.String()
+}
@@ -0,0 +1,180 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeSwitch records the actions performed on it
+type fakeSwitch struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (f *fakeSwitch) record(s string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, s)
+	return nil
+}
+
+func (f *fakeSwitch) On(ctx context.Context) error {
+	if lwl.IsDryRun(ctx) {
+		return f.record("on (dry run)")
+	}
+	return f.record("on")
+}
+func (f *fakeSwitch) Off(context.Context) error      { return f.record("off") }
+func (f *fakeSwitch) Dim(context.Context, int) error { return f.record("dim") }
+
+func (f *fakeSwitch) actions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.log...)
+}
+
+const testRules = `
+- name: Landing light
+  when: {pkt: 433T, room: 3, fn: "on"}
+  between: dusk-dawn
+  then: {device: R3D1, action: "on"}
+  for: 50ms
+- name: Never
+  enabled: false
+  when: {pkt: 433T}
+  then: {device: R3D1, action: "off"}
+`
+
+func TestEngine(t *testing.T) {
+	var rs []Rule
+	if err := yaml.Unmarshal([]byte(testRules), &rs); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(rs); err != nil {
+		t.Fatal(err)
+	}
+
+	sw := &fakeSwitch{}
+	e := NewEngine(rs, func(name string) (Switch, error) {
+		if name != "R3D1" {
+			return nil, errors.New("no such device")
+		}
+		return sw, nil
+	})
+	day := time.Date(2026, 1, 7, 0, 0, 0, 0, time.Local)
+	e.DuskDawn = func(context.Context) (time.Time, time.Time, error) {
+		return day.Add(16 * time.Hour), day.Add(8 * time.Hour), nil
+	}
+	pir := lwl.Response{Pkt: "433T", Room: 3, Fn: "on"}
+
+	// Daytime
+	e.now = func() time.Time { return day.Add(12 * time.Hour) }
+	e.Handle(t.Context(), pir)
+	if got := sw.actions(); len(got) != 0 {
+		t.Fatalf("fired during the day: %v", got)
+	}
+
+	// Night, then switch off after For
+	e.now = func() time.Time { return day.Add(22 * time.Hour) }
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 4, Fn: "on"})
+	e.Handle(t.Context(), pir)
+	if got := sw.actions(); len(got) != 1 || got[0] != "on" {
+		t.Fatalf("want [on] got %v", got)
+	}
+	if st := e.Rules()[0]; !st.Active || st.Fired.IsZero() {
+		t.Errorf("want active and fired, got %+v", st)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := sw.actions(); len(got) != 2 || got[1] != "off" {
+		t.Fatalf("want [on off] got %v", got)
+	}
+
+	// Disabled
+	if err := e.SetEnabled("Landing light", false); err != nil {
+		t.Fatal(err)
+	}
+	e.Handle(t.Context(), pir)
+	if got := sw.actions(); len(got) != 2 {
+		t.Fatalf("disabled rule fired: %v", got)
+	}
+	if err := e.SetEnabled("Nonexistent", true); err == nil {
+		t.Error("enabled a nonexistent rule")
+	}
+}
+
+func TestEngineUnknownDevice(t *testing.T) {
+	rs := []Rule{{Name: "Gone", When: map[string]string{"pkt": "433T"}, Then: Action{Device: "renamed", Action: "on"}, For: time.Millisecond}}
+	e := NewEngine(rs, func(name string) (Switch, error) {
+		return nil, errors.New("no such device")
+	})
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T"})
+	time.Sleep(20 * time.Millisecond) // An undo would have panicked by now
+	if st := e.Rules()[0]; st.Active {
+		t.Errorf("undo armed for a device which does not exist: %+v", st)
+	}
+}
+
+func TestEnginePost(t *testing.T) {
+	rs := []Rule{{Name: "Room 3", When: map[string]string{"pkt": "433T", "room": "3"}, Then: Action{Device: "R3D1", Action: "on"}}}
+	sw := &fakeSwitch{}
+	e := NewEngine(rs, func(name string) (Switch, error) { return sw, nil })
+	e.Post(lwl.Response{Pkt: "433T", Room: 3}) // Queued until Run
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx, make(chan lwl.Response))
+		close(done)
+	}()
+	for deadline := time.Now().Add(time.Second); len(sw.actions()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if got := sw.actions(); len(got) != 1 || got[0] != "on" {
+		t.Errorf("want [on] got %v", got)
+	}
+}
+
+// idSwitch is a fakeSwitch with an ID, like lwl.Device
+type idSwitch struct {
+	fakeSwitch
+	id string
+}
+
+func (s *idSwitch) ID() string { return s.id }
+
+func TestEngineReplay(t *testing.T) {
+	rs := []Rule{{Name: "Room 3", When: map[string]string{"pkt": "433T", "room": "3", "fn": "on"}, Then: Action{Device: "R3D1", Action: "on"}}}
+	sw := &fakeSwitch{}
+	e := NewEngine(rs, func(name string) (Switch, error) { return sw, nil })
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 3, Dev: 2, Fn: "on", Replay: true})
+	if got := sw.actions(); len(got) != 1 || got[0] != "on (dry run)" {
+		t.Fatalf("replay should fire as a dry run, got %v", got)
+	}
+}
+
+func TestEngineEcho(t *testing.T) {
+	rs := []Rule{{Name: "Room 3", When: map[string]string{"pkt": "433T", "room": "3", "fn": "on"}, Then: Action{Device: "R3D1", Action: "on"}, For: time.Hour}}
+	sw := &idSwitch{id: "R3D1"}
+	e := NewEngine(rs, func(name string) (Switch, error) { return sw, nil })
+	now := time.Date(2026, 1, 7, 22, 0, 0, 0, time.Local)
+	e.now = func() time.Time { return now }
+
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 3, Dev: 2, Fn: "on"}) // A remote
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 3, Dev: 1, Fn: "on"}) // The LWL's echo of R3D1 on
+	if got := sw.actions(); len(got) != 1 {
+		t.Fatalf("retriggered by its own echo: %v", got)
+	}
+	now = now.Add(time.Minute)
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 3, Dev: 1, Fn: "on"}) // Someone else, later
+	if got := sw.actions(); len(got) != 2 {
+		t.Fatalf("want a second firing, got %v", got)
+	}
+}
@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotRecording is returned by StopRecording when no recording is in
+// progress
+var ErrNotRecording = errors.New("not recording")
+
+// recording is a Scene being recorded, see Record
+type recording struct {
+	scene Scene
+	until time.Time
+	last  time.Time   // When the most recent step was recorded
+	timer *time.Timer // Stops the recording at until
+}
+
+// RecordingStatus describes a recording in progress, for reporting
+type RecordingStatus struct {
+	Scene string    `json:"scene"`
+	Until time.Time `json:"until"`
+	Steps int       `json:"steps"` // Recorded so far, including waits
+}
+
+// Record starts recording the devices switched by hand, i.e. the on, off and
+// dim commands the LWL echoes whether they come from the app, a remote or
+// lwlctl, as a Scene of the given name which replays them with the same
+// pauses between them. Rules and scenes which fire meanwhile are recorded
+// too, since their commands are echoed alike.
+//
+// The recording stops after d, or at StopRecording, whereupon the scene
+// replaces any of the same name and is passed to Engine.Recorded, unless
+// nothing was recorded.
+func (e *Engine) Record(name string, d time.Duration) error {
+	if name == "" {
+		return errors.New("missing scene name")
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid recording duration: %v", d)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.recording != nil {
+		return fmt.Errorf("already recording scene %s", e.recording.scene.Name)
+	}
+	rec := &recording{scene: Scene{Name: name}, until: e.now().Add(d)}
+	rec.timer = time.AfterFunc(d, panics.Func("recording", func() { e.stopRecording(rec) }))
+	e.recording = rec
+	slog.Info("Recording scene", "scene", name, "until", rec.until)
+	return nil
+}
+
+// StopRecording stops the recording in progress early, returning the scene
+// recorded
+func (e *Engine) StopRecording() (Scene, error) {
+	e.mu.Lock()
+	rec := e.recording
+	e.mu.Unlock()
+	if rec == nil {
+		return Scene{}, ErrNotRecording
+	}
+	rec.timer.Stop()
+	return e.stopRecording(rec)
+}
+
+// Recording returns the recording in progress, if any
+func (e *Engine) Recording() (RecordingStatus, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.recording == nil {
+		return RecordingStatus{}, false
+	}
+	return RecordingStatus{
+		Scene: e.recording.scene.Name,
+		Until: e.recording.until,
+		Steps: len(e.recording.scene.Steps),
+	}, true
+}
+
+// stopRecording finishes a recording, adding the scene to the engine
+func (e *Engine) stopRecording(rec *recording) (Scene, error) {
+	e.mu.Lock()
+	if e.recording != rec {
+		e.mu.Unlock()
+		return Scene{}, ErrNotRecording // Already stopped
+	}
+	e.recording = nil
+	sc := rec.scene
+	var err error
+	if len(sc.Steps) == 0 {
+		err = fmt.Errorf("scene %s: nothing recorded", sc.Name)
+	} else {
+		if e.scenes == nil {
+			e.scenes = make(map[string]*Scene)
+		}
+		e.scenes[sc.Name] = &sc
+	}
+	e.mu.Unlock()
+
+	if err != nil {
+		slog.Warn("Recorded nothing", "scene", sc.Name)
+		return sc, err
+	}
+	slog.Info("Recorded scene", "scene", sc.Name, "steps", len(sc.Steps))
+	if e.Recorded != nil {
+		e.Recorded(sc)
+	}
+	return sc, nil
+}
+
+// record adds a command echoed by the LWL to the recording in progress, if
+// any. Waits are rounded to the second, which is as precise as a routine
+// performed by hand can hope to be.
+func (e *Engine) record(ev Event) {
+	r, ok := ev.(lwl.Response)
+	if !ok || r.Replay || r.Pkt != "433T" || r.Room == 0 || r.Dev == 0 {
+		return
+	}
+	a := Action{Device: fmt.Sprintf("R%dD%d", r.Room, r.Dev), Action: r.Fn}
+	switch r.Fn {
+	case "on", "off":
+	case "dim":
+		a.Level = r.Param
+	default:
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rec := e.recording
+	if rec == nil {
+		return
+	}
+	now := e.now()
+	if len(rec.scene.Steps) > 0 {
+		if wait := now.Sub(rec.last).Round(time.Second); wait > 0 {
+			rec.scene.Steps = append(rec.scene.Steps, Step{Wait: wait})
+		}
+	}
+	rec.scene.Steps = append(rec.scene.Steps, Step{Action: a})
+	rec.last = now
+	slog.Debug("Recorded scene step", "scene", rec.scene.Name, "do", a)
+}
+
+// SaveScene writes a scene to a YAML file of scenes, see LoadScenes,
+// replacing any of the same name. The file is created if it does not exist.
+// Comments in the file are not preserved.
+func SaveScene(fn string, sc Scene) error {
+	ss, err := LoadScenes(fn)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if i := slices.IndexFunc(ss, func(s Scene) bool { return s.Name == sc.Name }); i >= 0 {
+		ss[i] = sc
+	} else {
+		ss = append(ss, sc)
+	}
+	buf, err := yaml.Marshal(ss)
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
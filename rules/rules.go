@@ -0,0 +1,186 @@
+// Package rules runs simple automations against messages from a LightwaveRF
+// Link (LWL), such as "when the PIR in R3 triggers after dusk, turn on R3D1
+// for 5 minutes"
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/timeofday"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is an automation, as configured in YAML:
+//
+//   - name: Landing light
+//     when: {pkt: 433T, room: 3, fn: "on"}
+//     between: dusk-dawn
+//     then: {device: R3D1, action: "on"}
+//     for: 5m
+type Rule struct {
+	Name    string            `yaml:"name"`
+	Enabled *bool             `yaml:"enabled"` // Defaults to true
+	When    map[string]string `yaml:"when"`    // Event fields (for messages from the LWL, by JSON name) and the values they must have
+	Between string            `yaml:"between"` // Only fire during this period, e.g. dusk-dawn or 22:00-07:00. Optional.
+	Then    Action            `yaml:"then"`
+	For     time.Duration     `yaml:"for"` // Switch off again after this long. Firing again restarts the period. Optional.
+}
+
+// Action is what a rule does when it fires: either an action on a device, or
+// running a Scene
+type Action struct {
+	Device string `yaml:"device,omitempty"` // ID or alias, e.g. R3D1
+	Action string `yaml:"action,omitempty"` // "on", "off" or "dim"
+	Level  int    `yaml:"level,omitempty"`  // For dim, 1-32 as accepted by lwl.Device.Dim
+	Scene  string `yaml:"scene,omitempty"`  // Name of the scene to run, instead of the above
+}
+
+func (a Action) String() string {
+	switch {
+	case a.Scene != "":
+		return "scene " + a.Scene
+	case a.Action == "dim":
+		return fmt.Sprintf("%s dim %d", a.Device, a.Level)
+	}
+	return a.Device + " " + a.Action
+}
+
+// Load reads rules from a YAML file, see Check for fields
+func Load(fn string, fields ...string) ([]Rule, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var rs []Rule
+	if err := yaml.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	return rs, Check(rs, fields...)
+}
+
+// Check returns every problem found with the rules, joined. fields are those
+// of Events other than lwl.Response which rules may match, e.g.
+// presence.Fields.
+func Check(rs []Rule, fields ...string) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, r := range rs {
+		bad := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %s", i+1, r.Name, fmt.Sprintf(format, args...)))
+		}
+		switch {
+		case r.Name == "":
+			bad("missing name")
+		case seen[r.Name]:
+			bad("duplicate name")
+		}
+		seen[r.Name] = true
+
+		if len(r.When) == 0 {
+			bad("missing when")
+		}
+		for k := range r.When {
+			if _, ok := (lwl.Response{}).Field(k); !ok && !slices.Contains(fields, k) {
+				bad("unknown message field %q", k)
+			}
+		}
+		if _, err := parsePeriod(r.Between); err != nil {
+			bad("%v", err)
+		}
+		if r.Then.Scene != "" {
+			if r.Then.Device != "" || r.Then.Action != "" {
+				bad("then.scene cannot be combined with then.device or then.action")
+			}
+			if r.For != 0 {
+				bad("for does not apply to scenes")
+			}
+			continue
+		}
+		checkAction(r.Then, "then.", bad)
+		if r.For < 0 || (r.For > 0 && r.Then.Action == "off") {
+			bad("for only applies to on and dim")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkAction reports the problems with an action on a device, with the
+// given prefix on field names, e.g. "then."
+func checkAction(a Action, prefix string, bad func(format string, args ...any)) {
+	if a.Device == "" {
+		bad("missing %sdevice", prefix)
+	}
+	switch a.Action {
+	case "on", "off":
+	case "dim":
+		if a.Level < 1 || a.Level > 32 {
+			bad("%slevel %d out of range 1-32", prefix, a.Level)
+		}
+	default:
+		bad("%saction should be on, off or dim, got %q", prefix, a.Action)
+	}
+}
+
+// endpoint is one end of a period, a time of day or dusk/dawn
+type endpoint struct {
+	sun    string        // "dusk" or "dawn", or empty for a fixed time
+	offset time.Duration // From midnight, if sun is empty
+}
+
+// period is a daily period. Start after end means it spans midnight.
+type period struct {
+	start, end endpoint
+}
+
+func (p *period) String() string {
+	if p == nil {
+		return "all day"
+	}
+	return p.start.String() + "-" + p.end.String()
+}
+
+func (e endpoint) String() string {
+	if e.sun != "" {
+		return e.sun
+	}
+	return fmt.Sprintf("%02d:%02d", int(e.offset.Hours()), int(e.offset.Minutes())%60)
+}
+
+// parsePeriod parses e.g. "dusk-dawn" or "22:00-07:00". An empty string means
+// all day.
+func parsePeriod(s string) (*period, error) {
+	if s == "" {
+		return nil, nil
+	}
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return nil, fmt.Errorf("between should look like dusk-dawn or 22:00-07:00, got %q", s)
+	}
+	start, err := parseEndpoint(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseEndpoint(to)
+	if err != nil {
+		return nil, err
+	}
+	return &period{start: start, end: end}, nil
+}
+
+func parseEndpoint(s string) (endpoint, error) {
+	s = strings.TrimSpace(s)
+	if s == "dusk" || s == "dawn" {
+		return endpoint{sun: s}, nil
+	}
+	offset, err := timeofday.Parse(s)
+	if err != nil {
+		return endpoint{}, fmt.Errorf("invalid time of day %q, should be dusk, dawn or like 07:30", s)
+	}
+	return endpoint{offset: offset}, nil
+}
@@ -0,0 +1,29 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	rs := []Rule{
+		{Name: "a", When: map[string]string{"nonsense": "1"}, Then: Action{Device: "R1D1", Action: "on"}},
+		{Name: "a", When: map[string]string{"fn": "on"}, Then: Action{Device: "R1D1", Action: "off"}, For: time.Minute},
+		{Name: "b", When: map[string]string{"fn": "on"}, Between: "noon-dusk", Then: Action{Device: "R1D1", Action: "dim", Level: 80}},
+	}
+	err := Check(rs)
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	for _, want := range []string{"unknown message field", "duplicate name", "for only applies", "invalid time of day", "out of range"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+
+	// Fields of other Events are known only when given
+	if err := Check(rs[:1], "nonsense"); err != nil {
+		t.Errorf("want no errors for a given field, got %v", err)
+	}
+}
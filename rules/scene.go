@@ -0,0 +1,189 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scene is an ordered sequence of actions, with pauses and conditions, run by
+// a rule (then: {scene: arriving_home}) or via the HTTP API. As configured in
+// YAML:
+//
+//	# scenes.yaml
+//	- name: arriving_home
+//	  steps:
+//	    - {device: hall, action: "on"}
+//	    - wait: 2s
+//	    - {device: landing, action: "on", between: dusk-dawn}
+type Scene struct {
+	Name  string `yaml:"name"`
+	Steps []Step `yaml:"steps"`
+}
+
+// Step is an action of a Scene, or a pause between actions
+type Step struct {
+	Action  `yaml:",inline"`
+	Wait    time.Duration `yaml:"wait,omitempty"`    // Pause for this long, instead of an action
+	Between string        `yaml:"between,omitempty"` // Only perform the action during this period, e.g. dusk-dawn. Optional.
+
+	period *period
+}
+
+func (s Step) String() string {
+	if s.Wait > 0 {
+		return "wait " + s.Wait.String()
+	}
+	return s.Action.String()
+}
+
+// LoadScenes reads scenes from a YAML file
+func LoadScenes(fn string) ([]Scene, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var ss []Scene
+	if err := yaml.Unmarshal(data, &ss); err != nil {
+		return nil, err
+	}
+	return ss, CheckScenes(ss)
+}
+
+// CheckScenes returns every problem found with the scenes, joined
+func CheckScenes(ss []Scene) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, sc := range ss {
+		bad := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("scene %d (%s): %s", i+1, sc.Name, fmt.Sprintf(format, args...)))
+		}
+		switch {
+		case sc.Name == "":
+			bad("missing name")
+		case seen[sc.Name]:
+			bad("duplicate name")
+		}
+		seen[sc.Name] = true
+
+		if len(sc.Steps) == 0 {
+			bad("missing steps")
+		}
+		for j, st := range sc.Steps {
+			stepBad := func(format string, args ...any) {
+				bad("step %d: %s", j+1, fmt.Sprintf(format, args...))
+			}
+			switch {
+			case st.Wait < 0:
+				stepBad("negative wait")
+			case st.Wait > 0 && (st.Device != "" || st.Between != ""):
+				stepBad("wait cannot be combined with an action")
+			case st.Wait > 0:
+			case st.Scene != "":
+				stepBad("scenes cannot run other scenes")
+			default:
+				checkAction(st.Action, "", stepBad)
+			}
+			if _, err := parsePeriod(st.Between); err != nil {
+				stepBad("%v", err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetScenes gives the engine scenes, which should have passed CheckScenes,
+// returning an error if any rule runs a scene which is not among them
+func (e *Engine) SetScenes(ss []Scene) error {
+	scenes := make(map[string]*Scene, len(ss))
+	for _, sc := range ss {
+		sc.Steps = append([]Step(nil), sc.Steps...)
+		for i := range sc.Steps {
+			sc.Steps[i].period, _ = parsePeriod(sc.Steps[i].Between)
+		}
+		scenes[sc.Name] = &sc
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenes = scenes
+	var errs []error
+	for _, s := range e.rules {
+		if name := s.Then.Scene; name != "" && scenes[name] == nil {
+			errs = append(errs, fmt.Errorf("rule %s: no such scene: %s", s.Name, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunScene performs the named scene's steps in order, returning once they
+// are done. Steps whose conditions are not met are skipped, and a failed step
+// does not stop those after it. Running a scene again before it is done
+// cancels the earlier run, during its next wait. Commands are attributed to
+// the scene, and whatever ran it, e.g. "rule:dusk/scene:evening".
+func (e *Engine) RunScene(ctx context.Context, name string) error {
+	ctx, cancel := context.WithCancel(lwl.WithSource(ctx, lwl.Source(ctx)+"/scene:"+name))
+	defer cancel()
+
+	e.mu.Lock()
+	sc := e.scenes[name]
+	if sc == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("no such scene: %s", name)
+	}
+	if e.running == nil {
+		e.running = make(map[string]*context.CancelFunc)
+	}
+	if earlier := e.running[name]; earlier != nil {
+		(*earlier)()
+	}
+	e.running[name] = &cancel
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		if e.running[name] == &cancel {
+			delete(e.running, name)
+		}
+		e.mu.Unlock()
+	}()
+
+	slog.Info("Scene started", "scene", name)
+	var errs []error
+	for i, st := range sc.Steps {
+		if st.Wait > 0 {
+			select {
+			case <-time.After(st.Wait):
+				continue
+			case <-ctx.Done():
+				slog.Info("Scene cancelled", "scene", name, "step", i+1)
+				return errors.Join(errs...)
+			}
+		}
+		if why := e.outside(ctx, st.period); why != "" {
+			slog.Debug("Scene step skipped", "scene", name, "step", i+1, "do", st, "why", why)
+			continue
+		}
+		if _, err := e.do(ctx, st.Action); err != nil {
+			errs = append(errs, fmt.Errorf("step %d (%v): %w", i+1, st, err))
+		}
+	}
+	slog.Info("Scene finished", "scene", name, "failed", len(errs))
+	return errors.Join(errs...)
+}
+
+// Scenes returns the names of the scenes, sorted
+func (e *Engine) Scenes() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Sorted(maps.Keys(e.scenes))
+}
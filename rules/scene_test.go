@@ -0,0 +1,180 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"gopkg.in/yaml.v3"
+)
+
+const testScenes = `
+- name: arriving_home
+  steps:
+    - {device: R1D1, action: "on"}
+    - wait: 20ms
+    - {device: R1D1, action: dim, level: 16}
+    - {device: R1D1, action: "off", between: dusk-dawn}
+`
+
+func TestCheckScenes(t *testing.T) {
+	for _, tc := range []struct {
+		yaml, want string
+	}{
+		{"- {name: a, steps: [{device: R1D1, action: 'on'}]}", ""},
+		{"- {steps: [{device: R1D1, action: 'on'}]}", "missing name"},
+		{"- {name: a}", "missing steps"},
+		{"- {name: a, steps: [{wait: 1s, device: R1D1}]}", "wait cannot be combined"},
+		{"- {name: a, steps: [{scene: b}]}", "cannot run other scenes"},
+		{"- {name: a, steps: [{device: R1D1, action: 'on', between: noon-dusk}]}", "step 1"},
+		{"- {name: a, steps: [{device: R1D1, action: 'on'}]}\n- {name: a, steps: [{wait: 1s}]}", "duplicate name"},
+	} {
+		var ss []Scene
+		if err := yaml.Unmarshal([]byte(tc.yaml), &ss); err != nil {
+			t.Fatal(err)
+		}
+		err := CheckScenes(ss)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.yaml, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: got %v, want %q", tc.yaml, err, tc.want)
+		}
+	}
+}
+
+func TestRunScene(t *testing.T) {
+	var ss []Scene
+	if err := yaml.Unmarshal([]byte(testScenes), &ss); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckScenes(ss); err != nil {
+		t.Fatal(err)
+	}
+	rs := []Rule{{
+		Name: "Front door",
+		When: map[string]string{"pkt": "433T", "room": "1", "fn": "on"},
+		Then: Action{Scene: "arriving_home"},
+	}}
+	if err := Check(rs); err != nil {
+		t.Fatal(err)
+	}
+
+	sw := &fakeSwitch{}
+	e := NewEngine(rs, func(name string) (Switch, error) {
+		if name != "R1D1" {
+			return nil, errors.New("no such device")
+		}
+		return sw, nil
+	})
+	if err := e.SetScenes(ss); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 1, 7, 0, 0, 0, 0, time.Local)
+	e.DuskDawn = func(context.Context) (time.Time, time.Time, error) {
+		return day.Add(16 * time.Hour), day.Add(8 * time.Hour), nil
+	}
+
+	// Daytime, so the last step is skipped
+	e.now = func() time.Time { return day.Add(12 * time.Hour) }
+	start := time.Now()
+	if err := e.RunScene(t.Context(), "arriving_home"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Errorf("did not wait")
+	}
+	if got, want := sw.actions(), []string{"on", "dim"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// Night, run by the rule
+	e.now = func() time.Time { return day.Add(22 * time.Hour) }
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 1, Fn: "on"})
+	want := []string{"on", "dim", "on", "dim", "off"}
+	for deadline := time.Now().Add(time.Second); !slices.Equal(sw.actions(), want); {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v, want %v", sw.actions(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := e.RunScene(t.Context(), "leaving"); err == nil {
+		t.Errorf("ran unknown scene")
+	}
+	if err := e.SetScenes(nil); err == nil {
+		t.Errorf("accepted rule running a missing scene")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	e := NewEngine(nil, nil)
+	now := time.Date(2026, 1, 7, 19, 0, 0, 0, time.Local)
+	e.now = func() time.Time { return now }
+	var saved Scene
+	e.Recorded = func(sc Scene) { saved = sc }
+
+	if _, err := e.StopRecording(); !errors.Is(err, ErrNotRecording) {
+		t.Fatalf("got %v, want ErrNotRecording", err)
+	}
+	if err := e.Record("evening", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Record("morning", time.Hour); err == nil {
+		t.Errorf("recorded two scenes at once")
+	}
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 1, Dev: 1, Fn: "on"})
+	now = now.Add(90 * time.Second)
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 1, Dev: 2, Fn: "dim", Param: 20})
+	e.Handle(t.Context(), lwl.Response{Pkt: "868R", Fn: "statusPush"})
+	e.Handle(t.Context(), lwl.Response{Pkt: "433T", Room: 1, Dev: 1, Fn: "off", Replay: true})
+	if st, ok := e.Recording(); !ok || st.Scene != "evening" || st.Steps != 3 {
+		t.Errorf("got %+v, %v", st, ok)
+	}
+
+	sc, err := e.StopRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, st := range sc.Steps {
+		got = append(got, st.String())
+	}
+	if want := []string{"R1D1 on", "wait 1m30s", "R1D2 dim 20"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if saved.Name != "evening" {
+		t.Errorf("Recorded not called")
+	}
+	if !slices.Contains(e.Scenes(), "evening") {
+		t.Errorf("scene not added: %v", e.Scenes())
+	}
+
+	fn := filepath.Join(t.TempDir(), "scenes.yaml")
+	if err := SaveScene(fn, sc); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveScene(fn, sc); err != nil {
+		t.Fatal(err)
+	}
+	ss, err := LoadScenes(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || len(ss[0].Steps) != 3 || ss[0].Steps[1].Wait != 90*time.Second {
+		t.Errorf("loaded %+v", ss)
+	}
+
+	// Nothing recorded
+	if err := e.Record("empty", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.StopRecording(); err == nil {
+		t.Errorf("saved an empty scene")
+	}
+}
@@ -0,0 +1,52 @@
+// Package sensor is the plumbing shared by packages which follow sensors
+// paired to LightwaveRF device slots, such as PIRs (package occupancy) and
+// door contacts (package contact). Such sensors report by switching the
+// device they are paired to, which the LightwaveRF Link (LWL) reports as a
+// 433T message, e.g. {pkt: 433T, fn: on, room: 3, dev: 1}.
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration of sensors from a YAML file into v
+func Load(fn string, v any) error {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// Run passes each message to observe, with the time it was received, until
+// the channel is closed or the context is done
+func Run(ctx context.Context, msgs <-chan lwl.Response, observe func(lwl.Response, time.Time)) {
+	for {
+		select {
+		case r, ok := <-msgs:
+			if !ok {
+				return
+			}
+			observe(r, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Report returns the Room+Device a message reports a sensor switching, e.g.
+// R3D1, or false if it is not from a sensor. Replayed messages are not, nor
+// are echoes of commands sent by this process to devices which happen to
+// share a slot with a sensor.
+func Report(r lwl.Response) (string, bool) {
+	if r.Pkt != "433T" || r.Replay || r.Echo {
+		return "", false
+	}
+	return fmt.Sprintf("R%dD%d", r.Room, r.Dev), true
+}
@@ -0,0 +1,39 @@
+package sensor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestLoad(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "sensors.yaml")
+	if err := os.WriteFile(fn, []byte("- name: landing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got []struct{ Name string }
+	if err := Load(fn, &got); err != nil || len(got) != 1 || got[0].Name != "landing" {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	if err := Load(filepath.Join(t.TempDir(), "missing.yaml"), &got); !os.IsNotExist(err) {
+		t.Errorf("want not exist, got %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	for _, tt := range []struct {
+		r    lwl.Response
+		want string
+	}{
+		{lwl.Response{Pkt: "433T", Fn: "on", Room: 3, Dev: 1}, "R3D1"},
+		{lwl.Response{Pkt: "433T", Fn: "on", Room: 3, Dev: 1, Echo: true}, ""},
+		{lwl.Response{Pkt: "433T", Fn: "on", Room: 3, Dev: 1, Replay: true}, ""},
+		{lwl.Response{Pkt: "868R", Fn: "statusPush"}, ""},
+	} {
+		if got, _ := Report(tt.r); got != tt.want {
+			t.Errorf("Report(%v) = %q, want %q", &tt.r, got, tt.want)
+		}
+	}
+}
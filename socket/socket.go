@@ -0,0 +1,238 @@
+// Package socket serves a line-delimited JSON interface over TCP, the
+// simplest integration surface for Node-RED (its TCP nodes, with a JSON
+// node) and home-grown scripts. Every message from the LightwaveRF Link (LWL)
+// is written to each client as an event, and each line a client writes is a
+// Request, answered with a Reply:
+//
+//	→ {"id":"1","device":"kitchen_ceiling","action":"dim","level":16}
+//	← {"type":"reply","id":"1","ok":true,"device":{"id":"R1D2","name":"kitchen_ceiling","on":true,"level":16,"state":"optimistic"}}
+//	← {"type":"event","event":{"pkt":"433T","fn":"dim","room":1,"dev":2,"param":16,...}}
+//
+// There is no authentication, so the socket should only be reachable from
+// trusted hosts, e.g. by listening on localhost. Clients on other hosts may
+// only act on devices: catalog commands, which include e.g. deregistering
+// from the LWL, are refused unless the client is on this host.
+package socket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+	"github.com/meermanr/LightwaveRF-go/panics"
+)
+
+// How long a client has to accept each line, before it is disconnected
+const writeTimeout = 10 * time.Second
+
+// Longest request accepted
+const maxLine = 64 << 10
+
+// Request is a line sent by a client: either an action on a device, or a
+// command from the catalog (see lwl.Commands), e.g. {"command":"set_target",
+// "args":["R7","20"]}, which only clients on this host may send
+type Request struct {
+	ID      string   `json:"id,omitempty"`      // Echoed in the Reply, to match them up. Optional.
+	Device  string   `json:"device,omitempty"`  // Alias or identifier, e.g. R1D2
+	Action  string   `json:"action,omitempty"`  // "on", "off" or "dim"
+	Level   int      `json:"level,omitempty"`   // For dim, 1-32
+	Command string   `json:"command,omitempty"` // Catalog name, instead of Device and Action
+	Args    []string `json:"args,omitempty"`    // For Command
+}
+
+// Reply is written in answer to each Request
+type Reply struct {
+	Type     string        `json:"type"` // Always "reply"
+	ID       string        `json:"id,omitempty"`
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Device   *Device       `json:"device,omitempty"`   // After an action
+	Response *lwl.Response `json:"response,omitempty"` // The LWL's reply to a command
+}
+
+// Device is the state of a device after an action
+type Device struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	On    bool   `json:"on"`
+	Level int    `json:"level,omitempty"`
+	State string `json:"state"` // Confidence in On and Level, see lwl.Confidence
+}
+
+// Event is written for each message from the LWL
+type Event struct {
+	Type  string       `json:"type"` // Always "event"
+	Event lwl.Response `json:"event"`
+}
+
+// Server serves clients, see Serve
+type Server struct {
+	c   lwl.HubClient
+	reg *lwl.Registry
+
+	// MaxClients served at once, beyond which clients are turned away, or 0
+	// for no limit
+	MaxClients int
+	clients    atomic.Int32
+}
+
+// New returns a Server sending commands via c, and actions on the devices of
+// reg
+func New(c lwl.HubClient, reg *lwl.Registry) *Server {
+	return &Server{c: c, reg: reg}
+}
+
+// Serve accepts clients on l until the context is done
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if n := s.clients.Add(1); s.MaxClients > 0 && int(n) > s.MaxClients {
+			s.clients.Add(-1)
+			slog.Warn("Too many socket clients", "remote", conn.RemoteAddr(), "max", s.MaxClients)
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			json.NewEncoder(conn).Encode(Reply{Type: "reply", Error: "too many clients"})
+			conn.Close()
+			continue
+		}
+		panics.Go("socket", func() {
+			defer s.clients.Add(-1)
+			s.serveConn(ctx, conn)
+		})
+	}
+}
+
+// serveConn serves a client until it disconnects, or the context is done
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+	slog.Info("Socket client connected", "remote", conn.RemoteAddr())
+	addr, _ := conn.RemoteAddr().(*net.TCPAddr)
+	local := addr != nil && addr.IP.IsLoopback()
+	// Commands are attributed to the client's host, rather than its port,
+	// which changes with every connection
+	source := "socket"
+	if addr != nil {
+		source += ":" + addr.IP.String()
+	}
+	defer slog.Info("Socket client disconnected", "remote", conn.RemoteAddr())
+
+	var mu sync.Mutex // Serialises lines written
+	enc := json.NewEncoder(conn)
+	write := func(v any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return enc.Encode(v)
+	}
+
+	events := s.c.Events(ctx)
+	go func() {
+		for m := range events {
+			if err := write(Event{Type: "event", Event: m}); err != nil {
+				slog.Debug("Socket client not accepting events", "remote", conn.RemoteAddr(), "err", err)
+				cancel()
+				conn.Close()
+				return
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(nil, maxLine)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var req Request
+		var rep Reply
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			rep = Reply{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			rep = s.handle(lwl.WithSource(ctx, source), req, local)
+		}
+		rep.Type, rep.ID = "reply", req.ID
+		if err := write(rep); err != nil {
+			return
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		slog.Debug("Socket client failed", "remote", conn.RemoteAddr(), "err", err)
+	}
+}
+
+// handle performs a request, from a client on this host if local
+func (s *Server) handle(ctx context.Context, req Request, local bool) Reply {
+	ctx, cancel := context.WithTimeout(ctx, lwl.CommandTimeout)
+	defer cancel()
+
+	if req.Command != "" {
+		if !local {
+			return Reply{Error: "commands are only accepted from this host, other clients may only act on devices"}
+		}
+		ci, ok := lwl.LookupCommand(req.Command)
+		if !ok {
+			return Reply{Error: fmt.Sprintf("unknown command: %q", req.Command)}
+		}
+		cmd, err := ci.Build(req.Args...)
+		if err != nil {
+			return Reply{Error: err.Error()}
+		}
+		r, err := s.c.Do(ctx, *cmd)
+		if err != nil {
+			return Reply{Error: err.Error()}
+		}
+		return Reply{OK: true, Response: &r}
+	}
+
+	if req.Device == "" {
+		return Reply{Error: "missing device or command"}
+	}
+	d, err := s.reg.Resolve(req.Device)
+	if err != nil {
+		return Reply{Error: err.Error()}
+	}
+	switch req.Action {
+	case "on":
+		err = d.On(ctx)
+	case "off":
+		err = d.Off(ctx)
+	case "dim":
+		err = d.Dim(ctx, req.Level)
+	default:
+		err = errors.New(`action should be "on", "off" or "dim"`)
+	}
+	if err != nil {
+		return Reply{Error: err.Error()}
+	}
+	st := d.State()
+	return Reply{OK: true, Device: &Device{
+		ID:    st.ID,
+		Name:  d.Name(),
+		On:    st.On,
+		Level: st.Level,
+		State: st.Confidence.String(),
+	}}
+}
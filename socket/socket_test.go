@@ -0,0 +1,154 @@
+package socket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// fakeHub is a HubClient which records the commands sent to it, and delivers
+// the messages sent on events
+type fakeHub struct {
+	mu      sync.Mutex
+	sent    []string
+	sources []string // Of each command sent, see lwl.Source
+	events  chan lwl.Response
+}
+
+func (f *fakeHub) Do(ctx context.Context, cmd lwl.Command) (lwl.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, cmd.String())
+	f.sources = append(f.sources, lwl.Source(ctx))
+	return lwl.Response{Fn: "ack", Status: "success"}, nil
+}
+
+func (f *fakeHub) Subscribe(sid string, chr chan lwl.Response, chs chan string) string {
+	return sid
+}
+func (f *fakeHub) Unsubscribe(sid string)                         {}
+func (f *fakeHub) Events(ctx context.Context) <-chan lwl.Response { return f.events }
+func (f *fakeHub) Close() error                                   { return nil }
+
+func TestServer(t *testing.T) {
+	hub := &fakeHub{events: make(chan lwl.Response, 1)}
+	reg := lwl.NewRegistry(hub)
+	reg.SetAlias("R1D2", "kitchen_ceiling")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go New(hub, reg).Serve(t.Context(), l)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	lines := bufio.NewScanner(conn)
+	roundTrip := func(req string) map[string]any {
+		t.Helper()
+		if _, err := conn.Write([]byte(req + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		if !lines.Scan() {
+			t.Fatal(lines.Err())
+		}
+		var out map[string]any
+		if err := json.Unmarshal(lines.Bytes(), &out); err != nil {
+			t.Fatalf("%s: %v", lines.Text(), err)
+		}
+		return out
+	}
+
+	rep := roundTrip(`{"id":"1","device":"kitchen_ceiling","action":"dim","level":16}`)
+	if rep["type"] != "reply" || rep["id"] != "1" || rep["ok"] != true {
+		t.Errorf("dim: %v", rep)
+	}
+	if dev, _ := rep["device"].(map[string]any); dev["id"] != "R1D2" || dev["level"] != 16.0 {
+		t.Errorf("dim: %v", rep)
+	}
+	rep = roundTrip(`{"command":"set_target","args":["R7","20"]}`)
+	if rep["ok"] != true || rep["response"] == nil {
+		t.Errorf("set_target: %v", rep)
+	}
+	for _, req := range []string{`not json`, `{"device":"R1D2","action":"toggle"}`, `{"command":"nosuch"}`, `{}`} {
+		if rep := roundTrip(req); rep["ok"] != false || rep["error"] == nil {
+			t.Errorf("%s: %v", req, rep)
+		}
+	}
+	hub.mu.Lock()
+	if want := []string{"!R1D2FdP16", "!R7F*tP20"}; !slices.Equal(hub.sent, want) {
+		t.Errorf("sent %q, want %q", hub.sent, want)
+	}
+	if want := []string{"socket:127.0.0.1", "socket:127.0.0.1"}; !slices.Equal(hub.sources, want) {
+		t.Errorf("sources %q, want %q", hub.sources, want)
+	}
+	hub.mu.Unlock()
+
+	hub.events <- lwl.Response{Pkt: "433T", Fn: "on", Room: 1, Dev: 2}
+	if !lines.Scan() {
+		t.Fatal(lines.Err())
+	}
+	var ev Event
+	if err := json.Unmarshal(lines.Bytes(), &ev); err != nil || ev.Type != "event" || ev.Event.Fn != "on" {
+		t.Errorf("event: %s, %v", lines.Text(), err)
+	}
+}
+
+func TestRemoteCommands(t *testing.T) {
+	hub := &fakeHub{}
+	s := New(hub, lwl.NewRegistry(hub))
+	if rep := s.handle(t.Context(), Request{Command: "deregister"}, false); rep.OK || !strings.Contains(rep.Error, "only accepted from this host") {
+		t.Errorf("command: %+v", rep)
+	}
+	if rep := s.handle(t.Context(), Request{Device: "R1D2", Action: "on"}, false); !rep.OK {
+		t.Errorf("action: %+v", rep)
+	}
+	if want := []string{"!R1D2F1"}; !slices.Equal(hub.sent, want) {
+		t.Errorf("sent %q, want %q", hub.sent, want)
+	}
+}
+
+func TestMaxClients(t *testing.T) {
+	hub := &fakeHub{events: make(chan lwl.Response)}
+	s := New(hub, lwl.NewRegistry(hub))
+	s.MaxClients = 1
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(t.Context(), l)
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if _, err := first.Write([]byte("{}\n")); err != nil { // Served
+		t.Fatal(err)
+	}
+	if lines := bufio.NewScanner(first); !lines.Scan() {
+		t.Fatal(lines.Err())
+	}
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	lines := bufio.NewScanner(second)
+	if !lines.Scan() || !strings.Contains(lines.Text(), "too many clients") {
+		t.Errorf("got %q, %v", lines.Text(), lines.Err())
+	}
+	if lines.Scan() {
+		t.Errorf("not disconnected: %q", lines.Text())
+	}
+}
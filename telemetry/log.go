@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/jsonl"
+)
+
+// Log is a Store which persists points as JSON lines
+type Log struct {
+	f *jsonl.File
+}
+
+// NewLog returns a Log which persists points to the named file
+func NewLog(fn string) *Log {
+	return &Log{f: jsonl.New(fn)}
+}
+
+// Put implements Store
+func (l *Log) Put(ps ...Point) error {
+	vs := make([]any, len(ps))
+	for i, p := range ps {
+		vs[i] = p
+	}
+	return l.f.Append(vs...)
+}
+
+// each calls fn for every point in the log. A missing log is treated as
+// empty.
+func (l *Log) each(fn func(Point)) error {
+	return l.f.Each(func(line []byte) error {
+		var p Point
+		if err := json.Unmarshal(line, &p); err != nil {
+			return err
+		}
+		fn(p)
+		return nil
+	})
+}
+
+// Query implements Store
+func (l *Log) Query(series string, from, to time.Time) ([]Point, error) {
+	var out []Point
+	err := l.each(func(p Point) {
+		if p.Series == series && !p.Time.Before(from) && p.Time.Before(to) {
+			out = append(out, p)
+		}
+	})
+	slices.SortStableFunc(out, func(a, b Point) int {
+		return a.Time.Compare(b.Time)
+	})
+	return out, err
+}
+
+// Series implements Store
+func (l *Log) Series() ([]string, error) {
+	seen := make(map[string]bool)
+	err := l.each(func(p Point) {
+		seen[p.Series] = true
+	})
+	out := make([]string, 0, len(seen))
+	for s := range seen {
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return out, err
+}
+
+// Compact implements Compactor. The log is streamed to a temporary file,
+// which then replaces it, so that the space is reclaimed.
+func (l *Log) Compact(now time.Time, policies []Policy) error {
+	return l.f.Rewrite(func(each func(func([]byte) error) error, emit func(any) error) error {
+		c := newCompactor(now, policies)
+		err := each(func(line []byte) error {
+			var p Point
+			if err := json.Unmarshal(line, &p); err != nil {
+				return err
+			}
+			if c.add(p) {
+				return emit(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range c.downsampled() {
+			if err := emit(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Memory is a Store which keeps points in memory, e.g. for tests or where
+// history need not survive a restart
+type Memory struct {
+	mu     sync.RWMutex
+	series map[string][]Point // Oldest first
+}
+
+// NewMemory returns an empty Memory
+func NewMemory() *Memory {
+	return &Memory{series: make(map[string][]Point)}
+}
+
+// Put implements Store
+func (m *Memory) Put(ps ...Point) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range ps {
+		s := m.series[p.Series]
+		// Points usually arrive in order, so this is normally an append
+		i, _ := slices.BinarySearchFunc(s, p.Time, func(p Point, t time.Time) int {
+			if p.Time.After(t) {
+				return 1
+			}
+			return -1
+		})
+		m.series[p.Series] = slices.Insert(s, i, p)
+	}
+	return nil
+}
+
+// Query implements Store
+func (m *Memory) Query(series string, from, to time.Time) ([]Point, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Point
+	for _, p := range m.series[series] {
+		if !p.Time.Before(from) && p.Time.Before(to) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// Series implements Store
+func (m *Memory) Series() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]string, 0, len(m.series))
+	for s := range m.series {
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// Compact implements Compactor
+func (m *Memory) Compact(now time.Time, policies []Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := newCompactor(now, policies)
+	for series, ps := range m.series {
+		ps = slices.DeleteFunc(ps, func(p Point) bool { return !c.add(p) })
+		if len(ps) == 0 {
+			delete(m.series, series)
+			continue
+		}
+		m.series[series] = ps
+	}
+	for _, p := range c.downsampled() {
+		i, _ := slices.BinarySearchFunc(m.series[p.Series], p.Time, func(p Point, t time.Time) int {
+			return p.Time.Compare(t)
+		})
+		m.series[p.Series] = slices.Insert(m.series[p.Series], i, p)
+	}
+	return nil
+}
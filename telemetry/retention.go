@@ -0,0 +1,177 @@
+package telemetry
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Policy determines how long the points of matching series are kept at full
+// resolution, and what happens to them afterwards
+type Policy struct {
+	Series   string        // Pattern (see path.Match), e.g. "*.temp"
+	After    time.Duration // Age beyond which points are downsampled
+	Interval time.Duration // Resolution to downsample to, e.g. an hour
+	Drop     time.Duration // Age beyond which points are deleted, or 0 to keep them forever
+}
+
+// ParsePolicies parses a comma separated list of policies, each of the form
+// series:after:interval[:drop], e.g. "*.batt:720h:24h,*:720h:1h:8760h". The
+// first policy matching a series applies to it.
+func ParsePolicies(s string) ([]Policy, error) {
+	var out []Policy
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		parts := strings.Split(f, ":")
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, fmt.Errorf("retention policy should look like series:after:interval[:drop], got %q", f)
+		}
+		p := Policy{Series: parts[0]}
+		if _, err := path.Match(p.Series, ""); err != nil {
+			return nil, fmt.Errorf("invalid series pattern %q: %w", p.Series, err)
+		}
+		durations := []*time.Duration{&p.After, &p.Interval, &p.Drop}
+		for i, v := range parts[1:] {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retention policy %q: %w", f, err)
+			}
+			*durations[i] = d
+		}
+		if p.Interval <= 0 {
+			return nil, fmt.Errorf("invalid retention policy %q: interval must be positive", f)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// policyFor returns the first policy matching the series, or false if none do
+func policyFor(series string, policies []Policy) (Policy, bool) {
+	for _, p := range policies {
+		if ok, _ := path.Match(p.Series, series); ok {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+// Compactor is implemented by stores which support retention policies
+type Compactor interface {
+	// Compact downsamples and deletes points according to the first
+	// matching policy for their series, as of now
+	Compact(now time.Time, policies []Policy) error
+}
+
+// RunCompaction compacts the store now, and then every interval, until the
+// context is cancelled. Stores which are not Compactors are left alone.
+func RunCompaction(ctx context.Context, s Store, policies []Policy, interval time.Duration) {
+	c, ok := s.(Compactor)
+	if !ok {
+		slog.Warn("Telemetry store does not support compaction", "store", fmt.Sprintf("%T", s))
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		start := time.Now()
+		if err := c.Compact(start, policies); err != nil {
+			slog.Error("Failed to compact telemetry", "err", err)
+		} else {
+			slog.Debug("Compacted telemetry", "took", time.Since(start))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// compactor applies retention policies to points fed to it one at a time,
+// in any order, so that a store need not be loaded whole. Only the buckets
+// being downsampled are held in memory.
+type compactor struct {
+	now      time.Time
+	policies []Policy
+	matched  map[string]*Policy // By series, nil if no policy matches
+	buckets  map[bucket]*mean
+}
+
+// bucket identifies the interval a point is downsampled into
+type bucket struct {
+	series string
+	start  int64 // Unix nanoseconds
+}
+
+// mean accumulates the points in a bucket
+type mean struct {
+	start time.Time
+	sum   float64
+	n     int
+}
+
+func newCompactor(now time.Time, policies []Policy) *compactor {
+	return &compactor{
+		now:      now,
+		policies: policies,
+		matched:  make(map[string]*Policy),
+		buckets:  make(map[bucket]*mean),
+	}
+}
+
+// add reports whether p is kept as it is. Points older than their policy's
+// Drop are discarded, whatever its After, and the remainder older than After
+// are held to be downsampled.
+func (c *compactor) add(p Point) bool {
+	pol, seen := c.matched[p.Series]
+	if !seen {
+		if found, ok := policyFor(p.Series, c.policies); ok {
+			pol = &found
+		}
+		c.matched[p.Series] = pol
+	}
+	if pol == nil {
+		return true
+	}
+	if pol.Drop > 0 && p.Time.Before(c.now.Add(-pol.Drop)) {
+		return false
+	}
+	// Align the cutoff to the interval, so no bucket is ever partially
+	// downsampled
+	if !p.Time.Before(c.now.Add(-pol.After).Truncate(pol.Interval)) {
+		return true
+	}
+	start := p.Time.Truncate(pol.Interval)
+	k := bucket{series: p.Series, start: start.UnixNano()}
+	m := c.buckets[k]
+	if m == nil {
+		m = &mean{start: start}
+		c.buckets[k] = m
+	}
+	m.sum += p.Value
+	m.n++
+	return false
+}
+
+// downsampled returns a point for each bucket, at its start, with the mean
+// of the points added to it, ordered by series and then time
+func (c *compactor) downsampled() []Point {
+	keys := slices.SortedFunc(maps.Keys(c.buckets), func(a, b bucket) int {
+		return cmp.Or(strings.Compare(a.series, b.series), cmp.Compare(a.start, b.start))
+	})
+	out := make([]Point, len(keys))
+	for i, k := range keys {
+		m := c.buckets[k]
+		out[i] = Point{Series: k.series, Time: m.start, Value: m.sum / float64(m.n)}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePolicies(t *testing.T) {
+	got, err := ParsePolicies("*.batt:720h:24h, *:720h:1h:8760h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Policy{
+		{Series: "*.batt", After: 720 * time.Hour, Interval: 24 * time.Hour},
+		{Series: "*", After: 720 * time.Hour, Interval: time.Hour, Drop: 8760 * time.Hour},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("want %+v got %+v", want, got)
+	}
+
+	for _, bad := range []string{"*:720h", "*:720h:0s", "[:1h:1h", "*:1d:1h"} {
+		if _, err := ParsePolicies(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	policies := []Policy{
+		{Series: "*.batt", After: 24 * time.Hour, Interval: time.Hour, Drop: 48 * time.Hour},
+		{Series: "*.temp", After: 24 * time.Hour, Interval: time.Hour},
+		{Series: "*.rssi", After: 48 * time.Hour, Interval: time.Hour, Drop: 24 * time.Hour},
+	}
+
+	stores := map[string]func(t *testing.T) Store{
+		"Log":    func(t *testing.T) Store { return NewLog(filepath.Join(t.TempDir(), "telemetry.jsonl")) },
+		"Memory": func(t *testing.T) Store { return NewMemory() },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			// Every 5 minutes for 3 days
+			for ts := now.Add(-72 * time.Hour); ts.Before(now); ts = ts.Add(5 * time.Minute) {
+				s.Put(
+					Point{Series: "A.temp", Time: ts, Value: 20},
+					Point{Series: "A.batt", Time: ts, Value: 3},
+					Point{Series: "A.power", Time: ts, Value: 100},
+					Point{Series: "A.rssi", Time: ts, Value: -60},
+				)
+			}
+			for range 2 { // Compaction must be idempotent
+				if err := s.(Compactor).Compact(now, policies); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				series string
+				want   int
+			}{
+				{series: "A.temp", want: 48 + 24*12}, // Hourly for 2 days, then every 5 minutes
+				{series: "A.batt", want: 24 + 24*12}, // Oldest day dropped
+				{series: "A.power", want: 72 * 12},   // No policy
+				{series: "A.rssi", want: 24 * 12},    // Dropped before it is old enough to downsample
+			}
+			for _, tt := range tests {
+				ps, err := s.Query(tt.series, time.Time{}, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ps) != tt.want {
+					t.Errorf("%s: want %d points got %d", tt.series, tt.want, len(ps))
+				}
+				for _, p := range ps {
+					if p.Value != ps[0].Value {
+						t.Fatalf("%s: downsampling changed value from %v to %v", tt.series, ps[0].Value, p.Value)
+					}
+				}
+			}
+		})
+	}
+}
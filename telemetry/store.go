@@ -0,0 +1,63 @@
+// Package telemetry records time-series of measurements reported by
+// LightwaveRF devices, such as temperatures and energy use
+package telemetry
+
+import (
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+// Store persists points. Embedders may supply their own implementation, e.g.
+// backed by a database.
+type Store interface {
+	// Put adds points to the store
+	Put(ps ...Point) error
+
+	// Query returns the points in a series from (inclusive) to
+	// (exclusive), oldest first
+	Query(series string, from, to time.Time) ([]Point, error)
+
+	// Series returns the name of every series in the store, sorted
+	Series() ([]string, error)
+}
+
+// Point is a measurement in a series at a point in time
+type Point struct {
+	Series string    `json:"series"` // Serial and quantity, e.g. "24C702.temp"
+	Time   time.Time `json:"time"`
+	Value  float64   `json:"value"`
+}
+
+// HubUptime is the series of the LWL's uptime, in seconds, which falls when
+// it restarts
+const HubUptime = "hub.uptime"
+
+// FromResponse returns the measurements in a message from the LWL, if any
+func FromResponse(r lwl.Response, t time.Time) []Point {
+	point := func(quantity string, v float64) Point {
+		return Point{Series: r.Serial + "." + quantity, Time: t, Value: v}
+	}
+	switch {
+	case r.Fn == "hubCall" && r.Uptime > 0:
+		return []Point{{Series: HubUptime, Time: t, Value: float64(r.Uptime)}}
+	case r.Serial == "":
+		return nil
+	case r.Fn == "statusPush":
+		out := []Point{
+			point("temp", float64(r.CTemp)),
+			point("target", float64(r.CTarg)),
+			point("output", float64(r.Output)),
+		}
+		if r.Batt > 0 {
+			out = append(out, point("batt", float64(r.Batt)))
+		}
+		return out
+	case r.Fn == "meterData":
+		return []Point{
+			point("power", float64(r.CUse)),
+			point("today", float64(r.TodUse)),
+		}
+	}
+	return nil
+}
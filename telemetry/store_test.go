@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/meermanr/LightwaveRF-go/lwl"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"Log":    func(t *testing.T) Store { return NewLog(filepath.Join(t.TempDir(), "telemetry.jsonl")) },
+		"Memory": func(t *testing.T) Store { return NewMemory() },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testStore(t, newStore(t))
+		})
+	}
+}
+
+func testStore(t *testing.T, l Store) {
+	t0 := time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC)
+	push := lwl.Response{Fn: "statusPush", Serial: "24C702", CTemp: 19.5, CTarg: 21, Output: 40, Batt: 2.9}
+	meter := lwl.Response{Fn: "meterData", Serial: "ABC123", CUse: 276, TodUse: 324}
+	hub := lwl.Response{Fn: "hubCall", Uptime: 2790197}
+	if err := l.Put(FromResponse(meter, t0.Add(time.Minute))...); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(FromResponse(hub, t0)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(FromResponse(push, t0)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(FromResponse(push, t0.Add(time.Hour))...); err != nil {
+		t.Fatal(err)
+	}
+
+	series, err := l.Series()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"24C702.batt", "24C702.output", "24C702.target", "24C702.temp", "ABC123.power", "ABC123.today", "hub.uptime"}
+	if !slices.Equal(series, want) {
+		t.Fatalf("want %v got %v", want, series)
+	}
+
+	ps, err := l.Query("24C702.temp", t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 1 || ps[0].Value != 19.5 || !ps[0].Time.Equal(t0) {
+		t.Fatalf("want one point of 19.5 at %v, got %v", t0, ps)
+	}
+}
@@ -0,0 +1,51 @@
+// Package timeofday handles times of day, as used by schedules, tariffs,
+// rules and quiet hours, represented as offsets from midnight
+package timeofday
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Parse parses a time of day, e.g. "07:30", into an offset from midnight.
+// "24:00" is the end of the day.
+func Parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, should look like 07:30", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// SinceMidnight returns the offset of t from the start of its day, in its
+// own location
+func SinceMidnight(t time.Time) time.Duration {
+	y, m, d := t.Date()
+	return t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+}
+
+// Next returns the first time after now at the given offset from midnight,
+// in now's location. It counts wall clock time, so is not thrown by daylight
+// saving changes.
+func Next(now time.Time, offset time.Duration) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, 0, 0, 0, int(offset), now.Location())
+	if !next.After(now) {
+		next = time.Date(y, m, d+1, 0, 0, 0, int(offset), now.Location())
+	}
+	return next
+}
+
+// Within reports whether offset falls in the period start-end, which spans
+// midnight if start is after end
+func Within(offset, start, end time.Duration) bool {
+	if start <= end {
+		return offset >= start && offset < end
+	}
+	return offset >= start || offset < end
+}
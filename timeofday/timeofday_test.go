@@ -0,0 +1,72 @@
+package timeofday
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"00:00":   0,
+		" 07:30 ": 7*time.Hour + 30*time.Minute,
+		"24:00":   24 * time.Hour,
+	} {
+		if got, err := Parse(s); err != nil || got != want {
+			t.Errorf("%q: want %v got %v, %v", s, want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "7", "25:00", "dusk"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestSinceMidnight(t *testing.T) {
+	// Across the clocks going forward, the offset is elapsed time
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := SinceMidnight(time.Date(2026, 3, 29, 3, 0, 0, 0, london)); got != 2*time.Hour {
+		t.Errorf("want 2h got %v", got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := 3 * time.Hour
+	for _, tt := range []struct {
+		now, want time.Time
+	}{
+		{time.Date(2026, 7, 1, 1, 0, 0, 0, london), time.Date(2026, 7, 1, 3, 0, 0, 0, london)},
+		{time.Date(2026, 7, 1, 3, 0, 0, 0, london), time.Date(2026, 7, 2, 3, 0, 0, 0, london)},
+		{time.Date(2026, 7, 31, 9, 0, 0, 0, london), time.Date(2026, 8, 1, 3, 0, 0, 0, london)},
+		{time.Date(2026, 10, 24, 12, 0, 0, 0, london), time.Date(2026, 10, 25, 3, 0, 0, 0, london)}, // Clocks go back
+	} {
+		if got := Next(tt.now, at); !got.Equal(tt.want) {
+			t.Errorf("%v: want %v got %v", tt.now, tt.want, got)
+		}
+	}
+}
+
+func TestWithin(t *testing.T) {
+	h := time.Hour
+	for _, tt := range []struct {
+		offset, start, end time.Duration
+		want               bool
+	}{
+		{12 * h, 9 * h, 17 * h, true},
+		{17 * h, 9 * h, 17 * h, false},
+		{23 * h, 22 * h, 7 * h, true},
+		{6 * h, 22 * h, 7 * h, true},
+		{12 * h, 22 * h, 7 * h, false},
+	} {
+		if got := Within(tt.offset, tt.start, tt.end); got != tt.want {
+			t.Errorf("%v in %v-%v: want %v", tt.offset, tt.start, tt.end, tt.want)
+		}
+	}
+}